@@ -0,0 +1,109 @@
+package rack
+
+import (
+	"encoding/base64"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Attachment writes the body to the response as a file attachment
+// Binary content is base64 encoded and marked as such in the response.
+func Attachment(c Context, name, contentType string, body []byte) error {
+	b := body
+	encode := !textual(contentType) || !utf8.Valid(body)
+	if encode {
+		b = make([]byte, base64.StdEncoding.EncodedLen(len(body)))
+		base64.StdEncoding.Encode(b, body)
+	}
+
+	ok, err := blob(c, http.StatusOK, contentType, b)
+	if !ok {
+		return err
+	}
+
+	r := c.Response()
+	r.IsBase64Encoded = encode
+	r.Headers.Set("Content-Disposition", contentDisposition(name))
+
+	return nil
+}
+
+// contentDisposition returns an attachment content disposition for the filename
+// Non-ASCII filenames are written using rfc 5987 encoding, with an ASCII fallback
+// for clients that do not support the extended parameter.
+func contentDisposition(name string) string {
+	if isASCII(name) {
+		return mime.FormatMediaType("attachment", map[string]string{"filename": name})
+	}
+
+	fallback := strings.Map(func(r rune) rune {
+		if r >= utf8.RuneSelf || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+
+	return `attachment; filename="` + fallback + `"; filename*=UTF-8''` + encodeExtValue(name)
+}
+
+// encodeExtValue percent-encodes all bytes that are not rfc 5987 attr-chars
+func encodeExtValue(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		if isAttrChar(b) {
+			sb.WriteByte(b)
+			continue
+		}
+
+		sb.WriteByte('%')
+		sb.WriteByte(hex[b>>4])
+		sb.WriteByte(hex[b&0x0f])
+	}
+
+	return sb.String()
+}
+
+func isAttrChar(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	}
+
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
+
+func textual(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mt, "text/"),
+		strings.HasSuffix(mt, "+json"),
+		strings.HasSuffix(mt, "+xml"):
+		return true
+	}
+
+	switch mt {
+	case "application/json", "application/xml", "application/javascript", "application/x-www-form-urlencoded":
+		return true
+	}
+
+	return false
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,85 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestAttachment(t *testing.T) {
+	tests := []struct {
+		name        string
+		filename    string
+		contentType string
+		body        []byte
+		exp         *events.APIGatewayV2HTTPResponse
+	}{
+		{
+			name:        "should write text attachments",
+			filename:    "report.txt",
+			contentType: "text/plain",
+			body:        []byte("value"),
+			exp: &events.APIGatewayV2HTTPResponse{
+				StatusCode: http.StatusOK,
+				Headers: map[string]string{
+					"Content-Type":        "text/plain",
+					"Content-Disposition": "attachment; filename=report.txt",
+				},
+				Body: "value",
+			},
+		},
+		{
+			name:        "should encode binary attachments",
+			filename:    "image.png",
+			contentType: "image/png",
+			body:        []byte{0x89, 0x50, 0x4e, 0x47},
+			exp: &events.APIGatewayV2HTTPResponse{
+				StatusCode: http.StatusOK,
+				Headers: map[string]string{
+					"Content-Type":        "image/png",
+					"Content-Disposition": "attachment; filename=image.png",
+				},
+				Body:            "iVBORw==",
+				IsBase64Encoded: true,
+			},
+		},
+		{
+			name:        "should encode non-ascii filenames",
+			filename:    "résumé 1.pdf",
+			contentType: "application/pdf",
+			body:        []byte("%PDF"),
+			exp: &events.APIGatewayV2HTTPResponse{
+				StatusCode: http.StatusOK,
+				Headers: map[string]string{
+					"Content-Type":        "application/pdf",
+					"Content-Disposition": `attachment; filename="r_sum_ 1.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9%201.pdf`,
+				},
+				Body:            "JVBERg==",
+				IsBase64Encoded: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				return rack.Attachment(c, tt.filename, tt.contentType, tt.body)
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if act.StatusCode != tt.exp.StatusCode || act.Body != tt.exp.Body || act.IsBase64Encoded != tt.exp.IsBase64Encoded {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+			assertDeepEqual(t, act.Headers, tt.exp.Headers)
+		})
+	}
+}
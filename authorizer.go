@@ -0,0 +1,357 @@
+package rack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tidwall/gjson"
+)
+
+// SimpleAuthorizerResponse represents an http api lambda authorizer simple response
+// The context values are passed through to the integration, and can be read by
+// downstream handlers using AuthorizerContext.
+type SimpleAuthorizerResponse struct {
+	IsAuthorized bool                   `json:"isAuthorized"`
+	Context      map[string]interface{} `json:"context,omitempty"`
+}
+
+// AuthorizerPolicy represents a custom authorizer iam policy response builder
+// The policy can be written using Context.JSON, and marshals to the response
+// format expected by API Gateway.
+type AuthorizerPolicy struct {
+	principalID        string
+	statements         []events.IAMPolicyStatement
+	context            map[string]interface{}
+	usageIdentifierKey string
+}
+
+// APIGatewayCustomAuthorizerEventProcessor is an api gateway custom authorizer event processor
+// Both TOKEN and REQUEST authorizer events are supported. TOKEN authorizer tokens are
+// exposed as the request Authorization header. The handler is expected to write an
+// AuthorizerPolicy response. An http.StatusUnauthorized response is returned as the
+// "Unauthorized" error, which API Gateway maps to a 401 response.
+var APIGatewayCustomAuthorizerEventProcessor Processor = &processor{
+	name: "apigw-authorizer",
+	canProcess: func(payload []byte) bool {
+		pv := gjson.GetManyBytes(payload, "type", "methodArn")
+		return (pv[0].String() == "TOKEN" || pv[0].String() == "REQUEST") && pv[1].Exists()
+	},
+	unmarshalRequest: func(payload []byte) (*Request, error) {
+		if gjson.GetBytes(payload, "type").String() == "TOKEN" {
+			e := new(events.APIGatewayCustomAuthorizerRequest)
+			if err := json.Unmarshal(payload, e); err != nil {
+				return nil, err
+			}
+
+			h := http.Header{}
+			if e.AuthorizationToken != "" {
+				h.Set("Authorization", e.AuthorizationToken)
+			}
+
+			return &Request{
+				Path:   map[string]string{},
+				Query:  url.Values{},
+				Header: h,
+				Event:  e,
+			}, nil
+		}
+
+		e := new(events.APIGatewayCustomAuthorizerRequestTypeRequest)
+		if err := json.Unmarshal(payload, e); err != nil {
+			return nil, err
+		}
+
+		q := url.Values{}
+		mergeMaps(nil, e.MultiValueQueryStringParameters, q.Add)
+
+		h := http.Header{}
+		mergeMaps(nil, e.MultiValueHeaders, h.Add)
+
+		return (&Request{
+			Method:   e.HTTPMethod,
+			RawPath:  e.Path,
+			Path:     pathParameters(e.PathParameters),
+			Query:    q,
+			Header:   h,
+			SourceIP: e.RequestContext.Identity.SourceIP,
+			Stage:    e.RequestContext.Stage,
+			Event:    e,
+		}).normalize(), nil
+	},
+	marshalResponse: func(r *Response) ([]byte, error) {
+		return authorizerResponse(r)
+	},
+}
+
+// APIGatewayV2AuthorizerRequest represents an http api lambda authorizer request
+// The version 2.0 payload is an http api request with additional authorizer fields.
+type APIGatewayV2AuthorizerRequest struct {
+	events.APIGatewayV2HTTPRequest
+	Type           string   `json:"type"`
+	RouteArn       string   `json:"routeArn"`
+	IdentitySource []string `json:"identitySource"`
+}
+
+// APIGatewayV2AuthorizerEventProcessor is an http api lambda authorizer event processor
+// Version 2.0 payloads are supported. The handler is expected to write a
+// SimpleAuthorizerResponse, or an AuthorizerPolicy if simple responses are not
+// enabled. An http.StatusForbidden response is returned as an unauthorized simple
+// response, while an http.StatusUnauthorized response is returned as the "Unauthorized" error.
+var APIGatewayV2AuthorizerEventProcessor Processor = &processor{
+	name: "apigw-v2-authorizer",
+	canProcess: func(payload []byte) bool {
+		pv := gjson.GetManyBytes(payload, "version", "type", "routeArn")
+		return pv[0].String() == "2.0" && pv[1].String() == "REQUEST" && pv[2].Exists()
+	},
+	unmarshalRequest: func(payload []byte) (*Request, error) {
+		e := new(APIGatewayV2AuthorizerRequest)
+		if err := json.Unmarshal(payload, e); err != nil {
+			return nil, err
+		}
+
+		return newV2HTTPRequest(&e.APIGatewayV2HTTPRequest, e), nil
+	},
+	marshalResponse: func(r *Response) ([]byte, error) {
+		if r.StatusCode == http.StatusForbidden {
+			return json.Marshal(&SimpleAuthorizerResponse{IsAuthorized: false})
+		}
+
+		return authorizerResponse(r)
+	},
+}
+
+// ErrNoAuthorizerContext indicates that the request does not contain a lambda authorizer context
+var ErrNoAuthorizerContext = errors.New("authorizer context not specified")
+
+// AuthorizerContext returns the lambda authorizer context for the request
+// Nil is returned if the request was not authorized by a lambda authorizer. REST
+// API authorizer context values are always strings, while http api simple
+// response context values retain their types.
+func AuthorizerContext(c Context) map[string]interface{} {
+	switch e := c.Request().Event.(type) {
+	case *events.APIGatewayProxyRequest:
+		return e.RequestContext.Authorizer
+	case *events.APIGatewayV2HTTPRequest:
+		if e.RequestContext.Authorizer != nil {
+			return e.RequestContext.Authorizer.Lambda
+		}
+	}
+
+	return nil
+}
+
+// BindAuthorizerContext unmarshals the lambda authorizer context into the specified value
+// The context is mapped using its JSON representation, so struct tags are honoured.
+func BindAuthorizerContext(c Context, v interface{}) error {
+	ac := AuthorizerContext(c)
+	if ac == nil {
+		return ErrNoAuthorizerContext
+	}
+
+	b, err := json.Marshal(ac)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+// MethodARN returns the method arn for custom authorizer requests
+// The route arn is returned for http api authorizer requests. An empty string is
+// returned for all other event types.
+func MethodARN(c Context) string {
+	switch e := c.Request().Event.(type) {
+	case *events.APIGatewayCustomAuthorizerRequest:
+		return e.MethodArn
+	case *events.APIGatewayCustomAuthorizerRequestTypeRequest:
+		return e.MethodArn
+	case *APIGatewayV2AuthorizerRequest:
+		return e.RouteArn
+	}
+
+	return ""
+}
+
+// Allow returns a new policy that allows invocation of the specified resources
+func Allow(resources ...string) *AuthorizerPolicy {
+	return new(AuthorizerPolicy).Allow(resources...)
+}
+
+// AllowAll returns a new policy that allows invocation of all resources
+// Allowing all resources ensures that cached policies apply to every method.
+func AllowAll() *AuthorizerPolicy {
+	return Allow("*")
+}
+
+// Deny returns a new policy that denies invocation of the specified resources
+func Deny(resources ...string) *AuthorizerPolicy {
+	return new(AuthorizerPolicy).Deny(resources...)
+}
+
+// DenyAll returns a new policy that denies invocation of all resources
+func DenyAll() *AuthorizerPolicy {
+	return Deny("*")
+}
+
+// Allow adds a statement allowing invocation of the specified resources
+func (p *AuthorizerPolicy) Allow(resources ...string) *AuthorizerPolicy {
+	return p.statement("Allow", resources)
+}
+
+// Deny adds a statement denying invocation of the specified resources
+func (p *AuthorizerPolicy) Deny(resources ...string) *AuthorizerPolicy {
+	return p.statement("Deny", resources)
+}
+
+// WithPrincipal sets the policy principal id
+func (p *AuthorizerPolicy) WithPrincipal(id string) *AuthorizerPolicy {
+	p.principalID = id
+	return p
+}
+
+// WithContext adds the specified value to the authorizer context
+// REST API context values must be strings, numbers or booleans. The values are
+// available to downstream handlers using AuthorizerContext.
+func (p *AuthorizerPolicy) WithContext(key string, v interface{}) *AuthorizerPolicy {
+	if p.context == nil {
+		p.context = map[string]interface{}{}
+	}
+
+	p.context[key] = v
+	return p
+}
+
+// WithUsageIdentifierKey sets the usage plan api key
+func (p *AuthorizerPolicy) WithUsageIdentifierKey(k string) *AuthorizerPolicy {
+	p.usageIdentifierKey = k
+	return p
+}
+
+// Response returns the custom authorizer response for the policy
+func (p *AuthorizerPolicy) Response() events.APIGatewayCustomAuthorizerResponse {
+	return events.APIGatewayCustomAuthorizerResponse{
+		PrincipalID: p.principalID,
+		PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
+			Version:   "2012-10-17",
+			Statement: p.statements,
+		},
+		Context:            p.context,
+		UsageIdentifierKey: p.usageIdentifierKey,
+	}
+}
+
+// MarshalJSON marshals the policy as a custom authorizer response
+func (p *AuthorizerPolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Response())
+}
+
+func (p *AuthorizerPolicy) statement(effect string, resources []string) *AuthorizerPolicy {
+	p.statements = append(p.statements, events.IAMPolicyStatement{
+		Action:   []string{"execute-api:Invoke"},
+		Effect:   effect,
+		Resource: resources,
+	})
+
+	return p
+}
+
+// authorizerResponse returns the authorizer response body
+// Authorizers cannot return http responses, so error status codes are returned as
+// errors. API Gateway requires the exact "Unauthorized" message to return a 401.
+func authorizerResponse(r *Response) ([]byte, error) {
+	switch {
+	case r.StatusCode == http.StatusUnauthorized:
+		return nil, errors.New("Unauthorized")
+	case r.StatusCode >= http.StatusBadRequest:
+		return nil, fmt.Errorf("authorizer: %s", http.StatusText(r.StatusCode))
+	case r.Body == "":
+		return nil, errors.New("authorizer: empty response")
+	}
+
+	return []byte(r.Body), nil
+}
+
+// AuthorizerCacheOptions represents authorizer decision cache options
+type AuthorizerCacheOptions struct {
+	// Cache is the decision cache, defaulting to a container-scoped memory cache
+	Cache Cache
+
+	// Key returns the cache key for the request, typically the token or identity
+	// Requests with an empty key are not cached. Keys are hashed before they are
+	// written to the cache, so raw tokens are not stored.
+	Key func(Context) string
+
+	// TTL is the decision lifetime, defaulting to five minutes
+	TTL time.Duration
+
+	// ShareAcrossMethods excludes the method or route arn from the cache key
+	// This should only be set if the handler writes policies that apply to all
+	// methods, such as wildcard resources, as decisions are otherwise replayed for
+	// methods that they do not allow.
+	ShareAcrossMethods bool
+}
+
+const defaultAuthorizerCacheTTL = 5 * time.Minute
+
+// CacheAuthorizer returns a middleware func that caches authorizer decisions
+// Responses written by the authorizer handler are cached by key and method arn, and
+// replayed for subsequent requests without invoking the handler, as policies are
+// typically built for the requested method. Handler errors and empty responses are not cached.
+// The func panics if no key func is specified.
+func CacheAuthorizer(o AuthorizerCacheOptions) MiddlewareFunc {
+	if o.Key == nil {
+		panic("rack: cache authorizer requires a key func")
+	}
+
+	cache := o.Cache
+	if cache == nil {
+		cache = NewMemoryCache()
+	}
+
+	ttl := o.TTL
+	if ttl <= 0 {
+		ttl = defaultAuthorizerCacheTTL
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			k := o.Key(c)
+			if k == "" {
+				return n(c)
+			}
+
+			if !o.ShareAcrossMethods {
+				k = MethodARN(c) + "\x00" + k
+			}
+
+			h := sha256.Sum256([]byte(k))
+			k = hex.EncodeToString(h[:])
+
+			b, ok, err := cache.Get(c.Context(), k)
+			if err != nil {
+				return err
+			}
+
+			if ok {
+				return replayResponse(c, b)
+			}
+
+			if err = n(c); err != nil || !c.Response().Committed() {
+				return err
+			}
+
+			if b, err = marshalCachedResponse(c.Response()); err != nil {
+				return err
+			}
+
+			return cache.Set(c.Context(), k, b, ttl)
+		}
+	}
+}
@@ -0,0 +1,581 @@
+package rack_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestBindAuthorizerContext(t *testing.T) {
+	type claims struct {
+		UserID string `json:"userId"`
+		Admin  bool   `json:"admin"`
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     claims
+		err     bool
+	}{
+		{
+			name:    "should return an error if there is no authorizer context",
+			payload: newV2Request(nil),
+			err:     true,
+		},
+		{
+			name: "should bind v2 lambda authorizer context",
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.Authorizer = &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+					Lambda: map[string]interface{}{"userId": "user", "admin": true},
+				}
+			}),
+			exp: claims{UserID: "user", Admin: true},
+		},
+		{
+			name: "should bind proxy authorizer context",
+			payload: marshal(&events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{
+					APIID:      "apiid",
+					Authorizer: map[string]interface{}{"userId": "user"},
+				},
+			}),
+			exp: claims{UserID: "user"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				var act claims
+				err := rack.BindAuthorizerContext(c, &act)
+
+				assertErrorExists(t, err, tt.err)
+				assertDeepEqual(t, act, tt.exp)
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), tt.payload)
+			assertErrorExists(t, err, false)
+		})
+	}
+}
+
+func TestCacheAuthorizer(t *testing.T) {
+	var calls int
+
+	h := rack.NewWithConfig(rack.Config{
+		Middleware: rack.CacheAuthorizer(rack.AuthorizerCacheOptions{
+			Key: func(c rack.Context) string {
+				return c.Request().Header.Get("Authorization")
+			},
+			TTL: time.Minute,
+		}),
+	}, func(c rack.Context) error {
+		calls++
+
+		token := c.Request().Header.Get("Authorization")
+		if token == "error" {
+			return errors.New("error")
+		}
+
+		c.Response().Headers.Set("X-Token", token)
+		return c.JSON(http.StatusOK, &rack.SimpleAuthorizerResponse{
+			IsAuthorized: token == "valid",
+		})
+	})
+
+	tests := []struct {
+		name  string
+		token string
+		calls int
+		exp   string
+	}{
+		{
+			name:  "should not cache requests without a key",
+			calls: 1,
+			exp:   `{"isAuthorized":false}`,
+		},
+		{
+			name:  "should invoke the handler on cache miss",
+			token: "valid",
+			calls: 2,
+			exp:   `{"isAuthorized":true}`,
+		},
+		{
+			name:  "should replay cached decisions",
+			token: "valid",
+			calls: 2,
+			exp:   `{"isAuthorized":true}`,
+		},
+		{
+			name:  "should cache deny decisions",
+			token: "invalid",
+			calls: 3,
+			exp:   `{"isAuthorized":false}`,
+		},
+		{
+			name:  "should not cache errors",
+			token: "error",
+			calls: 4,
+			exp:   `{"message":"error"}`,
+		},
+		{
+			name:  "should invoke the handler after errors",
+			token: "error",
+			calls: 5,
+			exp:   `{"message":"error"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				if tt.token != "" {
+					r.Headers = map[string]string{"authorization": tt.token}
+				}
+			}))
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if act.Body != tt.exp {
+				t.Errorf("got %s, expected %s", act.Body, tt.exp)
+			}
+			if tt.token != "error" && act.Headers["X-Token"] != tt.token {
+				t.Errorf("got %s, expected %s", act.Headers["X-Token"], tt.token)
+			}
+			if calls != tt.calls {
+				t.Errorf("got %d, expected %d", calls, tt.calls)
+			}
+		})
+	}
+
+	t.Run("should hash keys and apply the default ttl", func(t *testing.T) {
+		cache := &keyCache{Cache: rack.NewMemoryCache()}
+
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.CacheAuthorizer(rack.AuthorizerCacheOptions{
+				Cache: cache,
+				Key: func(c rack.Context) string {
+					return c.Request().Header.Get("Authorization")
+				},
+			}),
+		}, func(c rack.Context) error {
+			return c.JSON(http.StatusOK, &rack.SimpleAuthorizerResponse{IsAuthorized: true})
+		})
+
+		_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.Headers = map[string]string{"authorization": "token"}
+		}))
+		assertErrorExists(t, err, false)
+
+		exp := sha256.Sum256([]byte("\x00token"))
+		assertDeepEqual(t, cache.keys, []string{hex.EncodeToString(exp[:])})
+		assertDeepEqual(t, cache.ttls, []time.Duration{5 * time.Minute})
+	})
+
+	t.Run("should include the method arn in the key", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			share bool
+			calls int
+		}{
+			{
+				name:  "should not replay decisions for other methods",
+				calls: 2,
+			},
+			{
+				name:  "should replay decisions for other methods if shared",
+				share: true,
+				calls: 1,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var calls int
+				h := rack.NewWithConfig(rack.Config{
+					Middleware: rack.CacheAuthorizer(rack.AuthorizerCacheOptions{
+						Key: func(c rack.Context) string {
+							return c.Request().Header.Get("Authorization")
+						},
+						ShareAcrossMethods: tt.share,
+					}),
+				}, func(c rack.Context) error {
+					calls++
+					return c.JSON(http.StatusOK, rack.Allow(rack.MethodARN(c)))
+				})
+
+				for _, arn := range []string{"arn:get", "arn:delete"} {
+					payload := []byte(`{"type":"TOKEN","authorizationToken":"token","methodArn":"` + arn + `"}`)
+					_, err := h.Invoke(context.Background(), payload)
+					assertErrorExists(t, err, false)
+				}
+
+				assertDeepEqual(t, calls, tt.calls)
+			})
+		}
+	})
+
+	t.Run("should panic if no key func is specified", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got nil, expected panic")
+			}
+		}()
+
+		rack.CacheAuthorizer(rack.AuthorizerCacheOptions{})
+	})
+}
+
+type keyCache struct {
+	rack.Cache
+	keys []string
+	ttls []time.Duration
+}
+
+func (c *keyCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.keys = append(c.keys, key)
+	c.ttls = append(c.ttls, ttl)
+	return c.Cache.Set(ctx, key, value, ttl)
+}
+
+func TestAPIGatewayCustomAuthorizerEventProcessor_CanProcess(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     bool
+	}{
+		{
+			name:    "should return true for token events",
+			payload: []byte(`{"type":"TOKEN","authorizationToken":"token","methodArn":"arn"}`),
+			exp:     true,
+		},
+		{
+			name:    "should return true for request events",
+			payload: []byte(`{"type":"REQUEST","methodArn":"arn","requestContext":{"apiId":"id"}}`),
+			exp:     true,
+		},
+		{
+			name:    "should return false for proxy events",
+			payload: []byte(`{"httpMethod":"GET","requestContext":{"apiId":"id"}}`),
+		},
+		{
+			name:    "should return false for other types",
+			payload: []byte(`{"type":"OTHER","methodArn":"arn"}`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act := rack.APIGatewayCustomAuthorizerEventProcessor.CanProcess(tt.payload)
+			if act != tt.exp {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestAPIGatewayCustomAuthorizerEventProcessor_UnmarshalRequest(t *testing.T) {
+	tokenPayload := []byte(`{"type":"TOKEN","authorizationToken":"Bearer token","methodArn":"arn"}`)
+	requestPayload := []byte(`{
+		"type": "REQUEST",
+		"methodArn": "arn",
+		"path": "/resource",
+		"httpMethod": "GET",
+		"multiValueHeaders": {"authorization": ["Bearer token"]},
+		"multiValueQueryStringParameters": {"q": ["v1", "v2"]},
+		"pathParameters": {"id": "abc"}
+	}`)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     *rack.Request
+		err     bool
+	}{
+		{
+			name:    "should return an error if the payload is invalid",
+			payload: []byte(`{"type":"REQUEST",`),
+			err:     true,
+		},
+		{
+			name:    "should return token requests",
+			payload: tokenPayload,
+			exp: &rack.Request{
+				Path:   map[string]string{},
+				Query:  url.Values{},
+				Header: http.Header{"Authorization": {"Bearer token"}},
+				Event:  unmarshal(tokenPayload, new(events.APIGatewayCustomAuthorizerRequest)),
+			},
+		},
+		{
+			name:    "should return request requests",
+			payload: requestPayload,
+			exp: &rack.Request{
+				Method:   http.MethodGet,
+				RawPath:  "/resource",
+				RawQuery: "q=v1&q=v2",
+				Path:     map[string]string{"id": "abc"},
+				Query:    url.Values{"q": {"v1", "v2"}},
+				Header:   http.Header{"Authorization": {"Bearer token"}},
+				Event:    unmarshal(requestPayload, new(events.APIGatewayCustomAuthorizerRequestTypeRequest)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := rack.APIGatewayCustomAuthorizerEventProcessor.UnmarshalRequest(tt.payload)
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestAuthorizerPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *rack.AuthorizerPolicy
+		exp    events.APIGatewayCustomAuthorizerResponse
+	}{
+		{
+			name:   "should allow all resources",
+			policy: rack.AllowAll().WithPrincipal("user"),
+			exp: events.APIGatewayCustomAuthorizerResponse{
+				PrincipalID: "user",
+				PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
+					Version: "2012-10-17",
+					Statement: []events.IAMPolicyStatement{
+						{Action: []string{"execute-api:Invoke"}, Effect: "Allow", Resource: []string{"*"}},
+					},
+				},
+			},
+		},
+		{
+			name:   "should deny all resources",
+			policy: rack.DenyAll(),
+			exp: events.APIGatewayCustomAuthorizerResponse{
+				PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
+					Version: "2012-10-17",
+					Statement: []events.IAMPolicyStatement{
+						{Action: []string{"execute-api:Invoke"}, Effect: "Deny", Resource: []string{"*"}},
+					},
+				},
+			},
+		},
+		{
+			name: "should combine statements",
+			policy: rack.Allow("arn1", "arn2").
+				Deny("arn3").
+				WithPrincipal("user").
+				WithContext("userId", "abc").
+				WithContext("admin", true).
+				WithUsageIdentifierKey("key"),
+			exp: events.APIGatewayCustomAuthorizerResponse{
+				PrincipalID: "user",
+				PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
+					Version: "2012-10-17",
+					Statement: []events.IAMPolicyStatement{
+						{Action: []string{"execute-api:Invoke"}, Effect: "Allow", Resource: []string{"arn1", "arn2"}},
+						{Action: []string{"execute-api:Invoke"}, Effect: "Deny", Resource: []string{"arn3"}},
+					},
+				},
+				Context:            map[string]interface{}{"userId": "abc", "admin": true},
+				UsageIdentifierKey: "key",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertDeepEqual(t, tt.policy.Response(), tt.exp)
+
+			b, err := json.Marshal(tt.policy)
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, string(b), string(marshal(tt.exp)))
+		})
+	}
+}
+
+func TestAuthorizerHandler(t *testing.T) {
+	payload := []byte(`{"type":"TOKEN","authorizationToken":"token","methodArn":"arn:aws:execute-api:eu-west-1:123:api/prod/GET/"}`)
+
+	tests := []struct {
+		name    string
+		handler rack.HandlerFunc
+		exp     []byte
+		err     error
+	}{
+		{
+			name: "should return the policy",
+			handler: func(c rack.Context) error {
+				return c.JSON(http.StatusOK, rack.Allow(rack.MethodARN(c)).WithPrincipal(c.Request().HeaderValue("Authorization")))
+			},
+			exp: marshal(rack.Allow("arn:aws:execute-api:eu-west-1:123:api/prod/GET/").WithPrincipal("token")),
+		},
+		{
+			name: "should return unauthorized errors",
+			handler: func(c rack.Context) error {
+				return rack.WrapError(http.StatusUnauthorized, errors.New("invalid token"))
+			},
+			err: errors.New("Unauthorized"),
+		},
+		{
+			name: "should return status errors",
+			handler: func(c rack.Context) error {
+				return errors.New("error")
+			},
+			err: errors.New("authorizer: Internal Server Error"),
+		},
+		{
+			name: "should return an error for empty responses",
+			handler: func(c rack.Context) error {
+				return nil
+			},
+			err: errors.New("authorizer: empty response"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := rack.New(tt.handler).Invoke(context.Background(), payload)
+			assertDeepEqual(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestAPIGatewayV2AuthorizerEventProcessor_CanProcess(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     bool
+	}{
+		{
+			name:    "should return true for authorizer events",
+			payload: []byte(`{"version":"2.0","type":"REQUEST","routeArn":"arn","requestContext":{"apiId":"id"}}`),
+			exp:     true,
+		},
+		{
+			name:    "should return false for http events",
+			payload: newV2Request(nil),
+		},
+		{
+			name:    "should return false for version 1.0 events",
+			payload: []byte(`{"version":"1.0","type":"REQUEST","routeArn":"arn"}`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act := rack.APIGatewayV2AuthorizerEventProcessor.CanProcess(tt.payload)
+			if act != tt.exp {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestAPIGatewayV2AuthorizerEventProcessor_UnmarshalRequest(t *testing.T) {
+	payload := []byte(`{
+		"version": "2.0",
+		"type": "REQUEST",
+		"routeArn": "arn",
+		"identitySource": ["Bearer token"],
+		"headers": {"authorization": "Bearer token"},
+		"queryStringParameters": {"q": "v1,v2"},
+		"pathParameters": {"id": "abc"},
+		"requestContext": {"apiId": "id", "http": {"method": "GET", "path": "/resource"}}
+	}`)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     *rack.Request
+		err     bool
+	}{
+		{
+			name:    "should return an error if the payload is invalid",
+			payload: []byte(`{"version":"2.0",`),
+			err:     true,
+		},
+		{
+			name:    "should return the request",
+			payload: payload,
+			exp: &rack.Request{
+				Method:   http.MethodGet,
+				RawPath:  "/resource",
+				RawQuery: "q=v1&q=v2",
+				Path:     map[string]string{"id": "abc"},
+				Query:    url.Values{"q": {"v1", "v2"}},
+				Header:   http.Header{"Authorization": {"Bearer token"}},
+				Event:    unmarshal(payload, new(rack.APIGatewayV2AuthorizerRequest)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := rack.APIGatewayV2AuthorizerEventProcessor.UnmarshalRequest(tt.payload)
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestV2AuthorizerHandler(t *testing.T) {
+	payload := []byte(`{"version":"2.0","type":"REQUEST","routeArn":"arn","headers":{"authorization":"token"},"requestContext":{"apiId":"id"}}`)
+
+	tests := []struct {
+		name    string
+		handler rack.HandlerFunc
+		exp     []byte
+		err     error
+	}{
+		{
+			name: "should return the response",
+			handler: func(c rack.Context) error {
+				return c.JSON(http.StatusOK, &rack.SimpleAuthorizerResponse{
+					IsAuthorized: rack.MethodARN(c) == "arn",
+					Context:      map[string]interface{}{"token": c.Request().HeaderValue("Authorization")},
+				})
+			},
+			exp: []byte(`{"isAuthorized":true,"context":{"token":"token"}}`),
+		},
+		{
+			name: "should return unauthorized responses for forbidden errors",
+			handler: func(c rack.Context) error {
+				return rack.WrapError(http.StatusForbidden, errors.New("forbidden"))
+			},
+			exp: []byte(`{"isAuthorized":false}`),
+		},
+		{
+			name: "should return unauthorized errors",
+			handler: func(c rack.Context) error {
+				return rack.WrapError(http.StatusUnauthorized, errors.New("invalid token"))
+			},
+			err: errors.New("Unauthorized"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := rack.New(tt.handler).Invoke(context.Background(), payload)
+			assertDeepEqual(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package rack
+
+import (
+	"encoding/base64"
+	"mime"
+	"strings"
+)
+
+// newBinaryEncoder returns a func that base64 encodes responses with binary content types
+// Types are matched against the response media type, and may contain a wildcard
+// subtype, such as image/*. Responses that are already encoded are unchanged.
+func newBinaryEncoder(types []string) func(*Response) {
+	return func(r *Response) {
+		if r.IsBase64Encoded || r.Body == "" {
+			return
+		}
+
+		mt, _, err := mime.ParseMediaType(r.Headers.Get("Content-Type"))
+		if err != nil || !matchMediaType(types, mt) {
+			return
+		}
+
+		r.Body = base64.StdEncoding.EncodeToString([]byte(r.Body))
+		r.IsBase64Encoded = true
+	}
+}
+
+func matchMediaType(types []string, mt string) bool {
+	for _, t := range types {
+		t = strings.ToLower(t)
+		switch {
+		case t == "*/*", t == mt:
+			return true
+		case strings.HasSuffix(t, "/*") && strings.HasPrefix(mt, t[:len(t)-1]):
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,89 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestConfig_BinaryContentTypes(t *testing.T) {
+	tests := []struct {
+		name        string
+		types       []string
+		contentType string
+		body        string
+		exp         string
+		encoded     bool
+	}{
+		{
+			name:        "should not encode responses by default",
+			contentType: "image/png",
+			body:        "PNG",
+			exp:         "PNG",
+		},
+		{
+			name:        "should encode matching content types",
+			types:       []string{"application/pdf"},
+			contentType: "application/pdf",
+			body:        "%PDF",
+			exp:         "JVBERg==",
+			encoded:     true,
+		},
+		{
+			name:        "should encode wildcard content types",
+			types:       []string{"image/*"},
+			contentType: "image/png; charset=binary",
+			body:        "\x89PNG",
+			exp:         "iVBORw==",
+			encoded:     true,
+		},
+		{
+			name:        "should not encode other content types",
+			types:       []string{"image/*"},
+			contentType: "text/plain",
+			body:        "text",
+			exp:         "text",
+		},
+		{
+			name:        "should not encode empty bodies",
+			types:       []string{"*/*"},
+			contentType: "image/png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				BinaryContentTypes: tt.types,
+			}, func(c rack.Context) error {
+				return rack.Blob(c, http.StatusOK, tt.contentType, []byte(tt.body))
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			assertDeepEqual(t, res.Body, tt.exp)
+			assertDeepEqual(t, res.IsBase64Encoded, tt.encoded)
+		})
+	}
+
+	t.Run("should not encode encoded responses", func(t *testing.T) {
+		h := rack.NewWithConfig(rack.Config{
+			BinaryContentTypes: []string{"*/*"},
+		}, func(c rack.Context) error {
+			return rack.CBOR(c, http.StatusOK, "value")
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		assertDeepEqual(t, res.Body, "ZXZhbHVl")
+		assertDeepEqual(t, res.IsBase64Encoded, true)
+	})
+}
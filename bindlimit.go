@@ -0,0 +1,106 @@
+package rack
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// BindLimits represents limits applied to request bodies by Bind and BindPatch
+// Limits protect handlers from pathological payloads that consume excessive memory
+// or cpu when decoded. JSON bodies are checked before they are decoded, and CBOR
+// bodies are checked as they are decoded. Zero values disable the corresponding
+// limit.
+type BindLimits struct {
+	// MaxDepth is the maximum object and array nesting depth
+	MaxDepth int
+
+	// MaxArrayLength is the maximum number of elements in each array
+	MaxArrayLength int
+
+	// MaxStringLength is the maximum encoded length of each string, including object keys
+	MaxStringLength int
+}
+
+// ErrBindLimitExceeded indicates that the request body exceeds the configured bind limits
+var ErrBindLimitExceeded = errors.New("bind limit exceeded")
+
+func (l BindLimits) enabled() bool {
+	return l.MaxDepth > 0 || l.MaxArrayLength > 0 || l.MaxStringLength > 0
+}
+
+// checkBindLimits applies the configured limits to the JSON body
+func (c *handlerContext) checkBindLimits(b []byte) error {
+	if !c.bindLimits.enabled() {
+		return nil
+	}
+
+	if err := c.bindLimits.check(b); err != nil {
+		return WrapError(http.StatusBadRequest, err)
+	}
+
+	return nil
+}
+
+// check scans the JSON body and returns a bind error if a limit is exceeded
+// The scan does not validate the body, so syntax errors are returned by the codec.
+func (l BindLimits) check(b []byte) error {
+	// counts holds the element count of each open array, or -1 for objects
+	var counts []int
+	pending := false
+
+	fail := func(i int, format string, n int) error {
+		return newBindErrorAt(b, "", int64(i+1), fmt.Errorf("%w: "+format, ErrBindLimitExceeded, n))
+	}
+
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch c {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+
+		if pending && c != ']' {
+			pending = false
+			counts[len(counts)-1]++
+
+			if l.MaxArrayLength > 0 && counts[len(counts)-1] > l.MaxArrayLength {
+				return fail(i, "array length exceeds %d", l.MaxArrayLength)
+			}
+		}
+
+		switch c {
+		case '"':
+			start := i
+			for i++; i < len(b) && b[i] != '"'; i++ {
+				if b[i] == '\\' {
+					i++
+				}
+			}
+
+			if l.MaxStringLength > 0 && i-start-1 > l.MaxStringLength {
+				return fail(start, "string length exceeds %d", l.MaxStringLength)
+			}
+		case '{', '[':
+			if l.MaxDepth > 0 && len(counts) >= l.MaxDepth {
+				return fail(i, "nesting depth exceeds %d", l.MaxDepth)
+			}
+
+			if c == '[' {
+				counts = append(counts, 0)
+				pending = true
+			} else {
+				counts = append(counts, -1)
+			}
+		case '}', ']':
+			if len(counts) > 0 {
+				counts = counts[:len(counts)-1]
+			}
+			pending = false
+		case ',':
+			pending = len(counts) > 0 && counts[len(counts)-1] >= 0
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package rack_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestConfig_BindLimits(t *testing.T) {
+	limits := rack.BindLimits{MaxDepth: 3, MaxArrayLength: 3, MaxStringLength: 5}
+
+	tests := []struct {
+		name   string
+		limits rack.BindLimits
+		body   string
+		err    bool
+		exp    string
+	}{
+		{
+			name:   "should bind bodies within the limits",
+			limits: limits,
+			body:   `{"a":[1,2,{"b":"abcde"}],"c":[[],[1,2,3]],"d":"a\"b"}`,
+		},
+		{
+			name: "should not apply limits by default",
+			body: `{"a":[[[[1,2,3,4]]]],"b":"abcdefghij"}`,
+		},
+		{
+			name:   "should return an error if the depth is exceeded",
+			limits: limits,
+			body:   `{"a":[{"b":[]}]}`,
+			err:    true,
+			exp:    "invalid json at line 1, column 12: bind limit exceeded: nesting depth exceeds 3",
+		},
+		{
+			name:   "should return an error if an array length is exceeded",
+			limits: limits,
+			body:   `{"a":[1, 2, 3, 4]}`,
+			err:    true,
+			exp:    "invalid json at line 1, column 16: bind limit exceeded: array length exceeds 3",
+		},
+		{
+			name:   "should return an error if a nested array length is exceeded",
+			limits: limits,
+			body:   `[[1,2,3,4]]`,
+			err:    true,
+			exp:    "invalid json at line 1, column 9: bind limit exceeded: array length exceeds 3",
+		},
+		{
+			name:   "should return an error if a string length is exceeded",
+			limits: limits,
+			body:   `{"a":"abcdef"}`,
+			err:    true,
+			exp:    "invalid json at line 1, column 6: bind limit exceeded: string length exceeds 5",
+		},
+		{
+			name:   "should return an error if a key length is exceeded",
+			limits: limits,
+			body:   `{"abcdef":1}`,
+			err:    true,
+			exp:    "invalid json at line 1, column 2: bind limit exceeded: string length exceeds 5",
+		},
+		{
+			name:   "should ignore structural characters in strings",
+			limits: limits,
+			body:   `{"a":"[[[,"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				BindLimits: tt.limits,
+			}, func(c rack.Context) error {
+				var v interface{}
+				err := c.Bind(&v)
+				assertErrorExists(t, err, tt.err)
+
+				if err != nil {
+					assertDeepEqual(t, rack.StatusCode(err), http.StatusBadRequest)
+					assertDeepEqual(t, errors.Is(err, rack.ErrBindLimitExceeded), true)
+
+					var be *rack.BindError
+					if errors.As(err, &be) {
+						assertDeepEqual(t, be.Error(), tt.exp)
+					} else {
+						t.Errorf("got %T, expected *rack.BindError", err)
+					}
+				}
+
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Body = tt.body
+			}))
+			assertErrorExists(t, err, false)
+		})
+	}
+
+	t.Run("should stop scanning at the first violation", func(t *testing.T) {
+		h := rack.NewWithConfig(rack.Config{
+			BindLimits: rack.BindLimits{MaxDepth: 64},
+		}, func(c rack.Context) error {
+			var v interface{}
+			assertErrorExists(t, c.Bind(&v), true)
+			return nil
+		})
+
+		_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.Body = strings.Repeat("[", 1<<20)
+		}))
+		assertErrorExists(t, err, false)
+	})
+}
+
+func TestConfig_BindLimits_CBOR(t *testing.T) {
+	limits := rack.BindLimits{MaxDepth: 3, MaxArrayLength: 3, MaxStringLength: 5}
+
+	tests := []struct {
+		name   string
+		limits rack.BindLimits
+		body   string
+		err    bool
+	}{
+		{
+			name:   "should bind bodies within the limits",
+			limits: limits,
+			body:   "a1 6161 83 01 02 a1 6162 656162636465",
+		},
+		{
+			name: "should not apply limits by default",
+			body: "a1 6161 81 81 81 84 01 02 03 04",
+		},
+		{
+			name:   "should return an error if the depth is exceeded",
+			limits: limits,
+			body:   "a1 6161 81 a1 6162 80",
+			err:    true,
+		},
+		{
+			name:   "should return an error if an array length is exceeded",
+			limits: limits,
+			body:   "84 01 02 03 04",
+			err:    true,
+		},
+		{
+			name:   "should return an error if an indefinite array length is exceeded",
+			limits: limits,
+			body:   "9f 01 02 03 04 ff",
+			err:    true,
+		},
+		{
+			name:   "should return an error if a string length is exceeded",
+			limits: limits,
+			body:   "a1 6161 66616263646566",
+			err:    true,
+		},
+		{
+			name:   "should return an error if an indefinite string length is exceeded",
+			limits: limits,
+			body:   "a1 6161 7f 63616263 63646566 ff",
+			err:    true,
+		},
+		{
+			name:   "should return an error if a key length is exceeded",
+			limits: limits,
+			body:   "a1 66616263646566 01",
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(strings.ReplaceAll(tt.body, " ", ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			h := rack.NewWithConfig(rack.Config{
+				BindLimits: tt.limits,
+			}, func(c rack.Context) error {
+				var v interface{}
+				err := c.Bind(&v)
+				assertErrorExists(t, err, tt.err)
+
+				if err != nil {
+					assertDeepEqual(t, rack.StatusCode(err), http.StatusBadRequest)
+					assertDeepEqual(t, errors.Is(err, rack.ErrBindLimitExceeded), true)
+				}
+
+				return nil
+			})
+
+			_, err = h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"content-type": rack.CBORContentType}
+				r.Body = base64.StdEncoding.EncodeToString(b)
+				r.IsBase64Encoded = true
+			}))
+			assertErrorExists(t, err, false)
+		})
+	}
+}
+
+func TestConfig_BindLimits_BindPatch(t *testing.T) {
+	limits := rack.BindLimits{MaxDepth: 3, MaxArrayLength: 3, MaxStringLength: 5}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		err         bool
+	}{
+		{
+			name:        "should apply merge patches within the limits",
+			contentType: rack.MergePatchContentType,
+			body:        `{"a":"abc"}`,
+		},
+		{
+			name:        "should return an error if a merge patch exceeds the limits",
+			contentType: rack.MergePatchContentType,
+			body:        `{"a":"abcdef"}`,
+			err:         true,
+		},
+		{
+			name:        "should return an error if a json patch exceeds the limits",
+			contentType: rack.JSONPatchContentType,
+			body:        `[{"op":"add","path":"/a","value":[[[1]]]}]`,
+			err:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				BindLimits: limits,
+			}, func(c rack.Context) error {
+				v := map[string]interface{}{}
+				err := rack.BindPatch(c, &v)
+				assertErrorExists(t, err, tt.err)
+
+				if err != nil {
+					assertDeepEqual(t, rack.StatusCode(err), http.StatusBadRequest)
+					assertDeepEqual(t, errors.Is(err, rack.ErrBindLimitExceeded), true)
+				}
+
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"content-type": tt.contentType}
+				r.Body = tt.body
+			}))
+			assertErrorExists(t, err, false)
+		})
+	}
+}
@@ -0,0 +1,477 @@
+package rack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+	// Cache represents a shared key/value cache
+	// Stateful middleware should depend on this interface rather than defining
+	// individual store types, allowing a single backing store to be configured.
+	Cache interface {
+		// Get returns the value for the specified key
+		// False is returned if the key does not exist or has expired.
+		Get(ctx context.Context, key string) ([]byte, bool, error)
+
+		// Set stores the value with the specified ttl
+		// A zero ttl indicates that the value does not expire.
+		Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+		// SetIfAbsent atomically stores the value if the key does not exist or has expired
+		// False is returned if the key already exists.
+		SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+
+		// CompareAndSwap atomically replaces the value if the current value equals old
+		// False is returned if the key does not exist or the value has been modified.
+		CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error)
+
+		// Delete removes the value for the specified key
+		Delete(ctx context.Context, key string) error
+
+		// Increment atomically adds delta to the counter for the specified key
+		// Counters are stored as decimal strings. The ttl is applied if the counter is
+		// created, and the updated value is returned.
+		Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	}
+
+	// CacheItem represents an item written by ItemStoreCache
+	CacheItem struct {
+		Key       string
+		Value     []byte
+		ExpiresAt time.Time
+	}
+
+	// CacheItemStore represents the item store adapter used by ItemStoreCache
+	// Rack does not depend on the AWS SDK, so the store operations are not provided.
+	// Implementations would typically wrap a DynamoDB client in a single-table design,
+	// mapping the item to a partition key, a binary value attribute and a numeric ttl
+	// attribute, with DynamoDB TTL enabled on the latter. The conditional and increment
+	// operations must be atomic, as they are relied upon for claims and counters.
+	CacheItemStore interface {
+		GetItem(ctx context.Context, table, key string) (*CacheItem, error)
+		PutItem(ctx context.Context, table string, item *CacheItem) error
+		DeleteItem(ctx context.Context, table, key string) error
+
+		// PutItemIfAbsent puts the item if it does not exist or has expired
+		// Implementations would typically use PutItem with a condition expression,
+		// returning false if the conditional check fails.
+		PutItemIfAbsent(ctx context.Context, table string, item *CacheItem) (bool, error)
+
+		// PutItemIfEqual puts the item if the existing value equals old and has not expired
+		// Implementations would typically use PutItem with a condition expression on the
+		// value attribute, returning false if the conditional check fails.
+		PutItemIfEqual(ctx context.Context, table string, item *CacheItem, old []byte) (bool, error)
+
+		// IncrementItem atomically adds delta to the item counter and returns the updated value
+		// Implementations would typically use UpdateItem with an ADD expression, returning
+		// the value as a decimal string from GetItem. The expiry should be set, and the
+		// counter reset, if the item does not exist or has expired.
+		IncrementItem(ctx context.Context, table, key string, delta int64, expiresAt time.Time) (int64, error)
+	}
+
+	// RedisClient represents a Redis client
+	// Implementations would typically wrap GET, SET with PX and DEL commands, returning
+	// a nil value with no error for missing keys. SetNX would typically wrap SET with
+	// NX and PX, and IncrBy would typically wrap INCRBY, followed by PEXPIRE if the key
+	// was created. SetIfEqual would typically be a script that compares the GET result
+	// before calling SET with PX.
+	RedisClient interface {
+		Get(ctx context.Context, key string) ([]byte, error)
+		Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+		SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+		SetIfEqual(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error)
+		Del(ctx context.Context, key string) error
+		IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	}
+
+	// MemoryCache is an in-memory cache
+	// Values are only shared between invocations within the same container. Expired
+	// values are removed when they are read, and periodically as values are written,
+	// so that keys that are never read again do not accumulate.
+	MemoryCache struct {
+		items  map[string]memoryCacheItem
+		writes int
+		mu     sync.RWMutex
+	}
+
+	// ItemStoreCache is a cache backed by an item store adapter, such as DynamoDB
+	ItemStoreCache struct {
+		client CacheItemStore
+		table  string
+	}
+
+	// RedisCache is a Redis cache
+	// Expiry is handled by Redis, so values are never returned once expired.
+	RedisCache struct {
+		client RedisClient
+	}
+
+	memoryCacheItem struct {
+		value     []byte
+		expiresAt time.Time
+	}
+
+	prefixCache struct {
+		cache  Cache
+		prefix string
+	}
+)
+
+// minMemoryCacheSweep is the minimum number of writes between expiry sweeps
+const minMemoryCacheSweep = 64
+
+// NewMemoryCache returns a new in-memory cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		items: map[string]memoryCacheItem{},
+	}
+}
+
+// Get returns the value for the specified key
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.RLock()
+	i, ok := c.items[key]
+	c.mu.RUnlock()
+
+	if !ok {
+		return nil, false, nil
+	}
+
+	if !expired(i.expiresAt) {
+		return i.value, true, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the key may have been replaced since the read lock was released
+	if i, ok = c.items[key]; !ok {
+		return nil, false, nil
+	}
+
+	if expired(i.expiresAt) {
+		delete(c.items, key)
+		return nil, false, nil
+	}
+
+	return i.value, true, nil
+}
+
+// Set stores the value with the specified ttl
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.put(key, memoryCacheItem{
+		value:     value,
+		expiresAt: expiry(ttl),
+	})
+
+	return nil
+}
+
+// SetIfAbsent stores the value if the key does not exist or has expired
+func (c *MemoryCache) SetIfAbsent(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if i, ok := c.items[key]; ok && !expired(i.expiresAt) {
+		return false, nil
+	}
+
+	c.put(key, memoryCacheItem{
+		value:     value,
+		expiresAt: expiry(ttl),
+	})
+
+	return true, nil
+}
+
+// CompareAndSwap replaces the value if the current value equals old
+func (c *MemoryCache) CompareAndSwap(_ context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if i, ok := c.items[key]; !ok || expired(i.expiresAt) || !bytes.Equal(i.value, old) {
+		return false, nil
+	}
+
+	c.put(key, memoryCacheItem{
+		value:     value,
+		expiresAt: expiry(ttl),
+	})
+
+	return true, nil
+}
+
+// Len returns the number of values in the cache
+// Expired values that have not yet been removed are included.
+func (c *MemoryCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return len(c.items)
+}
+
+// put stores the item, the caller must hold the write lock
+func (c *MemoryCache) put(key string, i memoryCacheItem) {
+	c.items[key] = i
+
+	// the sweep cost is amortised over a number of writes proportional to the size
+	c.writes++
+	if c.writes >= minMemoryCacheSweep && c.writes >= len(c.items)/2 {
+		c.sweep()
+	}
+}
+
+// sweep removes expired values, the caller must hold the write lock
+func (c *MemoryCache) sweep() {
+	for k, i := range c.items {
+		if expired(i.expiresAt) {
+			delete(c.items, k)
+		}
+	}
+
+	c.writes = 0
+}
+
+// Delete removes the value for the specified key
+func (c *MemoryCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.items, key)
+	return nil
+}
+
+// Increment adds delta to the counter for the specified key
+// Expired and non-numeric values are replaced.
+func (c *MemoryCache) Increment(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int64
+	i, ok := c.items[key]
+	if ok && !expired(i.expiresAt) {
+		n, _ = strconv.ParseInt(string(i.value), 10, 64)
+	} else {
+		i.expiresAt = expiry(ttl)
+	}
+
+	n += delta
+	c.put(key, memoryCacheItem{
+		value:     []byte(strconv.FormatInt(n, 10)),
+		expiresAt: i.expiresAt,
+	})
+
+	return n, nil
+}
+
+// NewItemStoreCache returns a new item store cache for the specified table
+func NewItemStoreCache(client CacheItemStore, table string) *ItemStoreCache {
+	return &ItemStoreCache{
+		client: client,
+		table:  table,
+	}
+}
+
+// Get returns the value for the specified key
+// Store TTL deletion, such as DynamoDB TTL, is not immediate, so expired items are
+// treated as missing.
+func (c *ItemStoreCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	i, err := c.client.GetItem(ctx, c.table, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if i == nil || expired(i.ExpiresAt) {
+		return nil, false, nil
+	}
+
+	return i.Value, true, nil
+}
+
+// Set stores the value with the specified ttl
+func (c *ItemStoreCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.PutItem(ctx, c.table, &CacheItem{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: expiry(ttl),
+	})
+}
+
+// SetIfAbsent stores the value if the key does not exist or has expired
+func (c *ItemStoreCache) SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return c.client.PutItemIfAbsent(ctx, c.table, &CacheItem{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: expiry(ttl),
+	})
+}
+
+// CompareAndSwap replaces the value if the current value equals old
+func (c *ItemStoreCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	return c.client.PutItemIfEqual(ctx, c.table, &CacheItem{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: expiry(ttl),
+	}, old)
+}
+
+// Delete removes the value for the specified key
+func (c *ItemStoreCache) Delete(ctx context.Context, key string) error {
+	return c.client.DeleteItem(ctx, c.table, key)
+}
+
+// Increment adds delta to the counter for the specified key
+func (c *ItemStoreCache) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return c.client.IncrementItem(ctx, c.table, key, delta, expiry(ttl))
+}
+
+// NewRedisCache returns a new Redis cache
+func NewRedisCache(client RedisClient) *RedisCache {
+	return &RedisCache{
+		client: client,
+	}
+}
+
+// Get returns the value for the specified key
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	v, err := c.client.Get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if v == nil {
+		return nil, false, nil
+	}
+
+	return v, true, nil
+}
+
+// Set stores the value with the specified ttl
+func (c *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	return c.client.Set(ctx, key, value, ttl)
+}
+
+// SetIfAbsent stores the value if the key does not exist
+func (c *RedisCache) SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	return c.client.SetNX(ctx, key, value, ttl)
+}
+
+// CompareAndSwap replaces the value if the current value equals old
+func (c *RedisCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	return c.client.SetIfEqual(ctx, key, old, value, ttl)
+}
+
+// Delete removes the value for the specified key
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	return c.client.Del(ctx, key)
+}
+
+// Increment adds delta to the counter for the specified key
+func (c *RedisCache) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	return c.client.IncrBy(ctx, key, delta, ttl)
+}
+
+// PrefixCache returns a cache that prefixes all keys with the specified value
+// This allows multiple components to share a single cache, or a single DynamoDB
+// table, without key collisions.
+func PrefixCache(c Cache, prefix string) Cache {
+	return &prefixCache{
+		cache:  c,
+		prefix: prefix,
+	}
+}
+
+func (c *prefixCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return c.cache.Get(ctx, c.prefix+key)
+}
+
+func (c *prefixCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.cache.Set(ctx, c.prefix+key, value, ttl)
+}
+
+func (c *prefixCache) SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return c.cache.SetIfAbsent(ctx, c.prefix+key, value, ttl)
+}
+
+func (c *prefixCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	return c.cache.CompareAndSwap(ctx, c.prefix+key, old, value, ttl)
+}
+
+func (c *prefixCache) Delete(ctx context.Context, key string) error {
+	return c.cache.Delete(ctx, c.prefix+key)
+}
+
+func (c *prefixCache) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return c.cache.Increment(ctx, c.prefix+key, delta, ttl)
+}
+
+// marshalCachedResponse returns the json encoding of the response for replay
+// Set-Cookie headers are removed, as cookies are specific to the original client.
+func marshalCachedResponse(r *Response) ([]byte, error) {
+	cr := *r
+	cr.Headers = r.Headers.Clone()
+	cr.Headers.Del("Set-Cookie")
+
+	return json.Marshal(&cr)
+}
+
+// replayResponse writes the specified json encoded response
+// Set-Cookie headers are never replayed, regardless of how the response was cached.
+func replayResponse(c Context, b []byte) error {
+	r := new(Response)
+	if err := json.Unmarshal(b, r); err != nil {
+		return err
+	}
+
+	if err := Blob(c, r.StatusCode, r.Headers.Get("Content-Type"), []byte(r.Body)); err != nil {
+		return err
+	}
+
+	for k, vs := range r.Headers {
+		if http.CanonicalHeaderKey(k) == "Set-Cookie" {
+			continue
+		}
+		c.Response().Headers[k] = vs
+	}
+	c.Response().StatusDescription = r.StatusDescription
+	c.Response().IsBase64Encoded = r.IsBase64Encoded
+
+	return nil
+}
+
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(ttl)
+}
+
+func expired(t time.Time) bool {
+	return !t.IsZero() && !time.Now().Before(t)
+}
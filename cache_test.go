@@ -0,0 +1,340 @@
+package rack_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestMemoryCache(t *testing.T) {
+	testCache(t, func() rack.Cache {
+		return rack.NewMemoryCache()
+	})
+
+	t.Run("should remove expired values that are not read", func(t *testing.T) {
+		ctx := context.Background()
+		sut := rack.NewMemoryCache()
+
+		for i := 0; i < 1000; i++ {
+			if err := sut.Set(ctx, "expired"+strconv.Itoa(i), []byte("v"), 20*time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		time.Sleep(40 * time.Millisecond)
+
+		for i := 0; i < 100; i++ {
+			if err := sut.Set(ctx, "key"+strconv.Itoa(i), []byte("v"), 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		assertDeepEqual(t, sut.Len(), 100)
+	})
+
+	t.Run("should not remove values replaced during expired reads", func(t *testing.T) {
+		ctx := context.Background()
+		sut := rack.NewMemoryCache()
+
+		for i := 0; i < 100; i++ {
+			sut.Set(ctx, "key", []byte("expired"), time.Nanosecond)
+			time.Sleep(time.Microsecond)
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sut.Get(ctx, "key")
+			}()
+
+			sut.Set(ctx, "key", []byte("value"), 0)
+			wg.Wait()
+
+			act, ok, err := sut.Get(ctx, "key")
+			assertErrorExists(t, err, false)
+			if !ok {
+				t.Fatal("got false, expected true")
+			}
+			assertDeepEqual(t, act, []byte("value"))
+		}
+	})
+}
+
+func TestItemStoreCache(t *testing.T) {
+	testCache(t, func() rack.Cache {
+		return rack.NewItemStoreCache(newTestItemStore(), "table")
+	})
+
+	t.Run("should return client errors", func(t *testing.T) {
+		c := newTestItemStore()
+		c.err = errors.New("error")
+
+		sut := rack.NewItemStoreCache(c, "table")
+
+		_, _, err := sut.Get(context.Background(), "key")
+		assertErrorExists(t, err, true)
+	})
+}
+
+func TestRedisCache(t *testing.T) {
+	testCache(t, func() rack.Cache {
+		return rack.NewRedisCache(newTestRedisClient())
+	})
+
+	t.Run("should return client errors", func(t *testing.T) {
+		c := newTestRedisClient()
+		c.err = errors.New("error")
+
+		sut := rack.NewRedisCache(c)
+
+		_, _, err := sut.Get(context.Background(), "key")
+		assertErrorExists(t, err, true)
+	})
+}
+
+func TestPrefixCache(t *testing.T) {
+	testCache(t, func() rack.Cache {
+		return rack.PrefixCache(rack.NewMemoryCache(), "prefix#")
+	})
+
+	t.Run("should prefix keys", func(t *testing.T) {
+		ctx := context.Background()
+		c := rack.NewMemoryCache()
+		sut := rack.PrefixCache(c, "prefix#")
+
+		err := sut.Set(ctx, "key", []byte("value"), 0)
+		assertErrorExists(t, err, false)
+
+		_, ok, _ := c.Get(ctx, "prefix#key")
+		if !ok {
+			t.Error("got false, expected true")
+		}
+	})
+}
+
+func testCache(t *testing.T, fn func() rack.Cache) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		setup func(rack.Cache)
+		exp   []byte
+		ok    bool
+	}{
+		{
+			name:  "should return false if the key does not exist",
+			setup: func(rack.Cache) {},
+		},
+		{
+			name: "should return false if the key has expired",
+			setup: func(c rack.Cache) {
+				c.Set(ctx, "key", []byte("value"), time.Nanosecond)
+				time.Sleep(time.Millisecond)
+			},
+		},
+		{
+			name: "should return false if the key has been deleted",
+			setup: func(c rack.Cache) {
+				c.Set(ctx, "key", []byte("value"), 0)
+				c.Delete(ctx, "key")
+			},
+		},
+		{
+			name: "should return the value",
+			setup: func(c rack.Cache) {
+				c.Set(ctx, "key", []byte("value"), time.Minute)
+			},
+			exp: []byte("value"),
+			ok:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := fn()
+			tt.setup(sut)
+
+			act, ok, err := sut.Get(ctx, "key")
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, act, tt.exp)
+
+			if ok != tt.ok {
+				t.Errorf("got %v, expected %v", ok, tt.ok)
+			}
+		})
+	}
+
+	t.Run("should set absent values", func(t *testing.T) {
+		sut := fn()
+
+		sut.Set(ctx, "expired", []byte("value"), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+
+		var act []bool
+		for _, k := range []string{"key", "key", "expired"} {
+			ok, err := sut.SetIfAbsent(ctx, k, []byte(k), time.Minute)
+			assertErrorExists(t, err, false)
+			act = append(act, ok)
+		}
+
+		assertDeepEqual(t, act, []bool{true, false, true})
+
+		b, _, err := sut.Get(ctx, "expired")
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, b, []byte("expired"))
+	})
+
+	t.Run("should compare and swap values", func(t *testing.T) {
+		sut := fn()
+
+		sut.Set(ctx, "key", []byte("a"), time.Minute)
+
+		var act []bool
+		for _, kv := range [][3]string{{"key", "b", "c"}, {"key", "a", "b"}, {"missing", "", "a"}} {
+			ok, err := sut.CompareAndSwap(ctx, kv[0], []byte(kv[1]), []byte(kv[2]), time.Minute)
+			assertErrorExists(t, err, false)
+			act = append(act, ok)
+		}
+
+		assertDeepEqual(t, act, []bool{false, true, false})
+
+		b, _, err := sut.Get(ctx, "key")
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, b, []byte("b"))
+	})
+
+	t.Run("should increment counters", func(t *testing.T) {
+		sut := fn()
+
+		var act []int64
+		for _, d := range []int64{2, 3, -1} {
+			n, err := sut.Increment(ctx, "key", d, time.Minute)
+			assertErrorExists(t, err, false)
+			act = append(act, n)
+		}
+
+		assertDeepEqual(t, act, []int64{2, 5, 4})
+
+		b, _, err := sut.Get(ctx, "key")
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, b, []byte("4"))
+	})
+
+	t.Run("should reset expired counters", func(t *testing.T) {
+		sut := fn()
+
+		sut.Increment(ctx, "key", 2, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+
+		act, err := sut.Increment(ctx, "key", 1, time.Minute)
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, act, int64(1))
+	})
+}
+
+type testItemStore struct {
+	items map[string]*rack.CacheItem
+	err   error
+}
+
+func newTestItemStore() *testItemStore {
+	return &testItemStore{
+		items: map[string]*rack.CacheItem{},
+	}
+}
+
+func (c *testItemStore) GetItem(_ context.Context, table, key string) (*rack.CacheItem, error) {
+	return c.items[table+key], c.err
+}
+
+func (c *testItemStore) PutItem(_ context.Context, table string, item *rack.CacheItem) error {
+	c.items[table+item.Key] = item
+	return c.err
+}
+
+func (c *testItemStore) PutItemIfAbsent(_ context.Context, table string, item *rack.CacheItem) (bool, error) {
+	if i, ok := c.items[table+item.Key]; ok && (i.ExpiresAt.IsZero() || time.Now().Before(i.ExpiresAt)) {
+		return false, c.err
+	}
+
+	c.items[table+item.Key] = item
+	return true, c.err
+}
+
+func (c *testItemStore) PutItemIfEqual(_ context.Context, table string, item *rack.CacheItem, old []byte) (bool, error) {
+	i, ok := c.items[table+item.Key]
+	if !ok || (!i.ExpiresAt.IsZero() && !time.Now().Before(i.ExpiresAt)) || !bytes.Equal(i.Value, old) {
+		return false, c.err
+	}
+
+	c.items[table+item.Key] = item
+	return true, c.err
+}
+
+func (c *testItemStore) DeleteItem(_ context.Context, table, key string) error {
+	delete(c.items, table+key)
+	return c.err
+}
+
+func (c *testItemStore) IncrementItem(_ context.Context, table, key string, delta int64, expiresAt time.Time) (int64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	var n int64
+	i, ok := c.items[table+key]
+	if ok && (i.ExpiresAt.IsZero() || time.Now().Before(i.ExpiresAt)) {
+		n, _ = strconv.ParseInt(string(i.Value), 10, 64)
+		expiresAt = i.ExpiresAt
+	}
+
+	n += delta
+	c.items[table+key] = &rack.CacheItem{Key: key, Value: []byte(strconv.FormatInt(n, 10)), ExpiresAt: expiresAt}
+	return n, nil
+}
+
+type testRedisClient struct {
+	cache *rack.MemoryCache
+	err   error
+}
+
+func newTestRedisClient() *testRedisClient {
+	return &testRedisClient{
+		cache: rack.NewMemoryCache(),
+	}
+}
+
+func (c *testRedisClient) Get(ctx context.Context, key string) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	v, _, _ := c.cache.Get(ctx, key)
+	return v, nil
+}
+
+func (c *testRedisClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.cache.Set(ctx, key, value, ttl)
+}
+
+func (c *testRedisClient) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return c.cache.SetIfAbsent(ctx, key, value, ttl)
+}
+
+func (c *testRedisClient) SetIfEqual(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	return c.cache.CompareAndSwap(ctx, key, old, value, ttl)
+}
+
+func (c *testRedisClient) Del(ctx context.Context, key string) error {
+	return c.cache.Delete(ctx, key)
+}
+
+func (c *testRedisClient) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return c.cache.Increment(ctx, key, delta, ttl)
+}
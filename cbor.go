@@ -0,0 +1,1100 @@
+package rack
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CBORContentType is the CBOR media type
+const CBORContentType = "application/cbor"
+
+// defaultCBORMaxDepth matches the nesting limit applied by encoding/json
+const defaultCBORMaxDepth = 10000
+
+var (
+	errInvalidCBOR = errors.New("invalid cbor")
+
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+)
+
+// CBOR writes the specified status code and value to the response as CBOR
+// The response body is base64 encoded and marked as such in the response.
+func CBOR(c Context, code int, v interface{}) error {
+	b, err := MarshalCBOR(v)
+	if err != nil {
+		return err
+	}
+
+	ok, err := blob(c, code, CBORContentType, []byte(base64.StdEncoding.EncodeToString(b)))
+	if !ok {
+		return err
+	}
+
+	c.Response().IsBase64Encoded = true
+	return nil
+}
+
+// MarshalCBOR returns the CBOR encoding of the specified value
+// Values are encoded directly, so byte slices are encoded as byte strings, integers
+// and floats retain their major types, including NaN and infinite values, and map
+// keys retain their types. Struct fields are selected using the json struct tags,
+// and values that implement json.Marshaler or encoding.TextMarshaler are encoded
+// using their JSON or text representation. Map keys are sorted using the CBOR
+// deterministic encoding order.
+func MarshalCBOR(v interface{}) ([]byte, error) {
+	e := &cborEncoder{buf: new(bytes.Buffer)}
+	if err := e.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalCBOR decodes the CBOR data into the specified value
+// Struct fields are matched using the json struct tags, and values that implement
+// json.Unmarshaler or encoding.TextUnmarshaler are decoded from their JSON or text
+// representation. Integers, floats and byte strings are decoded into interface
+// values as int64, uint64 or *big.Int, float64 and []byte. Maps are decoded as
+// map[string]interface{} unless they contain non-text keys. Arrays, maps and tags
+// are limited to a nesting depth of 10000.
+func UnmarshalCBOR(data []byte, v interface{}) error {
+	return unmarshalCBOR(data, v, BindLimits{})
+}
+
+// unmarshalCBOR decodes the CBOR data with the specified limits
+// The default nesting limit is applied if MaxDepth is zero.
+func unmarshalCBOR(data []byte, v interface{}, l BindLimits) error {
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = defaultCBORMaxDepth
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+
+	d := &cborDecoder{data: data, limits: l}
+
+	t, err := d.decode()
+	if err != nil {
+		return err
+	}
+
+	if d.pos != len(data) {
+		return fmt.Errorf("%w: unexpected trailing data", errInvalidCBOR)
+	}
+
+	return assignCBOR(t, rv.Elem())
+}
+
+// cborEncoder holds the state of a single encode
+type cborEncoder struct {
+	buf   *bytes.Buffer
+	depth int
+}
+
+func (e *cborEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		e.buf.WriteByte(0xf6)
+		return nil
+	}
+
+	t := v.Type()
+
+	if (t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface) && v.IsNil() {
+		e.buf.WriteByte(0xf6)
+		return nil
+	}
+
+	if t.Kind() == reflect.Interface {
+		return e.encode(v.Elem())
+	}
+
+	if m, ok := marshalerValue(v); ok {
+		return e.encodeMarshaler(m)
+	}
+
+	if t.Kind() == reflect.Ptr {
+		leave, err := e.enter()
+		if err != nil {
+			return err
+		}
+		defer leave()
+
+		return e.encode(v.Elem())
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf.WriteByte(0xf5)
+		} else {
+			e.buf.WriteByte(0xf4)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := v.Int(); i < 0 {
+			writeCBORHead(e.buf, 1, uint64(-(i + 1)))
+		} else {
+			writeCBORHead(e.buf, 0, uint64(i))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeCBORHead(e.buf, 0, v.Uint())
+	case reflect.Float32:
+		e.buf.WriteByte(0xfa)
+		binary.Write(e.buf, binary.BigEndian, math.Float32bits(float32(v.Float())))
+	case reflect.Float64:
+		e.buf.WriteByte(0xfb)
+		binary.Write(e.buf, binary.BigEndian, math.Float64bits(v.Float()))
+	case reflect.String:
+		e.buf.Write(cborText(v.String()))
+	case reflect.Slice:
+		if v.IsNil() {
+			e.buf.WriteByte(0xf6)
+			return nil
+		}
+
+		if t.Elem().Kind() == reflect.Uint8 {
+			writeCBORHead(e.buf, 2, uint64(v.Len()))
+			e.buf.Write(v.Bytes())
+			return nil
+		}
+
+		fallthrough
+	case reflect.Array:
+		leave, err := e.enter()
+		if err != nil {
+			return err
+		}
+		defer leave()
+
+		writeCBORHead(e.buf, 4, uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			if err := e.encode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			e.buf.WriteByte(0xf6)
+			return nil
+		}
+
+		return e.encodeMap(v)
+	case reflect.Struct:
+		return e.encodeStruct(v)
+	default:
+		return fmt.Errorf("unsupported cbor type %s", t)
+	}
+
+	return nil
+}
+
+// enter increments the nesting depth, returning an error if the limit is exceeded
+// The limit also prevents cyclic values from being encoded indefinitely.
+func (e *cborEncoder) enter() (func(), error) {
+	if e.depth++; e.depth > defaultCBORMaxDepth {
+		return nil, fmt.Errorf("cbor nesting depth exceeds %d", defaultCBORMaxDepth)
+	}
+
+	return func() { e.depth-- }, nil
+}
+
+func (e *cborEncoder) encodeMarshaler(m interface{}) error {
+	if tm, ok := m.(encoding.TextMarshaler); ok {
+		if _, ok := m.(json.Marshaler); !ok {
+			b, err := tm.MarshalText()
+			if err != nil {
+				return err
+			}
+
+			e.buf.Write(cborText(string(b)))
+			return nil
+		}
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var t interface{}
+	if err = d.Decode(&t); err != nil {
+		return err
+	}
+
+	return encodeCBORTree(e.buf, t)
+}
+
+func (e *cborEncoder) encodeMap(v reflect.Value) error {
+	leave, err := e.enter()
+	if err != nil {
+		return err
+	}
+	defer leave()
+
+	entries := make([]cborEntry, 0, v.Len())
+
+	iter := v.MapRange()
+	for iter.Next() {
+		k := &cborEncoder{buf: new(bytes.Buffer), depth: e.depth}
+		if err := k.encode(iter.Key()); err != nil {
+			return err
+		}
+
+		entries = append(entries, cborEntry{key: k.buf.Bytes(), value: iter.Value()})
+	}
+
+	return e.encodeEntries(entries)
+}
+
+func (e *cborEncoder) encodeStruct(v reflect.Value) error {
+	leave, err := e.enter()
+	if err != nil {
+		return err
+	}
+	defer leave()
+
+	var entries []cborEntry
+	for _, f := range structFields(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) {
+			continue
+		}
+
+		entries = append(entries, cborEntry{key: cborText(f.name), value: fv})
+	}
+
+	return e.encodeEntries(entries)
+}
+
+// encodeEntries writes the map entries sorted by the bytewise order of the encoded keys
+func (e *cborEncoder) encodeEntries(entries []cborEntry) error {
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	writeCBORHead(e.buf, 5, uint64(len(entries)))
+	for _, en := range entries {
+		e.buf.Write(en.key)
+		if err := e.encode(en.value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cborEntry represents an encoded map key and the value to be encoded
+type cborEntry struct {
+	key   []byte
+	value reflect.Value
+}
+
+// cborText returns the encoding of the specified text string
+func cborText(s string) []byte {
+	buf := new(bytes.Buffer)
+	writeCBORHead(buf, 3, uint64(len(s)))
+	buf.WriteString(s)
+
+	return buf.Bytes()
+}
+
+// marshalerValue returns the json or text marshaler for the value
+// Values are copied if only the pointer type implements the interface, as with
+// the codec transform.
+func marshalerValue(v reflect.Value) (interface{}, bool) {
+	t := v.Type()
+	if t.Implements(marshalerType) || t.Implements(textMarshalerType) {
+		return v.Interface(), true
+	}
+
+	if t.Kind() != reflect.Ptr {
+		if pt := reflect.PtrTo(t); pt.Implements(marshalerType) || pt.Implements(textMarshalerType) {
+			p := reflect.New(t)
+			p.Elem().Set(v)
+			return p.Interface(), true
+		}
+	}
+
+	return nil, false
+}
+
+// encodeCBORTree encodes the value tree decoded from a json marshaler
+func encodeCBORTree(buf *bytes.Buffer, v interface{}) error {
+	switch t := v.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case bool:
+		if t {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			if i < 0 {
+				writeCBORHead(buf, 1, uint64(-(i + 1)))
+			} else {
+				writeCBORHead(buf, 0, uint64(i))
+			}
+			return nil
+		}
+
+		if u, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			writeCBORHead(buf, 0, u)
+			return nil
+		}
+
+		f, err := t.Float64()
+		if err != nil {
+			return err
+		}
+
+		buf.WriteByte(0xfb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+	case string:
+		buf.Write(cborText(t))
+	case []interface{}:
+		writeCBORHead(buf, 4, uint64(len(t)))
+		for _, e := range t {
+			if err := encodeCBORTree(buf, e); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		ks := make([]string, 0, len(t))
+		for k := range t {
+			ks = append(ks, k)
+		}
+
+		// text keys sort by length before content in the deterministic encoding order
+		sort.Slice(ks, func(i, j int) bool {
+			return bytes.Compare(cborText(ks[i]), cborText(ks[j])) < 0
+		})
+
+		writeCBORHead(buf, 5, uint64(len(t)))
+		for _, k := range ks {
+			buf.Write(cborText(k))
+			if err := encodeCBORTree(buf, t[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported cbor type %T", v)
+	}
+
+	return nil
+}
+
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	m := major << 5
+
+	switch {
+	case n < 24:
+		buf.WriteByte(m | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{m | 24, byte(n)})
+	case n <= math.MaxUint16:
+		buf.WriteByte(m | 25)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	case n <= math.MaxUint32:
+		buf.WriteByte(m | 26)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	default:
+		buf.WriteByte(m | 27)
+		binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// cborMap represents a decoded map
+// Pairs are held in order so that keys retain their types until they are assigned.
+type cborMap []cborPair
+
+type cborPair struct {
+	key   interface{}
+	value interface{}
+}
+
+type cborDecoder struct {
+	data   []byte
+	pos    int
+	depth  int
+	limits BindLimits
+}
+
+// cborBreak is returned when the break stop code is read
+var cborBreak = struct{}{}
+
+// decode decodes the next item, returning an error if it is a break stop code
+func (d *cborDecoder) decode() (interface{}, error) {
+	v, err := d.decodeItem()
+	if err == nil && v == cborBreak {
+		return nil, fmt.Errorf("%w: unexpected break", errInvalidCBOR)
+	}
+
+	return v, err
+}
+
+func (d *cborDecoder) decodeItem() (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, fmt.Errorf("%w: unexpected end of data", errInvalidCBOR)
+	}
+
+	ib := d.data[d.pos]
+	d.pos++
+
+	major, info := ib>>5, ib&0x1f
+
+	if major == 7 {
+		return d.decodeSimple(info)
+	}
+
+	if major >= 4 || info == 31 {
+		if d.depth++; d.depth > d.limits.MaxDepth {
+			return nil, fmt.Errorf("%w: nesting depth exceeds %d", ErrBindLimitExceeded, d.limits.MaxDepth)
+		}
+		defer func() { d.depth-- }()
+	}
+
+	if info == 31 {
+		return d.decodeIndefinite(major)
+	}
+
+	n, err := d.readArg(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case 0:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case 1:
+		if n > math.MaxInt64 {
+			// -1-n overflows int64
+			v := new(big.Int).SetUint64(n)
+			return v.Neg(v.Add(v, big.NewInt(1))), nil
+		}
+		return -1 - int64(n), nil
+	case 2:
+		if err = d.checkString(n); err != nil {
+			return nil, err
+		}
+		// byte strings are copied so that values do not alias the input
+		b, err := d.read(n)
+		return append([]byte{}, b...), err
+	case 3:
+		if err = d.checkString(n); err != nil {
+			return nil, err
+		}
+		b, err := d.read(n)
+		return string(b), err
+	case 4:
+		if err = d.checkArray(n); err != nil {
+			return nil, err
+		}
+		a := make([]interface{}, 0, capHint(n))
+		for i := uint64(0); i < n; i++ {
+			v, err := d.decode()
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		return a, nil
+	case 5:
+		m := make(cborMap, 0, capHint(n))
+		for i := uint64(0); i < n; i++ {
+			if err := d.decodePair(&m); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case 6:
+		// tags are not interpreted, the tagged value is returned
+		return d.decode()
+	}
+
+	return nil, fmt.Errorf("%w: unsupported major type %d", errInvalidCBOR, major)
+}
+
+func (d *cborDecoder) decodeIndefinite(major byte) (interface{}, error) {
+	switch major {
+	case 2, 3:
+		var b []byte
+		for {
+			v, err := d.decodeItem()
+			if err != nil {
+				return nil, err
+			}
+			if v == cborBreak {
+				break
+			}
+
+			switch c := v.(type) {
+			case []byte:
+				b = append(b, c...)
+			case string:
+				b = append(b, c...)
+			default:
+				return nil, fmt.Errorf("%w: invalid string chunk", errInvalidCBOR)
+			}
+
+			if err = d.checkString(uint64(len(b))); err != nil {
+				return nil, err
+			}
+		}
+
+		if major == 3 {
+			return string(b), nil
+		}
+		return b, nil
+	case 4:
+		a := []interface{}{}
+		for {
+			v, err := d.decodeItem()
+			if err != nil {
+				return nil, err
+			}
+			if v == cborBreak {
+				return a, nil
+			}
+			if err = d.checkArray(uint64(len(a) + 1)); err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+	case 5:
+		m := cborMap{}
+		for {
+			if d.pos < len(d.data) && d.data[d.pos] == 0xff {
+				d.pos++
+				return m, nil
+			}
+			if err := d.decodePair(&m); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%w: invalid indefinite length item", errInvalidCBOR)
+}
+
+func (d *cborDecoder) decodePair(m *cborMap) error {
+	k, err := d.decode()
+	if err != nil {
+		return err
+	}
+
+	v, err := d.decode()
+	if err != nil {
+		return err
+	}
+
+	*m = append(*m, cborPair{key: k, value: v})
+	return nil
+}
+
+func (d *cborDecoder) decodeSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		b, err := d.read(2)
+		if err != nil {
+			return nil, err
+		}
+		return halfToFloat(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := d.read(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.read(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 31:
+		return cborBreak, nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported simple value %d", errInvalidCBOR, info)
+}
+
+func (d *cborDecoder) readArg(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		b, err := d.read(1)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(b[0]), nil
+	case info == 25:
+		b, err := d.read(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err := d.read(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err := d.read(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(b), nil
+	}
+
+	return 0, fmt.Errorf("%w: invalid additional information %d", errInvalidCBOR, info)
+}
+
+// checkString returns an error if the string length exceeds the limit
+func (d *cborDecoder) checkString(n uint64) error {
+	if l := d.limits.MaxStringLength; l > 0 && n > uint64(l) {
+		return fmt.Errorf("%w: string length exceeds %d", ErrBindLimitExceeded, l)
+	}
+
+	return nil
+}
+
+// checkArray returns an error if the array length exceeds the limit
+func (d *cborDecoder) checkArray(n uint64) error {
+	if l := d.limits.MaxArrayLength; l > 0 && n > uint64(l) {
+		return fmt.Errorf("%w: array length exceeds %d", ErrBindLimitExceeded, l)
+	}
+
+	return nil
+}
+
+func (d *cborDecoder) read(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, fmt.Errorf("%w: unexpected end of data", errInvalidCBOR)
+	}
+
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+
+	return b, nil
+}
+
+// assignCBOR assigns the decoded value to the specified value
+func assignCBOR(t interface{}, v reflect.Value) error {
+	return assignCBORValue(t, v, "")
+}
+
+func assignCBORValue(t interface{}, v reflect.Value, field string) error {
+	if t == nil {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return assignCBORValue(t, v.Elem(), field)
+	}
+
+	if v.CanAddr() {
+		switch u := v.Addr().Interface().(type) {
+		case json.Unmarshaler:
+			b, err := json.Marshal(cborJSONValue(t))
+			if err != nil {
+				return err
+			}
+			return u.UnmarshalJSON(b)
+		case encoding.TextUnmarshaler:
+			switch s := t.(type) {
+			case string:
+				return u.UnmarshalText([]byte(s))
+			case []byte:
+				return u.UnmarshalText(s)
+			}
+			return cborTypeError(t, v.Type(), field)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			return cborTypeError(t, v.Type(), field)
+		}
+		v.Set(reflect.ValueOf(cborInterface(t)))
+	case reflect.Bool:
+		b, ok := t.(bool)
+		if !ok {
+			return cborTypeError(t, v.Type(), field)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := t.(int64)
+		if !ok || v.OverflowInt(i) {
+			return cborTypeError(t, v.Type(), field)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch n := t.(type) {
+		case int64:
+			if n < 0 {
+				return cborTypeError(t, v.Type(), field)
+			}
+			u = uint64(n)
+		case uint64:
+			u = n
+		default:
+			return cborTypeError(t, v.Type(), field)
+		}
+		if v.OverflowUint(u) {
+			return cborTypeError(t, v.Type(), field)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch n := t.(type) {
+		case float64:
+			f = n
+		case int64:
+			f = float64(n)
+		case uint64:
+			f = float64(n)
+		case *big.Int:
+			f, _ = new(big.Float).SetInt(n).Float64()
+		default:
+			return cborTypeError(t, v.Type(), field)
+		}
+		if v.OverflowFloat(f) {
+			return cborTypeError(t, v.Type(), field)
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		if v.Type() == jsonNumberType {
+			n, ok := cborNumber(t)
+			if !ok {
+				return cborTypeError(t, v.Type(), field)
+			}
+			v.SetString(n)
+			return nil
+		}
+
+		s, ok := t.(string)
+		if !ok {
+			return cborTypeError(t, v.Type(), field)
+		}
+		v.SetString(s)
+	case reflect.Slice:
+		if b, ok := t.([]byte); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes(b)
+			return nil
+		}
+
+		a, ok := t.([]interface{})
+		if !ok {
+			return cborTypeError(t, v.Type(), field)
+		}
+
+		s := reflect.MakeSlice(v.Type(), len(a), len(a))
+		for i, e := range a {
+			if err := assignCBORValue(e, s.Index(i), field); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		a, ok := t.([]interface{})
+		if !ok {
+			return cborTypeError(t, v.Type(), field)
+		}
+
+		// extra elements are ignored and missing elements are zeroed, as with encoding/json
+		for i := 0; i < v.Len(); i++ {
+			if i >= len(a) {
+				v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+				continue
+			}
+			if err := assignCBORValue(a[i], v.Index(i), field); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		m, ok := t.(cborMap)
+		if !ok {
+			return cborTypeError(t, v.Type(), field)
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(m)))
+		}
+
+		for _, p := range m {
+			k := reflect.New(v.Type().Key()).Elem()
+			if err := assignCBORValue(p.key, k, field); err != nil {
+				return err
+			}
+
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := assignCBORValue(p.value, e, field); err != nil {
+				return err
+			}
+
+			v.SetMapIndex(k, e)
+		}
+	case reflect.Struct:
+		m, ok := t.(cborMap)
+		if !ok {
+			return cborTypeError(t, v.Type(), field)
+		}
+
+		fields := structFields(v.Type())
+		for _, p := range m {
+			k, ok := p.key.(string)
+			if !ok {
+				continue
+			}
+
+			f, ok := matchField(fields, k)
+			if !ok {
+				continue
+			}
+
+			fv, ok := fieldByIndexAlloc(v, f.index)
+			if !ok {
+				continue
+			}
+
+			name := f.name
+			if field != "" {
+				name = field + "." + name
+			}
+
+			if err := assignCBORValue(p.value, fv, name); err != nil {
+				return err
+			}
+		}
+	default:
+		return cborTypeError(t, v.Type(), field)
+	}
+
+	return nil
+}
+
+// matchField returns the field with the specified name
+// Exact matches are preferred over case-insensitive matches, as with encoding/json.
+func matchField(fields []structField, name string) (structField, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+
+	for _, f := range fields {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+
+	return structField{}, false
+}
+
+// fieldByIndexAlloc returns the nested field, allocating nil embedded pointers
+// False is returned if an embedded pointer cannot be allocated because it is unexported.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v, true
+}
+
+// cborInterface converts the decoded value for assignment to an empty interface
+// Byte string keys are converted to strings as byte slices cannot be map keys.
+func cborInterface(t interface{}) interface{} {
+	switch c := t.(type) {
+	case []interface{}:
+		a := make([]interface{}, len(c))
+		for i, e := range c {
+			a[i] = cborInterface(e)
+		}
+		return a
+	case cborMap:
+		text := true
+		for _, p := range c {
+			if _, ok := p.key.(string); !ok {
+				text = false
+				break
+			}
+		}
+
+		if text {
+			m := make(map[string]interface{}, len(c))
+			for _, p := range c {
+				m[p.key.(string)] = cborInterface(p.value)
+			}
+			return m
+		}
+
+		m := make(map[interface{}]interface{}, len(c))
+		for _, p := range c {
+			k := p.key
+			switch kt := k.(type) {
+			case []byte:
+				k = string(kt)
+			case *big.Int:
+				k = kt.String()
+			case []interface{}, cborMap:
+				k = fmt.Sprint(cborInterface(kt))
+			}
+			m[k] = cborInterface(p.value)
+		}
+		return m
+	}
+
+	return t
+}
+
+// cborJSONValue converts the decoded value into a tree that can be marshaled as JSON
+// Non-text map keys are formatted as strings.
+func cborJSONValue(t interface{}) interface{} {
+	switch c := t.(type) {
+	case []interface{}:
+		a := make([]interface{}, len(c))
+		for i, e := range c {
+			a[i] = cborJSONValue(e)
+		}
+		return a
+	case cborMap:
+		m := make(map[string]interface{}, len(c))
+		for _, p := range c {
+			k, ok := p.key.(string)
+			if !ok {
+				k = fmt.Sprint(cborInterface(p.key))
+			}
+			m[k] = cborJSONValue(p.value)
+		}
+		return m
+	}
+
+	return t
+}
+
+// cborNumber returns the decimal representation of the decoded number
+func cborNumber(t interface{}) (string, bool) {
+	switch n := t.(type) {
+	case int64:
+		return strconv.FormatInt(n, 10), true
+	case uint64:
+		return strconv.FormatUint(n, 10), true
+	case *big.Int:
+		return n.String(), true
+	case float64:
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return "", false
+		}
+		return strconv.FormatFloat(n, 'g', -1, 64), true
+	}
+
+	return "", false
+}
+
+func cborTypeError(t interface{}, typ reflect.Type, field string) error {
+	var kind string
+	switch t.(type) {
+	case bool:
+		kind = "bool"
+	case int64, uint64, *big.Int:
+		kind = "integer"
+	case float64:
+		kind = "float"
+	case string:
+		kind = "text string"
+	case []byte:
+		kind = "byte string"
+	case []interface{}:
+		kind = "array"
+	case cborMap:
+		kind = "map"
+	default:
+		kind = fmt.Sprintf("%T", t)
+	}
+
+	if field != "" {
+		return fmt.Errorf("%w: cannot decode %s into field %q of type %s", errInvalidCBOR, kind, field, typ)
+	}
+
+	return fmt.Errorf("%w: cannot decode %s into %s", errInvalidCBOR, kind, typ)
+}
+
+// capHint limits initial allocations for untrusted lengths
+func capHint(n uint64) int {
+	if n > 1024 {
+		return 1024
+	}
+
+	return int(n)
+}
+
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+
+	if h&0x8000 != 0 {
+		return -f
+	}
+
+	return f
+}
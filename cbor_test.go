@@ -0,0 +1,484 @@
+package rack_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestMarshalCBOR(t *testing.T) {
+	tests := []struct {
+		name  string
+		input interface{}
+		exp   string
+		err   bool
+	}{
+		{
+			name:  "should return an error if the value cannot be marshaled",
+			input: make(chan int),
+			err:   true,
+		},
+		{
+			name:  "should encode null",
+			input: nil,
+			exp:   "f6",
+		},
+		{
+			name:  "should encode booleans",
+			input: []bool{true, false},
+			exp:   "82f5f4",
+		},
+		{
+			name:  "should encode integers",
+			input: []int64{0, 23, 24, 1000, -1, -1000, 1 << 40},
+			exp:   "87001718181903e8203903e71b0000010000000000",
+		},
+		{
+			name:  "should encode floats",
+			input: 1.5,
+			exp:   "fb3ff8000000000000",
+		},
+		{
+			name:  "should encode strings",
+			input: "IETF",
+			exp:   "6449455446",
+		},
+		{
+			name: "should encode maps with sorted keys",
+			input: struct {
+				B string `json:"b"`
+				A int    `json:"a"`
+			}{B: "x", A: 1},
+			exp: "a2616101616261 78",
+		},
+		{
+			name:  "should encode byte slices as byte strings",
+			input: []byte{1, 2},
+			exp:   "420102",
+		},
+		{
+			name:  "should encode non-finite floats",
+			input: []float64{math.NaN(), math.Inf(1)},
+			exp:   "82 fb7ff8000000000001 fb7ff0000000000000",
+		},
+		{
+			name:  "should encode map keys with their types",
+			input: map[int]string{10: "a", -1: "b"},
+			exp:   "a2 0a 6161 20 6162",
+		},
+		{
+			name:  "should encode json marshalers",
+			input: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			exp:   "74 323032302d30312d30315430303a30303a30305a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := rack.MarshalCBOR(tt.input)
+			assertErrorExists(t, err, tt.err)
+
+			if exp := strings.ReplaceAll(tt.exp, " ", ""); hex.EncodeToString(act) != exp {
+				t.Errorf("got %x, expected %s", act, exp)
+			}
+		})
+	}
+}
+
+func TestUnmarshalCBOR(t *testing.T) {
+	type value struct {
+		A int       `json:"a"`
+		B []string  `json:"b"`
+		C float64   `json:"c"`
+		D bool      `json:"d"`
+		E *struct{} `json:"e"`
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		exp   value
+		err   bool
+	}{
+		{
+			name:  "should return an error if the data is truncated",
+			input: "a16161",
+			err:   true,
+		},
+		{
+			name:  "should return an error if there is trailing data",
+			input: "a0 00",
+			err:   true,
+		},
+		{
+			name:  "should decode definite length items",
+			input: "a5 6161 1864 6162 82 6178 6179 6163 f93e00 6164 f5 6165 f6",
+			exp:   value{A: 100, B: []string{"x", "y"}, C: 1.5, D: true},
+		},
+		{
+			name:  "should decode indefinite length items",
+			input: "bf 6161 20 6162 9f 7f 6178 6179 ff ff 6163 fa3fc00000 ff",
+			exp:   value{A: -1, B: []string{"xy"}, C: 1.5},
+		},
+		{
+			name:  "should return an error for breaks in definite length arrays",
+			input: "a1 6162 81 ff",
+			err:   true,
+		},
+		{
+			name:  "should return an error for breaks in definite length maps",
+			input: "a1 6161 ff",
+			err:   true,
+		},
+		{
+			name:  "should return an error for breaks outside of indefinite length items",
+			input: "ff",
+			err:   true,
+		},
+		{
+			name:  "should ignore tags",
+			input: "a1 6161 c1 1a 5e0be100",
+			exp:   value{A: 1577836800},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(strings.ReplaceAll(tt.input, " ", ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var act value
+			err = rack.UnmarshalCBOR(b, &act)
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestUnmarshalCBOR_Types(t *testing.T) {
+	type value struct {
+		Bytes []byte            `json:"bytes"`
+		Keys  map[int]string    `json:"keys"`
+		Time  time.Time         `json:"time"`
+		Any   interface{}       `json:"any"`
+		Float float32           `json:"float"`
+		Uint  uint8             `json:"uint"`
+		Text  map[string]uint64 `json:"text"`
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		exp   value
+		err   bool
+	}{
+		{
+			name:  "should decode byte strings",
+			input: "a1 656279746573 420102",
+			exp:   value{Bytes: []byte{1, 2}},
+		},
+		{
+			name:  "should decode map keys with their types",
+			input: "a1 646b657973 a2 0a 6161 20 6162",
+			exp:   value{Keys: map[int]string{10: "a", -1: "b"}},
+		},
+		{
+			name:  "should decode json unmarshalers",
+			input: "a1 6474696d65 74 323032302d30312d30315430303a30303a30305a",
+			exp:   value{Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:  "should decode interface values",
+			input: "a1 63616e79 a2 6161 00 6162 82 41ff fb3ff8000000000000",
+			exp: value{Any: map[string]interface{}{
+				"a": int64(0),
+				"b": []interface{}{[]byte{0xff}, 1.5},
+			}},
+		},
+		{
+			name:  "should decode interface values with non-text keys",
+			input: "a1 63616e79 a1 01 6161",
+			exp:   value{Any: map[interface{}]interface{}{int64(1): "a"}},
+		},
+		{
+			name:  "should decode integers into floats",
+			input: "a1 65666c6f6174 02",
+			exp:   value{Float: 2},
+		},
+		{
+			name:  "should decode the maximum unsigned integer",
+			input: "a1 6474657874 a1 6161 1b ffffffffffffffff",
+			exp:   value{Text: map[string]uint64{"a": math.MaxUint64}},
+		},
+		{
+			name:  "should return an error if the integer overflows",
+			input: "a1 6475696e74 190100",
+			err:   true,
+		},
+		{
+			name:  "should return an error if a negative integer is decoded into an unsigned integer",
+			input: "a1 6475696e74 20",
+			err:   true,
+		},
+		{
+			name:  "should return an error if the types do not match",
+			input: "a1 656279746573 6161",
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(strings.ReplaceAll(tt.input, " ", ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var act value
+			err = rack.UnmarshalCBOR(b, &act)
+			assertErrorExists(t, err, tt.err)
+
+			if !tt.err {
+				assertDeepEqual(t, act, tt.exp)
+			}
+		})
+	}
+
+	t.Run("should return an error if the value is not a pointer", func(t *testing.T) {
+		var act value
+		err := rack.UnmarshalCBOR([]byte{0xa0}, act)
+		assertErrorExists(t, err, true)
+	})
+
+	t.Run("should round trip values", func(t *testing.T) {
+		exp := value{
+			Bytes: []byte{1, 2, 3},
+			Keys:  map[int]string{1: "a"},
+			Time:  time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			Any:   []interface{}{int64(-1), "a"},
+			Float: 1.5,
+			Uint:  255,
+			Text:  map[string]uint64{"a": 1},
+		}
+
+		b, err := rack.MarshalCBOR(exp)
+		assertErrorExists(t, err, false)
+
+		var act value
+		err = rack.UnmarshalCBOR(b, &act)
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, act, exp)
+	})
+}
+
+func TestUnmarshalCBOR_Integers(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		exp   json.Number
+	}{
+		{
+			name:  "should decode the maximum unsigned integer",
+			input: "1b ffffffffffffffff",
+			exp:   "18446744073709551615",
+		},
+		{
+			name:  "should decode the minimum int64",
+			input: "3b 7fffffffffffffff",
+			exp:   "-9223372036854775808",
+		},
+		{
+			name:  "should decode negative integers below the minimum int64",
+			input: "3b 8000000000000000",
+			exp:   "-9223372036854775809",
+		},
+		{
+			name:  "should decode the minimum negative integer",
+			input: "3b ffffffffffffffff",
+			exp:   "-18446744073709551616",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := hex.DecodeString(strings.ReplaceAll(tt.input, " ", ""))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var act json.Number
+			err = rack.UnmarshalCBOR(b, &act)
+			assertErrorExists(t, err, false)
+
+			if act != tt.exp {
+				t.Errorf("got %s, expected %s", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestUnmarshalCBOR_Depth(t *testing.T) {
+	nested := func(n int) []byte {
+		b := bytes.Repeat([]byte{0x81}, n)
+		return append(b, 0x00)
+	}
+
+	tests := []struct {
+		name  string
+		input []byte
+		err   bool
+	}{
+		{
+			name:  "should decode items within the nesting limit",
+			input: nested(100),
+		},
+		{
+			name:  "should return an error if the nesting limit is exceeded",
+			input: nested(5 << 20),
+			err:   true,
+		},
+		{
+			name:  "should apply the nesting limit to indefinite length items",
+			input: bytes.Repeat([]byte{0x9f}, 5<<20),
+			err:   true,
+		},
+		{
+			name:  "should apply the nesting limit to tags",
+			input: append(bytes.Repeat([]byte{0xc1}, 5<<20), 0x00),
+			err:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var act interface{}
+			err := rack.UnmarshalCBOR(tt.input, &act)
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestCBOR(t *testing.T) {
+	t.Run("should write base64 encoded cbor", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			return rack.CBOR(c, http.StatusOK, map[string]int{"a": 1})
+		})
+
+		act, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		exp := newV2Response(func(r *events.APIGatewayV2HTTPResponse) {
+			r.Headers = map[string]string{
+				"Content-Type": "application/cbor",
+			}
+			r.MultiValueHeaders = map[string][]string{
+				"Content-Type": {"application/cbor"},
+			}
+			r.Body = base64.StdEncoding.EncodeToString([]byte{0xa1, 0x61, 0x61, 0x01})
+			r.IsBase64Encoded = true
+		})
+
+		assertDeepEqual(t, act, exp)
+	})
+
+	t.Run("should not modify the response if the write is ignored", func(t *testing.T) {
+		h := rack.NewWithConfig(rack.Config{WritePolicy: rack.WriteFirstWins}, func(c rack.Context) error {
+			if err := c.String(http.StatusOK, "value"); err != nil {
+				return err
+			}
+			return rack.CBOR(c, http.StatusOK, map[string]int{"a": 1})
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		act := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		assertDeepEqual(t, act.Body, "value")
+		assertDeepEqual(t, act.IsBase64Encoded, false)
+	})
+}
+
+func TestContext_Bind_CBOR(t *testing.T) {
+	tests := []struct {
+		name    string
+		request func(*events.APIGatewayV2HTTPRequest)
+		exp     string
+		err     bool
+	}{
+		{
+			name: "should return an error if the body is invalid",
+			request: func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"content-type": "application/cbor"}
+				r.Body = base64.StdEncoding.EncodeToString([]byte{0xa1, 0x61})
+				r.IsBase64Encoded = true
+			},
+			err: true,
+		},
+		{
+			name: "should return an error if the body is not valid base64",
+			request: func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"content-type": "application/cbor"}
+				r.Body = "!"
+				r.IsBase64Encoded = true
+			},
+			err: true,
+		},
+		{
+			name: "should bind base64 encoded cbor",
+			request: func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"content-type": "application/cbor"}
+				r.Body = base64.StdEncoding.EncodeToString([]byte{0xa1, 0x63, 0x6b, 0x65, 0x79, 0x65, 0x76, 0x61, 0x6c, 0x75, 0x65})
+				r.IsBase64Encoded = true
+			},
+			exp: "value",
+		},
+		{
+			name: "should bind base64 encoded json",
+			request: func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"content-type": "application/json"}
+				r.Body = base64.StdEncoding.EncodeToString([]byte(`{"key":"value"}`))
+				r.IsBase64Encoded = true
+			},
+			exp: "value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var act string
+			var berr error
+
+			h := rack.New(func(c rack.Context) error {
+				v := struct {
+					Key string `json:"key"`
+				}{}
+
+				berr = c.Bind(&v)
+				act = v.Key
+
+				return c.NoContent(http.StatusOK)
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(tt.request))
+			assertErrorExists(t, err, false)
+			assertErrorExists(t, berr, tt.err)
+
+			if act != tt.exp {
+				t.Errorf("got %s, expected %s", act, tt.exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package rack
+
+import (
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// CorrelationChainOptions represents correlation chain options
+type CorrelationChainOptions struct {
+	// Header is the chain header name, defaulting to X-Correlation-Chain
+	Header string
+
+	// Hop is the value appended to the chain, defaulting to the function name and version
+	Hop string
+
+	// MaxHops limits the number of hops retained, discarding the oldest first
+	// A zero value indicates that the chain is unbounded.
+	MaxHops int
+}
+
+// DefaultCorrelationChainHeader is the default correlation chain header
+const DefaultCorrelationChainHeader = "X-Correlation-Chain"
+
+const correlationChainKey = "rack.correlationChain"
+
+// WithCorrelationChain returns a middleware func that maintains a service hop chain
+// The current hop is appended to the chain received in the request header, and the
+// resulting chain is written to the response header. Downstream calls should
+// propagate the value returned by CorrelationChain.
+func WithCorrelationChain(o CorrelationChainOptions) MiddlewareFunc {
+	header := o.Header
+	if header == "" {
+		header = DefaultCorrelationChainHeader
+	}
+
+	hop := o.Hop
+	if hop == "" {
+		hop = lambdacontext.FunctionName + ":" + lambdacontext.FunctionVersion
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			var hops []string
+			for _, v := range c.Request().Header.Values(header) {
+				for _, h := range strings.Split(v, ",") {
+					if h = strings.TrimSpace(h); h != "" {
+						hops = append(hops, h)
+					}
+				}
+			}
+
+			hops = append(hops, hop)
+			if o.MaxHops > 0 && len(hops) > o.MaxHops {
+				hops = hops[len(hops)-o.MaxHops:]
+			}
+
+			chain := strings.Join(hops, ", ")
+
+			c.Set(correlationChainKey, chain)
+			c.Response().Headers.Set(header, chain)
+
+			return n(c)
+		}
+	}
+}
+
+// CorrelationChain returns the correlation chain for the request
+// An empty string is returned if the WithCorrelationChain middleware has not been applied.
+func CorrelationChain(c Context) string {
+	s, _ := c.Get(correlationChainKey).(string)
+	return s
+}
@@ -0,0 +1,77 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestWithCorrelationChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    rack.CorrelationChainOptions
+		headers map[string]string
+		header  string
+		exp     string
+	}{
+		{
+			name:   "should start a new chain",
+			opts:   rack.CorrelationChainOptions{Hop: "c:1"},
+			header: "X-Correlation-Chain",
+			exp:    "c:1",
+		},
+		{
+			name:    "should append to an existing chain",
+			opts:    rack.CorrelationChainOptions{Hop: "c:1"},
+			headers: map[string]string{"x-correlation-chain": "a:1, b:$LATEST"},
+			header:  "X-Correlation-Chain",
+			exp:     "a:1, b:$LATEST, c:1",
+		},
+		{
+			name:    "should use the configured header",
+			opts:    rack.CorrelationChainOptions{Header: "X-Hops", Hop: "c:1"},
+			headers: map[string]string{"x-hops": "a:1"},
+			header:  "X-Hops",
+			exp:     "a:1, c:1",
+		},
+		{
+			name:    "should discard the oldest hops",
+			opts:    rack.CorrelationChainOptions{Hop: "c:1", MaxHops: 2},
+			headers: map[string]string{"x-correlation-chain": "a:1,b:1"},
+			header:  "X-Correlation-Chain",
+			exp:     "b:1, c:1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var chain string
+
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.WithCorrelationChain(tt.opts),
+			}, func(c rack.Context) error {
+				chain = rack.CorrelationChain(c)
+				return c.NoContent(http.StatusOK)
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = tt.headers
+			}))
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if act.Headers[tt.header] != tt.exp {
+				t.Errorf("got %s, expected %s", act.Headers[tt.header], tt.exp)
+			}
+			if chain != tt.exp {
+				t.Errorf("got %s, expected %s", chain, tt.exp)
+			}
+		})
+	}
+}
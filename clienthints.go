@@ -0,0 +1,155 @@
+package rack
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type (
+	// ClientHints represents the client hints sent with a request
+	// Low entropy hints are sent by default, while other hints are only sent once
+	// requested using the Accept-CH header.
+	ClientHints struct {
+		Brands          []Brand
+		FullVersionList []Brand
+		Mobile          bool
+		Platform        string
+		PlatformVersion string
+		Model           string
+		Arch            string
+		Bitness         string
+		DPR             float64
+		ViewportWidth   int
+		DeviceMemory    float64
+	}
+
+	// Brand represents a user agent brand and version
+	Brand struct {
+		Brand   string
+		Version string
+	}
+)
+
+// Client hint names
+const (
+	ClientHintUA                = "Sec-CH-UA"
+	ClientHintUAFullVersionList = "Sec-CH-UA-Full-Version-List"
+	ClientHintUAMobile          = "Sec-CH-UA-Mobile"
+	ClientHintUAPlatform        = "Sec-CH-UA-Platform"
+	ClientHintUAPlatformVersion = "Sec-CH-UA-Platform-Version"
+	ClientHintUAModel           = "Sec-CH-UA-Model"
+	ClientHintUAArch            = "Sec-CH-UA-Arch"
+	ClientHintUABitness         = "Sec-CH-UA-Bitness"
+	ClientHintDPR               = "Sec-CH-DPR"
+	ClientHintViewportWidth     = "Sec-CH-Viewport-Width"
+	ClientHintDeviceMemory      = "Sec-CH-Device-Memory"
+)
+
+// ParseClientHints parses the client hint headers
+// Hints that are missing or malformed are left at their zero value.
+func ParseClientHints(h http.Header) ClientHints {
+	ch := ClientHints{
+		Brands:          parseBrands(h.Get(ClientHintUA)),
+		FullVersionList: parseBrands(h.Get(ClientHintUAFullVersionList)),
+		Mobile:          strings.TrimSpace(h.Get(ClientHintUAMobile)) == "?1",
+		Platform:        unquote(h.Get(ClientHintUAPlatform)),
+		PlatformVersion: unquote(h.Get(ClientHintUAPlatformVersion)),
+		Model:           unquote(h.Get(ClientHintUAModel)),
+		Arch:            unquote(h.Get(ClientHintUAArch)),
+		Bitness:         unquote(h.Get(ClientHintUABitness)),
+	}
+
+	ch.DPR, _ = strconv.ParseFloat(strings.TrimSpace(h.Get(ClientHintDPR)), 64)
+	ch.ViewportWidth, _ = strconv.Atoi(strings.TrimSpace(h.Get(ClientHintViewportWidth)))
+	ch.DeviceMemory, _ = strconv.ParseFloat(strings.TrimSpace(h.Get(ClientHintDeviceMemory)), 64)
+
+	return ch
+}
+
+// AcceptClientHints adds the hints to the Accept-CH response header
+// Hints are combined into a single header value, as single value header maps only
+// contain the first value. Responses that vary by hint should also set the Vary header.
+func AcceptClientHints(c Context, hints ...string) {
+	if len(hints) == 0 {
+		return
+	}
+
+	vs := make([]string, 0, len(hints)+1)
+	if v := c.Response().Headers.Get("Accept-CH"); v != "" {
+		vs = append(vs, v)
+	}
+
+	c.Response().Headers.Set("Accept-CH", strings.Join(append(vs, hints...), ", "))
+}
+
+// WithClientHints returns a middleware func that requests the hints in html responses
+// Browsers only act on the Accept-CH header in navigation responses, so the hints
+// are sent with subsequent requests.
+func WithClientHints(hints ...string) MiddlewareFunc {
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			if err := n(c); err != nil {
+				return err
+			}
+
+			if mt, _, _ := mime.ParseMediaType(c.Response().Headers.Get("Content-Type")); mt == "text/html" {
+				AcceptClientHints(c, hints...)
+			}
+
+			return nil
+		}
+	}
+}
+
+// parseBrands parses a structured header brand list, such as "Chromium";v="120"
+func parseBrands(v string) []Brand {
+	var bs []Brand
+	for _, m := range splitQuoted(v, ',') {
+		ps := splitQuoted(m, ';')
+
+		b := Brand{Brand: unquote(ps[0])}
+		for _, p := range ps[1:] {
+			if k, v, ok := strings.Cut(strings.TrimSpace(p), "="); ok && k == "v" {
+				b.Version = unquote(v)
+			}
+		}
+
+		if b.Brand != "" {
+			bs = append(bs, b)
+		}
+	}
+
+	return bs
+}
+
+// splitQuoted splits the string on the separator, ignoring separators in quoted strings
+func splitQuoted(s string, sep byte) []string {
+	var ps []string
+	quoted, start := false, 0
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			ps = append(ps, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(ps, s[start:])
+}
+
+// unquote returns the value of a structured header string
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+
+	return s
+}
@@ -0,0 +1,132 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestParseClientHints(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		exp    rack.ClientHints
+	}{
+		{
+			name:   "should return zero values if no hints are sent",
+			header: map[string]string{},
+		},
+		{
+			name: "should parse the hints",
+			header: map[string]string{
+				rack.ClientHintUA:                `"Not_A Brand";v="8", "Chromium";v="120", "Google Chrome";v="120"`,
+				rack.ClientHintUAFullVersionList: `"Chromium";v="120.0.6099.129"`,
+				rack.ClientHintUAMobile:          "?1",
+				rack.ClientHintUAPlatform:        `"Android"`,
+				rack.ClientHintUAPlatformVersion: `"14.0.0"`,
+				rack.ClientHintUAModel:           `"Pixel 8"`,
+				rack.ClientHintUAArch:            `""`,
+				rack.ClientHintUABitness:         `"64"`,
+				rack.ClientHintDPR:               "2.625",
+				rack.ClientHintViewportWidth:     "412",
+				rack.ClientHintDeviceMemory:      "8",
+			},
+			exp: rack.ClientHints{
+				Brands: []rack.Brand{
+					{Brand: "Not_A Brand", Version: "8"},
+					{Brand: "Chromium", Version: "120"},
+					{Brand: "Google Chrome", Version: "120"},
+				},
+				FullVersionList: []rack.Brand{{Brand: "Chromium", Version: "120.0.6099.129"}},
+				Mobile:          true,
+				Platform:        "Android",
+				PlatformVersion: "14.0.0",
+				Model:           "Pixel 8",
+				Bitness:         "64",
+				DPR:             2.625,
+				ViewportWidth:   412,
+				DeviceMemory:    8,
+			},
+		},
+		{
+			name: "should handle separators in quoted brands",
+			header: map[string]string{
+				rack.ClientHintUA:       `"Not;A=Brand";v="99", "Chromium";v="118"`,
+				rack.ClientHintUAMobile: "?0",
+			},
+			exp: rack.ClientHints{
+				Brands: []rack.Brand{
+					{Brand: "Not;A=Brand", Version: "99"},
+					{Brand: "Chromium", Version: "118"},
+				},
+			},
+		},
+		{
+			name: "should ignore malformed numeric hints",
+			header: map[string]string{
+				rack.ClientHintDPR:           "invalid",
+				rack.ClientHintViewportWidth: "invalid",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range tt.header {
+				h.Set(k, v)
+			}
+
+			assertDeepEqual(t, rack.ParseClientHints(h), tt.exp)
+		})
+	}
+}
+
+func TestWithClientHints(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		existing    string
+		exp         string
+	}{
+		{
+			name:        "should add the hints to html responses",
+			contentType: "text/html; charset=utf-8",
+			exp:         "Sec-CH-UA-Model, Sec-CH-DPR",
+		},
+		{
+			name:        "should append to existing hints",
+			contentType: "text/html",
+			existing:    "Sec-CH-UA-Arch",
+			exp:         "Sec-CH-UA-Arch, Sec-CH-UA-Model, Sec-CH-DPR",
+		},
+		{
+			name:        "should not add the hints to other responses",
+			contentType: "application/json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.WithClientHints(rack.ClientHintUAModel, rack.ClientHintDPR),
+			}, func(c rack.Context) error {
+				if tt.existing != "" {
+					rack.AcceptClientHints(c, tt.existing)
+				}
+
+				return rack.Blob(c, http.StatusOK, tt.contentType, nil)
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			assertDeepEqual(t, res.Headers["Accept-Ch"], tt.exp)
+		})
+	}
+}
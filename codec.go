@@ -0,0 +1,552 @@
+package rack
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Codec represents a request and response body codec
+	Codec interface {
+		Marshal(v interface{}) ([]byte, error)
+		Unmarshal(b []byte, v interface{}) error
+	}
+
+	// JSONOptions represents JSON encoding options
+	JSONOptions struct {
+		// TimeLayout is the layout used to format time.Time values
+		// The default time.Time encoding is used if the layout is empty.
+		TimeLayout string
+
+		// Int64AsString encodes int64 and uint64 values as JSON strings
+		// Named types, such as time.Duration, are encoded as numbers.
+		Int64AsString bool
+
+		// OmitEmptyMaps omits nil and empty map values from objects
+		OmitEmptyMaps bool
+	}
+
+	jsonCodec struct {
+		opts JSONOptions
+	}
+
+	// jsonTransformer holds the state of a single transform
+	jsonTransformer struct {
+		opts JSONOptions
+		seen map[cycleKey]bool
+	}
+
+	// cycleKey identifies a pointer, map or slice on the current transform path
+	cycleKey struct {
+		ptr uintptr
+		typ reflect.Type
+		len int
+	}
+
+	structField struct {
+		name      string
+		index     []int
+		tagged    bool
+		omitEmpty bool
+		quoted    bool
+	}
+
+	jsonObject []jsonField
+
+	jsonField struct {
+		name  string
+		value interface{}
+	}
+)
+
+var (
+	// DefaultJSONCodec is the default JSON codec
+	DefaultJSONCodec Codec = &jsonCodec{}
+
+	timeType          = reflect.TypeOf(time.Time{})
+	int64Type         = reflect.TypeOf(int64(0))
+	uint64Type        = reflect.TypeOf(uint64(0))
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	// fieldCache holds the encoded fields for each struct type
+	fieldCache sync.Map
+)
+
+// NewJSONCodec returns a new JSON codec with the specified options
+// Options are applied when encoding, struct tags and marshalers are honoured as
+// with encoding/json. Decoding is unaffected by the options.
+func NewJSONCodec(o JSONOptions) Codec {
+	return &jsonCodec{opts: o}
+}
+
+// contextCodec returns the codec configured for the context
+// DefaultJSONCodec is returned for other Context implementations.
+func contextCodec(c Context) Codec {
+	if hc, ok := c.(*handlerContext); ok && hc.codec != nil {
+		return hc.codec
+	}
+
+	return DefaultJSONCodec
+}
+
+func (c *jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	if c.opts == (JSONOptions{}) {
+		return json.Marshal(v)
+	}
+
+	tr := &jsonTransformer{
+		opts: c.opts,
+		seen: map[cycleKey]bool{},
+	}
+
+	t, err := tr.transform(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(t)
+}
+
+func (c *jsonCodec) Unmarshal(b []byte, v interface{}) error {
+	return json.Unmarshal(b, v)
+}
+
+// transform converts the value into a tree with the encoding options applied
+func (tr *jsonTransformer) transform(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	t := v.Type()
+
+	if t == timeType && tr.opts.TimeLayout != "" {
+		return v.Interface().(time.Time).Format(tr.opts.TimeLayout), nil
+	}
+
+	if (t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface) && v.IsNil() {
+		return nil, nil
+	}
+
+	if t.Kind() == reflect.Interface {
+		return tr.transform(v.Elem())
+	}
+
+	if t.Kind() == reflect.Ptr {
+		leave, err := tr.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+
+		return tr.transform(v.Elem())
+	}
+
+	if t.Implements(marshalerType) || t.Implements(textMarshalerType) {
+		return v.Interface(), nil
+	}
+
+	if pt := reflect.PtrTo(t); pt.Implements(marshalerType) || pt.Implements(textMarshalerType) {
+		p := reflect.New(t)
+		p.Elem().Set(v)
+		return p.Interface(), nil
+	}
+
+	if tr.opts.Int64AsString {
+		switch t {
+		case int64Type:
+			return strconv.FormatInt(v.Int(), 10), nil
+		case uint64Type:
+			return strconv.FormatUint(v.Uint(), 10), nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return tr.transformStruct(v)
+	case reflect.Map:
+		return tr.transformMap(v)
+	case reflect.Slice:
+		if v.IsNil() || t.Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil
+		}
+
+		leave, err := tr.enter(v)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+
+		fallthrough
+	case reflect.Array:
+		a := make([]interface{}, v.Len())
+		for i := range a {
+			e, err := tr.transform(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			a[i] = e
+		}
+		return a, nil
+	}
+
+	return v.Interface(), nil
+}
+
+// enter records the pointer, map or slice as being on the current path
+// An error is returned if the value has already been entered, as encoding/json
+// does for cyclic values.
+func (tr *jsonTransformer) enter(v reflect.Value) (func(), error) {
+	k := cycleKey{ptr: v.Pointer(), typ: v.Type()}
+	if v.Kind() == reflect.Slice {
+		k.len = v.Len()
+	}
+
+	if tr.seen[k] {
+		return nil, &json.UnsupportedValueError{
+			Value: v,
+			Str:   fmt.Sprintf("encountered a cycle via %s", v.Type()),
+		}
+	}
+
+	tr.seen[k] = true
+	return func() { delete(tr.seen, k) }, nil
+}
+
+func (tr *jsonTransformer) transformStruct(v reflect.Value) (interface{}, error) {
+	var o jsonObject
+	for _, f := range structFields(v.Type()) {
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok {
+			continue
+		}
+
+		if (f.omitEmpty && isEmptyValue(fv)) || tr.omitMap(fv) {
+			continue
+		}
+
+		if f.quoted {
+			q, ok, err := quoteValue(fv)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				o = append(o, jsonField{name: f.name, value: q})
+				continue
+			}
+		}
+
+		e, err := tr.transform(fv)
+		if err != nil {
+			return nil, err
+		}
+
+		o = append(o, jsonField{name: f.name, value: e})
+	}
+
+	return o, nil
+}
+
+func (tr *jsonTransformer) transformMap(v reflect.Value) (interface{}, error) {
+	if v.IsNil() {
+		return nil, nil
+	}
+
+	leave, err := tr.enter(v)
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	m := make(map[string]interface{}, v.Len())
+
+	iter := v.MapRange()
+	for iter.Next() {
+		if tr.omitMap(iter.Value()) {
+			continue
+		}
+
+		k, err := mapKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := tr.transform(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+
+		m[k] = e
+	}
+
+	return m, nil
+}
+
+func (tr *jsonTransformer) omitMap(v reflect.Value) bool {
+	if !tr.opts.OmitEmptyMaps {
+		return false
+	}
+
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+
+	return v.Kind() == reflect.Map && v.Len() < 1
+}
+
+// structFields returns the encoded fields of the struct type
+// Fields are selected using the encoding/json rules. Promoted fields are shadowed
+// by fields at shallower depths, and if multiple fields have the same name at the
+// same depth then the tagged field is used, otherwise all of them are dropped.
+func structFields(t reflect.Type) []structField {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]structField)
+	}
+
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.([]structField)
+}
+
+func typeFields(t reflect.Type) []structField {
+	var fields []structField
+
+	visited := map[reflect.Type]bool{}
+
+	var walk func(reflect.Type, []int)
+	walk = func(t reflect.Type, index []int) {
+		// embedded types that have already been walked cannot contribute fields
+		if visited[t] {
+			return
+		}
+		visited[t] = true
+		defer delete(visited, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+
+			tag := sf.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+
+			name, opts := tag, ""
+			if n := strings.Index(tag, ","); n >= 0 {
+				name, opts = tag[:n], tag[n+1:]
+			}
+
+			idx := append(append([]int{}, index...), i)
+
+			if sf.Anonymous && name == "" {
+				ft := sf.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft, idx)
+					continue
+				}
+			}
+
+			if sf.PkgPath != "" {
+				continue
+			}
+
+			f := structField{
+				name:      name,
+				index:     idx,
+				tagged:    name != "",
+				omitEmpty: hasOption(opts, "omitempty"),
+				quoted:    hasOption(opts, "string"),
+			}
+			if !f.tagged {
+				f.name = sf.Name
+			}
+
+			fields = append(fields, f)
+		}
+	}
+
+	walk(t, nil)
+
+	byName := map[string][]int{}
+	for i, f := range fields {
+		byName[f.name] = append(byName[f.name], i)
+	}
+
+	var res []structField
+	for i, f := range fields {
+		if dominantField(fields, byName[f.name]) == i {
+			res = append(res, f)
+		}
+	}
+
+	return res
+}
+
+// dominantField returns the index of the field that is encoded, or -1 if the fields are ambiguous
+func dominantField(fields []structField, candidates []int) int {
+	depth := -1
+	for _, i := range candidates {
+		if d := len(fields[i].index); depth < 0 || d < depth {
+			depth = d
+		}
+	}
+
+	res := -1
+	var tagged bool
+	for _, i := range candidates {
+		f := fields[i]
+		if len(f.index) != depth {
+			continue
+		}
+
+		switch {
+		case res < 0:
+			res, tagged = i, f.tagged
+		case f.tagged && !tagged:
+			res, tagged = i, true
+		case f.tagged == tagged:
+			return -1
+		}
+	}
+
+	return res
+}
+
+// fieldByIndex returns the nested field, or false if it is within a nil embedded pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v, true
+}
+
+// MarshalJSON writes the object fields in declaration order
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		return string(b), err
+	}
+
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+
+	return "", &json.UnsupportedTypeError{Type: k.Type()}
+}
+
+// quoteValue applies the json string tag option to the scalar value
+// False is returned if the option does not apply, in which case the value is
+// encoded as normal. Options are not applied to quoted values, matching encoding/json.
+func quoteValue(v reflect.Value) (interface{}, bool, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		v = v.Elem()
+	}
+
+	if t := v.Type(); t.Implements(marshalerType) || t.Implements(textMarshalerType) {
+		return nil, false, nil
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+
+	switch v.Kind() {
+	case reflect.String:
+		b, err = json.Marshal(v.String())
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		b, err = json.Marshal(v.Interface())
+	default:
+		return nil, false, nil
+	}
+
+	return string(b), true, err
+}
+
+func hasOption(opts, name string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+
+	return false
+}
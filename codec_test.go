@@ -0,0 +1,223 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+type codecBase struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner,omitempty"`
+}
+
+type codecValue struct {
+	codecBase
+	Name     string            `json:"name"`
+	Count    uint64            `json:"count"`
+	Size     int               `json:"size"`
+	Created  time.Time         `json:"created"`
+	Updated  *time.Time        `json:"updated,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	Nested   []codecBase       `json:"nested,omitempty"`
+	Quoted   int               `json:"quoted,string"`
+	Ignored  string            `json:"-"`
+	internal string
+}
+
+type (
+	codecA struct {
+		Name string
+		Tag  string `json:"tag"`
+	}
+
+	codecB struct {
+		Name string
+		Tag  string
+	}
+
+	codecAmbiguous struct {
+		codecA
+		codecB
+		ID int64 `json:"id"`
+	}
+
+	codecC struct {
+		Label string `json:"Name"`
+	}
+
+	codecTagged struct {
+		codecA
+		codecC
+	}
+
+	codecShadow struct {
+		*codecA
+		Name string `json:"Name,omitempty"`
+	}
+
+	codecQuoted struct {
+		ID       int64         `json:"id,string"`
+		Count    *uint64       `json:"count,string"`
+		Name     string        `json:"name,string"`
+		Timeout  time.Duration `json:"timeout"`
+		Disabled *bool         `json:"disabled,string"`
+	}
+
+	codecNode struct {
+		Next *codecNode `json:"next"`
+	}
+)
+
+func TestJSONCodec_Marshal(t *testing.T) {
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	value := codecValue{
+		codecBase: codecBase{ID: 9007199254740993},
+		Name:      "name",
+		Count:     1,
+		Size:      2,
+		Created:   created,
+		Labels:    map[string]string{},
+		Nested:    []codecBase{{ID: 1, Owner: "owner"}},
+		Quoted:    3,
+		Ignored:   "ignored",
+		internal:  "internal",
+	}
+
+	tests := []struct {
+		name  string
+		opts  rack.JSONOptions
+		input interface{}
+		exp   string
+		err   bool
+	}{
+		{
+			name:  "should return an error if the value cannot be marshaled",
+			opts:  rack.JSONOptions{Int64AsString: true},
+			input: map[string]interface{}{"a": make(chan int)},
+			err:   true,
+		},
+		{
+			name:  "should use the default encoding",
+			input: value,
+			exp:   `{"id":9007199254740993,"name":"name","count":1,"size":2,"created":"2020-01-02T03:04:05Z","labels":{},"nested":[{"id":1,"owner":"owner"}],"quoted":"3"}`,
+		},
+		{
+			name:  "should apply the time layout",
+			opts:  rack.JSONOptions{TimeLayout: "2006-01-02"},
+			input: value,
+			exp:   `{"id":9007199254740993,"name":"name","count":1,"size":2,"created":"2020-01-02","labels":{},"nested":[{"id":1,"owner":"owner"}],"quoted":"3"}`,
+		},
+		{
+			name:  "should encode int64 values as strings",
+			opts:  rack.JSONOptions{Int64AsString: true},
+			input: &value,
+			exp:   `{"id":"9007199254740993","name":"name","count":"1","size":2,"created":"2020-01-02T03:04:05Z","labels":{},"nested":[{"id":"1","owner":"owner"}],"quoted":"3"}`,
+		},
+		{
+			name: "should omit empty maps",
+			opts: rack.JSONOptions{OmitEmptyMaps: true},
+			input: map[string]interface{}{
+				"a": map[string]int{},
+				"b": map[string]int{"c": 1},
+			},
+			exp: `{"b":{"c":1}}`,
+		},
+		{
+			name:  "should drop ambiguous fields and use tagged fields",
+			opts:  rack.JSONOptions{Int64AsString: true},
+			input: codecAmbiguous{codecA: codecA{Name: "a", Tag: "a"}, codecB: codecB{Name: "b", Tag: "b"}, ID: 1},
+			exp:   `{"tag":"a","Tag":"b","id":"1"}`,
+		},
+		{
+			name:  "should use tagged fields over untagged fields at the same depth",
+			opts:  rack.JSONOptions{Int64AsString: true},
+			input: codecTagged{codecA: codecA{Name: "a", Tag: "a"}, codecC: codecC{Label: "c"}},
+			exp:   `{"tag":"a","Name":"c"}`,
+		},
+		{
+			name:  "should not quote string tagged fields twice",
+			opts:  rack.JSONOptions{Int64AsString: true},
+			input: codecQuoted{ID: 1, Count: func() *uint64 { v := uint64(2); return &v }(), Name: "a", Timeout: time.Second},
+			exp:   `{"id":"1","count":"2","name":"\"a\"","timeout":1000000000,"disabled":null}`,
+		},
+		{
+			name:  "should shadow promoted fields with empty fields",
+			opts:  rack.JSONOptions{Int64AsString: true},
+			input: codecShadow{codecA: &codecA{Name: "a", Tag: "a"}},
+			exp:   `{"tag":"a"}`,
+		},
+		{
+			name:  "should skip fields of nil embedded pointers",
+			opts:  rack.JSONOptions{Int64AsString: true},
+			input: codecShadow{Name: "b"},
+			exp:   `{"Name":"b"}`,
+		},
+		{
+			name:  "should return an error for cyclic values",
+			opts:  rack.JSONOptions{Int64AsString: true},
+			input: func() interface{} { n := &codecNode{}; n.Next = n; return n }(),
+			err:   true,
+		},
+		{
+			name:  "should return an error for cyclic maps",
+			opts:  rack.JSONOptions{Int64AsString: true},
+			input: func() interface{} { m := map[string]interface{}{}; m["a"] = m; return m }(),
+			err:   true,
+		},
+		{
+			name:  "should allow repeated acyclic values",
+			opts:  rack.JSONOptions{Int64AsString: true},
+			input: func() interface{} { n := &codecNode{}; return []*codecNode{n, n} }(),
+			exp:   `[{"next":null},{"next":null}]`,
+		},
+		{
+			name:  "should apply the time layout to pointers",
+			opts:  rack.JSONOptions{TimeLayout: time.Kitchen},
+			input: []*time.Time{&created, nil},
+			exp:   `["3:04AM",null]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := rack.NewJSONCodec(tt.opts).Marshal(tt.input)
+			assertErrorExists(t, err, tt.err)
+
+			if string(act) != tt.exp {
+				t.Errorf("got %s, expected %s", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestConfig_Codec(t *testing.T) {
+	t.Run("should use the codec to write json", func(t *testing.T) {
+		h := rack.NewWithConfig(rack.Config{
+			Codec: rack.NewJSONCodec(rack.JSONOptions{Int64AsString: true}),
+		}, func(c rack.Context) error {
+			return c.JSON(http.StatusOK, map[string]int64{"id": 1})
+		})
+
+		act, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		exp := newV2Response(func(r *events.APIGatewayV2HTTPResponse) {
+			r.Headers = map[string]string{
+				"Content-Type": "application/json",
+			}
+			r.MultiValueHeaders = map[string][]string{
+				"Content-Type": {"application/json"},
+			}
+			r.Body = `{"id":"1"}`
+		})
+
+		assertDeepEqual(t, act, exp)
+	})
+}
@@ -0,0 +1,187 @@
+package rack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+type (
+	// ConnectionState represents per-connection websocket state
+	ConnectionState struct {
+		UserID        string            `json:"userId,omitempty"`
+		Subscriptions []string          `json:"subscriptions,omitempty"`
+		Values        map[string]string `json:"values,omitempty"`
+	}
+
+	// ConnectionStore represents a websocket connection state store
+	ConnectionStore interface {
+		// Load returns the state for the specified connection
+		// Nil is returned if no state exists.
+		Load(ctx context.Context, connectionID string) (*ConnectionState, error)
+
+		// Save stores the state for the specified connection
+		Save(ctx context.Context, connectionID string, s *ConnectionState) error
+
+		// Delete removes the state for the specified connection
+		Delete(ctx context.Context, connectionID string) error
+	}
+
+	cacheConnectionStore struct {
+		cache Cache
+		ttl   time.Duration
+	}
+
+	connectionState struct {
+		store ConnectionStore
+		state *ConnectionState
+		raw   []byte
+		mu    sync.Mutex
+	}
+)
+
+// WebSocketDisconnectRoute is the websocket $disconnect route key
+const WebSocketDisconnectRoute = "$disconnect"
+
+const connectionStateKey = "rack.connectionState"
+
+var (
+	// ErrNoConnectionStore indicates that no connection store has been configured
+	ErrNoConnectionStore = errors.New("no connection store configured")
+
+	// ErrNotWebSocket indicates that the request is not a websocket event
+	ErrNotWebSocket = errors.New("request is not a websocket event")
+)
+
+// NewCacheConnectionStore returns a new connection store backed by the specified cache
+// The ttl should exceed the maximum websocket connection duration, which is two
+// hours for API Gateway, so that abandoned state is eventually removed.
+func NewCacheConnectionStore(c Cache, ttl time.Duration) ConnectionStore {
+	return &cacheConnectionStore{
+		cache: c,
+		ttl:   ttl,
+	}
+}
+
+func (s *cacheConnectionStore) Load(ctx context.Context, connectionID string) (*ConnectionState, error) {
+	b, ok, err := s.cache.Get(ctx, connectionID)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	cs := new(ConnectionState)
+	if err = json.Unmarshal(b, cs); err != nil {
+		return nil, err
+	}
+
+	return cs, nil
+}
+
+func (s *cacheConnectionStore) Save(ctx context.Context, connectionID string, cs *ConnectionState) error {
+	b, err := json.Marshal(cs)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Set(ctx, connectionID, b, s.ttl)
+}
+
+func (s *cacheConnectionStore) Delete(ctx context.Context, connectionID string) error {
+	return s.cache.Delete(ctx, connectionID)
+}
+
+// WithConnectionStore returns a middleware func that manages websocket connection state
+// State is loaded from the store on first access using LoadConnectionState, and
+// saved if it has been modified once the handler returns without error. State is
+// deleted once the connection has been closed.
+func WithConnectionStore(s ConnectionStore) MiddlewareFunc {
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			cs := &connectionState{store: s}
+			c.Set(connectionStateKey, cs)
+
+			if err := n(c); err != nil {
+				return err
+			}
+
+			return cs.persist(c)
+		}
+	}
+}
+
+// LoadConnectionState returns the state for the websocket connection
+// State is loaded from the configured connection store on first access.
+func LoadConnectionState(c Context) (*ConnectionState, error) {
+	cs, ok := c.Get(connectionStateKey).(*connectionState)
+	if !ok {
+		return nil, ErrNoConnectionStore
+	}
+
+	return cs.load(c)
+}
+
+func (s *connectionState) load(c Context) (*ConnectionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != nil {
+		return s.state, nil
+	}
+
+	rc, ok := WebSocketRequestContext(c)
+	if !ok {
+		return nil, ErrNotWebSocket
+	}
+
+	cs, err := s.store.Load(c.Context(), rc.ConnectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cs == nil {
+		cs = new(ConnectionState)
+	}
+
+	// retain the loaded state so that unchanged state is not saved
+	if s.raw, err = json.Marshal(cs); err != nil {
+		return nil, err
+	}
+
+	s.state = cs
+	return cs, nil
+}
+
+// persist saves the connection state if it has been modified
+// State is deleted once the connection has been closed.
+func (s *connectionState) persist(c Context) error {
+	s.mu.Lock()
+	cs, raw := s.state, s.raw
+	s.mu.Unlock()
+
+	rc, ok := WebSocketRequestContext(c)
+	if !ok {
+		return nil
+	}
+
+	if rc.RouteKey == WebSocketDisconnectRoute {
+		return s.store.Delete(c.Context(), rc.ConnectionID)
+	}
+
+	if cs == nil {
+		return nil
+	}
+
+	b, err := json.Marshal(cs)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(b, raw) {
+		return nil
+	}
+
+	return s.store.Save(c.Context(), rc.ConnectionID, cs)
+}
@@ -0,0 +1,119 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestLoadConnectionState(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   bool
+		payload []byte
+		initial *rack.ConnectionState
+		fn      func(*rack.ConnectionState)
+		exp     *rack.ConnectionState
+		err     bool
+	}{
+		{
+			name:    "should return an error if no store is configured",
+			payload: newWebSocketRequest(nil),
+			err:     true,
+		},
+		{
+			name:    "should return an error for non-websocket requests",
+			store:   true,
+			payload: newV2Request(nil),
+			err:     true,
+		},
+		{
+			name:    "should return empty state for new connections",
+			store:   true,
+			payload: newWebSocketRequest(nil),
+			fn:      func(*rack.ConnectionState) {},
+		},
+		{
+			name:    "should save modified state",
+			store:   true,
+			payload: newWebSocketRequest(nil),
+			fn: func(s *rack.ConnectionState) {
+				s.UserID = "user"
+			},
+			exp: &rack.ConnectionState{UserID: "user"},
+		},
+		{
+			name:    "should load existing state",
+			store:   true,
+			payload: newWebSocketRequest(func(r *events.APIGatewayWebsocketProxyRequest) { r.RequestContext.RouteKey = "$default" }),
+			initial: &rack.ConnectionState{UserID: "user"},
+			fn: func(s *rack.ConnectionState) {
+				s.Subscriptions = append(s.Subscriptions, "topic")
+			},
+			exp: &rack.ConnectionState{UserID: "user", Subscriptions: []string{"topic"}},
+		},
+		{
+			name:    "should delete state on disconnect",
+			store:   true,
+			payload: newWebSocketRequest(func(r *events.APIGatewayWebsocketProxyRequest) { r.RequestContext.RouteKey = "$disconnect" }),
+			initial: &rack.ConnectionState{UserID: "user"},
+			fn:      func(*rack.ConnectionState) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg rack.Config
+			store := rack.NewCacheConnectionStore(rack.NewMemoryCache(), time.Hour)
+
+			if tt.store {
+				cfg.Middleware = rack.WithConnectionStore(store)
+			}
+
+			if tt.initial != nil {
+				store.Save(context.Background(), "connectionid", tt.initial)
+			}
+
+			var serr error
+			h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+				var s *rack.ConnectionState
+				if s, serr = rack.LoadConnectionState(c); serr == nil {
+					tt.fn(s)
+				}
+				return c.NoContent(http.StatusOK)
+			})
+
+			_, err := h.Invoke(context.Background(), tt.payload)
+			assertErrorExists(t, err, false)
+			assertErrorExists(t, serr, tt.err)
+
+			act, err := store.Load(context.Background(), "connectionid")
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestCacheConnectionStore(t *testing.T) {
+	t.Run("should return cache errors", func(t *testing.T) {
+		c := newTestItemStore()
+		c.err = errors.New("error")
+
+		sut := rack.NewCacheConnectionStore(rack.NewItemStoreCache(c, "table"), time.Hour)
+
+		_, err := sut.Load(context.Background(), "id")
+		assertErrorExists(t, err, true)
+
+		err = sut.Save(context.Background(), "id", &rack.ConnectionState{})
+		assertErrorExists(t, err, true)
+
+		err = sut.Delete(context.Background(), "id")
+		assertErrorExists(t, err, true)
+	})
+}
@@ -2,9 +2,13 @@ package rack
 
 import (
 	"context"
-	"encoding/json"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
 	"sync"
+	"time"
 )
 
 type (
@@ -16,6 +20,12 @@ type (
 		// Request returns the canonical request
 		Request() *Request
 
+		// SetRequest replaces the canonical request
+		// Subsequent middleware and the handler receive the new request, allowing
+		// paths to be rewritten or bodies substituted. Nil requests are ignored, and
+		// the raw event is unchanged.
+		SetRequest(r *Request)
+
 		// Response returns the canonical response
 		Response() *Response
 
@@ -35,7 +45,8 @@ type (
 		Query(key string) string
 
 		// Bind unmarshals the request body into the specified value
-		// Currently only JSON request bodies are supported.
+		// CBOR request bodies are decoded if the content type is application/cbor,
+		// otherwise the body is unmarshaled as JSON.
 		Bind(v interface{}) error
 
 		// NoContent writes the specified status code to the response without a body
@@ -45,27 +56,89 @@ type (
 		String(code int, s string) error
 
 		// JSON writes the specified status code and value to the response as JSON
+		// The value is encoded using the configured codec.
+		// If JSON entity tags are enabled then a weak ETag header is written, and
+		// matching conditional requests receive a 304 response with no body.
+		// If sparse fields are enabled then the response is pruned to the requested fields.
 		JSON(code int, v interface{}) error
 	}
 
+	// WritePolicy represents the behaviour when a response is written more than once
+	WritePolicy int
+
 	handlerContext struct {
-		ctx      context.Context
-		store    map[string]interface{}
-		request  *Request
-		response *Response
-		onBind   func(Context, interface{}) error
-		mu       *sync.RWMutex
+		ctx        context.Context
+		store      map[string]interface{}
+		request    *Request
+		rawEvent   []byte
+		response   *Response
+		onBind     func(Context, interface{}) error
+		onError    func(Context, error) error
+		deferred   []func(context.Context) error
+		finish     []func(error)
+		etag       bool
+		sparse     bool
+		codec      Codec
+		bindLimits BindLimits
+		policy     WritePolicy
+		mu         *sync.RWMutex
 	}
 )
 
+const (
+	// WriteLastWins overwrites the existing response on subsequent writes
+	WriteLastWins WritePolicy = iota
+
+	// WriteFirstWins ignores subsequent writes once the response is committed
+	WriteFirstWins
+
+	// WriteError returns ErrResponseCommitted from subsequent writes
+	WriteError
+)
+
+// ErrResponseCommitted indicates that the response has already been written
+var ErrResponseCommitted = errors.New("response already committed")
+
 func (c *handlerContext) Context() context.Context {
 	return c.ctx
 }
 
+// RemainingTime returns the time remaining until the invocation deadline
+// False is returned if the invocation context does not have a deadline. The deadline
+// is read from Context, so the func supports other Context implementations.
+func RemainingTime(c Context) (time.Duration, bool) {
+	d, ok := c.Context().Deadline()
+	if !ok {
+		return 0, false
+	}
+
+	return time.Until(d), true
+}
+
 func (c *handlerContext) Request() *Request {
 	return c.request
 }
 
+func (c *handlerContext) SetRequest(r *Request) {
+	if r == nil {
+		return
+	}
+
+	r.ensureMaps()
+	c.request = r
+}
+
+// RawEvent returns the original invocation payload
+// The payload must not be modified, as it is shared with the processor. Nil is
+// returned for other Context implementations.
+func RawEvent(c Context) []byte {
+	if hc, ok := c.(*handlerContext); ok {
+		return hc.rawEvent
+	}
+
+	return nil
+}
+
 func (c *handlerContext) Response() *Response {
 	return c.response
 }
@@ -93,33 +166,66 @@ func (c *handlerContext) Set(key string, v interface{}) {
 }
 
 func (c *handlerContext) Path(key string) string {
-	return c.request.Path[key]
+	return c.request.PathValue(key)
 }
 
 func (c *handlerContext) Query(key string) string {
-	return c.request.Query.Get(key)
+	return c.request.QueryValue(key)
 }
 
 func (c *handlerContext) Bind(v interface{}) error {
-	if c.request.Body == "" {
-		return nil
+	b, err := c.body()
+	if err != nil || len(b) == 0 {
+		return err
 	}
 
-	err := json.Unmarshal([]byte(c.request.Body), v)
-	if err != nil {
-		return WrapError(http.StatusBadRequest, err)
+	unmarshal := c.codec.Unmarshal
+	if mt, _, _ := mime.ParseMediaType(c.request.Header.Get("Content-Type")); mt == CBORContentType {
+		// the cbor decoder applies the limits as the body is read
+		unmarshal = func(b []byte, v interface{}) error {
+			return unmarshalCBOR(b, v, c.bindLimits)
+		}
+	} else if err := c.checkBindLimits(b); err != nil {
+		return err
+	}
+
+	if err := unmarshal(b, v); err != nil {
+		return WrapError(http.StatusBadRequest, newBindError(b, err))
 	}
 
 	return c.onBind(c, v)
 }
 
+func (c *handlerContext) body() ([]byte, error) {
+	return requestBody(c.request)
+}
+
+// requestBody returns the request body
+// Bodies are decoded by the built-in processors, but custom processors may return
+// base64 encoded bodies.
+func requestBody(r *Request) ([]byte, error) {
+	if !r.IsBase64Encoded {
+		return []byte(r.Body), nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(r.Body)
+	if err != nil {
+		return nil, WrapError(http.StatusBadRequest, err)
+	}
+
+	return b, nil
+}
+
 func (c *handlerContext) NoContent(code int) error {
-	c.response.StatusCode = code
-	return nil
+	_, err := c.writeHeader(code)
+	return err
 }
 
 func (c *handlerContext) String(code int, s string) error {
-	c.response.StatusCode = code
+	if ok, err := c.writeHeader(code); !ok {
+		return err
+	}
+
 	c.response.Body = s
 	c.response.Headers["Content-Type"] = []string{"text/plain"}
 
@@ -127,14 +233,177 @@ func (c *handlerContext) String(code int, s string) error {
 }
 
 func (c *handlerContext) JSON(code int, v interface{}) error {
-	b, err := json.Marshal(v)
+	b, err := c.codec.Marshal(v)
 	if err != nil {
 		return err
 	}
 
-	c.response.StatusCode = code
+	// error bodies are not pruned, as the mask applies to the requested resource
+	if c.sparse && code >= 200 && code < 300 {
+		if b, err = PruneJSON(b, RequestedFields(c)); err != nil {
+			return err
+		}
+	}
+
+	var tag string
+	if c.etag {
+		tag = WeakETag(b)
+		if code = conditionalStatus(c.request, code, tag); code == http.StatusNotModified {
+			b = nil
+		}
+	}
+
+	if ok, err := c.writeHeader(code); !ok {
+		return err
+	}
+
 	c.response.Body = string(b)
 	c.response.Headers["Content-Type"] = []string{"application/json"}
 
+	if tag != "" {
+		c.response.Headers.Set("ETag", tag)
+	}
+
 	return nil
 }
+
+// Blob writes the specified status code, content type and body to the response
+func Blob(c Context, code int, contentType string, b []byte) error {
+	_, err := blob(c, code, contentType, b)
+	return err
+}
+
+// SetErrorHandler replaces the error handler for the current request
+// A nil func is ignored, as are other Context implementations.
+func SetErrorHandler(c Context, fn func(Context, error) error) {
+	hc, ok := c.(*handlerContext)
+	if !ok || fn == nil {
+		return
+	}
+
+	hc.onError = fn
+}
+
+// Defer registers a func to be run after the response has been marshaled
+// Deferred funcs run concurrently before the invocation returns, and are bound
+// by the configured defer timeout and the invocation deadline. Other Context
+// implementations are ignored.
+func Defer(c Context, fn func(context.Context) error) {
+	hc, ok := c.(*handlerContext)
+	if !ok {
+		return
+	}
+
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	hc.deferred = append(hc.deferred, fn)
+}
+
+// OnFinish registers a func to be run after the handler chain has returned
+// Funcs are run in reverse order of registration with the handler error, allowing
+// resources acquired by middleware to be released reliably. Other Context
+// implementations are ignored.
+func OnFinish(c Context, fn func(error)) {
+	hc, ok := c.(*handlerContext)
+	if !ok {
+		return
+	}
+
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	hc.finish = append(hc.finish, fn)
+}
+
+func (c *handlerContext) runFinish(err error) {
+	c.mu.RLock()
+	fns := c.finish
+	c.mu.RUnlock()
+
+	for i := len(fns) - 1; i >= 0; i-- {
+		fns[i](err)
+	}
+}
+
+func (c *handlerContext) runDeferred(timeout time.Duration) []error {
+	c.mu.RLock()
+	fns := c.deferred
+	c.mu.RUnlock()
+
+	if len(fns) < 1 {
+		return nil
+	}
+
+	ctx := c.ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	errs := make(chan error, len(fns))
+	for _, fn := range fns {
+		go func(fn func(context.Context) error) {
+			errs <- fn(ctx)
+		}(fn)
+	}
+
+	var res []error
+	for range fns {
+		select {
+		case err := <-errs:
+			if err != nil {
+				res = append(res, err)
+			}
+		case <-ctx.Done():
+			return append(res, ctx.Err())
+		}
+	}
+
+	return res
+}
+
+// blob writes the specified status code, content type and body to the response
+// False is returned if the write was ignored by the write policy, allowing helpers
+// to modify the written response. Other Context implementations are written to the
+// response directly, as they do not have a write policy.
+func blob(c Context, code int, contentType string, b []byte) (bool, error) {
+	r := c.Response()
+	if hc, ok := c.(*handlerContext); ok {
+		if ok, err := hc.writeHeader(code); !ok {
+			return false, err
+		}
+	} else {
+		r.committed = true
+		r.StatusCode = code
+		r.StatusDescription = ""
+		r.IsBase64Encoded = false
+		if r.Headers == nil {
+			r.Headers = http.Header{}
+		}
+	}
+
+	r.Body = string(b)
+	r.Headers["Content-Type"] = []string{contentType}
+
+	return true, nil
+}
+
+func (c *handlerContext) writeHeader(code int) (bool, error) {
+	if c.response.committed {
+		switch c.policy {
+		case WriteFirstWins:
+			return false, nil
+		case WriteError:
+			return false, fmt.Errorf("%w with status %d", ErrResponseCommitted, c.response.StatusCode)
+		}
+	}
+
+	c.response.committed = true
+	c.response.StatusCode = code
+	c.response.StatusDescription = ""
+	c.response.IsBase64Encoded = false
+
+	return true, nil
+}
@@ -2,8 +2,12 @@ package rack_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 
@@ -26,6 +30,34 @@ func TestContext_Context(t *testing.T) {
 	})
 }
 
+func TestRemainingTime(t *testing.T) {
+	t.Run("should return false if there is no deadline", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			if _, ok := rack.RemainingTime(c); ok {
+				t.Error("got true, expected false")
+			}
+			return nil
+		})
+
+		h.Invoke(context.Background(), newV2Request(nil))
+	})
+
+	t.Run("should return the remaining time", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		h := rack.New(func(c rack.Context) error {
+			act, ok := rack.RemainingTime(c)
+			if !ok || act <= 0 || act > time.Minute {
+				t.Errorf("got %v, expected a value less than %v", act, time.Minute)
+			}
+			return nil
+		})
+
+		h.Invoke(ctx, newV2Request(nil))
+	})
+}
+
 func TestContext_Request(t *testing.T) {
 	t.Run("should return the request", func(t *testing.T) {
 		const exp = "expected"
@@ -45,6 +77,69 @@ func TestContext_Request(t *testing.T) {
 	})
 }
 
+func TestContext_SetRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *rack.Request
+		exp  string
+	}{
+		{
+			name: "should ignore nil requests",
+			exp:  "/original",
+		},
+		{
+			name: "should replace the request",
+			req:  &rack.Request{RawPath: "/replaced"},
+			exp:  "/replaced",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var original *rack.Request
+			mw := func(n rack.HandlerFunc) rack.HandlerFunc {
+				return func(c rack.Context) error {
+					original = c.Request()
+					c.SetRequest(tt.req)
+					return n(c)
+				}
+			}
+
+			h := rack.NewWithConfig(rack.Config{Middleware: mw}, func(c rack.Context) error {
+				r := c.Request()
+				assertDeepEqual(t, r.RawPath, tt.exp)
+
+				if r.Path == nil || r.Query == nil || r.Header == nil {
+					t.Error("got nil maps, expected non-nil")
+				}
+
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Path = "/original"
+			}))
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, original.RawPath, "/original")
+		})
+	}
+}
+
+func TestRawEvent(t *testing.T) {
+	t.Run("should return the raw event", func(t *testing.T) {
+		p := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.Body = `{ "key":  "value" }`
+		})
+
+		h := rack.New(func(c rack.Context) error {
+			assertDeepEqual(t, rack.RawEvent(c), p)
+			return nil
+		})
+
+		h.Invoke(context.Background(), p)
+	})
+}
+
 func TestContext_Get(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -230,6 +325,111 @@ func TestContext_Bind(t *testing.T) {
 	}
 }
 
+func TestRequest_BodyReader_Events(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     map[string]string
+	}{
+		{
+			name: "should read the body",
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Body = `{"key":"value"}`
+			}),
+			exp: map[string]string{"key": "value"},
+		},
+		{
+			name: "should read encoded bodies",
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Body = "eyJrZXkiOiJ2YWx1ZSJ9"
+				r.IsBase64Encoded = true
+			}),
+			exp: map[string]string{"key": "value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				var act map[string]string
+				err := json.NewDecoder(c.Request().BodyReader()).Decode(&act)
+
+				assertErrorExists(t, err, false)
+				assertDeepEqual(t, act, tt.exp)
+
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), tt.payload)
+			assertErrorExists(t, err, false)
+		})
+	}
+}
+
+func TestCookie(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		cookies func(b []byte) []string
+	}{
+		{
+			name: "should handle api gateway proxy events",
+			payload: marshal(&events.APIGatewayProxyRequest{
+				HTTPMethod:        http.MethodGet,
+				MultiValueHeaders: map[string][]string{"Cookie": {"a=1; b=2"}},
+				RequestContext:    events.APIGatewayProxyRequestContext{APIID: "apiid"},
+			}),
+			cookies: func(b []byte) []string {
+				return unmarshal(b, new(events.APIGatewayProxyResponse)).(*events.APIGatewayProxyResponse).MultiValueHeaders["Set-Cookie"]
+			},
+		},
+		{
+			name: "should handle api gateway v2 events",
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Cookies = []string{"a=1", "b=2"}
+			}),
+			cookies: func(b []byte) []string {
+				return unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse).Cookies
+			},
+		},
+		{
+			name: "should handle alb target group events",
+			payload: marshal(&events.ALBTargetGroupRequest{
+				HTTPMethod:     http.MethodGet,
+				Headers:        map[string]string{"cookie": "a=1; b=2"},
+				RequestContext: events.ALBTargetGroupRequestContext{ELB: events.ELBContext{TargetGroupArn: "arn"}},
+			}),
+			cookies: func(b []byte) []string {
+				return unmarshal(b, new(events.ALBTargetGroupResponse)).(*events.ALBTargetGroupResponse).MultiValueHeaders["Set-Cookie"]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				ck, err := rack.Cookie(c, "b")
+				assertErrorExists(t, err, false)
+				assertDeepEqual(t, ck.Value, "2")
+
+				_, err = rack.Cookie(c, "c")
+				assertDeepEqual(t, err, http.ErrNoCookie)
+				assertDeepEqual(t, len(rack.Cookies(c)), 2)
+
+				rack.SetCookie(c, &http.Cookie{Name: "c", Value: "3"})
+				rack.SetCookie(c, &http.Cookie{Name: "d", Value: "4"})
+				rack.SetCookie(c, &http.Cookie{Name: "invalid name"})
+
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			b, err := h.Invoke(context.Background(), tt.payload)
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, tt.cookies(b), []string{"c=3", "d=4"})
+		})
+	}
+}
+
 func TestContext_NoContent(t *testing.T) {
 	t.Run("should set the status code", func(t *testing.T) {
 		exp := &events.APIGatewayV2HTTPResponse{
@@ -251,6 +451,25 @@ func TestContext_NoContent(t *testing.T) {
 
 		assertDeepEqual(t, *act, *exp)
 	})
+
+	t.Run("should reset the status description", func(t *testing.T) {
+		var act string
+		h := rack.NewWithConfig(rack.Config{
+			OnComplete: func(_ rack.Context, r rack.FinalizedResponse, _ error) {
+				act = r.StatusDescription
+			},
+		}, func(c rack.Context) error {
+			c.Response().StatusDescription = "custom"
+			return c.NoContent(http.StatusCreated)
+		})
+
+		_, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		if act != "" {
+			t.Errorf("got %s, expected empty", act)
+		}
+	})
 }
 
 func TestContext_String(t *testing.T) {
@@ -331,3 +550,270 @@ func TestContext_JSON(t *testing.T) {
 		})
 	}
 }
+
+func TestRetryAfter(t *testing.T) {
+	t.Run("should set the retry after header", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			rack.RetryAfter(c, 90*time.Second)
+			return c.NoContent(http.StatusServiceUnavailable)
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		act := new(events.APIGatewayV2HTTPResponse)
+		unmarshal(b, act)
+
+		if act, exp := act.Headers["Retry-After"], "90"; act != exp {
+			t.Errorf("got %s, expected %s", act, exp)
+		}
+	})
+}
+
+func TestRetryAt(t *testing.T) {
+	t.Run("should set the retry after header", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			rack.RetryAt(c, time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			return c.NoContent(http.StatusServiceUnavailable)
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		act := new(events.APIGatewayV2HTTPResponse)
+		unmarshal(b, act)
+
+		if act, exp := act.Headers["Retry-After"], "Mon, 01 Jan 2024 12:00:00 GMT"; act != exp {
+			t.Errorf("got %s, expected %s", act, exp)
+		}
+	})
+}
+
+func TestSetErrorHandler(t *testing.T) {
+	t.Run("should replace the error handler", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			rack.SetErrorHandler(c, func(c rack.Context, err error) error {
+				return c.String(rack.StatusCode(err), err.Error())
+			})
+
+			return rack.WrapError(http.StatusConflict, errors.New("error"))
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		act := new(events.APIGatewayV2HTTPResponse)
+		unmarshal(b, act)
+
+		if act.StatusCode != http.StatusConflict || act.Body != "error" {
+			t.Errorf("got %d %s, expected %d error", act.StatusCode, act.Body, http.StatusConflict)
+		}
+	})
+
+	t.Run("should ignore nil error handlers", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			rack.SetErrorHandler(c, nil)
+			return rack.WrapError(http.StatusConflict, errors.New("error"))
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		act := new(events.APIGatewayV2HTTPResponse)
+		unmarshal(b, act)
+
+		assertDeepEqual(t, act.StatusCode, http.StatusConflict)
+	})
+}
+
+func TestConfig_WritePolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy rack.WritePolicy
+		exp    string
+		err    bool
+	}{
+		{
+			name:   "should overwrite the response by default",
+			policy: rack.WriteLastWins,
+			exp:    "second",
+		},
+		{
+			name:   "should ignore subsequent writes",
+			policy: rack.WriteFirstWins,
+			exp:    "first",
+		},
+		{
+			name:   "should return an error for subsequent writes",
+			policy: rack.WriteError,
+			exp:    "first",
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{WritePolicy: tt.policy}, func(c rack.Context) error {
+				if c.Response().Committed() {
+					t.Error("got true, expected false")
+				}
+
+				c.String(http.StatusOK, "first")
+
+				if !c.Response().Committed() {
+					t.Error("got false, expected true")
+				}
+
+				err := c.String(http.StatusOK, "second")
+				assertErrorExists(t, err, tt.err)
+
+				if act := c.Response().Body; act != tt.exp {
+					t.Errorf("got %s, expected %s", act, tt.exp)
+				}
+
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+		})
+	}
+}
+
+func TestDefer(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		fn      func(context.Context) error
+		err     bool
+	}{
+		{
+			name: "should run deferred funcs",
+			fn: func(context.Context) error {
+				return nil
+			},
+		},
+		{
+			name: "should report deferred errors",
+			fn: func(context.Context) error {
+				return errors.New("error")
+			},
+			err: true,
+		},
+		{
+			name:    "should report timeouts",
+			timeout: 10 * time.Millisecond,
+			fn: func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ran int32
+			var derr error
+
+			h := rack.NewWithConfig(rack.Config{
+				DeferTimeout: tt.timeout,
+				OnDeferError: func(_ rack.Context, err error) {
+					derr = err
+				},
+			}, func(c rack.Context) error {
+				rack.Defer(c, func(ctx context.Context) error {
+					atomic.StoreInt32(&ran, 1)
+					return tt.fn(ctx)
+				})
+
+				if atomic.LoadInt32(&ran) != 0 {
+					t.Error("got true, expected false")
+				}
+
+				return c.NoContent(http.StatusOK)
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+			assertErrorExists(t, derr, tt.err)
+
+			if atomic.LoadInt32(&ran) != 1 {
+				t.Error("got false, expected true")
+			}
+		})
+	}
+}
+
+func TestOnFinish(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler rack.HandlerFunc
+		err     bool
+	}{
+		{
+			name: "should run finish funcs on success",
+			handler: func(c rack.Context) error {
+				return c.NoContent(http.StatusOK)
+			},
+		},
+		{
+			name: "should run finish funcs with the handler error",
+			handler: func(c rack.Context) error {
+				return errors.New("error")
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var order []int
+			var ferr error
+
+			h := rack.New(func(c rack.Context) error {
+				for i := 1; i <= 2; i++ {
+					i := i
+					rack.OnFinish(c, func(err error) {
+						order = append(order, i)
+						ferr = err
+					})
+				}
+
+				if len(order) > 0 {
+					t.Error("got true, expected false")
+				}
+
+				return tt.handler(c)
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+			assertErrorExists(t, ferr, tt.err)
+			assertDeepEqual(t, order, []int{2, 1})
+		})
+	}
+}
+
+func TestBlob(t *testing.T) {
+	t.Run("should set the status code, content type and body", func(t *testing.T) {
+		exp := newV2Response(func(r *events.APIGatewayV2HTTPResponse) {
+			r.StatusCode = http.StatusOK
+			r.Body = "<p>value</p>"
+			r.Headers = map[string]string{
+				"Content-Type": "text/html",
+			}
+			r.MultiValueHeaders = map[string][]string{
+				"Content-Type": {"text/html"},
+			}
+		})
+
+		h := rack.New(func(c rack.Context) error {
+			return rack.Blob(c, http.StatusOK, "text/html", []byte("<p>value</p>"))
+		})
+
+		act, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, act, exp)
+	})
+}
@@ -0,0 +1,24 @@
+package rack
+
+import "net/http"
+
+// Cookie returns the named request cookie
+// http.ErrNoCookie is returned if the cookie does not exist. Cookies are read
+// uniformly for each event type, including API Gateway V2 cookie fields.
+func Cookie(c Context, name string) (*http.Cookie, error) {
+	return c.Request().Cookie(name)
+}
+
+// Cookies returns the request cookies
+func Cookies(c Context) []*http.Cookie {
+	return c.Request().Cookies()
+}
+
+// SetCookie adds a Set-Cookie header to the response
+// Invalid cookies are dropped. Cookies are written to the response in the
+// format expected by each event type.
+func SetCookie(c Context, ck *http.Cookie) {
+	if v := ck.String(); v != "" {
+		c.Response().Headers.Add("Set-Cookie", v)
+	}
+}
@@ -0,0 +1,163 @@
+package rack
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// CSRFOptions represents csrf protection options
+type CSRFOptions struct {
+	// Key is the HMAC key used to derive tokens from the cookie secret
+	Key []byte
+
+	// CookieName is the secret cookie name, defaulting to _csrf
+	CookieName string
+
+	// HeaderName is the token request header name, defaulting to X-CSRF-Token
+	HeaderName string
+
+	// FieldName is the token form field name, defaulting to csrf_token
+	FieldName string
+
+	// InsecureCookie omits the Secure cookie attribute for local development
+	InsecureCookie bool
+}
+
+type csrfState struct {
+	token string
+	field string
+}
+
+const csrfKey = "rack.csrf"
+
+// ErrInvalidCSRFToken indicates that the request csrf token is missing or invalid
+var ErrInvalidCSRFToken = errors.New("invalid csrf token")
+
+// CSRF returns a middleware func that protects against cross-site request forgery
+// A random secret is stored in an HttpOnly cookie, and the request token is an HMAC
+// of the secret, so tokens cannot be forged by setting the cookie from a sibling
+// domain. Unsafe requests must include the token in the header or, for url encoded
+// forms, the form field, otherwise a 403 error is returned. The func panics if no
+// key is specified.
+func CSRF(o CSRFOptions) MiddlewareFunc {
+	if len(o.Key) == 0 {
+		panic("rack: csrf requires a key")
+	}
+
+	o = o.withDefaults()
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			r := c.Request()
+
+			var secret []byte
+			if ck, err := r.Cookie(o.CookieName); err == nil {
+				secret, _ = base64.RawURLEncoding.DecodeString(ck.Value)
+			}
+
+			issued := len(secret) == 0
+			if issued {
+				secret = make([]byte, 32)
+				if _, err := rand.Read(secret); err != nil {
+					return err
+				}
+
+				SetCookie(c, &http.Cookie{
+					Name:     o.CookieName,
+					Value:    base64.RawURLEncoding.EncodeToString(secret),
+					Path:     "/",
+					HttpOnly: true,
+					Secure:   !o.InsecureCookie,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+
+			token := o.token(secret)
+			c.Set(csrfKey, &csrfState{token: token, field: o.FieldName})
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+				return n(c)
+			}
+
+			if issued || !hmac.Equal([]byte(o.requestToken(r)), []byte(token)) {
+				return WrapError(http.StatusForbidden, ErrInvalidCSRFToken)
+			}
+
+			return n(c)
+		}
+	}
+}
+
+// CSRFToken returns the csrf token for the request
+// An empty string is returned if the CSRF middleware has not been configured.
+func CSRFToken(c Context) string {
+	if s, ok := c.Get(csrfKey).(*csrfState); ok {
+		return s.token
+	}
+
+	return ""
+}
+
+// CSRFField returns a hidden form input containing the csrf token for the request
+func CSRFField(c Context) template.HTML {
+	s, ok := c.Get(csrfKey).(*csrfState)
+	if !ok {
+		return ""
+	}
+
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(s.field) +
+		`" value="` + template.HTMLEscapeString(s.token) + `">`)
+}
+
+func (o CSRFOptions) withDefaults() CSRFOptions {
+	if o.CookieName == "" {
+		o.CookieName = "_csrf"
+	}
+
+	if o.HeaderName == "" {
+		o.HeaderName = "X-CSRF-Token"
+	}
+
+	if o.FieldName == "" {
+		o.FieldName = "csrf_token"
+	}
+
+	return o
+}
+
+func (o CSRFOptions) token(secret []byte) string {
+	h := hmac.New(sha256.New, o.Key)
+	h.Write(secret)
+
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (o CSRFOptions) requestToken(r *Request) string {
+	if t := r.HeaderValue(o.HeaderName); t != "" {
+		return t
+	}
+
+	if mt, _, _ := mime.ParseMediaType(r.HeaderValue("Content-Type")); mt != "application/x-www-form-urlencoded" {
+		return ""
+	}
+
+	b, err := requestBody(r)
+	if err != nil {
+		return ""
+	}
+
+	f, err := url.ParseQuery(string(b))
+	if err != nil {
+		return ""
+	}
+
+	return f.Get(o.FieldName)
+}
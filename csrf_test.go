@@ -0,0 +1,159 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestCSRF(t *testing.T) {
+	newHandler := func() (lambda.Handler, *string) {
+		token := new(string)
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.CSRF(rack.CSRFOptions{Key: []byte("key")}),
+		}, func(c rack.Context) error {
+			*token = rack.CSRFToken(c)
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		return h, token
+	}
+
+	issue := func(t *testing.T) (string, string) {
+		h, token := newHandler()
+		b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.RequestContext.HTTP.Method = http.MethodGet
+		}))
+		assertErrorExists(t, err, false)
+
+		res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		if len(res.Cookies) != 1 {
+			t.Fatalf("got %d cookies, expected 1", len(res.Cookies))
+		}
+
+		return strings.SplitN(res.Cookies[0], ";", 2)[0], *token
+	}
+
+	cookie, token := issue(t)
+
+	tests := []struct {
+		name    string
+		setup   func(*events.APIGatewayV2HTTPRequest)
+		exp     int
+		cookies int
+	}{
+		{
+			name: "should issue a cookie for safe requests",
+			setup: func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodGet
+			},
+			exp:     http.StatusNoContent,
+			cookies: 1,
+		},
+		{
+			name: "should not reissue existing cookies",
+			setup: func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodGet
+				r.Cookies = []string{cookie}
+			},
+			exp: http.StatusNoContent,
+		},
+		{
+			name: "should reject unsafe requests without a cookie",
+			setup: func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodPost
+				r.Headers = map[string]string{"X-CSRF-Token": token}
+			},
+			exp:     http.StatusForbidden,
+			cookies: 1,
+		},
+		{
+			name: "should reject unsafe requests without a token",
+			setup: func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodPost
+				r.Cookies = []string{cookie}
+			},
+			exp: http.StatusForbidden,
+		},
+		{
+			name: "should reject unsafe requests with an invalid token",
+			setup: func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodDelete
+				r.Cookies = []string{cookie}
+				r.Headers = map[string]string{"X-CSRF-Token": "invalid"}
+			},
+			exp: http.StatusForbidden,
+		},
+		{
+			name: "should accept header tokens",
+			setup: func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodPost
+				r.Cookies = []string{cookie}
+				r.Headers = map[string]string{"X-CSRF-Token": token}
+			},
+			exp: http.StatusNoContent,
+		},
+		{
+			name: "should accept form field tokens",
+			setup: func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodPost
+				r.Cookies = []string{cookie}
+				r.Headers = map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+				r.Body = "name=a&csrf_token=" + token
+			},
+			exp: http.StatusNoContent,
+		},
+		{
+			name: "should ignore form field tokens for other content types",
+			setup: func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodPost
+				r.Cookies = []string{cookie}
+				r.Headers = map[string]string{"Content-Type": "text/plain"}
+				r.Body = "csrf_token=" + token
+			},
+			exp: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, act := newHandler()
+
+			b, err := h.Invoke(context.Background(), newV2Request(tt.setup))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			assertDeepEqual(t, res.StatusCode, tt.exp)
+			assertDeepEqual(t, len(res.Cookies), tt.cookies)
+
+			if tt.exp == http.StatusNoContent && *act == "" {
+				t.Error("got empty token, expected a value")
+			}
+		})
+	}
+
+	t.Run("should derive the token from the cookie", func(t *testing.T) {
+		h, act := newHandler()
+		_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.RequestContext.HTTP.Method = http.MethodGet
+			r.Cookies = []string{cookie}
+		}))
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, *act, token)
+	})
+	t.Run("should panic if no key is specified", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got nil, expected panic")
+			}
+		}()
+
+		rack.CSRF(rack.CSRFOptions{})
+	})
+}
@@ -0,0 +1,28 @@
+package rack
+
+import (
+	"bytes"
+	"encoding/csv"
+)
+
+// CSV writes the specified status code and rows to the response as CSV
+// If a filename is specified then the response is marked as an attachment.
+func CSV(c Context, code int, filename string, rows [][]string) error {
+	buf := new(bytes.Buffer)
+
+	w := csv.NewWriter(buf)
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+
+	ok, err := blob(c, code, "text/csv; charset=utf-8", buf.Bytes())
+	if !ok {
+		return err
+	}
+
+	if filename != "" {
+		c.Response().Headers.Set("Content-Disposition", contentDisposition(filename))
+	}
+
+	return nil
+}
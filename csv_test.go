@@ -0,0 +1,56 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestCSV(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		exp      map[string]string
+	}{
+		{
+			name: "should write inline csv",
+			exp: map[string]string{
+				"Content-Type": "text/csv; charset=utf-8",
+			},
+		},
+		{
+			name:     "should write csv attachments",
+			filename: "report.csv",
+			exp: map[string]string{
+				"Content-Type":        "text/csv; charset=utf-8",
+				"Content-Disposition": `attachment; filename=report.csv`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				return rack.CSV(c, http.StatusOK, tt.filename, [][]string{
+					{"id", "title"},
+					{"1", "a, b"},
+				})
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if exp := "id,title\n1,\"a, b\"\n"; act.Body != exp {
+				t.Errorf("got %s, expected %s", act.Body, exp)
+			}
+			assertDeepEqual(t, act.Headers, tt.exp)
+		})
+	}
+}
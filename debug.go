@@ -0,0 +1,81 @@
+package rack
+
+import (
+	"runtime/metrics"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// DebugOptions represents debug middleware options
+type DebugOptions struct {
+	// Stages are the api stages for which debug headers are written
+	// Debug headers are written for all stages if none are specified, so the
+	// middleware should only be configured for non-production deployments. ALB
+	// events do not have a stage, so headers are never written for them if stages
+	// are specified.
+	Stages []string
+}
+
+// Debug response headers
+const (
+	DebugDurationHeader  = "X-Debug-Duration"
+	DebugMaxMemoryHeader = "X-Debug-MaxMemory"
+	DebugColdStartHeader = "X-Debug-Cold-Start"
+)
+
+const totalMemoryMetric = "/memory/classes/total:bytes"
+
+var (
+	debugInvoked   int32
+	debugMaxMemory uint64
+)
+
+// Debug returns a middleware func that writes performance debug headers
+// The handler duration, the maximum runtime memory observed by the container in
+// bytes and a cold start flag are written to the response. Memory is read from
+// the go runtime, so it excludes memory used by the lambda runtime itself.
+func Debug(o DebugOptions) MiddlewareFunc {
+	stages := make(map[string]bool, len(o.Stages))
+	for _, s := range o.Stages {
+		stages[s] = true
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			if len(stages) > 0 && !stages[c.Request().Stage] {
+				return n(c)
+			}
+
+			cold := atomic.CompareAndSwapInt32(&debugInvoked, 0, 1)
+			st := time.Now()
+
+			err := n(c)
+
+			h := c.Response().Headers
+			h.Set(DebugDurationHeader, time.Since(st).String())
+			h.Set(DebugMaxMemoryHeader, strconv.FormatUint(maxMemory(), 10))
+			h.Set(DebugColdStartHeader, strconv.FormatBool(cold))
+
+			return err
+		}
+	}
+}
+
+// maxMemory returns the maximum total runtime memory observed by the container
+func maxMemory() uint64 {
+	s := []metrics.Sample{{Name: totalMemoryMetric}}
+	metrics.Read(s)
+	v := s[0].Value.Uint64()
+
+	for {
+		m := atomic.LoadUint64(&debugMaxMemory)
+		if v <= m {
+			return m
+		}
+
+		if atomic.CompareAndSwapUint64(&debugMaxMemory, m, v) {
+			return v
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestDebug(t *testing.T) {
+	newRequest := func(stage string) []byte {
+		return newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.RequestContext.Stage = stage
+			r.RawPath = "/"
+		})
+	}
+
+	handler := func(c rack.Context) error {
+		if c.Request().Header.Get("X-Error") != "" {
+			return errors.New("error")
+		}
+		return c.NoContent(http.StatusOK)
+	}
+
+	tests := []struct {
+		name    string
+		opts    rack.DebugOptions
+		payload []byte
+		exp     bool
+	}{
+		{
+			name:    "should write headers for all stages",
+			payload: newRequest("prod"),
+			exp:     true,
+		},
+		{
+			name:    "should write headers for configured stages",
+			opts:    rack.DebugOptions{Stages: []string{"dev", "test"}},
+			payload: newRequest("dev"),
+			exp:     true,
+		},
+		{
+			name:    "should not write headers for other stages",
+			opts:    rack.DebugOptions{Stages: []string{"dev", "test"}},
+			payload: newRequest("prod"),
+		},
+		{
+			name:    "should not write headers for alb events if stages are configured",
+			opts:    rack.DebugOptions{Stages: []string{"dev", "test"}},
+			payload: []byte(albTargetGroupSingleValueEventPayload),
+		},
+		{
+			name: "should write headers for errors",
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"X-Error": "true"}
+			}),
+			exp: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{Middleware: rack.Debug(tt.opts)}, handler)
+
+			b, err := h.Invoke(context.Background(), tt.payload)
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			hdr := http.Header{}
+			for k, v := range res.Headers {
+				hdr.Set(k, v)
+			}
+
+			_, ok := hdr[rack.DebugDurationHeader]
+			assertDeepEqual(t, ok, tt.exp)
+			if !tt.exp {
+				return
+			}
+
+			if _, err = time.ParseDuration(hdr.Get(rack.DebugDurationHeader)); err != nil {
+				t.Errorf("got %v, expected a duration", err)
+			}
+
+			if n, err := strconv.ParseUint(hdr.Get(rack.DebugMaxMemoryHeader), 10, 64); err != nil || n == 0 {
+				t.Errorf("got %s, expected a memory value", hdr.Get(rack.DebugMaxMemoryHeader))
+			}
+
+			// the cold start flag is container-scoped, so only subsequent requests are asserted
+			assertDeepEqual(t, hdr.Get(rack.DebugColdStartHeader) != "", true)
+		})
+	}
+
+	t.Run("should only flag the first invocation as a cold start", func(t *testing.T) {
+		h := rack.NewWithConfig(rack.Config{Middleware: rack.Debug(rack.DebugOptions{})}, handler)
+
+		for i := 0; i < 2; i++ {
+			b, err := h.Invoke(context.Background(), newRequest("dev"))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			assertDeepEqual(t, res.Headers[rack.DebugColdStartHeader], "false")
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package rack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DeduplicateOptions represents request deduplication options
+type DeduplicateOptions struct {
+	// Cache is the response cache, defaulting to a container-scoped memory cache
+	Cache Cache
+
+	// Window is the deduplication window, defaulting to 5 seconds
+	Window time.Duration
+
+	// Client returns the client identity, defaulting to the source ip address
+	// Requests with an empty client identity are not deduplicated.
+	Client func(Context) string
+}
+
+const defaultDeduplicateWindow = 5 * time.Second
+
+// ErrDuplicateRequest indicates that an identical request is in progress
+var ErrDuplicateRequest = errors.New("duplicate request in progress")
+
+// Deduplicate returns a middleware func that deduplicates identical requests
+// Requests are identified by client, credentials, method, path, query string and
+// body, with the Authorization and Cookie headers used as credentials. The
+// response to the first request is replayed for identical requests within the
+// window, and identical requests received while the first is in progress receive
+// a 409 error. Safe methods, handler errors and empty responses are not
+// deduplicated. Requests are claimed using an atomic cache write, so concurrent
+// identical requests are only handled once. Set-Cookie headers are not replayed.
+func Deduplicate(o DeduplicateOptions) MiddlewareFunc {
+	cache := o.Cache
+	if cache == nil {
+		cache = NewMemoryCache()
+	}
+
+	window := o.Window
+	if window <= 0 {
+		window = defaultDeduplicateWindow
+	}
+
+	client := o.Client
+	if client == nil {
+		client = sourceIP
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			r := c.Request()
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return n(c)
+			}
+
+			id := client(c)
+			if id == "" {
+				return n(c)
+			}
+
+			k := requestHash(id, r)
+
+			// an empty value marks the request as in progress
+			ok, err := cache.SetIfAbsent(c.Context(), k, []byte{}, window)
+			if err != nil {
+				return err
+			}
+
+			if !ok {
+				b, ok, err := cache.Get(c.Context(), k)
+				if err != nil {
+					return err
+				}
+
+				// the first request may have failed since the key was claimed
+				if !ok || len(b) == 0 {
+					return WrapError(http.StatusConflict, ErrDuplicateRequest)
+				}
+
+				return replayResponse(c, b)
+			}
+
+			if err = n(c); err != nil || !c.Response().Committed() {
+				if derr := cache.Delete(c.Context(), k); derr != nil && err == nil {
+					err = derr
+				}
+				return err
+			}
+
+			b, err := marshalCachedResponse(c.Response())
+			if err != nil {
+				return err
+			}
+
+			return cache.Set(c.Context(), k, b, window)
+		}
+	}
+}
+
+// requestHash returns the hash of the request
+// Credential headers are included so that clients that share a source ip address
+// do not receive each other's responses.
+func requestHash(client string, r *Request) string {
+	auth := strings.Join(r.Header.Values("Authorization"), ",")
+	cookie := strings.Join(r.Header.Values("Cookie"), ";")
+
+	h := sha256.New()
+	for _, s := range []string{client, auth, cookie, r.Method, r.RawPath, r.Query.Encode(), r.Body} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sourceIP returns the source ip address of the request
+func sourceIP(c Context) string {
+	return c.Request().SourceIP
+}
@@ -0,0 +1,227 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestDeduplicate(t *testing.T) {
+	newRequest := func(method, ip, body string) []byte {
+		return newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.RequestContext.HTTP.Method = method
+			r.RequestContext.HTTP.Path = "/orders"
+			r.RequestContext.HTTP.SourceIP = ip
+			r.Body = body
+		})
+	}
+
+	tests := []struct {
+		name     string
+		payloads [][]byte
+		calls    int
+		exp      []int
+	}{
+		{
+			name:     "should replay identical requests",
+			payloads: [][]byte{newRequest(http.MethodPost, "1.1.1.1", "a"), newRequest(http.MethodPost, "1.1.1.1", "a")},
+			calls:    1,
+			exp:      []int{http.StatusCreated, http.StatusCreated},
+		},
+		{
+			name:     "should not replay requests with different bodies",
+			payloads: [][]byte{newRequest(http.MethodPost, "1.1.1.1", "a"), newRequest(http.MethodPost, "1.1.1.1", "b")},
+			calls:    2,
+			exp:      []int{http.StatusCreated, http.StatusCreated},
+		},
+		{
+			name:     "should not replay requests from different clients",
+			payloads: [][]byte{newRequest(http.MethodPost, "1.1.1.1", "a"), newRequest(http.MethodPost, "2.2.2.2", "a")},
+			calls:    2,
+			exp:      []int{http.StatusCreated, http.StatusCreated},
+		},
+		{
+			name:     "should not replay requests without a client",
+			payloads: [][]byte{newRequest(http.MethodPost, "", "a"), newRequest(http.MethodPost, "", "a")},
+			calls:    2,
+			exp:      []int{http.StatusCreated, http.StatusCreated},
+		},
+		{
+			name:     "should not replay safe methods",
+			payloads: [][]byte{newRequest(http.MethodGet, "1.1.1.1", ""), newRequest(http.MethodGet, "1.1.1.1", "")},
+			calls:    2,
+			exp:      []int{http.StatusCreated, http.StatusCreated},
+		},
+		{
+			name:     "should not replay errors",
+			payloads: [][]byte{newRequest(http.MethodPost, "1.1.1.1", "error"), newRequest(http.MethodPost, "1.1.1.1", "error")},
+			calls:    2,
+			exp:      []int{http.StatusInternalServerError, http.StatusInternalServerError},
+		},
+		{
+			name:     "should return conflict for requests in progress",
+			payloads: [][]byte{newRequest(http.MethodPost, "1.1.1.1", "nested")},
+			calls:    1,
+			exp:      []int{http.StatusCreated},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h lambda.Handler
+			calls := 0
+
+			h = rack.NewWithConfig(rack.Config{
+				Middleware: rack.Deduplicate(rack.DeduplicateOptions{}),
+			}, func(c rack.Context) error {
+				calls++
+
+				switch c.Request().Body {
+				case "error":
+					return errors.New("error")
+				case "nested":
+					b, err := h.Invoke(c.Context(), rack.RawEvent(c))
+					if err != nil {
+						return err
+					}
+
+					res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+					assertDeepEqual(t, res.StatusCode, http.StatusConflict)
+				}
+
+				c.Response().Headers.Set("X-Call", strconv.Itoa(calls))
+				return c.String(http.StatusCreated, "created")
+			})
+
+			var header string
+			for i, p := range tt.payloads {
+				b, err := h.Invoke(context.Background(), p)
+				assertErrorExists(t, err, false)
+
+				res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+				assertDeepEqual(t, res.StatusCode, tt.exp[i])
+
+				if i == 0 {
+					header = res.Headers["X-Call"]
+				} else if tt.calls == 1 && res.Headers["X-Call"] != header {
+					t.Errorf("got %s, expected %s", res.Headers["X-Call"], header)
+				}
+			}
+
+			assertDeepEqual(t, calls, tt.calls)
+		})
+	}
+
+	t.Run("should use the client func", func(t *testing.T) {
+		calls := 0
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.Deduplicate(rack.DeduplicateOptions{
+				Client: func(c rack.Context) string { return "client" },
+			}),
+		}, func(c rack.Context) error {
+			calls++
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		for i := 0; i < 2; i++ {
+			_, err := h.Invoke(context.Background(), newRequest(http.MethodPost, "", "a"))
+			assertErrorExists(t, err, false)
+		}
+
+		assertDeepEqual(t, calls, 1)
+	})
+
+	t.Run("should not replay requests with different credentials", func(t *testing.T) {
+		calls := 0
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.Deduplicate(rack.DeduplicateOptions{}),
+		}, func(c rack.Context) error {
+			calls++
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		for _, cred := range []string{"Authorization", "Cookie"} {
+			for _, v := range []string{"a", "b"} {
+				p := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+					r.RequestContext.HTTP.Method = http.MethodPost
+					r.RequestContext.HTTP.SourceIP = "1.1.1.1"
+					r.Headers = map[string]string{cred: v}
+				})
+
+				_, err := h.Invoke(context.Background(), p)
+				assertErrorExists(t, err, false)
+			}
+		}
+
+		assertDeepEqual(t, calls, 4)
+	})
+
+	t.Run("should not replay set-cookie headers", func(t *testing.T) {
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.Deduplicate(rack.DeduplicateOptions{}),
+		}, func(c rack.Context) error {
+			rack.SetCookie(c, &http.Cookie{Name: "session", Value: "value"})
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		for i := 0; i < 2; i++ {
+			b, err := h.Invoke(context.Background(), newRequest(http.MethodPost, "1.1.1.1", "a"))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			if exp := i == 0; (len(res.Cookies) > 0) != exp {
+				t.Errorf("got %v, expected cookies %v", res.Cookies, exp)
+			}
+		}
+	})
+
+	t.Run("should handle concurrent identical requests once", func(t *testing.T) {
+		var calls int32
+		release := make(chan struct{})
+
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.Deduplicate(rack.DeduplicateOptions{}),
+		}, func(c rack.Context) error {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		const n = 10
+		conflicts := make(chan int, n)
+
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				b, err := h.Invoke(context.Background(), newRequest(http.MethodPost, "1.2.3.4", "a"))
+				assertErrorExists(t, err, false)
+
+				res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+				if res.StatusCode == http.StatusConflict {
+					conflicts <- res.StatusCode
+				}
+			}()
+		}
+
+		for i := 0; i < n-1; i++ {
+			<-conflicts
+		}
+
+		close(release)
+		wg.Wait()
+
+		assertDeepEqual(t, atomic.LoadInt32(&calls), int32(1))
+	})
+}
@@ -0,0 +1,120 @@
+package rack
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Environment variables read by ConfigFromEnv
+const (
+	EnvStrict        = "RACK_STRICT"
+	EnvRecover       = "RACK_RECOVER"
+	EnvJSONETag      = "RACK_JSON_ETAG"
+	EnvWritePolicy   = "RACK_WRITE_POLICY"
+	EnvMaxHeaderSize = "RACK_MAX_HEADER_SIZE"
+	EnvDeferTimeout  = "RACK_DEFER_TIMEOUT"
+	EnvEventType     = "RACK_EVENT_TYPE"
+)
+
+var writePolicies = map[string]WritePolicy{
+	"last-wins":  WriteLastWins,
+	"first-wins": WriteFirstWins,
+	"error":      WriteError,
+}
+
+// ConfigFromEnv returns a new configuration populated from environment variables
+// Unset variables leave the corresponding option at its zero value. An error is
+// returned if any variable cannot be parsed, allowing misconfiguration to fail fast.
+func ConfigFromEnv() (Config, error) {
+	var c Config
+
+	if err := envBool(EnvStrict, &c.Strict); err != nil {
+		return Config{}, err
+	}
+
+	if err := envBool(EnvRecover, &c.Recover); err != nil {
+		return Config{}, err
+	}
+
+	if err := envBool(EnvJSONETag, &c.JSONETag); err != nil {
+		return Config{}, err
+	}
+
+	if v, ok := os.LookupEnv(EnvWritePolicy); ok {
+		p, ok := writePolicies[v]
+		if !ok {
+			return Config{}, fmt.Errorf("invalid %s: unknown write policy %q", EnvWritePolicy, v)
+		}
+
+		c.WritePolicy = p
+	}
+
+	if v, ok := os.LookupEnv(EnvMaxHeaderSize); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return Config{}, fmt.Errorf("invalid %s: expected a non-negative integer", EnvMaxHeaderSize)
+		}
+
+		c.MaxHeaderSize = n
+	}
+
+	if v, ok := os.LookupEnv(EnvDeferTimeout); ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid %s: expected a non-negative duration", EnvDeferTimeout)
+		}
+
+		c.DeferTimeout = d
+	}
+
+	if os.Getenv(EnvEventType) != "" {
+		r, err := ResolveFromEnv()
+		if err != nil {
+			return Config{}, err
+		}
+
+		c.Resolver = r
+	}
+
+	return c, nil
+}
+
+// ResolveFromEnv returns a resolver for the processor named by the RACK_EVENT_TYPE variable
+// Built-in and registered processor names are supported, and the named processor is
+// used regardless of the payload. The default resolver is returned if the variable
+// is not set.
+func ResolveFromEnv() (Resolver, error) {
+	v, ok := os.LookupEnv(EnvEventType)
+	if !ok || v == "" {
+		return defaultResolver, nil
+	}
+
+	registryMu.RLock()
+	ps := registry
+	registryMu.RUnlock()
+
+	for _, p := range append(ps[:len(ps):len(ps)], builtinProcessors...) {
+		if processorName(p) == v {
+			return ResolveStatic(p), nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid %s: unknown event type %q", EnvEventType, v)
+}
+
+func envBool(key string, b *bool) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+
+	p, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	*b = p
+	return nil
+}
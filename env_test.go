@@ -0,0 +1,137 @@
+package rack_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		exp  rack.Config
+		err  bool
+	}{
+		{
+			name: "should return an error if a bool is invalid",
+			env:  map[string]string{rack.EnvStrict: "yes please"},
+			err:  true,
+		},
+		{
+			name: "should return an error if the write policy is invalid",
+			env:  map[string]string{rack.EnvWritePolicy: "random"},
+			err:  true,
+		},
+		{
+			name: "should return an error if the max header size is invalid",
+			env:  map[string]string{rack.EnvMaxHeaderSize: "-1"},
+			err:  true,
+		},
+		{
+			name: "should return an error if the defer timeout is invalid",
+			env:  map[string]string{rack.EnvDeferTimeout: "10"},
+			err:  true,
+		},
+		{
+			name: "should return an error if the event type is invalid",
+			env:  map[string]string{rack.EnvEventType: "sqs"},
+			err:  true,
+		},
+		{
+			name: "should return an empty config if no variables are set",
+		},
+		{
+			name: "should populate the config",
+			env: map[string]string{
+				rack.EnvStrict:        "true",
+				rack.EnvRecover:       "1",
+				rack.EnvJSONETag:      "true",
+				rack.EnvWritePolicy:   "first-wins",
+				rack.EnvMaxHeaderSize: "10240",
+				rack.EnvDeferTimeout:  "2s",
+			},
+			exp: rack.Config{
+				Strict:        true,
+				Recover:       true,
+				JSONETag:      true,
+				WritePolicy:   rack.WriteFirstWins,
+				MaxHeaderSize: 10240,
+				DeferTimeout:  2 * time.Second,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			act, err := rack.ConfigFromEnv()
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestResolveFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		payload []byte
+		exp     rack.Processor
+		err     bool
+	}{
+		{
+			name:    "should return the default resolver if the variable is not set",
+			payload: newV2Request(nil),
+			exp:     rack.APIGatewayV2HTTPEventProcessor,
+		},
+		{
+			name: "should return an error if the event type is unknown",
+			env:  "sqs",
+			err:  true,
+		},
+		{
+			name:    "should resolve the named processor regardless of the payload",
+			env:     "alb",
+			payload: newV2Request(nil),
+			exp:     rack.ALBTargetGroupEventProcessor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv(rack.EnvEventType, tt.env)
+			}
+
+			r, err := rack.ResolveFromEnv()
+			assertErrorExists(t, err, tt.err)
+			if err != nil {
+				return
+			}
+
+			act, err := r.Resolve(tt.payload)
+			assertErrorExists(t, err, false)
+			if act != tt.exp {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+
+	t.Run("should configure the resolver", func(t *testing.T) {
+		t.Setenv(rack.EnvEventType, "apigw-v1")
+
+		c, err := rack.ConfigFromEnv()
+		assertErrorExists(t, err, false)
+
+		act, err := c.Resolver.Resolve(nil)
+		assertErrorExists(t, err, false)
+		if act != rack.APIGatewayProxyEventProcessor {
+			t.Errorf("got %v, expected %v", act, rack.APIGatewayProxyEventProcessor)
+		}
+	})
+}
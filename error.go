@@ -1,21 +1,56 @@
 package rack
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
 )
 
 type (
 	// StatusError represents a status code error
 	StatusError struct {
-		code int
-		err  error
+		code    int
+		errCode string
+		header  http.Header
+		err     error
+	}
+
+	// PanicError represents a recovered handler panic
+	PanicError struct {
+		value interface{}
+		stack []byte
+	}
+
+	// BindError represents a request body bind error
+	// The position of the error within the body is included in the message.
+	BindError struct {
+		field  string
+		offset int64
+		line   int
+		column int
+		err    error
 	}
 
 	statusError interface {
 		Code() int
 		error
 	}
+
+	codedError interface {
+		ErrorCode() string
+		error
+	}
+
+	headerError interface {
+		Header() http.Header
+		error
+	}
 )
 
 // StatusCode returns the status code for the specified error
@@ -28,6 +63,43 @@ func StatusCode(err error) int {
 	return http.StatusInternalServerError
 }
 
+// TooManyRequests returns a new 429 status error with the specified retry duration
+// The Retry-After header is written to the response before the error handler is invoked.
+func TooManyRequests(retryAfter time.Duration) *StatusError {
+	return WrapError(http.StatusTooManyRequests, errors.New("too many requests")).
+		WithHeader("Retry-After", formatRetryAfter(retryAfter))
+}
+
+// TooManyRequestsUntil returns a new 429 status error with the specified retry time
+// The Retry-After header is written in HTTP-date form, as defined in rfc 7231.
+func TooManyRequestsUntil(t time.Time) *StatusError {
+	return WrapError(http.StatusTooManyRequests, errors.New("too many requests")).
+		WithHeader("Retry-After", formatRetryAt(t))
+}
+
+// RetryAfter sets the Retry-After response header to the specified duration
+// The header is written in delay-seconds form, rounded up to the next second.
+func RetryAfter(c Context, d time.Duration) {
+	c.Response().Headers.Set("Retry-After", formatRetryAfter(d))
+}
+
+// RetryAt sets the Retry-After response header to the specified time
+// The header is written in HTTP-date form, as defined in rfc 7231.
+func RetryAt(c Context, t time.Time) {
+	c.Response().Headers.Set("Retry-After", formatRetryAt(t))
+}
+
+// ErrorCode returns the machine-readable error code for the specified error
+// An empty string is returned if the error does not have a code.
+func ErrorCode(err error) string {
+	var ce codedError
+	if errors.As(err, &ce) {
+		return ce.ErrorCode()
+	}
+
+	return ""
+}
+
 // WrapError wraps the specified error
 func WrapError(code int, err error) *StatusError {
 	return &StatusError{
@@ -41,6 +113,32 @@ func (e *StatusError) Code() int {
 	return e.code
 }
 
+// ErrorCode returns the machine-readable error code
+func (e *StatusError) ErrorCode() string {
+	return e.errCode
+}
+
+// WithCode sets the machine-readable error code and returns the error
+func (e *StatusError) WithCode(code string) *StatusError {
+	e.errCode = code
+	return e
+}
+
+// Header returns the response headers for the error
+func (e *StatusError) Header() http.Header {
+	return e.header
+}
+
+// WithHeader adds a response header value and returns the error
+func (e *StatusError) WithHeader(key, value string) *StatusError {
+	if e.header == nil {
+		e.header = http.Header{}
+	}
+
+	e.header.Add(key, value)
+	return e
+}
+
 // Error returns the error message
 func (e *StatusError) Error() string {
 	return e.err.Error()
@@ -50,3 +148,144 @@ func (e *StatusError) Error() string {
 func (e *StatusError) Unwrap() error {
 	return e.err
 }
+
+// NewPanicError returns a new panic error for the specified recovered value
+// The stack of the calling goroutine is captured at the point of creation,
+// so the function should be called from the deferred recover func.
+func NewPanicError(v interface{}) *PanicError {
+	return &PanicError{
+		value: v,
+		stack: debug.Stack(),
+	}
+}
+
+// Value returns the recovered panic value
+func (e *PanicError) Value() interface{} {
+	return e.value
+}
+
+// Stack returns the stack trace captured when the panic was recovered
+func (e *PanicError) Stack() []byte {
+	return e.stack
+}
+
+// Error returns the error message
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.value.(error); ok {
+		return err
+	}
+
+	return nil
+}
+
+func errorHeader(err error) http.Header {
+	var he headerError
+	if errors.As(err, &he) {
+		return he.Header()
+	}
+
+	return nil
+}
+
+// formatRetryAfter returns the delay-seconds form of the Retry-After header
+// Durations are rounded up to the next second so that clients never retry early.
+func formatRetryAfter(d time.Duration) string {
+	if d <= 0 {
+		return "0"
+	}
+
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// formatRetryAt returns the HTTP-date form of the Retry-After header
+func formatRetryAt(t time.Time) string {
+	return t.UTC().Format(http.TimeFormat)
+}
+
+// newBindError returns a bind error for json syntax and type errors
+// All other errors are returned unchanged.
+func newBindError(b []byte, err error) error {
+	var (
+		se *json.SyntaxError
+		te *json.UnmarshalTypeError
+	)
+
+	switch {
+	case errors.As(err, &se):
+		return newBindErrorAt(b, "", se.Offset, err)
+	case errors.As(err, &te):
+		return newBindErrorAt(b, te.Field, te.Offset, err)
+	}
+
+	return err
+}
+
+func newBindErrorAt(b []byte, field string, offset int64, err error) *BindError {
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+
+	// the offset follows the last byte read, which is the byte in error
+	i := int(offset) - 1
+	if i < 0 {
+		i = 0
+	}
+
+	line := bytes.Count(b[:i], []byte("\n")) + 1
+	column := i - bytes.LastIndexByte(b[:i], '\n')
+
+	return &BindError{
+		field:  field,
+		offset: offset,
+		line:   line,
+		column: column,
+		err:    err,
+	}
+}
+
+// Field returns the path of the field that could not be bound
+// An empty string is returned for syntax errors.
+func (e *BindError) Field() string {
+	return e.field
+}
+
+// Offset returns the byte offset of the error within the body
+func (e *BindError) Offset() int64 {
+	return e.offset
+}
+
+// Line returns the line number of the error within the body
+func (e *BindError) Line() int {
+	return e.line
+}
+
+// Column returns the column number of the error within the body
+func (e *BindError) Column() int {
+	return e.column
+}
+
+// Error returns the error message
+func (e *BindError) Error() string {
+	var te *json.UnmarshalTypeError
+	if errors.As(e.err, &te) {
+		if e.field != "" {
+			return fmt.Sprintf("invalid value for field %q at line %d, column %d: expected %s, got %s",
+				e.field, e.line, e.column, te.Type, te.Value)
+		}
+
+		return fmt.Sprintf("invalid value at line %d, column %d: expected %s, got %s",
+			e.line, e.column, te.Type, te.Value)
+	}
+
+	return fmt.Sprintf("invalid json at line %d, column %d: %s", e.line, e.column, e.err)
+}
+
+// Unwrap returns the wrapped error
+func (e *BindError) Unwrap() error {
+	return e.err
+}
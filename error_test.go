@@ -1,9 +1,14 @@
 package rack_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
 
 	"github.com/stevecallear/rack"
 )
@@ -38,6 +43,86 @@ func TestStatusCode(t *testing.T) {
 	}
 }
 
+func TestErrorCode(t *testing.T) {
+	err := errors.New("error")
+
+	tests := []struct {
+		name string
+		err  error
+		exp  string
+	}{
+		{
+			name: "should return empty if the error is not a status error",
+			err:  err,
+			exp:  "",
+		},
+		{
+			name: "should return empty if the status error does not have a code",
+			err:  rack.WrapError(http.StatusNotFound, err),
+			exp:  "",
+		},
+		{
+			name: "should return the error code",
+			err:  fmt.Errorf("wrapped: %w", rack.WrapError(http.StatusNotFound, err).WithCode("NOT_FOUND")),
+			exp:  "NOT_FOUND",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act := rack.ErrorCode(tt.err)
+			if act != tt.exp {
+				t.Errorf("got %s, expected %s", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestTooManyRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		exp  string
+	}{
+		{
+			name: "should return zero for negative durations",
+			d:    -time.Second,
+			exp:  "0",
+		},
+		{
+			name: "should round up to the next second",
+			d:    1500 * time.Millisecond,
+			exp:  "2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := rack.TooManyRequests(tt.d)
+
+			if act, exp := sut.Code(), http.StatusTooManyRequests; act != exp {
+				t.Errorf("got %d, expected %d", act, exp)
+			}
+			if act := sut.Header().Get("Retry-After"); act != tt.exp {
+				t.Errorf("got %s, expected %s", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestTooManyRequestsUntil(t *testing.T) {
+	t.Run("should write the retry time as an http date", func(t *testing.T) {
+		sut := rack.TooManyRequestsUntil(time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("", 3600)))
+
+		if act, exp := sut.Code(), http.StatusTooManyRequests; act != exp {
+			t.Errorf("got %d, expected %d", act, exp)
+		}
+		if act, exp := sut.Header().Get("Retry-After"), "Mon, 01 Jan 2024 11:00:00 GMT"; act != exp {
+			t.Errorf("got %s, expected %s", act, exp)
+		}
+	})
+}
+
 func TestStatusError_Code(t *testing.T) {
 	t.Run("should return the status code", func(t *testing.T) {
 		const exp = http.StatusConflict
@@ -73,3 +158,120 @@ func TestStatusError_Unwrap(t *testing.T) {
 		}
 	})
 }
+
+func TestPanicError(t *testing.T) {
+	t.Run("should return the panic value", func(t *testing.T) {
+		const exp = "value"
+		sut := rack.NewPanicError(exp)
+
+		if act := sut.Value(); act != exp {
+			t.Errorf("got %v, expected %v", act, exp)
+		}
+		if act, exp := sut.Error(), "panic: value"; act != exp {
+			t.Errorf("got %s, expected %s", act, exp)
+		}
+		if len(sut.Stack()) == 0 {
+			t.Error("got empty stack, expected a value")
+		}
+	})
+
+	t.Run("should unwrap error values", func(t *testing.T) {
+		exp := errors.New("error")
+		sut := rack.NewPanicError(exp)
+
+		if act := errors.Unwrap(sut); act != exp {
+			t.Errorf("got %v, expected %v", act, exp)
+		}
+	})
+
+	t.Run("should return nil for non-error values", func(t *testing.T) {
+		sut := rack.NewPanicError("value")
+
+		if act := errors.Unwrap(sut); act != nil {
+			t.Errorf("got %v, expected nil", act)
+		}
+	})
+}
+
+func TestBindError(t *testing.T) {
+	type item struct {
+		Qty int `json:"qty"`
+	}
+
+	type obj struct {
+		Name  string `json:"name"`
+		Items []item `json:"items"`
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		msg    string
+		field  string
+		line   int
+		column int
+	}{
+		{
+			name:   "should return syntax error positions",
+			body:   "{\n  \"name\": \"a\",\n  \"items\": [}\n}",
+			msg:    "invalid json at line 3, column 13: invalid character '}' looking for beginning of value",
+			line:   3,
+			column: 13,
+		},
+		{
+			name:   "should return unexpected end positions",
+			body:   `{"name":`,
+			msg:    "invalid json at line 1, column 8: unexpected end of JSON input",
+			line:   1,
+			column: 8,
+		},
+		{
+			name:   "should return type error field paths",
+			body:   `{"name":"a","items":[{"qty":"1"}]}`,
+			msg:    `invalid value for field "items.0.qty" at line 1, column 31: expected int, got string`,
+			field:  "items.0.qty",
+			line:   1,
+			column: 31,
+		},
+		{
+			name:   "should return root type errors",
+			body:   `[]`,
+			msg:    "invalid value at line 1, column 1: expected rack_test.obj, got array",
+			line:   1,
+			column: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				err := c.Bind(new(obj))
+
+				if act := rack.StatusCode(err); act != http.StatusBadRequest {
+					t.Errorf("got %d, expected %d", act, http.StatusBadRequest)
+				}
+				if act := err.Error(); act != tt.msg {
+					t.Errorf("got %s, expected %s", act, tt.msg)
+				}
+
+				var be *rack.BindError
+				if !errors.As(err, &be) {
+					t.Fatalf("got %T, expected *rack.BindError", err)
+				}
+
+				act := []interface{}{be.Field(), be.Line(), be.Column()}
+				assertDeepEqual(t, act, []interface{}{tt.field, tt.line, tt.column})
+
+				return nil
+			})
+
+			payload := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Body = tt.body
+			})
+
+			if _, err := h.Invoke(context.Background(), payload); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package rack
+
+import (
+	"encoding/hex"
+	"errors"
+	"hash/fnv"
+	"net/http"
+	"strings"
+)
+
+// WeakETag returns a weak entity tag for the specified body
+// Weak tags never match If-Match headers, so StrongETag should be used with RequireIfMatch.
+func WeakETag(b []byte) string {
+	return "W/" + StrongETag(b)
+}
+
+// StrongETag returns a strong entity tag for the specified body
+// The tag can be compared using RequireIfMatch, providing the body is serialized
+// consistently for each version of the resource.
+func StrongETag(b []byte) string {
+	h := fnv.New64a()
+	h.Write(b)
+
+	return `"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// RequireIfMatch returns an error if the If-Match request header does not match the specified tag
+// A 428 status error is returned if the header is missing, and a 412 status
+// error is returned if the tag does not match. Strong comparison is used, as
+// defined in rfc 7232, so weak tags such as those written by JSONETag never
+// match. An empty tag indicates that the resource does not exist.
+func RequireIfMatch(c Context, etag string) error {
+	h := c.Request().Header.Get("If-Match")
+	if h == "" {
+		return WrapError(http.StatusPreconditionRequired, errors.New("precondition required"))
+	}
+
+	if etag == "" || !etagMatch(h, etag, false) {
+		return WrapError(http.StatusPreconditionFailed, errors.New("precondition failed"))
+	}
+
+	return nil
+}
+
+// conditionalStatus returns the status code for the specified entity tag
+// Not modified is returned for successful GET and HEAD requests where the tag
+// matches the If-None-Match request header.
+func conditionalStatus(r *Request, code int, tag string) int {
+	if code != http.StatusOK {
+		return code
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return code
+	}
+
+	if etagMatch(r.Header.Get("If-None-Match"), tag, true) {
+		return http.StatusNotModified
+	}
+
+	return code
+}
+
+// etagMatch returns true if the header value contains the specified tag
+// Weak comparison ignores the weak indicator on both tags, as defined in rfc 7232.
+func etagMatch(header, tag string, weak bool) bool {
+	if header == "" {
+		return false
+	}
+
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	if weak {
+		tag = strings.TrimPrefix(tag, "W/")
+	} else if strings.HasPrefix(tag, "W/") {
+		return false
+	}
+
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if weak {
+			v = strings.TrimPrefix(v, "W/")
+		}
+
+		if v == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,196 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestConfig_JSONETag(t *testing.T) {
+	body := []byte(`{"key":"value"}`)
+	tag := rack.WeakETag(body)
+
+	tests := []struct {
+		name      string
+		method    string
+		header    string
+		code      int
+		expStatus int
+		expBody   string
+	}{
+		{
+			name:      "should write the etag",
+			method:    http.MethodGet,
+			code:      http.StatusOK,
+			expStatus: http.StatusOK,
+			expBody:   string(body),
+		},
+		{
+			name:      "should return not modified for matching tags",
+			method:    http.MethodGet,
+			header:    `"other", ` + tag,
+			code:      http.StatusOK,
+			expStatus: http.StatusNotModified,
+		},
+		{
+			name:      "should use weak comparison",
+			method:    http.MethodGet,
+			header:    tag[2:],
+			code:      http.StatusOK,
+			expStatus: http.StatusNotModified,
+		},
+		{
+			name:      "should ignore tags for non-get requests",
+			method:    http.MethodPost,
+			header:    tag,
+			code:      http.StatusOK,
+			expStatus: http.StatusOK,
+			expBody:   string(body),
+		},
+		{
+			name:      "should ignore tags for non-200 responses",
+			method:    http.MethodGet,
+			header:    tag,
+			code:      http.StatusCreated,
+			expStatus: http.StatusCreated,
+			expBody:   string(body),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{JSONETag: true}, func(c rack.Context) error {
+				return c.JSON(tt.code, map[string]string{"key": "value"})
+			})
+
+			p := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = tt.method
+				if tt.header != "" {
+					r.Headers = map[string]string{"If-None-Match": tt.header}
+				}
+			})
+
+			b, err := h.Invoke(context.Background(), p)
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if act.StatusCode != tt.expStatus {
+				t.Errorf("got %d, expected %d", act.StatusCode, tt.expStatus)
+			}
+			if act.Body != tt.expBody {
+				t.Errorf("got %s, expected %s", act.Body, tt.expBody)
+			}
+			if act.Headers["Etag"] != tag {
+				t.Errorf("got %s, expected %s", act.Headers["Etag"], tag)
+			}
+		})
+	}
+}
+
+func TestStrongETag(t *testing.T) {
+	body := []byte(`{"key":"value"}`)
+
+	t.Run("should return a strong tag", func(t *testing.T) {
+		act := rack.StrongETag(body)
+		if exp := rack.WeakETag(body)[2:]; act != exp {
+			t.Errorf("got %s, expected %s", act, exp)
+		}
+	})
+
+	t.Run("should match if-match headers", func(t *testing.T) {
+		tag := rack.StrongETag(body)
+
+		h := rack.New(func(c rack.Context) error {
+			return rack.RequireIfMatch(c, tag)
+		})
+
+		p := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.RequestContext.HTTP.Method = http.MethodPut
+			r.Headers = map[string]string{"If-Match": tag}
+		})
+
+		b, err := h.Invoke(context.Background(), p)
+		assertErrorExists(t, err, false)
+
+		act := new(events.APIGatewayV2HTTPResponse)
+		unmarshal(b, act)
+
+		if act.StatusCode != http.StatusOK {
+			t.Errorf("got %d, expected %d", act.StatusCode, http.StatusOK)
+		}
+	})
+}
+
+func TestRequireIfMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		etag   string
+		code   int
+	}{
+		{
+			name: "should return precondition required if the header is missing",
+			etag: `"v1"`,
+			code: http.StatusPreconditionRequired,
+		},
+		{
+			name:   "should return precondition failed if the tag is stale",
+			header: `"v1"`,
+			etag:   `"v2"`,
+			code:   http.StatusPreconditionFailed,
+		},
+		{
+			name:   "should return precondition failed for weak tags",
+			header: `W/"v1"`,
+			etag:   `W/"v1"`,
+			code:   http.StatusPreconditionFailed,
+		},
+		{
+			name:   "should return precondition failed if the resource does not exist",
+			header: "*",
+			code:   http.StatusPreconditionFailed,
+		},
+		{
+			name:   "should return nil if the tag matches",
+			header: `"v0", "v1"`,
+			etag:   `"v1"`,
+		},
+		{
+			name:   "should return nil for wildcards if the resource exists",
+			header: "*",
+			etag:   `"v1"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				err := rack.RequireIfMatch(c, tt.etag)
+				if tt.code == 0 {
+					assertErrorExists(t, err, false)
+				} else if act := rack.StatusCode(err); act != tt.code {
+					t.Errorf("got %d, expected %d", act, tt.code)
+				}
+
+				return nil
+			})
+
+			payload := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodPut
+				if tt.header != "" {
+					r.Headers = map[string]string{"If-Match": tt.header}
+				}
+			})
+
+			if _, err := h.Invoke(context.Background(), payload); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
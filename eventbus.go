@@ -0,0 +1,99 @@
+package rack
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+type (
+	// Event represents a domain event
+	Event struct {
+		Bus         string
+		Source      string
+		DetailType  string
+		Detail      string
+		TraceHeader string
+		Attributes  map[string]string
+	}
+
+	// EventPublisher represents an event bus client
+	// Implementations would typically wrap an EventBridge PutEvents call. The event
+	// attributes contain the request correlation values, which can be added to the
+	// event detail or resources as required.
+	EventPublisher interface {
+		Publish(ctx context.Context, e *Event) error
+	}
+
+	// EventPublisherFunc represents an event publisher func
+	EventPublisherFunc func(ctx context.Context, e *Event) error
+
+	// EventBusOptions represents event bus options
+	EventBusOptions struct {
+		// Publisher publishes events to the bus
+		Publisher EventPublisher
+
+		// Bus is the event bus name, defaulting to the RACK_EVENT_BUS variable
+		Bus string
+
+		// Source is the event source, defaulting to the RACK_EVENT_SOURCE variable
+		Source string
+	}
+)
+
+// Environment variables read by WithEventBus
+const (
+	EnvEventBus    = "RACK_EVENT_BUS"
+	EnvEventSource = "RACK_EVENT_SOURCE"
+)
+
+const eventBusKey = "rack.eventBus"
+
+// ErrNoEventPublisher indicates that no event publisher has been configured
+var ErrNoEventPublisher = errors.New("no event publisher configured")
+
+// Publish publishes the event
+func (fn EventPublisherFunc) Publish(ctx context.Context, e *Event) error {
+	return fn(ctx, e)
+}
+
+// WithEventBus returns a middleware func that configures the event bus used by EmitEvent
+func WithEventBus(o EventBusOptions) MiddlewareFunc {
+	if o.Bus == "" {
+		o.Bus = os.Getenv(EnvEventBus)
+	}
+
+	if o.Source == "" {
+		o.Source = os.Getenv(EnvEventSource)
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			c.Set(eventBusKey, o)
+			return n(c)
+		}
+	}
+}
+
+// EmitEvent publishes a domain event to the configured event bus
+// The detail is marshaled using the configured codec unless it is a string or byte slice.
+func EmitEvent(c Context, detailType string, detail interface{}) error {
+	o, ok := c.Get(eventBusKey).(EventBusOptions)
+	if !ok || o.Publisher == nil {
+		return ErrNoEventPublisher
+	}
+
+	d, err := marshalPayload(contextCodec(c), detail)
+	if err != nil {
+		return err
+	}
+
+	return o.Publisher.Publish(c.Context(), &Event{
+		Bus:         o.Bus,
+		Source:      o.Source,
+		DetailType:  detailType,
+		Detail:      d,
+		TraceHeader: traceID(c),
+		Attributes:  correlationAttributes(c),
+	})
+}
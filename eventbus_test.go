@@ -0,0 +1,122 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestEmitEvent(t *testing.T) {
+	tests := []struct {
+		name      string
+		publisher func(*rack.Event) error
+		detail    interface{}
+		exp       *rack.Event
+		err       bool
+	}{
+		{
+			name:   "should return an error if no publisher is configured",
+			detail: "{}",
+			err:    true,
+		},
+		{
+			name: "should return publisher errors",
+			publisher: func(*rack.Event) error {
+				return errors.New("error")
+			},
+			detail: "{}",
+			err:    true,
+		},
+		{
+			name:      "should return marshal errors",
+			publisher: func(*rack.Event) error { return nil },
+			detail:    make(chan struct{}),
+			err:       true,
+		},
+		{
+			name:      "should publish the event",
+			publisher: func(*rack.Event) error { return nil },
+			detail:    map[string]string{"id": "123"},
+			exp: &rack.Event{
+				Bus:         "bus",
+				Source:      "source",
+				DetailType:  "TaskCreated",
+				Detail:      `{"id":"123"}`,
+				TraceHeader: "traceid",
+				Attributes: map[string]string{
+					"RequestId": "requestid",
+					"TraceId":   "traceid",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var act *rack.Event
+
+			o := rack.EventBusOptions{
+				Bus:    "bus",
+				Source: "source",
+			}
+
+			if tt.publisher != nil {
+				o.Publisher = rack.EventPublisherFunc(func(_ context.Context, e *rack.Event) error {
+					act = e
+					return tt.publisher(e)
+				})
+			}
+
+			h := rack.NewWithConfig(rack.Config{Middleware: rack.WithEventBus(o)}, func(c rack.Context) error {
+				err := rack.EmitEvent(c, "TaskCreated", tt.detail)
+				assertErrorExists(t, err, tt.err)
+
+				return c.NoContent(http.StatusCreated)
+			})
+
+			ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+				AwsRequestID: "requestid",
+			})
+
+			p := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"X-Amzn-Trace-Id": "traceid"}
+			})
+
+			_, err := h.Invoke(ctx, p)
+			assertErrorExists(t, err, false)
+
+			if tt.exp != nil {
+				assertDeepEqual(t, act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestWithEventBus(t *testing.T) {
+	t.Run("should default the bus and source from the environment", func(t *testing.T) {
+		t.Setenv(rack.EnvEventBus, "envbus")
+		t.Setenv(rack.EnvEventSource, "envsource")
+
+		var act *rack.Event
+		m := rack.WithEventBus(rack.EventBusOptions{
+			Publisher: rack.EventPublisherFunc(func(_ context.Context, e *rack.Event) error {
+				act = e
+				return nil
+			}),
+		})
+
+		h := rack.NewWithConfig(rack.Config{Middleware: m}, func(c rack.Context) error {
+			return rack.EmitEvent(c, "TaskCreated", "{}")
+		})
+
+		_, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, []string{act.Bus, act.Source}, []string{"envbus", "envsource"})
+	})
+}
@@ -0,0 +1,149 @@
+package rack
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+type (
+	// Experiment represents an A/B experiment
+	Experiment struct {
+		// Variants are the weighted experiment variants
+		// The first variant is the control, which is assigned if the subject is unknown.
+		Variants []Variant
+	}
+
+	// Variant represents a weighted experiment variant
+	// Variants are weighted equally if no weights are specified.
+	Variant struct {
+		Name   string
+		Weight int
+	}
+
+	// Exposure represents an experiment exposure
+	Exposure struct {
+		Experiment string
+		Variant    string
+		Subject    string
+	}
+
+	// ExperimentOptions represents experiment options
+	ExperimentOptions struct {
+		// Experiments are the experiment definitions, keyed by name
+		Experiments map[string]Experiment
+
+		// Subject returns the request subject, such as the user id
+		Subject func(Context) string
+
+		// Metrics records an exposure count for each assigned variant
+		Metrics Metrics
+
+		// OnExposure is called once per request for each exposed experiment
+		OnExposure func(Context, Exposure)
+	}
+)
+
+// MetricExperimentExposure is the experiment exposure metric name
+const MetricExperimentExposure = "rack.experiment.exposure"
+
+const (
+	experimentsKey    = "rack.experiments"
+	exposureKeyPrefix = "rack.exposure."
+)
+
+// AssignVariant returns the variant of the experiment assigned to the specified subject
+// Assignment is deterministic, hashing the experiment name and subject, so a subject
+// receives the same variant for the lifetime of the experiment. An empty string is
+// returned if the experiment has no variants.
+func AssignVariant(name string, e Experiment, subject string) string {
+	var total uint64
+	for _, v := range e.Variants {
+		if v.Weight > 0 {
+			total += uint64(v.Weight)
+		}
+	}
+
+	switch {
+	case len(e.Variants) == 0:
+		return ""
+	case subject == "":
+		return e.Variants[0].Name
+	}
+
+	h := sha256.Sum256([]byte(name + "\x00" + subject))
+	n := binary.BigEndian.Uint64(h[:8])
+
+	if total == 0 {
+		return e.Variants[n%uint64(len(e.Variants))].Name
+	}
+
+	n %= total
+	for _, v := range e.Variants {
+		if v.Weight <= 0 {
+			continue
+		}
+
+		if n < uint64(v.Weight) {
+			return v.Name
+		}
+		n -= uint64(v.Weight)
+	}
+
+	return e.Variants[0].Name
+}
+
+// WithExperiments returns a middleware func that configures the experiments used by ExperimentVariant
+func WithExperiments(o ExperimentOptions) MiddlewareFunc {
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			c.Set(experimentsKey, o)
+			return n(c)
+		}
+	}
+}
+
+// ExperimentVariant returns the variant of the specified experiment assigned to the request subject
+// The exposure is recorded once per request using the configured metrics and
+// exposure hook. The control variant is returned without recording an exposure
+// if the subject is unknown, and an empty string is returned if the experiment
+// is not configured.
+func ExperimentVariant(c Context, experiment string) string {
+	o, ok := c.Get(experimentsKey).(ExperimentOptions)
+	if !ok {
+		return ""
+	}
+
+	e, ok := o.Experiments[experiment]
+	if !ok {
+		return ""
+	}
+
+	var subject string
+	if o.Subject != nil {
+		subject = o.Subject(c)
+	}
+
+	v := AssignVariant(experiment, e, subject)
+	if subject == "" || c.Get(exposureKeyPrefix+experiment) != nil {
+		return v
+	}
+
+	c.Set(exposureKeyPrefix+experiment, v)
+
+	if o.Metrics != nil {
+		o.Metrics.Count(MetricExperimentExposure, 1, map[string]string{
+			"experiment": experiment,
+			"variant":    v,
+		})
+	}
+
+	if o.OnExposure != nil {
+		o.OnExposure(c, Exposure{
+			Experiment: experiment,
+			Variant:    v,
+			Subject:    subject,
+		})
+	}
+
+	return v
+}
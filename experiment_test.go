@@ -0,0 +1,146 @@
+package rack_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestAssignVariant(t *testing.T) {
+	tests := []struct {
+		name       string
+		experiment rack.Experiment
+		subject    string
+		exp        string
+	}{
+		{
+			name: "should return an empty string if there are no variants",
+		},
+		{
+			name: "should return the control if the subject is unknown",
+			experiment: rack.Experiment{Variants: []rack.Variant{
+				{Name: "control", Weight: 0},
+				{Name: "treatment", Weight: 100},
+			}},
+			exp: "control",
+		},
+		{
+			name: "should ignore variants without weight",
+			experiment: rack.Experiment{Variants: []rack.Variant{
+				{Name: "control", Weight: 0},
+				{Name: "treatment", Weight: 100},
+			}},
+			subject: "user",
+			exp:     "treatment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act := rack.AssignVariant("experiment", tt.experiment, tt.subject)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+
+	t.Run("should assign variants deterministically", func(t *testing.T) {
+		e := rack.Experiment{Variants: []rack.Variant{{Name: "a"}, {Name: "b"}}}
+
+		for i := 0; i < 100; i++ {
+			s := fmt.Sprintf("user-%d", i)
+			if a, b := rack.AssignVariant("experiment", e, s), rack.AssignVariant("experiment", e, s); a != b {
+				t.Errorf("got %s, expected %s", b, a)
+			}
+		}
+	})
+
+	t.Run("should assign variants by weight", func(t *testing.T) {
+		e := rack.Experiment{Variants: []rack.Variant{
+			{Name: "control", Weight: 90},
+			{Name: "treatment", Weight: 10},
+		}}
+
+		counts := map[string]int{}
+		for i := 0; i < 10000; i++ {
+			counts[rack.AssignVariant("experiment", e, fmt.Sprintf("user-%d", i))]++
+		}
+
+		if n := counts["treatment"]; n < 800 || n > 1200 {
+			t.Errorf("got %d treatment assignments, expected approximately 1000", n)
+		}
+	})
+}
+
+func TestExperimentVariant(t *testing.T) {
+	experiment := rack.Experiment{Variants: []rack.Variant{
+		{Name: "control", Weight: 0},
+		{Name: "treatment", Weight: 1},
+	}}
+
+	tests := []struct {
+		name      string
+		user      string
+		key       string
+		exp       string
+		exposures []rack.Exposure
+	}{
+		{
+			name: "should return an empty string if the experiment is not configured",
+			user: "user",
+			key:  "unknown",
+		},
+		{
+			name: "should return the control without exposure if the subject is unknown",
+			key:  "checkout",
+			exp:  "control",
+		},
+		{
+			name: "should record the exposure once per request",
+			user: "user",
+			key:  "checkout",
+			exp:  "treatment",
+			exposures: []rack.Exposure{
+				{Experiment: "checkout", Variant: "treatment", Subject: "user"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var exposures []rack.Exposure
+			m := newTestMetrics()
+
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.WithExperiments(rack.ExperimentOptions{
+					Experiments: map[string]rack.Experiment{"checkout": experiment},
+					Subject: func(c rack.Context) string {
+						return c.Request().Header.Get("X-User")
+					},
+					OnExposure: func(_ rack.Context, e rack.Exposure) {
+						exposures = append(exposures, e)
+					},
+					Metrics: m,
+				}),
+			}, func(c rack.Context) error {
+				act := rack.ExperimentVariant(c, tt.key)
+				assertDeepEqual(t, rack.ExperimentVariant(c, tt.key), act)
+
+				return c.String(http.StatusOK, act)
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"X-User": tt.user}
+			}))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			assertDeepEqual(t, res.Body, tt.exp)
+			assertDeepEqual(t, exposures, tt.exposures)
+			assertDeepEqual(t, m.counts[rack.MetricExperimentExposure], int64(len(tt.exposures)))
+		})
+	}
+}
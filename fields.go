@@ -0,0 +1,134 @@
+package rack
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// FieldsQueryParam is the query string parameter used to request sparse fields
+	FieldsQueryParam = "fields"
+
+	// FieldsHeader is the header used to request sparse fields
+	FieldsHeader = "X-Fields"
+)
+
+var errInvalidJSON = errors.New("invalid json")
+
+// fieldMask represents a parsed field mask
+// A nil value indicates that the field is included in its entirety.
+type fieldMask map[string]fieldMask
+
+// RequestedFields returns the sparse fields requested by the client
+// Fields are read from the comma separated fields query string parameter, falling
+// back to the X-Fields header. Nested fields are specified using dot notation.
+// Nil is returned if no fields were requested.
+func RequestedFields(c Context) []string {
+	vs := c.Request().Query[FieldsQueryParam]
+	if len(vs) == 0 {
+		vs = c.Request().Header.Values(FieldsHeader)
+	}
+
+	var fs []string
+	for _, f := range strings.Split(strings.Join(vs, ","), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fs = append(fs, f)
+		}
+	}
+
+	return fs
+}
+
+// PruneJSON removes all members from the JSON document that are not in the specified fields
+// The mask is applied to each element of arrays, and member order is preserved. The
+// document is returned unchanged if no fields are specified.
+func PruneJSON(b []byte, fields []string) ([]byte, error) {
+	if len(fields) == 0 {
+		return b, nil
+	}
+
+	if !gjson.ValidBytes(b) {
+		return nil, errInvalidJSON
+	}
+
+	buf := new(bytes.Buffer)
+	pruneJSON(buf, gjson.ParseBytes(b), parseFieldMask(fields))
+
+	return buf.Bytes(), nil
+}
+
+func parseFieldMask(fields []string) fieldMask {
+	m := fieldMask{}
+	for _, f := range fields {
+		cm := m
+		ps := strings.Split(f, ".")
+		for i, p := range ps {
+			sm, ok := cm[p]
+			if ok && sm == nil {
+				// the field is already included in its entirety
+				break
+			}
+
+			if i == len(ps)-1 {
+				cm[p] = nil
+				break
+			}
+
+			if !ok {
+				sm = fieldMask{}
+				cm[p] = sm
+			}
+
+			cm = sm
+		}
+	}
+
+	return m
+}
+
+func pruneJSON(buf *bytes.Buffer, r gjson.Result, m fieldMask) {
+	switch {
+	case r.IsArray():
+		buf.WriteByte('[')
+		i := 0
+		r.ForEach(func(_, v gjson.Result) bool {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			pruneJSON(buf, v, m)
+			i++
+			return true
+		})
+		buf.WriteByte(']')
+	case r.IsObject():
+		buf.WriteByte('{')
+		i := 0
+		r.ForEach(func(k, v gjson.Result) bool {
+			sm, ok := m[k.String()]
+			if !ok {
+				return true
+			}
+
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(k.Raw)
+			buf.WriteByte(':')
+
+			if sm == nil {
+				buf.WriteString(v.Raw)
+			} else {
+				pruneJSON(buf, v, sm)
+			}
+
+			i++
+			return true
+		})
+		buf.WriteByte('}')
+	default:
+		buf.WriteString(r.Raw)
+	}
+}
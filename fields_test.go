@@ -0,0 +1,193 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestRequestedFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     []string
+	}{
+		{
+			name:    "should return nil if no fields are requested",
+			payload: newV2Request(nil),
+		},
+		{
+			name: "should return query fields",
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RawQueryString = "fields=id,+name,,author.name"
+				r.QueryStringParameters = map[string]string{"fields": "id, name,,author.name"}
+				r.Headers = map[string]string{"X-Fields": "other"}
+			}),
+			exp: []string{"id", "name", "author.name"},
+		},
+		{
+			name: "should return header fields",
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"X-Fields": "id,name"}
+			}),
+			exp: []string{"id", "name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				assertDeepEqual(t, rack.RequestedFields(c), tt.exp)
+				return nil
+			})
+
+			if _, err := h.Invoke(context.Background(), tt.payload); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestPruneJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		fields []string
+		exp    string
+		err    bool
+	}{
+		{
+			name:  "should return the input if no fields are specified",
+			input: `{"id":1,"name":"a"}`,
+			exp:   `{"id":1,"name":"a"}`,
+		},
+		{
+			name:   "should return an error if the input is invalid",
+			input:  `{"id":`,
+			fields: []string{"id"},
+			err:    true,
+		},
+		{
+			name:   "should prune objects preserving order",
+			input:  `{"name":"a","id":1,"other":true}`,
+			fields: []string{"id", "name", "missing"},
+			exp:    `{"name":"a","id":1}`,
+		},
+		{
+			name:   "should prune nested objects",
+			input:  `{"id":1,"author":{"id":2,"name":"b"},"tags":["x"]}`,
+			fields: []string{"author.name", "tags"},
+			exp:    `{"author":{"name":"b"},"tags":["x"]}`,
+		},
+		{
+			name:   "should include entire fields over nested fields",
+			input:  `{"author":{"id":2,"name":"b"}}`,
+			fields: []string{"author.name", "author", "author.id"},
+			exp:    `{"author":{"id":2,"name":"b"}}`,
+		},
+		{
+			name:   "should prune array elements",
+			input:  `[{"id":1,"name":"a"},{"id":2,"name":"b"}]`,
+			fields: []string{"id"},
+			exp:    `[{"id":1},{"id":2}]`,
+		},
+		{
+			name:   "should prune nested arrays",
+			input:  `{"items":[{"id":1,"name":"a"},{"id":2}],"total":2}`,
+			fields: []string{"items.name", "total"},
+			exp:    `{"items":[{"name":"a"},{}],"total":2}`,
+		},
+		{
+			name:   "should return scalar values",
+			input:  `"value"`,
+			fields: []string{"id"},
+			exp:    `"value"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := rack.PruneJSON([]byte(tt.input), tt.fields)
+			assertErrorExists(t, err, tt.err)
+
+			if string(act) != tt.exp {
+				t.Errorf("got %s, expected %s", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestConfig_SparseFields(t *testing.T) {
+	body := map[string]interface{}{"id": 1, "name": "a"}
+
+	tests := []struct {
+		name   string
+		sparse bool
+		code   int
+		exp    string
+	}{
+		{
+			name: "should not prune the response if disabled",
+			code: http.StatusOK,
+			exp:  `{"id":1,"name":"a"}`,
+		},
+		{
+			name:   "should prune the response if enabled",
+			sparse: true,
+			code:   http.StatusOK,
+			exp:    `{"name":"a"}`,
+		},
+		{
+			name:   "should not prune error responses",
+			sparse: true,
+			code:   http.StatusNotFound,
+			exp:    `{"id":1,"name":"a"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := rack.Config{SparseFields: tt.sparse, JSONETag: true}
+
+			h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+				return c.JSON(tt.code, body)
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.QueryStringParameters = map[string]string{"fields": "name"}
+			}))
+			assertErrorExists(t, err, false)
+
+			act := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			if act.Body != tt.exp {
+				t.Errorf("got %s, expected %s", act.Body, tt.exp)
+			}
+			if tag := rack.WeakETag([]byte(tt.exp)); act.Headers["Etag"] != tag {
+				t.Errorf("got %s, expected %s", act.Headers["Etag"], tag)
+			}
+		})
+	}
+}
+
+func TestConfig_SparseFields_Errors(t *testing.T) {
+	cfg := rack.Config{SparseFields: true}
+
+	h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+		return rack.WrapError(http.StatusNotFound, errors.New("not found")).WithCode("TASK_NOT_FOUND")
+	})
+
+	b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+		r.QueryStringParameters = map[string]string{"fields": "id,name"}
+	}))
+	assertErrorExists(t, err, false)
+
+	act := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+	if exp := `{"code":"TASK_NOT_FOUND","message":"not found"}`; act.Body != exp {
+		t.Errorf("got %s, expected %s", act.Body, exp)
+	}
+}
@@ -0,0 +1,177 @@
+package rack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type (
+	// FlashMessage represents a flash message
+	FlashMessage struct {
+		Kind    string `json:"k"`
+		Message string `json:"m"`
+	}
+
+	// FlashOptions represents flash message options
+	FlashOptions struct {
+		// Key is the HMAC key used to sign the flash cookie
+		Key []byte
+
+		// CookieName is the flash cookie name, defaulting to _flash
+		CookieName string
+
+		// InsecureCookie omits the Secure cookie attribute for local development
+		InsecureCookie bool
+	}
+
+	flashState struct {
+		opts FlashOptions
+		in   []FlashMessage
+		out  []FlashMessage
+		read bool
+	}
+)
+
+const flashKey = "rack.flash"
+
+// ErrNoFlashMessages indicates that the FlashMessages middleware has not been configured
+var ErrNoFlashMessages = errors.New("flash messages not configured")
+
+// FlashMessages returns a middleware func that enables flash messages
+// Messages are persisted in a signed cookie, and cookies with an invalid signature
+// are ignored. The func panics if no key is specified.
+func FlashMessages(o FlashOptions) MiddlewareFunc {
+	if len(o.Key) == 0 {
+		panic("rack: flash messages requires a key")
+	}
+
+	if o.CookieName == "" {
+		o.CookieName = "_flash"
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			s := &flashState{opts: o}
+			if ck, err := c.Request().Cookie(o.CookieName); err == nil {
+				s.in = o.decode(ck.Value)
+			}
+
+			c.Set(flashKey, s)
+			return n(c)
+		}
+	}
+}
+
+// Flash adds a flash message to be read by the next request
+// ErrNoFlashMessages is returned if the FlashMessages middleware has not been configured.
+func Flash(c Context, kind, msg string) error {
+	s, ok := c.Get(flashKey).(*flashState)
+	if !ok {
+		return ErrNoFlashMessages
+	}
+
+	s.out = append(s.out, FlashMessage{Kind: kind, Message: msg})
+	writeFlashCookie(c, s)
+
+	return nil
+}
+
+// Flashes returns the flash messages sent by the previous request
+// The messages are consumed, and are not returned to subsequent requests. Nil is
+// returned if the FlashMessages middleware has not been configured.
+func Flashes(c Context) []FlashMessage {
+	s, ok := c.Get(flashKey).(*flashState)
+	if !ok {
+		return nil
+	}
+
+	if !s.read && len(s.in) > 0 {
+		s.read = true
+		writeFlashCookie(c, s)
+	}
+
+	return s.in
+}
+
+// writeFlashCookie replaces any existing flash cookie in the response
+// Unread request messages are retained along with the pending messages, and the
+// cookie is expired once all messages have been read.
+func writeFlashCookie(c Context, s *flashState) {
+	h := c.Response().Headers
+	cs := h.Values("Set-Cookie")
+	h.Del("Set-Cookie")
+	for _, v := range cs {
+		if !strings.HasPrefix(v, s.opts.CookieName+"=") {
+			h.Add("Set-Cookie", v)
+		}
+	}
+
+	ck := &http.Cookie{
+		Name:     s.opts.CookieName,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   !s.opts.InsecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	ms := s.out
+	if !s.read {
+		ms = append(s.in[:len(s.in):len(s.in)], ms...)
+	}
+
+	switch {
+	case len(ms) > 0:
+		b, _ := json.Marshal(ms)
+		ck.Value = s.opts.encode(b)
+	case s.read:
+		ck.MaxAge = -1
+	default:
+		return
+	}
+
+	SetCookie(c, ck)
+}
+
+// encode returns the signed cookie value for the specified payload
+func (o FlashOptions) encode(b []byte) string {
+	p := base64.RawURLEncoding.EncodeToString(b)
+	return p + "." + base64.RawURLEncoding.EncodeToString(o.sign(p))
+}
+
+// decode returns the messages for the specified cookie value
+// Nil is returned if the value is malformed or the signature is invalid.
+func (o FlashOptions) decode(v string) []FlashMessage {
+	i := strings.LastIndexByte(v, '.')
+	if i < 0 {
+		return nil
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(v[i+1:])
+	if err != nil || !hmac.Equal(sig, o.sign(v[:i])) {
+		return nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(v[:i])
+	if err != nil {
+		return nil
+	}
+
+	var ms []FlashMessage
+	if err = json.Unmarshal(b, &ms); err != nil {
+		return nil
+	}
+
+	return ms
+}
+
+func (o FlashOptions) sign(p string) []byte {
+	h := hmac.New(sha256.New, o.Key)
+	h.Write([]byte(p))
+
+	return h.Sum(nil)
+}
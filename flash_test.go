@@ -0,0 +1,161 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestFlashMessages(t *testing.T) {
+	t.Run("should panic if no key is specified", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got nil, expected panic")
+			}
+		}()
+
+		rack.FlashMessages(rack.FlashOptions{})
+	})
+
+	t.Run("should omit the secure attribute for insecure cookies", func(t *testing.T) {
+		for _, insecure := range []bool{false, true} {
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.FlashMessages(rack.FlashOptions{Key: []byte("key"), InsecureCookie: insecure}),
+			}, func(c rack.Context) error {
+				if err := rack.Flash(c, "info", "a"); err != nil {
+					return err
+				}
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			assertDeepEqual(t, strings.Contains(res.Cookies[0], "Secure"), !insecure)
+		}
+	})
+}
+
+func TestFlash(t *testing.T) {
+	t.Run("should return an error if flash messages are not configured", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			if err := rack.Flash(c, "info", "a"); err != rack.ErrNoFlashMessages {
+				t.Errorf("got %v, expected %v", err, rack.ErrNoFlashMessages)
+			}
+			assertDeepEqual(t, len(rack.Flashes(c)), 0)
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		_, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+	})
+
+	invokeKey := func(t *testing.T, key string, cookies []string, fn func(rack.Context)) []string {
+		cfg := rack.Config{
+			Middleware: rack.FlashMessages(rack.FlashOptions{Key: []byte(key)}),
+		}
+
+		h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+			fn(c)
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.Cookies = cookies
+		}))
+		assertErrorExists(t, err, false)
+
+		res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		return res.Cookies
+	}
+
+	invoke := func(t *testing.T, cookies []string, fn func(rack.Context)) []string {
+		return invokeKey(t, "key", cookies, fn)
+	}
+
+	cookie := func(t *testing.T, cs []string) string {
+		if len(cs) != 1 {
+			t.Fatalf("got %d cookies, expected 1", len(cs))
+		}
+
+		return strings.SplitN(cs[0], ";", 2)[0]
+	}
+
+	flashed := cookie(t, invoke(t, nil, func(c rack.Context) {
+		assertErrorExists(t, rack.Flash(c, "success", "created"), false)
+		assertErrorExists(t, rack.Flash(c, "info", "pending"), false)
+	}))
+
+	exp := []rack.FlashMessage{{Kind: "success", Message: "created"}, {Kind: "info", Message: "pending"}}
+
+	t.Run("should return no messages without a cookie", func(t *testing.T) {
+		cs := invoke(t, nil, func(c rack.Context) {
+			assertDeepEqual(t, len(rack.Flashes(c)), 0)
+		})
+		assertDeepEqual(t, len(cs), 0)
+	})
+
+	t.Run("should return and consume the messages", func(t *testing.T) {
+		cs := invoke(t, []string{flashed}, func(c rack.Context) {
+			assertDeepEqual(t, rack.Flashes(c), exp)
+			assertDeepEqual(t, rack.Flashes(c), exp)
+		})
+
+		if !strings.Contains(cs[0], "Max-Age=0") {
+			t.Errorf("got %s, expected an expired cookie", cs[0])
+		}
+	})
+
+	t.Run("should retain unread messages", func(t *testing.T) {
+		act := cookie(t, invoke(t, []string{flashed}, func(c rack.Context) {
+			assertErrorExists(t, rack.Flash(c, "error", "failed"), false)
+		}))
+
+		invoke(t, []string{act}, func(c rack.Context) {
+			assertDeepEqual(t, rack.Flashes(c), append(exp, rack.FlashMessage{Kind: "error", Message: "failed"}))
+		})
+	})
+
+	t.Run("should replace read messages", func(t *testing.T) {
+		act := cookie(t, invoke(t, []string{flashed}, func(c rack.Context) {
+			rack.Flashes(c)
+			assertErrorExists(t, rack.Flash(c, "error", "failed"), false)
+		}))
+
+		invoke(t, []string{act}, func(c rack.Context) {
+			assertDeepEqual(t, rack.Flashes(c), []rack.FlashMessage{{Kind: "error", Message: "failed"}})
+		})
+	})
+
+	t.Run("should ignore invalid cookies", func(t *testing.T) {
+		invoke(t, []string{"_flash=invalid"}, func(c rack.Context) {
+			assertDeepEqual(t, len(rack.Flashes(c)), 0)
+		})
+	})
+
+	t.Run("should ignore cookies with an invalid signature", func(t *testing.T) {
+		invokeKey(t, "other", []string{flashed}, func(c rack.Context) {
+			assertDeepEqual(t, len(rack.Flashes(c)), 0)
+		})
+
+		v := strings.SplitN(flashed, ".", 2)[0] + ".c2lnbmF0dXJl"
+		invoke(t, []string{v}, func(c rack.Context) {
+			assertDeepEqual(t, len(rack.Flashes(c)), 0)
+		})
+	})
+
+	t.Run("should retain other cookies", func(t *testing.T) {
+		cs := invoke(t, nil, func(c rack.Context) {
+			rack.SetCookie(c, &http.Cookie{Name: "a", Value: "1"})
+			assertErrorExists(t, rack.Flash(c, "info", "a"), false)
+			assertErrorExists(t, rack.Flash(c, "info", "b"), false)
+		})
+		assertDeepEqual(t, len(cs), 2)
+	})
+}
@@ -0,0 +1,94 @@
+package rack
+
+import (
+	"context"
+	"net/http"
+)
+
+type (
+	// Location represents the geographic location of a request
+	Location struct {
+		Country    string
+		Region     string
+		City       string
+		PostalCode string
+		TimeZone   string
+	}
+
+	// GeoResolver represents a source ip address location resolver
+	GeoResolver interface {
+		ResolveLocation(ctx context.Context, ip string) (Location, error)
+	}
+
+	// GeoResolverFunc is a func that satisfies the GeoResolver interface
+	GeoResolverFunc func(ctx context.Context, ip string) (Location, error)
+
+	// GeoOptions represents geolocation middleware options
+	GeoOptions struct {
+		// Resolver resolves the location if the request does not contain CloudFront viewer headers
+		Resolver GeoResolver
+	}
+)
+
+// CloudFront viewer location headers
+const (
+	ViewerCountryHeader    = "CloudFront-Viewer-Country"
+	ViewerRegionHeader     = "CloudFront-Viewer-Country-Region"
+	ViewerCityHeader       = "CloudFront-Viewer-City"
+	ViewerPostalCodeHeader = "CloudFront-Viewer-Postal-Code"
+	ViewerTimeZoneHeader   = "CloudFront-Viewer-Time-Zone"
+)
+
+const geoKey = "rack.geo"
+
+// ResolveLocation calls the underlying func
+func (fn GeoResolverFunc) ResolveLocation(ctx context.Context, ip string) (Location, error) {
+	return fn(ctx, ip)
+}
+
+// Geo returns a middleware func that enriches the context with the request location
+// CloudFront viewer headers are used if present, otherwise the location is resolved
+// from the request source ip address using the configured resolver. Resolver errors
+// are returned to the error handler.
+func Geo(o GeoOptions) MiddlewareFunc {
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			l := viewerLocation(c.Request().Header)
+			if l.Country == "" && o.Resolver != nil && c.Request().SourceIP != "" {
+				var err error
+				if l, err = o.Resolver.ResolveLocation(c.Context(), c.Request().SourceIP); err != nil {
+					return err
+				}
+			}
+
+			c.Set(geoKey, l)
+			return n(c)
+		}
+	}
+}
+
+// GeoLocation returns the request location
+// CloudFront viewer headers are used if the Geo middleware has not been configured.
+func GeoLocation(c Context) Location {
+	if l, ok := c.Get(geoKey).(Location); ok {
+		return l
+	}
+
+	return viewerLocation(c.Request().Header)
+}
+
+// Country returns the ISO 3166-1 alpha-2 country code of the request
+// An empty string is returned if the country is unknown.
+func Country(c Context) string {
+	return GeoLocation(c).Country
+}
+
+func viewerLocation(h http.Header) Location {
+	return Location{
+		Country:    h.Get(ViewerCountryHeader),
+		Region:     h.Get(ViewerRegionHeader),
+		City:       h.Get(ViewerCityHeader),
+		PostalCode: h.Get(ViewerPostalCodeHeader),
+		TimeZone:   h.Get(ViewerTimeZoneHeader),
+	}
+}
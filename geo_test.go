@@ -0,0 +1,111 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestGeo(t *testing.T) {
+	resolver := rack.GeoResolverFunc(func(_ context.Context, ip string) (rack.Location, error) {
+		switch ip {
+		case "1.1.1.1":
+			return rack.Location{Country: "AU", City: "Sydney"}, nil
+		case "2.2.2.2":
+			return rack.Location{}, errors.New("error")
+		default:
+			return rack.Location{}, nil
+		}
+	})
+
+	tests := []struct {
+		name     string
+		resolver rack.GeoResolver
+		ip       string
+		headers  map[string]string
+		exp      rack.Location
+		status   int
+	}{
+		{
+			name: "should use cloudfront viewer headers",
+			ip:   "1.1.1.1",
+			headers: map[string]string{
+				rack.ViewerCountryHeader:    "GB",
+				rack.ViewerRegionHeader:     "ENG",
+				rack.ViewerCityHeader:       "London",
+				rack.ViewerPostalCodeHeader: "EC1",
+				rack.ViewerTimeZoneHeader:   "Europe/London",
+			},
+			resolver: resolver,
+			exp: rack.Location{
+				Country:    "GB",
+				Region:     "ENG",
+				City:       "London",
+				PostalCode: "EC1",
+				TimeZone:   "Europe/London",
+			},
+			status: http.StatusNoContent,
+		},
+		{
+			name:     "should use the resolver",
+			ip:       "1.1.1.1",
+			resolver: resolver,
+			exp:      rack.Location{Country: "AU", City: "Sydney"},
+			status:   http.StatusNoContent,
+		},
+		{
+			name:   "should return empty locations without a resolver",
+			ip:     "1.1.1.1",
+			status: http.StatusNoContent,
+		},
+		{
+			name:     "should not resolve requests without a source ip",
+			resolver: resolver,
+			status:   http.StatusNoContent,
+		},
+		{
+			name:     "should return resolver errors",
+			ip:       "2.2.2.2",
+			resolver: resolver,
+			status:   http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.Geo(rack.GeoOptions{Resolver: tt.resolver}),
+			}, func(c rack.Context) error {
+				assertDeepEqual(t, rack.GeoLocation(c), tt.exp)
+				assertDeepEqual(t, rack.Country(c), tt.exp.Country)
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.SourceIP = tt.ip
+				r.Headers = tt.headers
+			}))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			assertDeepEqual(t, res.StatusCode, tt.status)
+		})
+	}
+
+	t.Run("should use cloudfront viewer headers without the middleware", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			assertDeepEqual(t, rack.Country(c), "GB")
+			return nil
+		})
+
+		_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.Headers = map[string]string{rack.ViewerCountryHeader: "GB"}
+		}))
+		assertErrorExists(t, err, false)
+	})
+}
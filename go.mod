@@ -1,8 +1,13 @@
 module github.com/stevecallear/rack
 
-go 1.16
+go 1.18
 
 require (
 	github.com/aws/aws-lambda-go v1.25.0
 	github.com/tidwall/gjson v1.8.1
 )
+
+require (
+	github.com/tidwall/match v1.0.3 // indirect
+	github.com/tidwall/pretty v1.1.0 // indirect
+)
@@ -0,0 +1,146 @@
+package rack
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type (
+	// HALResource represents a HAL resource
+	HALResource struct {
+		value    interface{}
+		links    map[string][]HALLink
+		embedded map[string][]interface{}
+	}
+
+	// HALLink represents a HAL link object
+	HALLink struct {
+		Href      string `json:"href"`
+		Templated bool   `json:"templated,omitempty"`
+		Title     string `json:"title,omitempty"`
+		Name      string `json:"name,omitempty"`
+	}
+)
+
+// HALContentType is the HAL media type
+const HALContentType = "application/hal+json"
+
+// NewHALResource returns a new HAL resource for the specified value
+// The value must marshal to a JSON object, or be nil.
+func NewHALResource(v interface{}) *HALResource {
+	return &HALResource{
+		value:    v,
+		links:    map[string][]HALLink{},
+		embedded: map[string][]interface{}{},
+	}
+}
+
+// Link adds a link with the specified relation and href
+func (r *HALResource) Link(rel, href string) *HALResource {
+	return r.AddLink(rel, HALLink{Href: href})
+}
+
+// AddLink adds the specified link object with the relation
+// Relations with a single link are written as an object, and relations with
+// multiple links as an array.
+func (r *HALResource) AddLink(rel string, l HALLink) *HALResource {
+	r.links[rel] = append(r.links[rel], l)
+	return r
+}
+
+// Embed adds the specified resources with the relation
+// Embedded values can be HAL resources or any value that marshals to a JSON object.
+// Relations with a single embedded resource are written as an object.
+func (r *HALResource) Embed(rel string, v ...interface{}) *HALResource {
+	r.embedded[rel] = append(r.embedded[rel], v...)
+	return r
+}
+
+// MarshalJSON marshals the resource as HAL JSON
+func (r *HALResource) MarshalJSON() ([]byte, error) {
+	return r.marshal(DefaultJSONCodec)
+}
+
+// marshal marshals the resource as HAL JSON using the specified codec
+func (r *HALResource) marshal(codec Codec) ([]byte, error) {
+	m := map[string]json.RawMessage{}
+
+	if r.value != nil {
+		b, err := codec.Marshal(r.value)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("hal resource must be a json object: %w", err)
+		}
+	}
+
+	if len(r.links) > 0 {
+		ls := make(map[string]interface{}, len(r.links))
+		for rel, l := range r.links {
+			if len(l) == 1 {
+				ls[rel] = l[0]
+			} else {
+				ls[rel] = l
+			}
+		}
+
+		b, err := json.Marshal(ls)
+		if err != nil {
+			return nil, err
+		}
+
+		m["_links"] = b
+	}
+
+	if len(r.embedded) > 0 {
+		es := make(map[string]json.RawMessage, len(r.embedded))
+		for rel, e := range r.embedded {
+			vs := make([]json.RawMessage, len(e))
+			for i, v := range e {
+				b, err := marshalEmbedded(codec, v)
+				if err != nil {
+					return nil, err
+				}
+
+				vs[i] = b
+			}
+
+			var err error
+			if len(vs) == 1 {
+				es[rel] = vs[0]
+			} else if es[rel], err = json.Marshal(vs); err != nil {
+				return nil, err
+			}
+		}
+
+		b, err := json.Marshal(es)
+		if err != nil {
+			return nil, err
+		}
+
+		m["_embedded"] = b
+	}
+
+	return json.Marshal(m)
+}
+
+// HAL writes the specified status code and resource to the response
+// The resource and embedded values are encoded using the configured codec.
+func HAL(c Context, code int, r *HALResource) error {
+	b, err := r.marshal(contextCodec(c))
+	if err != nil {
+		return err
+	}
+
+	return Blob(c, code, HALContentType, b)
+}
+
+func marshalEmbedded(codec Codec, v interface{}) ([]byte, error) {
+	if r, ok := v.(*HALResource); ok && r != nil {
+		return r.marshal(codec)
+	}
+
+	return codec.Marshal(v)
+}
@@ -0,0 +1,97 @@
+package rack_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestHALResource_MarshalJSON(t *testing.T) {
+	type task struct {
+		ID string `json:"id"`
+	}
+
+	tests := []struct {
+		name     string
+		resource *rack.HALResource
+		exp      string
+		err      bool
+	}{
+		{
+			name:     "should return an error if the value is not an object",
+			resource: rack.NewHALResource("value"),
+			err:      true,
+		},
+		{
+			name:     "should marshal empty resources",
+			resource: rack.NewHALResource(nil),
+			exp:      `{}`,
+		},
+		{
+			name: "should marshal the resource",
+			resource: rack.NewHALResource(&task{ID: "1"}).
+				Link("self", "/tasks/1").
+				Link("related", "/tasks/2").
+				Link("related", "/tasks/3").
+				Embed("owner", rack.NewHALResource(map[string]string{"name": "user"}).Link("self", "/users/1")),
+			exp: `{"_embedded":{"owner":{"_links":{"self":{"href":"/users/1"}},"name":"user"}},"_links":{"related":[{"href":"/tasks/2"},{"href":"/tasks/3"}],"self":{"href":"/tasks/1"}},"id":"1"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := json.Marshal(tt.resource)
+			assertErrorExists(t, err, tt.err)
+
+			if !tt.err && string(act) != tt.exp {
+				t.Errorf("got %s, expected %s", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestHAL(t *testing.T) {
+	t.Run("should write the resource", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			return rack.HAL(c, http.StatusOK, rack.NewHALResource(nil).Link("self", "/"))
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		act := new(events.APIGatewayV2HTTPResponse)
+		unmarshal(b, act)
+
+		if exp := `{"_links":{"self":{"href":"/"}}}`; act.Body != exp {
+			t.Errorf("got %s, expected %s", act.Body, exp)
+		}
+		if ct := act.Headers["Content-Type"]; ct != rack.HALContentType {
+			t.Errorf("got %s, expected %s", ct, rack.HALContentType)
+		}
+	})
+	t.Run("should encode the resource using the configured codec", func(t *testing.T) {
+		h := rack.NewWithConfig(rack.Config{
+			Codec: rack.NewJSONCodec(rack.JSONOptions{Int64AsString: true}),
+		}, func(c rack.Context) error {
+			r := rack.NewHALResource(map[string]int64{"id": 1}).
+				Embed("item", rack.NewHALResource(map[string]int64{"id": 2}))
+
+			return rack.HAL(c, http.StatusOK, r)
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		act := new(events.APIGatewayV2HTTPResponse)
+		unmarshal(b, act)
+
+		if exp := `{"_embedded":{"item":{"id":"2"}},"id":"1"}`; act.Body != exp {
+			t.Errorf("got %s, expected %s", act.Body, exp)
+		}
+	})
+}
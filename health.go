@@ -0,0 +1,163 @@
+package rack
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// HealthChecker represents a dependency health checker
+	HealthChecker interface {
+		Check(ctx context.Context) error
+	}
+
+	// HealthCheckerFunc represents a health checker func
+	HealthCheckerFunc func(ctx context.Context) error
+
+	// HealthCheck represents a named health check
+	// A zero timeout indicates that the check is only bound by the invocation deadline.
+	HealthCheck struct {
+		Name    string
+		Checker HealthChecker
+		Timeout time.Duration
+	}
+
+	// HealthStatus represents an aggregated health status
+	HealthStatus struct {
+		Status string                       `json:"status"`
+		Checks map[string]HealthCheckStatus `json:"checks,omitempty"`
+	}
+
+	// HealthCheckStatus represents an individual health check status
+	HealthCheckStatus struct {
+		Status   string `json:"status"`
+		Error    string `json:"error,omitempty"`
+		Duration string `json:"duration"`
+	}
+)
+
+const (
+	// HealthStatusPass indicates that a check has passed
+	HealthStatusPass = "pass"
+
+	// HealthStatusFail indicates that a check has failed
+	HealthStatusFail = "fail"
+)
+
+const healthCheckKey = "rack.health"
+
+// Check checks the dependency health
+func (fn HealthCheckerFunc) Check(ctx context.Context) error {
+	return fn(ctx)
+}
+
+// Health returns a handler func that aggregates the specified health checks
+// Checks run concurrently, and the status is written as JSON with a 200 status
+// code if all checks pass, or a 503 status code otherwise.
+func Health(checks ...HealthCheck) HandlerFunc {
+	return func(c Context) error {
+		s := HealthStatus{
+			Status: HealthStatusPass,
+			Checks: make(map[string]HealthCheckStatus, len(checks)),
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+
+		for _, hc := range checks {
+			wg.Add(1)
+			go func(hc HealthCheck) {
+				defer wg.Done()
+
+				cs := runHealthCheck(c.Context(), hc)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				s.Checks[hc.Name] = cs
+				if cs.Status != HealthStatusPass {
+					s.Status = HealthStatusFail
+				}
+			}(hc)
+		}
+
+		wg.Wait()
+
+		code := http.StatusOK
+		if s.Status != HealthStatusPass {
+			code = http.StatusServiceUnavailable
+		}
+
+		return c.JSON(code, &s)
+	}
+}
+
+// HTTPHealthChecker returns a health checker for the specified url
+// The check fails if the request fails or a non-2xx status code is returned.
+func HTTPHealthChecker(client *http.Client, url string) HealthChecker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return HealthCheckerFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("unexpected status code %d", res.StatusCode)
+		}
+
+		return nil
+	})
+}
+
+// ItemStoreHealthChecker returns a health checker for the specified table
+// The check reads a single item, and fails if the store returns an error.
+func ItemStoreHealthChecker(client CacheItemStore, table string) HealthChecker {
+	return HealthCheckerFunc(func(ctx context.Context) error {
+		_, err := client.GetItem(ctx, table, healthCheckKey)
+		return err
+	})
+}
+
+func runHealthCheck(ctx context.Context, hc HealthCheck) (s HealthCheckStatus) {
+	if hc.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hc.Timeout)
+		defer cancel()
+	}
+
+	st := time.Now()
+	defer func() {
+		s.Duration = time.Since(st).String()
+	}()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- hc.Checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		return HealthCheckStatus{Status: HealthStatusFail, Error: err.Error()}
+	}
+
+	return HealthCheckStatus{Status: HealthStatusPass}
+}
@@ -0,0 +1,145 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestHealth(t *testing.T) {
+	pass := rack.HealthCheckerFunc(func(context.Context) error {
+		return nil
+	})
+
+	tests := []struct {
+		name   string
+		checks []rack.HealthCheck
+		status int
+		exp    map[string]string
+	}{
+		{
+			name:   "should pass if no checks are registered",
+			status: http.StatusOK,
+			exp:    map[string]string{},
+		},
+		{
+			name: "should pass if all checks pass",
+			checks: []rack.HealthCheck{
+				{Name: "a", Checker: pass},
+				{Name: "b", Checker: pass},
+			},
+			status: http.StatusOK,
+			exp:    map[string]string{"a": rack.HealthStatusPass, "b": rack.HealthStatusPass},
+		},
+		{
+			name: "should fail if any check fails",
+			checks: []rack.HealthCheck{
+				{Name: "a", Checker: pass},
+				{Name: "b", Checker: rack.HealthCheckerFunc(func(context.Context) error {
+					return errors.New("error")
+				})},
+			},
+			status: http.StatusServiceUnavailable,
+			exp:    map[string]string{"a": rack.HealthStatusPass, "b": rack.HealthStatusFail},
+		},
+		{
+			name: "should fail if a check times out",
+			checks: []rack.HealthCheck{
+				{Name: "a", Timeout: time.Millisecond, Checker: rack.HealthCheckerFunc(func(ctx context.Context) error {
+					<-ctx.Done()
+					return nil
+				})},
+			},
+			status: http.StatusServiceUnavailable,
+			exp:    map[string]string{"a": rack.HealthStatusFail},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(rack.Health(tt.checks...))
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			res := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, res)
+
+			if res.StatusCode != tt.status {
+				t.Errorf("got %d, expected %d", res.StatusCode, tt.status)
+			}
+
+			s := new(rack.HealthStatus)
+			unmarshal([]byte(res.Body), s)
+
+			act := map[string]string{}
+			for n, cs := range s.Checks {
+				act[n] = cs.Status
+			}
+
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestHTTPHealthChecker(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    bool
+	}{
+		{
+			name:   "should return an error on non-2xx status codes",
+			status: http.StatusBadGateway,
+			err:    true,
+		},
+		{
+			name:   "should return nil on success",
+			status: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer s.Close()
+
+			err := rack.HTTPHealthChecker(nil, s.URL).Check(context.Background())
+			assertErrorExists(t, err, tt.err)
+		})
+	}
+}
+
+func TestItemStoreHealthChecker(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "should return client errors",
+			err:  errors.New("error"),
+		},
+		{
+			name: "should return nil on success",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestItemStore()
+			c.err = tt.err
+
+			err := rack.ItemStoreHealthChecker(c, "table").Check(context.Background())
+			assertErrorExists(t, err, tt.err != nil)
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package rack
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type (
+	// JobState represents an asynchronous job state
+	JobState string
+
+	// Job represents an asynchronous job
+	Job struct {
+		ID        string          `json:"id"`
+		State     JobState        `json:"state"`
+		Result    json.RawMessage `json:"result,omitempty"`
+		Error     string          `json:"error,omitempty"`
+		CreatedAt time.Time       `json:"createdAt"`
+		UpdatedAt time.Time       `json:"updatedAt"`
+	}
+
+	// JobMessage represents the queue message for an asynchronous job
+	JobMessage struct {
+		JobID   string          `json:"jobId"`
+		Payload json.RawMessage `json:"payload"`
+	}
+
+	// JobStore represents an asynchronous job store
+	JobStore interface {
+		// Load returns the job with the specified id
+		// Nil is returned if the job does not exist.
+		Load(ctx context.Context, id string) (*Job, error)
+
+		// Save stores the specified job
+		Save(ctx context.Context, j *Job) error
+	}
+
+	// AsyncOptions represents asynchronous request-reply options
+	AsyncOptions struct {
+		// Store is the job store
+		Store JobStore
+
+		// Queue is the queue that job messages are enqueued to
+		Queue string
+
+		// Location returns the status url for the specified job id
+		Location func(id string) string
+
+		// PathParam is the status route job id path parameter, defaulting to id
+		PathParam string
+
+		// RetryAfter is the suggested polling interval for incomplete jobs
+		RetryAfter time.Duration
+	}
+
+	cacheJobStore struct {
+		cache Cache
+		ttl   time.Duration
+	}
+)
+
+const (
+	// JobPending indicates that the job has been accepted
+	JobPending JobState = "pending"
+
+	// JobRunning indicates that the job is being processed
+	JobRunning JobState = "running"
+
+	// JobSucceeded indicates that the job completed successfully
+	JobSucceeded JobState = "succeeded"
+
+	// JobFailed indicates that the job failed
+	JobFailed JobState = "failed"
+)
+
+var (
+	// ErrJobNotFound indicates that the job does not exist
+	ErrJobNotFound = errors.New("job not found")
+
+	errJobNotEnqueued = errors.New("job could not be enqueued")
+)
+
+// NewCacheJobStore returns a new job store backed by the specified cache
+// The ttl determines how long job status can be polled after the last update.
+func NewCacheJobStore(c Cache, ttl time.Duration) JobStore {
+	return &cacheJobStore{
+		cache: c,
+		ttl:   ttl,
+	}
+}
+
+func (s *cacheJobStore) Load(ctx context.Context, id string) (*Job, error) {
+	b, ok, err := s.cache.Get(ctx, id)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	j := new(Job)
+	if err = json.Unmarshal(b, j); err != nil {
+		return nil, err
+	}
+
+	return j, nil
+}
+
+func (s *cacheJobStore) Save(ctx context.Context, j *Job) error {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Set(ctx, j.ID, b, s.ttl)
+}
+
+// NewJobID returns a new random job id
+func NewJobID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// Accept accepts the payload for asynchronous processing
+// A pending job is stored and a JobMessage is enqueued using the configured
+// Enqueuer. A 202 response is written containing the job, with a Location header
+// referencing the job status url. The job is marked as failed if the message cannot
+// be enqueued. Payloads, results and stored jobs are always encoded using
+// encoding/json rather than the configured codec, as they are read by workers that
+// may not share the handler configuration.
+func Accept(c Context, o AsyncOptions, payload interface{}) error {
+	p, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	j := &Job{
+		ID:        NewJobID(),
+		State:     JobPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err = o.Store.Save(c.Context(), j); err != nil {
+		return err
+	}
+
+	if err = Enqueue(c, o.Queue, &JobMessage{JobID: j.ID, Payload: p}); err != nil {
+		// the job would otherwise be reported as pending until it expires
+		if ferr := FailJob(c.Context(), o.Store, j.ID, errJobNotEnqueued); ferr != nil {
+			err = fmt.Errorf("%w: fail job: %v", err, ferr)
+		}
+		return err
+	}
+
+	if o.Location != nil {
+		c.Response().Headers.Set("Location", o.Location(j.ID))
+	}
+
+	if o.RetryAfter > 0 {
+		RetryAfter(c, o.RetryAfter)
+	}
+
+	return c.JSON(http.StatusAccepted, j)
+}
+
+// JobStatus returns a handler func that writes the status of the requested job
+// The job id is read from the configured path parameter. Incomplete jobs include a
+// Retry-After header if configured.
+func JobStatus(o AsyncOptions) HandlerFunc {
+	param := o.PathParam
+	if param == "" {
+		param = "id"
+	}
+
+	return func(c Context) error {
+		j, err := o.Store.Load(c.Context(), c.Path(param))
+		if err != nil {
+			return err
+		}
+
+		if j == nil {
+			return WrapError(http.StatusNotFound, ErrJobNotFound)
+		}
+
+		if o.RetryAfter > 0 && (j.State == JobPending || j.State == JobRunning) {
+			RetryAfter(c, o.RetryAfter)
+		}
+
+		return c.JSON(http.StatusOK, j)
+	}
+}
+
+// StartJob marks the job with the specified id as running
+func StartJob(ctx context.Context, s JobStore, id string) error {
+	return updateJob(ctx, s, id, func(j *Job) error {
+		j.State = JobRunning
+		return nil
+	})
+}
+
+// CompleteJob marks the job with the specified id as succeeded with the specified result
+func CompleteJob(ctx context.Context, s JobStore, id string, result interface{}) error {
+	return updateJob(ctx, s, id, func(j *Job) (err error) {
+		j.State = JobSucceeded
+		j.Result, err = json.Marshal(result)
+		return err
+	})
+}
+
+// FailJob marks the job with the specified id as failed with the specified error
+func FailJob(ctx context.Context, s JobStore, id string, jobErr error) error {
+	return updateJob(ctx, s, id, func(j *Job) error {
+		j.State = JobFailed
+		j.Error = jobErr.Error()
+		return nil
+	})
+}
+
+func updateJob(ctx context.Context, s JobStore, id string, fn func(*Job) error) error {
+	j, err := s.Load(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if j == nil {
+		return ErrJobNotFound
+	}
+
+	if err = fn(j); err != nil {
+		return err
+	}
+
+	j.UpdatedAt = time.Now().UTC()
+	return s.Save(ctx, j)
+}
@@ -0,0 +1,215 @@
+package rack_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestAccept(t *testing.T) {
+	t.Run("should return enqueuer errors", func(t *testing.T) {
+		store := &recordingJobStore{JobStore: rack.NewCacheJobStore(rack.NewMemoryCache(), time.Hour)}
+
+		h := rack.New(func(c rack.Context) error {
+			err := rack.Accept(c, rack.AsyncOptions{Store: store, Queue: "queue"}, "payload")
+			if err != rack.ErrNoEnqueuer {
+				t.Errorf("got %v, expected %v", err, rack.ErrNoEnqueuer)
+			}
+			return nil
+		})
+
+		if _, err := h.Invoke(context.Background(), newV2Request(nil)); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(store.ids) < 1 {
+			t.Fatal("got 0 jobs, expected 1")
+		}
+
+		stored, err := store.Load(context.Background(), store.ids[0])
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, stored.State, rack.JobFailed)
+	})
+
+	t.Run("should encode the payload as json regardless of the codec", func(t *testing.T) {
+		store := rack.NewCacheJobStore(rack.NewMemoryCache(), time.Hour)
+
+		var msg *rack.Message
+		cfg := rack.Config{
+			Codec: rack.NewJSONCodec(rack.JSONOptions{Int64AsString: true}),
+			Middleware: rack.WithEnqueuer(rack.EnqueuerFunc(func(_ context.Context, m *rack.Message) error {
+				msg = m
+				return nil
+			})),
+		}
+
+		h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+			return rack.Accept(c, rack.AsyncOptions{Store: store, Queue: "queue"}, map[string]int64{"key": 1})
+		})
+
+		_, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		jm := unmarshal([]byte(msg.Body), new(rack.JobMessage)).(*rack.JobMessage)
+		assertDeepEqual(t, string(jm.Payload), `{"key":1}`)
+	})
+
+	t.Run("should enqueue the job and return accepted", func(t *testing.T) {
+		store := rack.NewCacheJobStore(rack.NewMemoryCache(), time.Hour)
+
+		var msg *rack.Message
+		cfg := rack.Config{
+			Middleware: rack.WithEnqueuer(rack.EnqueuerFunc(func(_ context.Context, m *rack.Message) error {
+				msg = m
+				return nil
+			})),
+		}
+
+		o := rack.AsyncOptions{
+			Store:      store,
+			Queue:      "queue",
+			Location:   func(id string) string { return "/jobs/" + id },
+			RetryAfter: 5 * time.Second,
+		}
+
+		h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+			return rack.Accept(c, o, map[string]string{"key": "value"})
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		if res.StatusCode != http.StatusAccepted {
+			t.Errorf("got %d, expected %d", res.StatusCode, http.StatusAccepted)
+		}
+
+		job := unmarshal([]byte(res.Body), new(rack.Job)).(*rack.Job)
+		if job.ID == "" || job.State != rack.JobPending {
+			t.Errorf("got %+v, expected a pending job", job)
+		}
+
+		assertDeepEqual(t, res.Headers["Location"], "/jobs/"+job.ID)
+		assertDeepEqual(t, res.Headers["Retry-After"], "5")
+
+		jm := unmarshal([]byte(msg.Body), new(rack.JobMessage)).(*rack.JobMessage)
+		assertDeepEqual(t, msg.Queue, "queue")
+		assertDeepEqual(t, jm.JobID, job.ID)
+		assertDeepEqual(t, string(jm.Payload), `{"key":"value"}`)
+
+		stored, err := store.Load(context.Background(), job.ID)
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, stored.State, rack.JobPending)
+	})
+}
+
+func TestJobStatus(t *testing.T) {
+	ctx := context.Background()
+	store := rack.NewCacheJobStore(rack.NewMemoryCache(), time.Hour)
+
+	for _, j := range []*rack.Job{
+		{ID: "pending", State: rack.JobPending},
+		{ID: "running", State: rack.JobPending},
+		{ID: "succeeded", State: rack.JobPending},
+		{ID: "failed", State: rack.JobPending},
+	} {
+		if err := store.Save(ctx, j); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assertErrorExists(t, rack.StartJob(ctx, store, "running"), false)
+	assertErrorExists(t, rack.CompleteJob(ctx, store, "succeeded", map[string]int{"count": 1}), false)
+	assertErrorExists(t, rack.FailJob(ctx, store, "failed", errors.New("error")), false)
+	assertErrorExists(t, rack.CompleteJob(ctx, store, "missing", nil), true)
+
+	tests := []struct {
+		name       string
+		id         string
+		code       int
+		state      rack.JobState
+		result     json.RawMessage
+		err        string
+		retryAfter string
+	}{
+		{
+			name: "should return not found for missing jobs",
+			id:   "missing",
+			code: http.StatusNotFound,
+		},
+		{
+			name:       "should return pending jobs",
+			id:         "pending",
+			code:       http.StatusOK,
+			state:      rack.JobPending,
+			retryAfter: "2",
+		},
+		{
+			name:       "should return running jobs",
+			id:         "running",
+			code:       http.StatusOK,
+			state:      rack.JobRunning,
+			retryAfter: "2",
+		},
+		{
+			name:   "should return succeeded jobs",
+			id:     "succeeded",
+			code:   http.StatusOK,
+			state:  rack.JobSucceeded,
+			result: json.RawMessage(`{"count":1}`),
+		},
+		{
+			name:  "should return failed jobs",
+			id:    "failed",
+			code:  http.StatusOK,
+			state: rack.JobFailed,
+			err:   "error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(rack.JobStatus(rack.AsyncOptions{
+				Store:      store,
+				RetryAfter: 2 * time.Second,
+			}))
+
+			b, err := h.Invoke(ctx, newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.PathParameters = map[string]string{"id": tt.id}
+			}))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			if res.StatusCode != tt.code {
+				t.Errorf("got %d, expected %d", res.StatusCode, tt.code)
+			}
+			assertDeepEqual(t, res.Headers["Retry-After"], tt.retryAfter)
+
+			if tt.code != http.StatusOK {
+				return
+			}
+
+			job := unmarshal([]byte(res.Body), new(rack.Job)).(*rack.Job)
+			assertDeepEqual(t, job.State, tt.state)
+			assertDeepEqual(t, job.Result, tt.result)
+			assertDeepEqual(t, job.Error, tt.err)
+		})
+	}
+}
+
+type recordingJobStore struct {
+	rack.JobStore
+	ids []string
+}
+
+func (s *recordingJobStore) Save(ctx context.Context, j *rack.Job) error {
+	s.ids = append(s.ids, j.ID)
+	return s.JobStore.Save(ctx, j)
+}
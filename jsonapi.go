@@ -0,0 +1,91 @@
+package rack
+
+import (
+	"net/http"
+	"strconv"
+)
+
+type (
+	// JSONAPIDocument represents a JSON:API top level document
+	JSONAPIDocument struct {
+		Data     interface{}            `json:"data,omitempty"`
+		Errors   []JSONAPIError         `json:"errors,omitempty"`
+		Included []JSONAPIResource      `json:"included,omitempty"`
+		Links    map[string]string      `json:"links,omitempty"`
+		Meta     map[string]interface{} `json:"meta,omitempty"`
+	}
+
+	// JSONAPIResource represents a JSON:API resource object
+	JSONAPIResource struct {
+		Type          string                         `json:"type"`
+		ID            string                         `json:"id,omitempty"`
+		Attributes    interface{}                    `json:"attributes,omitempty"`
+		Relationships map[string]JSONAPIRelationship `json:"relationships,omitempty"`
+		Links         map[string]string              `json:"links,omitempty"`
+		Meta          map[string]interface{}         `json:"meta,omitempty"`
+	}
+
+	// JSONAPIResourceIdentifier represents a JSON:API resource identifier object
+	JSONAPIResourceIdentifier struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	}
+
+	// JSONAPIRelationship represents a JSON:API relationship object
+	// Data should be a resource identifier for to-one relationships, or a slice of
+	// resource identifiers for to-many relationships.
+	JSONAPIRelationship struct {
+		Data  interface{}       `json:"data"`
+		Links map[string]string `json:"links,omitempty"`
+	}
+
+	// JSONAPIError represents a JSON:API error object
+	JSONAPIError struct {
+		ID     string              `json:"id,omitempty"`
+		Status string              `json:"status,omitempty"`
+		Code   string              `json:"code,omitempty"`
+		Title  string              `json:"title,omitempty"`
+		Detail string              `json:"detail,omitempty"`
+		Source *JSONAPIErrorSource `json:"source,omitempty"`
+	}
+
+	// JSONAPIErrorSource represents a JSON:API error source object
+	JSONAPIErrorSource struct {
+		Pointer   string `json:"pointer,omitempty"`
+		Parameter string `json:"parameter,omitempty"`
+	}
+)
+
+// JSONAPIContentType is the JSON:API media type
+const JSONAPIContentType = "application/vnd.api+json"
+
+// JSONAPI writes the specified status code and document to the response
+// The document is encoded using the configured codec.
+func JSONAPI(c Context, code int, doc *JSONAPIDocument) error {
+	b, err := contextCodec(c).Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return Blob(c, code, JSONAPIContentType, b)
+}
+
+// NewJSONAPIError returns a new JSON:API error object for the specified error
+// The status and code are taken from the error if it is a status error.
+func NewJSONAPIError(err error) JSONAPIError {
+	code := StatusCode(err)
+
+	return JSONAPIError{
+		Status: strconv.Itoa(code),
+		Code:   ErrorCode(err),
+		Title:  http.StatusText(code),
+		Detail: err.Error(),
+	}
+}
+
+// JSONAPIErrorHandler is an error handler that writes JSON:API error documents
+func JSONAPIErrorHandler(c Context, err error) error {
+	return JSONAPI(c, StatusCode(err), &JSONAPIDocument{
+		Errors: []JSONAPIError{NewJSONAPIError(err)},
+	})
+}
@@ -0,0 +1,112 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestJSONAPI(t *testing.T) {
+	t.Run("should write the document", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			return rack.JSONAPI(c, http.StatusOK, &rack.JSONAPIDocument{
+				Data: rack.JSONAPIResource{
+					Type:       "tasks",
+					ID:         "1",
+					Attributes: map[string]string{"title": "title"},
+					Relationships: map[string]rack.JSONAPIRelationship{
+						"owner": {Data: rack.JSONAPIResourceIdentifier{Type: "users", ID: "2"}},
+					},
+				},
+			})
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		act := new(events.APIGatewayV2HTTPResponse)
+		unmarshal(b, act)
+
+		exp := `{"data":{"type":"tasks","id":"1","attributes":{"title":"title"},"relationships":{"owner":{"data":{"type":"users","id":"2"}}}}}`
+		if act.Body != exp {
+			t.Errorf("got %s, expected %s", act.Body, exp)
+		}
+		if ct := act.Headers["Content-Type"]; ct != rack.JSONAPIContentType {
+			t.Errorf("got %s, expected %s", ct, rack.JSONAPIContentType)
+		}
+	})
+}
+
+func TestJSONAPI_Codec(t *testing.T) {
+	t.Run("should encode the document using the configured codec", func(t *testing.T) {
+		h := rack.NewWithConfig(rack.Config{
+			Codec: rack.NewJSONCodec(rack.JSONOptions{Int64AsString: true}),
+		}, func(c rack.Context) error {
+			return rack.JSONAPI(c, http.StatusOK, &rack.JSONAPIDocument{
+				Data: rack.JSONAPIResource{
+					Type:       "tasks",
+					ID:         "1",
+					Attributes: map[string]int64{"count": 2},
+				},
+			})
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		act := new(events.APIGatewayV2HTTPResponse)
+		unmarshal(b, act)
+
+		if exp := `{"data":{"type":"tasks","id":"1","attributes":{"count":"2"}}}`; act.Body != exp {
+			t.Errorf("got %s, expected %s", act.Body, exp)
+		}
+	})
+}
+
+func TestJSONAPIErrorHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		exp    string
+	}{
+		{
+			name:   "should map errors",
+			err:    errors.New("error"),
+			status: http.StatusInternalServerError,
+			exp:    `{"errors":[{"status":"500","title":"Internal Server Error","detail":"error"}]}`,
+		},
+		{
+			name:   "should map status errors",
+			err:    rack.WrapError(http.StatusNotFound, errors.New("task not found")).WithCode("TASK_NOT_FOUND"),
+			status: http.StatusNotFound,
+			exp:    `{"errors":[{"status":"404","code":"TASK_NOT_FOUND","title":"Not Found","detail":"task not found"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{OnError: rack.JSONAPIErrorHandler}, func(c rack.Context) error {
+				return tt.err
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if act.StatusCode != tt.status {
+				t.Errorf("got %d, expected %d", act.StatusCode, tt.status)
+			}
+			if act.Body != tt.exp {
+				t.Errorf("got %s, expected %s", act.Body, tt.exp)
+			}
+		})
+	}
+}
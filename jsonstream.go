@@ -0,0 +1,59 @@
+package rack
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which buffers are not returned to the pool
+// This prevents a single large response from being retained for the container lifetime.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// JSONStream writes the elements yielded by the iterator to the response as a JSON array
+// Elements are encoded incrementally into a pooled buffer using the configured
+// codec. Iteration stops if yield returns false. The array is buffered in full before
+// it is written, as Lambda responses are not streamed. Entity tags and sparse fields
+// are not applied, so c.JSON should be used if either is required.
+func JSONStream(c Context, code int, iter func(yield func(v interface{}) bool)) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			buf.Reset()
+			bufferPool.Put(buf)
+		}
+	}()
+
+	codec := contextCodec(c)
+
+	var err error
+	buf.WriteByte('[')
+
+	iter(func(v interface{}) bool {
+		var b []byte
+		if b, err = codec.Marshal(v); err != nil {
+			return false
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+
+		buf.Write(b)
+		return true
+	})
+
+	if err != nil {
+		return err
+	}
+
+	buf.WriteByte(']')
+
+	_, err = blob(c, code, "application/json", buf.Bytes())
+	return err
+}
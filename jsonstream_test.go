@@ -0,0 +1,83 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestJSONStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		items  []interface{}
+		limit  int
+		status int
+		exp    string
+	}{
+		{
+			name:   "should return an error if an element cannot be encoded",
+			items:  []interface{}{1, make(chan int)},
+			limit:  2,
+			status: http.StatusInternalServerError,
+			exp:    `{"message":"json: unsupported type: chan int"}`,
+		},
+		{
+			name:   "should write empty arrays",
+			status: http.StatusOK,
+			exp:    `[]`,
+		},
+		{
+			name:   "should write the yielded elements",
+			items:  []interface{}{1, "a", map[string]int{"b": 2}},
+			limit:  3,
+			status: http.StatusOK,
+			exp:    `[1,"a",{"b":2}]`,
+		},
+		{
+			name:   "should write partial iterations",
+			items:  []interface{}{1, 2, 3},
+			limit:  2,
+			status: http.StatusOK,
+			exp:    `[1,2]`,
+		},
+		{
+			name:   "should write elements that exceed the pooled buffer size",
+			items:  []interface{}{strings.Repeat("a", 70<<10)},
+			limit:  1,
+			status: http.StatusOK,
+			exp:    `["` + strings.Repeat("a", 70<<10) + `"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				return rack.JSONStream(c, http.StatusOK, func(yield func(interface{}) bool) {
+					for i, v := range tt.items {
+						if i >= tt.limit || !yield(v) {
+							return
+						}
+					}
+				})
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if act.StatusCode != tt.status {
+				t.Errorf("got %d, expected %d", act.StatusCode, tt.status)
+			}
+			if act.Body != tt.exp {
+				t.Errorf("got %s, expected %s", act.Body, tt.exp)
+			}
+		})
+	}
+}
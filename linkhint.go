@@ -0,0 +1,95 @@
+package rack
+
+import (
+	"mime"
+	"strings"
+)
+
+// LinkHint represents a Link header resource hint
+type LinkHint struct {
+	URL         string
+	Rel         string
+	As          string
+	Type        string
+	CrossOrigin string
+}
+
+// Preload returns a preload hint for the specified url and destination, such as style or script
+// Fonts are always fetched in anonymous mode, so font hints include the crossorigin
+// attribute.
+func Preload(url, as string) LinkHint {
+	h := LinkHint{URL: url, Rel: "preload", As: as}
+	if as == "font" {
+		h.CrossOrigin = "anonymous"
+	}
+
+	return h
+}
+
+// Preconnect returns a preconnect hint for the specified origin
+func Preconnect(origin string) LinkHint {
+	return LinkHint{URL: origin, Rel: "preconnect"}
+}
+
+// String returns the Link header value for the hint
+func (h LinkHint) String() string {
+	var sb strings.Builder
+	sb.WriteString("<" + h.URL + ">; rel=" + h.Rel)
+
+	if h.As != "" {
+		sb.WriteString("; as=" + h.As)
+	}
+
+	if h.Type != "" {
+		sb.WriteString("; type=\"" + h.Type + "\"")
+	}
+
+	switch h.CrossOrigin {
+	case "":
+	case "anonymous":
+		sb.WriteString("; crossorigin")
+	default:
+		sb.WriteString("; crossorigin=" + h.CrossOrigin)
+	}
+
+	return sb.String()
+}
+
+// AddLinkHints adds the hints to the response Link header
+// Hints are appended to a single comma separated header value, as processors only
+// write the first value of each header to single value header maps.
+func AddLinkHints(c Context, hints ...LinkHint) {
+	if len(hints) == 0 {
+		return
+	}
+
+	vs := make([]string, 0, len(hints)+1)
+	if v := c.Response().Headers.Get("Link"); v != "" {
+		vs = append(vs, v)
+	}
+
+	for _, h := range hints {
+		vs = append(vs, h.String())
+	}
+
+	c.Response().Headers.Set("Link", strings.Join(vs, ", "))
+}
+
+// WithLinkHints returns a middleware func that adds the hints to html responses
+// Lambda cannot send 103 Early Hints responses, but browsers act on Link headers
+// in the final response as soon as the headers are received.
+func WithLinkHints(hints ...LinkHint) MiddlewareFunc {
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			if err := n(c); err != nil {
+				return err
+			}
+
+			if mt, _, _ := mime.ParseMediaType(c.Response().Headers.Get("Content-Type")); mt == "text/html" {
+				AddLinkHints(c, hints...)
+			}
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestLinkHint_String(t *testing.T) {
+	tests := []struct {
+		name string
+		hint rack.LinkHint
+		exp  string
+	}{
+		{
+			name: "should format preload hints",
+			hint: rack.Preload("/app.css", "style"),
+			exp:  "</app.css>; rel=preload; as=style",
+		},
+		{
+			name: "should format font preload hints",
+			hint: rack.Preload("/font.woff2", "font"),
+			exp:  "</font.woff2>; rel=preload; as=font; crossorigin",
+		},
+		{
+			name: "should format preconnect hints",
+			hint: rack.Preconnect("https://cdn.example.com"),
+			exp:  "<https://cdn.example.com>; rel=preconnect",
+		},
+		{
+			name: "should format all attributes",
+			hint: rack.LinkHint{
+				URL:         "/data.json",
+				Rel:         "preload",
+				As:          "fetch",
+				Type:        "application/json",
+				CrossOrigin: "use-credentials",
+			},
+			exp: `</data.json>; rel=preload; as=fetch; type="application/json"; crossorigin=use-credentials`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertDeepEqual(t, tt.hint.String(), tt.exp)
+		})
+	}
+}
+
+func TestWithLinkHints(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		link        string
+		exp         string
+	}{
+		{
+			name:        "should add hints to html responses",
+			contentType: "text/html; charset=utf-8",
+			exp:         "</app.css>; rel=preload; as=style, <https://cdn.example.com>; rel=preconnect",
+		},
+		{
+			name:        "should append hints to existing links",
+			contentType: "text/html",
+			link:        "</next>; rel=next",
+			exp:         "</next>; rel=next, </app.css>; rel=preload; as=style, <https://cdn.example.com>; rel=preconnect",
+		},
+		{
+			name:        "should not add hints to other responses",
+			contentType: "application/json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.WithLinkHints(
+					rack.Preload("/app.css", "style"),
+					rack.Preconnect("https://cdn.example.com"),
+				),
+			}, func(c rack.Context) error {
+				if tt.link != "" {
+					c.Response().Headers.Set("Link", tt.link)
+				}
+				return rack.Blob(c, http.StatusOK, tt.contentType, []byte("body"))
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			assertDeepEqual(t, res.Headers["Link"], tt.exp)
+		})
+	}
+}
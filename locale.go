@@ -0,0 +1,103 @@
+package rack
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Catalog represents a localized message catalog
+	Catalog interface {
+		// Message returns the message for the specified locale and key
+		// Keys are either error codes or the original error message. False is
+		// returned if no message exists for the locale.
+		Message(locale, key string) (string, bool)
+	}
+
+	// MapCatalog is a map based catalog, keyed by locale then message key
+	MapCatalog map[string]map[string]string
+)
+
+// Message returns the message for the specified locale and key
+func (m MapCatalog) Message(locale, key string) (string, bool) {
+	msgs, ok := m[locale]
+	if !ok {
+		return "", false
+	}
+
+	msg, ok := msgs[key]
+	return msg, ok
+}
+
+// Locales returns the locales accepted by the request in order of preference
+// Locales are read from the Accept-Language header. Wildcards and zero quality
+// values are excluded.
+func Locales(c Context) []string {
+	type tag struct {
+		locale  string
+		quality float64
+	}
+
+	var tags []tag
+	for _, h := range c.Request().Header.Values("Accept-Language") {
+		for _, p := range strings.Split(h, ",") {
+			ps := strings.Split(strings.TrimSpace(p), ";")
+			t := tag{locale: strings.TrimSpace(ps[0]), quality: 1}
+
+			for _, pp := range ps[1:] {
+				pp = strings.TrimSpace(pp)
+				if strings.HasPrefix(pp, "q=") {
+					if q, err := strconv.ParseFloat(pp[2:], 64); err == nil {
+						t.quality = q
+					}
+				}
+			}
+
+			if t.locale == "" || t.locale == "*" || t.quality <= 0 {
+				continue
+			}
+
+			tags = append(tags, t)
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].quality > tags[j].quality
+	})
+
+	ls := make([]string, len(tags))
+	for i, t := range tags {
+		ls[i] = t.locale
+	}
+
+	return ls
+}
+
+func localize(c Context, cat Catalog, code, msg string) string {
+	if cat == nil {
+		return msg
+	}
+
+	for _, l := range Locales(c) {
+		// fall back to the base language for regional tags, e.g. en-GB > en
+		ls := []string{l}
+		if i := strings.IndexByte(l, '-'); i > 0 {
+			ls = append(ls, l[:i])
+		}
+
+		for _, ll := range ls {
+			if code != "" {
+				if m, ok := cat.Message(ll, code); ok {
+					return m
+				}
+			}
+
+			if m, ok := cat.Message(ll, msg); ok {
+				return m
+			}
+		}
+	}
+
+	return msg
+}
@@ -0,0 +1,108 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestLocales(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		exp    []string
+	}{
+		{
+			name: "should return empty if the header does not exist",
+			exp:  []string{},
+		},
+		{
+			name:   "should return the locales in order of preference",
+			header: "fr;q=0.5, en-GB, de;q=0.8, *;q=0.1, es;q=0",
+			exp:    []string{"en-GB", "de", "fr"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				if tt.header != "" {
+					r.Headers = map[string]string{"Accept-Language": tt.header}
+				}
+			})
+
+			h := rack.New(func(c rack.Context) error {
+				assertDeepEqual(t, rack.Locales(c), tt.exp)
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), p)
+			assertErrorExists(t, err, false)
+		})
+	}
+}
+
+func TestConfig_ErrorCatalog(t *testing.T) {
+	cat := rack.MapCatalog{
+		"de": {"not found": "nicht gefunden"},
+		"es": {"NOT_FOUND": "no encontrado"},
+		"fr": {"not found": "introuvable"},
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		code   string
+		exp    string
+	}{
+		{
+			name: "should return the original message if no locale matches",
+			exp:  `{"message":"not found"}`,
+		},
+		{
+			name:   "should return the localized message",
+			header: "it, fr;q=0.9",
+			exp:    `{"message":"introuvable"}`,
+		},
+		{
+			name:   "should fall back to the base language",
+			header: "de-AT",
+			exp:    `{"message":"nicht gefunden"}`,
+		},
+		{
+			name:   "should prefer the error code",
+			header: "es",
+			code:   "NOT_FOUND",
+			exp:    `{"code":"NOT_FOUND","message":"no encontrado"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				if tt.header != "" {
+					r.Headers = map[string]string{"Accept-Language": tt.header}
+				}
+			})
+
+			h := rack.NewWithConfig(rack.Config{ErrorCatalog: cat}, func(c rack.Context) error {
+				return rack.WrapError(http.StatusNotFound, errors.New("not found")).WithCode(tt.code)
+			})
+
+			b, err := h.Invoke(context.Background(), p)
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if act.Body != tt.exp {
+				t.Errorf("got %s, expected %s", act.Body, tt.exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package rack
+
+import (
+	"fmt"
+	"runtime/metrics"
+	"time"
+)
+
+// Metrics represents a metrics client
+// Implementations would typically wrap a StatsD or CloudWatch EMF client.
+type Metrics interface {
+	// Count records a counter value
+	Count(name string, value int64, tags map[string]string)
+
+	// Timing records a duration
+	Timing(name string, d time.Duration, tags map[string]string)
+}
+
+// Resolver metric names
+const (
+	MetricResolveDuration = "rack.resolve.duration"
+	MetricResolveAllocs   = "rack.resolve.allocs"
+)
+
+const heapAllocsMetric = "/gc/heap/allocs:objects"
+
+// InstrumentResolver returns a resolver that reports detection cost to the specified metrics
+// The detection duration and heap allocation count are recorded with a processor tag,
+// which is set to unsupported if no processor matches. Allocations are read from
+// process-wide runtime metrics, so concurrent allocations are included.
+func InstrumentResolver(r Resolver, m Metrics) Resolver {
+	return ResolverFunc(func(payload []byte) (Processor, error) {
+		s := []metrics.Sample{{Name: heapAllocsMetric}}
+
+		metrics.Read(s)
+		allocs := s[0].Value.Uint64()
+		st := time.Now()
+
+		p, err := r.Resolve(payload)
+
+		d := time.Since(st)
+		metrics.Read(s)
+
+		tags := map[string]string{"processor": processorName(p)}
+		m.Timing(MetricResolveDuration, d, tags)
+		m.Count(MetricResolveAllocs, int64(s[0].Value.Uint64()-allocs), tags)
+
+		return p, err
+	})
+}
+
+// DetectEventType returns the name of the built-in processor for the specified payload
+// An empty string is returned if the payload is not supported. The func allows
+// captured payloads to be verified against the default resolver.
+func DetectEventType(payload []byte) string {
+	p, err := defaultResolver.Resolve(payload)
+	if err != nil {
+		return ""
+	}
+
+	return processorName(p)
+}
+
+func processorName(p Processor) string {
+	switch t := p.(type) {
+	case nil:
+		return "unsupported"
+	case fmt.Stringer:
+		return t.String()
+	}
+
+	return fmt.Sprintf("%T", p)
+}
@@ -0,0 +1,118 @@
+package rack_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+type testMetrics struct {
+	counts  map[string]int64
+	timings map[string]time.Duration
+	tags    map[string]string
+}
+
+func newTestMetrics() *testMetrics {
+	return &testMetrics{
+		counts:  map[string]int64{},
+		timings: map[string]time.Duration{},
+	}
+}
+
+func (m *testMetrics) Count(name string, value int64, tags map[string]string) {
+	m.counts[name] += value
+	m.tags = tags
+}
+
+func (m *testMetrics) Timing(name string, d time.Duration, tags map[string]string) {
+	m.timings[name] += d
+	m.tags = tags
+}
+
+func TestInstrumentResolver(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     string
+		err     bool
+	}{
+		{
+			name:    "should report unsupported payloads",
+			payload: []byte(`{}`),
+			exp:     "unsupported",
+			err:     true,
+		},
+		{
+			name:    "should report the resolved processor",
+			payload: newV2Request(nil),
+			exp:     "apigw-v2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMetrics()
+			sut := rack.InstrumentResolver(rack.ResolveConditional(
+				rack.APIGatewayV2HTTPEventProcessor,
+			), m)
+
+			_, err := sut.Resolve(tt.payload)
+			assertErrorExists(t, err, tt.err)
+
+			if _, ok := m.timings[rack.MetricResolveDuration]; !ok {
+				t.Error("got false, expected true")
+			}
+			if _, ok := m.counts[rack.MetricResolveAllocs]; !ok {
+				t.Error("got false, expected true")
+			}
+			if act := m.tags["processor"]; act != tt.exp {
+				t.Errorf("got %s, expected %s", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestDetectEventType(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     string
+	}{
+		{
+			name:    "should return an empty string for unsupported payloads",
+			payload: []byte(`{}`),
+		},
+		{
+			name: "should detect api gateway proxy events",
+			payload: marshal(&events.APIGatewayProxyRequest{
+				RequestContext: events.APIGatewayProxyRequestContext{APIID: "apiid"},
+			}),
+			exp: "apigw-v1",
+		},
+		{
+			name:    "should detect api gateway v2 events",
+			payload: newV2Request(nil),
+			exp:     "apigw-v2",
+		},
+		{
+			name: "should detect alb events",
+			payload: marshal(&events.ALBTargetGroupRequest{
+				RequestContext: events.ALBTargetGroupRequestContext{
+					ELB: events.ELBContext{TargetGroupArn: "arn"},
+				},
+			}),
+			exp: "alb",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if act := rack.DetectEventType(tt.payload); act != tt.exp {
+				t.Errorf("got %s, expected %s", act, tt.exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package rack
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/tidwall/gjson"
+)
+
+// Mux represents a lambda handler that routes events by event family
+type Mux struct {
+	http     lambda.Handler
+	sqs      lambda.Handler
+	schedule lambda.Handler
+}
+
+// NewMux returns a new event mux
+// HTTP events are detected using the default resolver, so registered processors
+// are supported.
+func NewMux() *Mux {
+	return new(Mux)
+}
+
+// HTTP sets the handler for api gateway, alb and authorizer events
+func (m *Mux) HTTP(h lambda.Handler) *Mux {
+	m.http = h
+	return m
+}
+
+// SQS sets the handler for sqs events
+func (m *Mux) SQS(h lambda.Handler) *Mux {
+	m.sqs = h
+	return m
+}
+
+// Schedule sets the handler for eventbridge scheduled events
+func (m *Mux) Schedule(h lambda.Handler) *Mux {
+	m.schedule = h
+	return m
+}
+
+// Invoke invokes the handler registered for the payload event family
+// ErrUnsupportedEventType is returned if no handler has been registered for the
+// event family.
+func (m *Mux) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
+	if h := m.handler(payload); h != nil {
+		return h.Invoke(ctx, payload)
+	}
+
+	return nil, ErrUnsupportedEventType
+}
+
+func (m *Mux) handler(payload []byte) lambda.Handler {
+	pv := gjson.GetManyBytes(payload, "Records.0.eventSource", "source", "detail-type")
+
+	switch {
+	case pv[0].String() == "aws:sqs":
+		return m.sqs
+	case pv[1].String() == "aws.events" && pv[2].String() == "Scheduled Event":
+		return m.schedule
+	}
+
+	if _, err := defaultResolver.Resolve(payload); err == nil {
+		return m.http
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package rack_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestMux_Invoke(t *testing.T) {
+	handler := func(name string) lambda.Handler {
+		return lambda.NewHandler(func(context.Context, interface{}) (string, error) {
+			return name, nil
+		})
+	}
+
+	sqsEvent := marshal(events.SQSEvent{Records: []events.SQSMessage{{EventSource: "aws:sqs"}}})
+	scheduleEvent := []byte(`{"source":"aws.events","detail-type":"Scheduled Event"}`)
+
+	tests := []struct {
+		name    string
+		mux     *rack.Mux
+		payload []byte
+		exp     []byte
+		err     bool
+	}{
+		{
+			name:    "should route http events",
+			mux:     rack.NewMux().HTTP(handler("http")).SQS(handler("sqs")).Schedule(handler("schedule")),
+			payload: newV2Request(func(*events.APIGatewayV2HTTPRequest) {}),
+			exp:     []byte(`"http"`),
+		},
+		{
+			name:    "should route sqs events",
+			mux:     rack.NewMux().HTTP(handler("http")).SQS(handler("sqs")).Schedule(handler("schedule")),
+			payload: sqsEvent,
+			exp:     []byte(`"sqs"`),
+		},
+		{
+			name:    "should route scheduled events",
+			mux:     rack.NewMux().HTTP(handler("http")).SQS(handler("sqs")).Schedule(handler("schedule")),
+			payload: scheduleEvent,
+			exp:     []byte(`"schedule"`),
+		},
+		{
+			name:    "should return an error if the handler is not registered",
+			mux:     rack.NewMux().HTTP(handler("http")),
+			payload: sqsEvent,
+			err:     true,
+		},
+		{
+			name:    "should return an error for unknown events",
+			mux:     rack.NewMux().HTTP(handler("http")).SQS(handler("sqs")).Schedule(handler("schedule")),
+			payload: []byte(`{"key":"value"}`),
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := tt.mux.Invoke(context.Background(), tt.payload)
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
@@ -0,0 +1,567 @@
+package rack
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MergePatchContentType is the JSON merge patch media type
+	MergePatchContentType = "application/merge-patch+json"
+
+	// JSONPatchContentType is the JSON patch media type
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+var (
+	// ErrPatchConflict indicates that a patch could not be applied to the resource
+	ErrPatchConflict = errors.New("patch conflict")
+
+	errInvalidPatch = errors.New("invalid patch")
+)
+
+type (
+	patchOperation struct {
+		Op    string     `json:"op"`
+		Path  *string    `json:"path"`
+		From  *string    `json:"from"`
+		Value patchValue `json:"value"`
+	}
+
+	// patchValue represents an operation value
+	// Presence is recorded separately, as null is a valid value.
+	patchValue struct {
+		raw json.RawMessage
+		set bool
+	}
+)
+
+// UnmarshalJSON records the raw value, including null values
+func (v *patchValue) UnmarshalJSON(b []byte) error {
+	v.raw = append(v.raw[:0], b...)
+	v.set = true
+	return nil
+}
+
+// BindPatch applies the request body as a patch to the specified value
+// The value must contain the existing resource. JSON patch bodies are applied
+// if the content type is application/json-patch+json, otherwise the body is
+// applied as a JSON merge patch. The post-bind operation is invoked with the result.
+// Bind limits and the post-bind operation are not applied for other Context
+// implementations.
+func BindPatch(c Context, v interface{}) error {
+	b, err := requestBody(c.Request())
+	if err != nil || len(b) == 0 {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("rack: bind patch requires a non-nil pointer, got %T", v)
+	}
+
+	hc, ok := c.(*handlerContext)
+	if ok {
+		if err = hc.checkBindLimits(b); err != nil {
+			return err
+		}
+	}
+
+	orig, err := marshalPatchDocument(v)
+	if err != nil {
+		return err
+	}
+
+	// the original document is retained to determine the removed members
+	doc := copyPatchValue(orig)
+
+	mt, _, _ := mime.ParseMediaType(c.Request().Header.Get("Content-Type"))
+	switch mt {
+	case JSONPatchContentType:
+		doc, err = applyJSONPatch(doc, b)
+	case MergePatchContentType, "application/json", "":
+		doc, err = applyMergePatch(doc, b)
+	default:
+		return WrapError(http.StatusUnsupportedMediaType, fmt.Errorf("unsupported patch media type %q", mt))
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrPatchConflict) {
+			return WrapError(http.StatusConflict, err)
+		}
+
+		return WrapError(http.StatusBadRequest, err)
+	}
+
+	if b, err = json.Marshal(doc); err != nil {
+		return err
+	}
+
+	// the document is decoded into the existing value so that fields that are not
+	// encoded retain their values, with removed members cleared beforehand
+	clearRemovedMembers(rv, orig, doc)
+
+	if err = contextCodec(c).Unmarshal(b, v); err != nil {
+		return WrapError(http.StatusBadRequest, err)
+	}
+
+	if !ok {
+		return nil
+	}
+
+	return hc.onBind(c, v)
+}
+
+// clearRemovedMembers zeroes the struct fields and map entries that were removed by the patch
+// Members that were replaced with null are also cleared, as decoding null into a
+// struct or scalar value leaves it unchanged.
+func clearRemovedMembers(v reflect.Value, prev, next interface{}) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		pm, ok := prev.(map[string]interface{})
+		if !ok {
+			return
+		}
+		nm, _ := next.(map[string]interface{})
+
+		fields := structFields(v.Type())
+		for k, pv := range pm {
+			f, ok := matchField(fields, k)
+			if !ok {
+				continue
+			}
+
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok || !fv.CanSet() {
+				continue
+			}
+
+			if nv, ok := nm[k]; ok && nv != nil {
+				clearRemovedMembers(fv, pv, nv)
+			} else {
+				fv.Set(reflect.Zero(fv.Type()))
+			}
+		}
+	case reflect.Map:
+		nm, _ := next.(map[string]interface{})
+
+		// map elements are replaced when decoded, so only removed keys are cleared
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := mapKey(iter.Key())
+			if err != nil {
+				continue
+			}
+
+			if nv, ok := nm[k]; !ok || nv == nil {
+				v.SetMapIndex(iter.Key(), reflect.Value{})
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		pa, _ := prev.([]interface{})
+		na, _ := next.([]interface{})
+
+		// existing elements are reused when decoded
+		for i := 0; i < v.Len() && i < len(pa) && i < len(na); i++ {
+			clearRemovedMembers(v.Index(i), pa[i], na[i])
+		}
+	}
+}
+
+func marshalPatchDocument(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodePatchValue(b)
+}
+
+func decodePatchValue(b []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// applyMergePatch applies the RFC 7386 merge patch to the document
+func applyMergePatch(doc interface{}, b []byte) (interface{}, error) {
+	p, err := decodePatchValue(b)
+	if err != nil {
+		return nil, newBindError(b, err)
+	}
+
+	return mergePatch(doc, p), nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+
+	return t
+}
+
+// applyJSONPatch applies the RFC 6902 json patch to the document
+// Operations are applied in order, and the document is unchanged if any operation fails.
+func applyJSONPatch(doc interface{}, b []byte) (interface{}, error) {
+	var ops []patchOperation
+	if err := json.Unmarshal(b, &ops); err != nil {
+		return nil, newBindError(b, err)
+	}
+
+	for i, op := range ops {
+		var err error
+		if doc, err = applyPatchOperation(doc, op); err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func applyPatchOperation(doc interface{}, op patchOperation) (interface{}, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: missing path", errInvalidPatch)
+	}
+
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch op.Op {
+	case "add", "replace", "test":
+		if !op.Value.set {
+			return nil, fmt.Errorf("%w: missing value", errInvalidPatch)
+		}
+
+		if value, err = decodePatchValue(op.Value.raw); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: missing from", errInvalidPatch)
+		}
+
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" && len(from) < len(path) && isPointerPrefix(from, path) {
+			return nil, fmt.Errorf("%w: cannot move %s into its child %s", errInvalidPatch, *op.From, *op.Path)
+		}
+
+		if value, err = getPointer(doc, from); err != nil {
+			return nil, err
+		}
+
+		if op.Op == "move" {
+			if doc, err = removePointer(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = copyPatchValue(value)
+		}
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return addPointer(doc, path, value)
+	case "remove":
+		return removePointer(doc, path)
+	case "replace":
+		if _, err = getPointer(doc, path); err != nil {
+			return nil, err
+		}
+
+		if doc, err = removePointer(doc, path); err != nil {
+			return nil, err
+		}
+
+		return addPointer(doc, path, value)
+	case "test":
+		act, err := getPointer(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !patchValueEqual(act, value) {
+			return nil, fmt.Errorf("%w: test failed for %s", ErrPatchConflict, *op.Path)
+		}
+
+		return doc, nil
+	}
+
+	return nil, fmt.Errorf("%w: unsupported operation %q", errInvalidPatch, op.Op)
+}
+
+// parsePointer parses the RFC 6901 json pointer into reference tokens
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+
+	if p[0] != '/' {
+		return nil, fmt.Errorf("%w: invalid pointer %q", errInvalidPatch, p)
+	}
+
+	ts := strings.Split(p[1:], "/")
+	for i, t := range ts {
+		ts[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return ts, nil
+}
+
+func isPointerPrefix(prefix, p []string) bool {
+	for i := range prefix {
+		if prefix[i] != p[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func getPointer(doc interface{}, p []string) (interface{}, error) {
+	for _, t := range p {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			v, ok := d[t]
+			if !ok {
+				return nil, pointerNotFound(p)
+			}
+
+			doc = v
+		case []interface{}:
+			i, err := pointerIndex(t, len(d)-1)
+			if err != nil {
+				return nil, pointerNotFound(p)
+			}
+
+			doc = d[i]
+		default:
+			return nil, pointerNotFound(p)
+		}
+	}
+
+	return doc, nil
+}
+
+func addPointer(doc interface{}, p []string, v interface{}) (interface{}, error) {
+	if len(p) == 0 {
+		return v, nil
+	}
+
+	return updatePointer(doc, p, p, func(parent interface{}, t string) (interface{}, error) {
+		switch d := parent.(type) {
+		case map[string]interface{}:
+			d[t] = v
+			return d, nil
+		case []interface{}:
+			if t == "-" {
+				return append(d, v), nil
+			}
+
+			i, err := pointerIndex(t, len(d))
+			if err != nil {
+				return nil, pointerNotFound(p)
+			}
+
+			d = append(d, nil)
+			copy(d[i+1:], d[i:])
+			d[i] = v
+			return d, nil
+		}
+
+		return nil, pointerNotFound(p)
+	})
+}
+
+func removePointer(doc interface{}, p []string) (interface{}, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+
+	return updatePointer(doc, p, p, func(parent interface{}, t string) (interface{}, error) {
+		switch d := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := d[t]; !ok {
+				return nil, pointerNotFound(p)
+			}
+
+			delete(d, t)
+			return d, nil
+		case []interface{}:
+			i, err := pointerIndex(t, len(d)-1)
+			if err != nil {
+				return nil, pointerNotFound(p)
+			}
+
+			return append(d[:i], d[i+1:]...), nil
+		}
+
+		return nil, pointerNotFound(p)
+	})
+}
+
+// updatePointer applies fn to the parent of the final token, returning the updated document
+// Array modifications can change the slice header, so each parent is reassigned.
+func updatePointer(doc interface{}, p, rem []string, fn func(interface{}, string) (interface{}, error)) (interface{}, error) {
+	if len(rem) == 1 {
+		return fn(doc, rem[0])
+	}
+
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		c, ok := d[rem[0]]
+		if !ok {
+			return nil, pointerNotFound(p)
+		}
+
+		v, err := updatePointer(c, p, rem[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		d[rem[0]] = v
+		return d, nil
+	case []interface{}:
+		i, err := pointerIndex(rem[0], len(d)-1)
+		if err != nil {
+			return nil, pointerNotFound(p)
+		}
+
+		v, err := updatePointer(d[i], p, rem[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		d[i] = v
+		return d, nil
+	}
+
+	return nil, pointerNotFound(p)
+}
+
+func pointerIndex(t string, max int) (int, error) {
+	if t == "" || (len(t) > 1 && t[0] == '0') {
+		return 0, errInvalidPatch
+	}
+
+	i, err := strconv.Atoi(t)
+	if err != nil || i < 0 || i > max {
+		return 0, errInvalidPatch
+	}
+
+	return i, nil
+}
+
+func pointerNotFound(p []string) error {
+	ts := make([]string, len(p))
+	for i, t := range p {
+		ts[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1")
+	}
+
+	return fmt.Errorf("%w: path /%s does not exist", ErrPatchConflict, strings.Join(ts, "/"))
+}
+
+func copyPatchValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, vv := range t {
+			m[k] = copyPatchValue(vv)
+		}
+
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, vv := range t {
+			s[i] = copyPatchValue(vv)
+		}
+
+		return s
+	}
+
+	return v
+}
+
+func patchValueEqual(a, b interface{}) bool {
+	switch at := a.(type) {
+	case json.Number:
+		bt, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		af, aerr := at.Float64()
+		bf, berr := bt.Float64()
+		return aerr == nil && berr == nil && af == bf
+	case map[string]interface{}:
+		bt, ok := b.(map[string]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+
+		for k, av := range at {
+			bv, ok := bt[k]
+			if !ok || !patchValueEqual(av, bv) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		bt, ok := b.([]interface{})
+		if !ok || len(at) != len(bt) {
+			return false
+		}
+
+		for i := range at {
+			if !patchValueEqual(at[i], bt[i]) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return a == b
+}
@@ -0,0 +1,324 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestBindPatch(t *testing.T) {
+	type address struct {
+		City     string `json:"city"`
+		Postcode string `json:"postcode,omitempty"`
+	}
+
+	type resource struct {
+		Name    string   `json:"name"`
+		Count   int      `json:"count"`
+		Tags    []string `json:"tags,omitempty"`
+		Address *address `json:"address,omitempty"`
+	}
+
+	newResource := func() resource {
+		return resource{
+			Name:    "name",
+			Count:   1,
+			Tags:    []string{"a", "b"},
+			Address: &address{City: "London", Postcode: "SW1"},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		exp         resource
+		code        int
+	}{
+		{
+			name: "should do nothing if the body is empty",
+			exp:  newResource(),
+		},
+		{
+			name:        "should apply merge patches",
+			contentType: rack.MergePatchContentType,
+			body:        `{"name":"updated","tags":["c"],"address":{"postcode":null}}`,
+			exp: resource{
+				Name:    "updated",
+				Count:   1,
+				Tags:    []string{"c"},
+				Address: &address{City: "London"},
+			},
+		},
+		{
+			name:        "should apply json content as merge patches",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"count":2,"address":null}`,
+			exp: resource{
+				Name:  "name",
+				Count: 2,
+				Tags:  []string{"a", "b"},
+			},
+		},
+		{
+			name:        "should return an error for invalid merge patches",
+			contentType: rack.MergePatchContentType,
+			body:        `{"name":`,
+			exp:         newResource(),
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "should return an error for invalid merge patch values",
+			contentType: rack.MergePatchContentType,
+			body:        `{"count":"1"}`,
+			exp:         newResource(),
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "should apply json patches",
+			contentType: rack.JSONPatchContentType,
+			body: `[
+				{"op":"test","path":"/count","value":1.0},
+				{"op":"replace","path":"/name","value":"updated"},
+				{"op":"add","path":"/tags/1","value":"c"},
+				{"op":"add","path":"/tags/-","value":"d"},
+				{"op":"remove","path":"/tags/0"},
+				{"op":"copy","from":"/address/city","path":"/tags/0"},
+				{"op":"move","from":"/address/postcode","path":"/address/city"}
+			]`,
+			exp: resource{
+				Name:    "updated",
+				Count:   1,
+				Tags:    []string{"London", "c", "b", "d"},
+				Address: &address{City: "SW1"},
+			},
+		},
+		{
+			name:        "should handle escaped pointers",
+			contentType: rack.JSONPatchContentType,
+			body:        `[{"op":"add","path":"/address/a~1b~0c","value":"v"},{"op":"remove","path":"/address/a~1b~0c"}]`,
+			exp:         newResource(),
+		},
+		{
+			name:        "should return conflict errors for failed tests",
+			contentType: rack.JSONPatchContentType,
+			body:        `[{"op":"replace","path":"/name","value":"updated"},{"op":"test","path":"/count","value":2}]`,
+			exp:         newResource(),
+			code:        http.StatusConflict,
+		},
+		{
+			name:        "should return conflict errors for missing paths",
+			contentType: rack.JSONPatchContentType,
+			body:        `[{"op":"remove","path":"/address/country"}]`,
+			exp:         newResource(),
+			code:        http.StatusConflict,
+		},
+		{
+			name:        "should return conflict errors for out of range indices",
+			contentType: rack.JSONPatchContentType,
+			body:        `[{"op":"add","path":"/tags/3","value":"c"}]`,
+			exp:         newResource(),
+			code:        http.StatusConflict,
+		},
+		{
+			name:        "should return an error for unsupported operations",
+			contentType: rack.JSONPatchContentType,
+			body:        `[{"op":"other","path":"/name"}]`,
+			exp:         newResource(),
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "should apply null values",
+			contentType: rack.JSONPatchContentType,
+			body:        `[{"op":"test","path":"/address/postcode","value":"SW1"},{"op":"replace","path":"/address","value":null},{"op":"test","path":"/address","value":null}]`,
+			exp: resource{
+				Name:  "name",
+				Count: 1,
+				Tags:  []string{"a", "b"},
+			},
+		},
+		{
+			name:        "should return an error for missing values",
+			contentType: rack.JSONPatchContentType,
+			body:        `[{"op":"add","path":"/name"}]`,
+			exp:         newResource(),
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "should return an error for moves into children",
+			contentType: rack.JSONPatchContentType,
+			body:        `[{"op":"move","from":"/address","path":"/address/child"}]`,
+			exp:         newResource(),
+			code:        http.StatusBadRequest,
+		},
+		{
+			name:        "should return an error for unsupported media types",
+			contentType: "text/plain",
+			body:        `name`,
+			exp:         newResource(),
+			code:        http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				act := newResource()
+				err := rack.BindPatch(c, &act)
+
+				if tt.code == 0 {
+					assertErrorExists(t, err, false)
+				} else if code := rack.StatusCode(err); code != tt.code {
+					t.Errorf("got %d, expected %d (%v)", code, tt.code, err)
+				}
+
+				assertDeepEqual(t, act, tt.exp)
+				return nil
+			})
+
+			payload := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"Content-Type": tt.contentType}
+				r.Body = tt.body
+			})
+
+			if _, err := h.Invoke(context.Background(), payload); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	t.Run("should invoke the post-bind operation", func(t *testing.T) {
+		exp := errors.New("error")
+
+		cfg := rack.Config{
+			OnBind: func(c rack.Context, v interface{}) error {
+				if v.(*resource).Name == "invalid" {
+					return exp
+				}
+				return nil
+			},
+		}
+
+		h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+			act := newResource()
+			if err := rack.BindPatch(c, &act); err != exp {
+				t.Errorf("got %v, expected %v", err, exp)
+			}
+			return nil
+		})
+
+		payload := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.Body = `{"name":"invalid"}`
+		})
+
+		if _, err := h.Invoke(context.Background(), payload); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("should retain fields that are not encoded", func(t *testing.T) {
+		type owned struct {
+			Title   string            `json:"title"`
+			Notes   map[string]string `json:"notes"`
+			Owner   string            `json:"-"`
+			Ignore  []int             `json:"-"`
+			version int
+		}
+
+		tests := []struct {
+			name        string
+			contentType string
+			body        string
+			exp         owned
+		}{
+			{
+				name:        "merge patch",
+				contentType: rack.MergePatchContentType,
+				body:        `{"title":"b","notes":{"x":null}}`,
+				exp:         owned{Title: "b", Notes: map[string]string{"y": "2"}, Owner: "owner", Ignore: []int{1}, version: 2},
+			},
+			{
+				name:        "json patch",
+				contentType: rack.JSONPatchContentType,
+				body:        `[{"op":"remove","path":"/title"},{"op":"remove","path":"/notes/y"}]`,
+				exp:         owned{Notes: map[string]string{"x": "1"}, Owner: "owner", Ignore: []int{1}, version: 2},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				h := rack.New(func(c rack.Context) error {
+					act := owned{
+						Title:   "a",
+						Notes:   map[string]string{"x": "1", "y": "2"},
+						Owner:   "owner",
+						Ignore:  []int{1},
+						version: 2,
+					}
+
+					if err := rack.BindPatch(c, &act); err != nil {
+						t.Fatal(err)
+					}
+
+					assertDeepEqual(t, act, tt.exp)
+					return nil
+				})
+
+				payload := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+					r.Headers = map[string]string{"Content-Type": tt.contentType}
+					r.Body = tt.body
+				})
+
+				if _, err := h.Invoke(context.Background(), payload); err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	})
+
+	t.Run("should decode the patched document using the configured codec", func(t *testing.T) {
+		var calls int
+		cfg := rack.Config{
+			Codec: &testCodec{
+				Codec: rack.DefaultJSONCodec,
+				onUnmarshal: func() {
+					calls++
+				},
+			},
+		}
+
+		h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+			act := newResource()
+			if err := rack.BindPatch(c, &act); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		})
+
+		payload := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.Body = `{"name":"updated"}`
+		})
+
+		if _, err := h.Invoke(context.Background(), payload); err != nil {
+			t.Fatal(err)
+		}
+
+		if calls != 1 {
+			t.Errorf("got %d, expected 1", calls)
+		}
+	})
+}
+
+type testCodec struct {
+	rack.Codec
+	onUnmarshal func()
+}
+
+func (c *testCodec) Unmarshal(b []byte, v interface{}) error {
+	c.onUnmarshal()
+	return c.Codec.Unmarshal(b, v)
+}
@@ -0,0 +1,100 @@
+package rack
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Precompile eagerly initializes the state used to serve requests for the config
+// Synthetic events for each built-in event type are resolved using the configured
+// resolver, or the default resolver including registered processors, and run against
+// the resolved processor. The configured codec is then used to encode and decode a
+// value, populating the encoding/json type caches. Calling the func from init with
+// the handler config allows SnapStart snapshots and provisioned concurrency
+// containers to serve the first request without the associated latency.
+func Precompile(c Config) error {
+	resolver := c.Resolver
+	if resolver == nil {
+		resolver = defaultResolver
+	}
+
+	codec := c.Codec
+	if codec == nil {
+		codec = DefaultJSONCodec
+	}
+
+	var payloads [][]byte
+
+	for _, e := range []interface{}{
+		&events.APIGatewayCustomAuthorizerRequest{
+			Type:      "TOKEN",
+			MethodArn: "precompile",
+		},
+		&APIGatewayV2AuthorizerRequest{
+			APIGatewayV2HTTPRequest: events.APIGatewayV2HTTPRequest{
+				Version:        "2.0",
+				RequestContext: events.APIGatewayV2HTTPRequestContext{APIID: "precompile"},
+			},
+			Type:     "REQUEST",
+			RouteArn: "precompile",
+		},
+		&events.APIGatewayProxyRequest{
+			RequestContext: events.APIGatewayProxyRequestContext{APIID: "precompile"},
+		},
+		&events.APIGatewayV2HTTPRequest{
+			Version:        "2.0",
+			RequestContext: events.APIGatewayV2HTTPRequestContext{APIID: "precompile"},
+		},
+		&events.ALBTargetGroupRequest{
+			RequestContext: events.ALBTargetGroupRequestContext{
+				ELB: events.ELBContext{TargetGroupArn: "precompile"},
+			},
+		},
+	} {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		payloads = append(payloads, b)
+	}
+
+	res := &Response{
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": []string{"application/json"}},
+		Body:       "{}",
+	}
+
+	for _, p := range payloads {
+		pp, err := resolver.Resolve(p)
+		if errors.Is(err, ErrUnsupportedEventType) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// static and fallback processors are not run against other event types
+		if !pp.CanProcess(p) {
+			continue
+		}
+
+		if _, err = pp.UnmarshalRequest(p); err != nil {
+			return err
+		}
+
+		if _, err = pp.MarshalResponse(res); err != nil {
+			return err
+		}
+	}
+
+	b, err := codec.Marshal(map[string]interface{}{"precompile": true})
+	if err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(b, new(map[string]interface{}))
+}
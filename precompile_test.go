@@ -0,0 +1,53 @@
+package rack_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestPrecompile(t *testing.T) {
+	t.Run("should initialize the processors", func(t *testing.T) {
+		err := rack.Precompile(rack.Config{})
+		assertErrorExists(t, err, false)
+	})
+
+	t.Run("should use the configured resolver and codec", func(t *testing.T) {
+		var resolves, unmarshals int
+
+		err := rack.Precompile(rack.Config{
+			Resolver: rack.ResolverFunc(func(b []byte) (rack.Processor, error) {
+				resolves++
+				if resolves > 1 {
+					return nil, rack.ErrUnsupportedEventType
+				}
+				return rack.APIGatewayCustomAuthorizerEventProcessor, nil
+			}),
+			Codec: &testCodec{
+				Codec:       rack.DefaultJSONCodec,
+				onUnmarshal: func() { unmarshals++ },
+			},
+		})
+		assertErrorExists(t, err, false)
+
+		assertDeepEqual(t, resolves, 5)
+		assertDeepEqual(t, unmarshals, 1)
+	})
+
+	t.Run("should skip payloads that the resolved processor cannot process", func(t *testing.T) {
+		err := rack.Precompile(rack.Config{
+			Resolver: rack.ResolveStatic(&testProcessor{canProcess: false}),
+		})
+		assertErrorExists(t, err, false)
+	})
+
+	t.Run("should return resolver errors", func(t *testing.T) {
+		err := rack.Precompile(rack.Config{
+			Resolver: rack.ResolverFunc(func([]byte) (rack.Processor, error) {
+				return nil, errors.New("error")
+			}),
+		})
+		assertErrorExists(t, err, true)
+	})
+}
@@ -0,0 +1,203 @@
+// Package presign provides presigned S3 upload and download url helpers
+package presign
+
+import (
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stevecallear/rack"
+)
+
+type (
+	// Signer represents an S3 request presigner
+	// Implementations would typically wrap the AWS SDK s3.PresignClient, using
+	// PresignPutObject for PUT requests and PresignGetObject for GET requests, and
+	// return the presigned url.
+	Signer interface {
+		Presign(ctx context.Context, r *Request) (string, error)
+	}
+
+	// SignerFunc represents a signer func
+	SignerFunc func(ctx context.Context, r *Request) (string, error)
+
+	// Request represents an S3 request to be presigned
+	// The content type and length are specified for uploads, and must be included
+	// in the signature. The content disposition is specified for attachment downloads.
+	Request struct {
+		Method             string
+		Bucket             string
+		Key                string
+		ContentType        string
+		ContentLength      int64
+		ContentDisposition string
+		Expires            time.Duration
+	}
+
+	// Options represents presigner options
+	Options struct {
+		// Expires is the presigned url lifetime, defaulting to 15 minutes
+		Expires time.Duration
+
+		// MaxSize is the maximum upload size, or zero for no limit
+		MaxSize int64
+
+		// ContentTypes are the permitted upload media types, or empty for any
+		ContentTypes []string
+
+		// Now returns the current time, defaulting to time.Now
+		Now func() time.Time
+	}
+
+	// URL represents a presigned url response
+	// Clients must send the specified headers with the request.
+	URL struct {
+		Method    string            `json:"method"`
+		URL       string            `json:"url"`
+		Headers   map[string]string `json:"headers,omitempty"`
+		ExpiresAt time.Time         `json:"expiresAt"`
+	}
+
+	// Presigner represents an S3 url presigner for a single bucket
+	Presigner struct {
+		bucket       string
+		signer       Signer
+		expires      time.Duration
+		maxSize      int64
+		contentTypes []string
+		now          func() time.Time
+	}
+)
+
+const defaultExpires = 15 * time.Minute
+
+var (
+	// ErrInvalidSize indicates that the upload size is not specified
+	ErrInvalidSize = errors.New("presign: invalid upload size")
+
+	// ErrTooLarge indicates that the upload size exceeds the maximum size
+	ErrTooLarge = errors.New("presign: upload size exceeds the maximum size")
+
+	// ErrUnsupportedContentType indicates that the upload media type is not permitted
+	ErrUnsupportedContentType = errors.New("presign: unsupported content type")
+)
+
+// Presign presigns the request
+func (fn SignerFunc) Presign(ctx context.Context, r *Request) (string, error) {
+	return fn(ctx, r)
+}
+
+// New returns a new presigner for the specified bucket
+// The func panics if no signer is specified.
+func New(bucket string, s Signer, o Options) *Presigner {
+	if s == nil {
+		panic("presign: presigner requires a signer")
+	}
+
+	expires := o.Expires
+	if expires <= 0 {
+		expires = defaultExpires
+	}
+
+	now := o.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Presigner{
+		bucket:       bucket,
+		signer:       s,
+		expires:      expires,
+		maxSize:      o.MaxSize,
+		contentTypes: o.ContentTypes,
+		now:          now,
+	}
+}
+
+// Upload returns a presigned PUT url for the specified key
+// The content type and size are included in the signature, so the upload is
+// rejected by S3 if either differs. Errors are returned with 400, 413 and 415 status
+// codes, so they can be returned directly by handlers.
+func (p *Presigner) Upload(ctx context.Context, key, contentType string, size int64) (*URL, error) {
+	if size <= 0 {
+		return nil, rack.WrapError(http.StatusBadRequest, ErrInvalidSize)
+	}
+
+	if p.maxSize > 0 && size > p.maxSize {
+		return nil, rack.WrapError(http.StatusRequestEntityTooLarge, ErrTooLarge)
+	}
+
+	if !p.allowContentType(contentType) {
+		return nil, rack.WrapError(http.StatusUnsupportedMediaType, ErrUnsupportedContentType)
+	}
+
+	return p.presign(ctx, &Request{
+		Method:        http.MethodPut,
+		Key:           key,
+		ContentType:   contentType,
+		ContentLength: size,
+	}, map[string]string{
+		"Content-Type":   contentType,
+		"Content-Length": strconv.FormatInt(size, 10),
+	})
+}
+
+// Download returns a presigned GET url for the specified key
+// If a filename is specified, the object is downloaded as an attachment with
+// that name.
+func (p *Presigner) Download(ctx context.Context, key, filename string) (*URL, error) {
+	r := &Request{
+		Method: http.MethodGet,
+		Key:    key,
+	}
+
+	if filename != "" {
+		r.ContentDisposition = mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	}
+
+	return p.presign(ctx, r, nil)
+}
+
+func (p *Presigner) presign(ctx context.Context, r *Request, headers map[string]string) (*URL, error) {
+	r.Bucket = p.bucket
+	r.Key = strings.TrimPrefix(r.Key, "/")
+	r.Expires = p.expires
+
+	// the expiry is calculated before signing so that it does not exceed the signature expiry
+	exp := p.now().Add(p.expires).UTC()
+
+	u, err := p.signer.Presign(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &URL{
+		Method:    r.Method,
+		URL:       u,
+		Headers:   headers,
+		ExpiresAt: exp,
+	}, nil
+}
+
+func (p *Presigner) allowContentType(ct string) bool {
+	if len(p.contentTypes) == 0 {
+		return true
+	}
+
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+
+	for _, a := range p.contentTypes {
+		if strings.EqualFold(a, mt) {
+			return true
+		}
+	}
+
+	return false
+}
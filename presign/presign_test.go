@@ -0,0 +1,206 @@
+package presign_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stevecallear/rack"
+	"github.com/stevecallear/rack/presign"
+)
+
+func TestPresigner_Upload(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        presign.Options
+		key         string
+		contentType string
+		size        int64
+		req         *presign.Request
+		exp         *presign.URL
+		code        int
+		err         error
+	}{
+		{
+			name: "should return an error if the size is invalid",
+			key:  "file.txt",
+			code: http.StatusBadRequest,
+			err:  presign.ErrInvalidSize,
+		},
+		{
+			name: "should return an error if the size exceeds the maximum",
+			opts: presign.Options{MaxSize: 10},
+			key:  "file.txt",
+			size: 11,
+			code: http.StatusRequestEntityTooLarge,
+			err:  presign.ErrTooLarge,
+		},
+		{
+			name:        "should return an error if the content type is not permitted",
+			opts:        presign.Options{ContentTypes: []string{"image/png"}},
+			key:         "file.txt",
+			contentType: "text/plain",
+			size:        1,
+			code:        http.StatusUnsupportedMediaType,
+			err:         presign.ErrUnsupportedContentType,
+		},
+		{
+			name:        "should presign uploads",
+			opts:        presign.Options{MaxSize: 10, ContentTypes: []string{"text/plain"}},
+			key:         "/files/a b.txt",
+			contentType: "text/plain; charset=utf-8",
+			size:        10,
+			req: &presign.Request{
+				Method:        http.MethodPut,
+				Bucket:        "bucket",
+				Key:           "files/a b.txt",
+				ContentType:   "text/plain; charset=utf-8",
+				ContentLength: 10,
+				Expires:       15 * time.Minute,
+			},
+			exp: &presign.URL{
+				Method: http.MethodPut,
+				URL:    "https://signed",
+				Headers: map[string]string{
+					"Content-Type":   "text/plain; charset=utf-8",
+					"Content-Length": "10",
+				},
+				ExpiresAt: time.Date(2020, 1, 1, 0, 15, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(mockSigner)
+			sut := presign.New("bucket", s, withNow(tt.opts))
+
+			act, err := sut.Upload(context.Background(), tt.key, tt.contentType, tt.size)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got %v, expected %v", err, tt.err)
+			}
+
+			if err != nil {
+				if c := rack.StatusCode(err); c != tt.code {
+					t.Errorf("got %d, expected %d", c, tt.code)
+				}
+				return
+			}
+
+			assertDeepEqual(t, s.req, tt.req)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestPresigner_Download(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     presign.Options
+		key      string
+		filename string
+		req      *presign.Request
+		exp      *presign.URL
+	}{
+		{
+			name: "should presign downloads",
+			key:  "/file.txt",
+			req: &presign.Request{
+				Method:  http.MethodGet,
+				Bucket:  "bucket",
+				Key:     "file.txt",
+				Expires: 15 * time.Minute,
+			},
+			exp: &presign.URL{
+				Method:    http.MethodGet,
+				URL:       "https://signed",
+				ExpiresAt: time.Date(2020, 1, 1, 0, 15, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:     "should presign attachment downloads",
+			opts:     presign.Options{Expires: time.Hour},
+			key:      "file.txt",
+			filename: "report.txt",
+			req: &presign.Request{
+				Method:             http.MethodGet,
+				Bucket:             "bucket",
+				Key:                "file.txt",
+				ContentDisposition: "attachment; filename=report.txt",
+				Expires:            time.Hour,
+			},
+			exp: &presign.URL{
+				Method:    http.MethodGet,
+				URL:       "https://signed",
+				ExpiresAt: time.Date(2020, 1, 1, 1, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := new(mockSigner)
+			sut := presign.New("bucket", s, withNow(tt.opts))
+
+			act, err := sut.Download(context.Background(), tt.key, tt.filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertDeepEqual(t, s.req, tt.req)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+
+	t.Run("should return signer errors", func(t *testing.T) {
+		exp := errors.New("error")
+		s := presign.SignerFunc(func(context.Context, *presign.Request) (string, error) {
+			return "", exp
+		})
+
+		_, err := presign.New("bucket", s, presign.Options{}).Download(context.Background(), "file.txt", "")
+		if err != exp {
+			t.Errorf("got %v, expected %v", err, exp)
+		}
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should panic if no signer is specified", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got nil, expected panic")
+			}
+		}()
+
+		presign.New("bucket", nil, presign.Options{})
+	})
+}
+
+type mockSigner struct {
+	req *presign.Request
+}
+
+func (s *mockSigner) Presign(ctx context.Context, r *presign.Request) (string, error) {
+	s.req = r
+	return "https://signed", nil
+}
+
+func withNow(o presign.Options) presign.Options {
+	o.Now = func() time.Time {
+		return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return o
+}
+
+func assertDeepEqual(t *testing.T, act, exp interface{}) {
+	t.Helper()
+
+	if !reflect.DeepEqual(act, exp) {
+		t.Errorf("got %+v, expected %+v", act, exp)
+	}
+}
@@ -2,6 +2,7 @@ package rack
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
@@ -24,18 +25,37 @@ type (
 	}
 
 	processor struct {
+		name             string
 		canProcess       func([]byte) bool
 		unmarshalRequest func([]byte) (*Request, error)
 		marshalResponse  func(*Response) ([]byte, error)
 	}
+
+	// ProcessorOption represents a processor option
+	ProcessorOption func(*processor)
+
+	// ALBOptions represents alb target group event processor options
+	ALBOptions struct {
+		// DisableQueryDecoding disables decoding of query string keys and values
+		// ALB passes query parameters through as received, so they are decoded by default.
+		DisableQueryDecoding bool
+
+		// TrustedProxies is the number of proxies in front of the load balancer
+		// ALB appends the address of the connecting client to X-Forwarded-For, so the
+		// source ip is read from the right, skipping the addresses appended by trusted
+		// proxies such as CloudFront.
+		TrustedProxies int
+	}
 )
 
 var (
 	// APIGatewayProxyEventProcessor is an api gateway proxy event processor
 	APIGatewayProxyEventProcessor Processor = &processor{
+		name: "apigw-v1",
 		canProcess: func(payload []byte) bool {
-			pv := gjson.GetManyBytes(payload, "version", "requestContext.apiId")
-			return !pv[0].Exists() && pv[1].Exists()
+			// request authorizer events include the request context, so are excluded
+			pv := gjson.GetManyBytes(payload, "version", "requestContext.apiId", "type", "methodArn")
+			return !pv[0].Exists() && pv[1].Exists() && !(pv[2].Exists() && pv[3].Exists())
 		},
 		unmarshalRequest: func(payload []byte) (*Request, error) {
 			e := new(events.APIGatewayProxyRequest)
@@ -43,18 +63,27 @@ var (
 				return nil, err
 			}
 
-			q := url.Values(e.MultiValueQueryStringParameters)
-			h := http.Header(e.MultiValueHeaders)
-
-			return &Request{
-				Method:  e.HTTPMethod,
-				RawPath: e.Path,
-				Path:    e.PathParameters,
-				Query:   q,
-				Header:  h,
-				Body:    e.Body,
-				Event:   e,
-			}, nil
+			q := url.Values{}
+			mergeMaps(nil, e.MultiValueQueryStringParameters, q.Add)
+
+			h := http.Header{}
+			mergeMaps(nil, e.MultiValueHeaders, h.Add)
+
+			return (&Request{
+				Method:          e.HTTPMethod,
+				Host:            e.RequestContext.DomainName,
+				Protocol:        e.RequestContext.Protocol,
+				RawPath:         e.Path,
+				Path:            pathParameters(e.PathParameters),
+				Query:           q,
+				Header:          h,
+				SourceIP:        e.RequestContext.Identity.SourceIP,
+				UserAgent:       e.RequestContext.Identity.UserAgent,
+				Stage:           e.RequestContext.Stage,
+				Body:            e.Body,
+				IsBase64Encoded: e.IsBase64Encoded,
+				Event:           e,
+			}).normalize(), nil
 		},
 		marshalResponse: func(r *Response) ([]byte, error) {
 			return json.Marshal(&events.APIGatewayProxyResponse{
@@ -62,16 +91,18 @@ var (
 				Headers:           reduceHeaders(r.Headers),
 				MultiValueHeaders: r.Headers,
 				Body:              r.Body,
-				IsBase64Encoded:   false,
+				IsBase64Encoded:   r.IsBase64Encoded,
 			})
 		},
 	}
 
 	// APIGatewayV2HTTPEventProcessor is an api gateway v2 http event processor
 	APIGatewayV2HTTPEventProcessor Processor = &processor{
+		name: "apigw-v2",
 		canProcess: func(payload []byte) bool {
-			pv := gjson.GetManyBytes(payload, "version", "requestContext.apiId")
-			return pv[0].String() == "2.0" && pv[1].Exists()
+			// authorizer events include the request context, so are excluded
+			pv := gjson.GetManyBytes(payload, "version", "requestContext.apiId", "type", "routeArn")
+			return pv[0].String() == "2.0" && pv[1].Exists() && !(pv[2].Exists() && pv[3].Exists())
 		},
 		unmarshalRequest: func(payload []byte) (*Request, error) {
 			e := new(events.APIGatewayV2HTTPRequest)
@@ -79,40 +110,94 @@ var (
 				return nil, err
 			}
 
-			q := url.Values{}
-			for k, ps := range e.QueryStringParameters {
-				for _, v := range strings.Split(ps, ",") {
-					q.Add(k, v)
-				}
-			}
-
-			h := http.Header{}
-			mergeMaps(e.Headers, nil, h.Add)
-
-			return &Request{
-				Method:  e.RequestContext.HTTP.Method,
-				RawPath: e.RequestContext.HTTP.Path,
-				Path:    e.PathParameters,
-				Query:   q,
-				Header:  h,
-				Body:    e.Body,
-				Event:   e,
-			}, nil
+			return newV2HTTPRequest(e, e), nil
 		},
 		marshalResponse: func(r *Response) ([]byte, error) {
+			h := withoutCookies(r.Headers)
 			return json.Marshal(&events.APIGatewayV2HTTPResponse{
 				StatusCode:        r.StatusCode,
-				Headers:           reduceHeaders(r.Headers),
-				MultiValueHeaders: r.Headers,
+				Headers:           reduceHeaders(h),
+				MultiValueHeaders: h,
 				Body:              r.Body,
-				IsBase64Encoded:   false,
-				Cookies:           []string{},
+				IsBase64Encoded:   r.IsBase64Encoded,
+				Cookies:           responseCookies(r.Headers),
 			})
 		},
 	}
 
 	// ALBTargetGroupEventProcessor is an alb target group event processor
-	ALBTargetGroupEventProcessor Processor = &processor{
+	ALBTargetGroupEventProcessor = NewALBTargetGroupEventProcessor(ALBOptions{})
+)
+
+// NewProcessor returns a new processor with the specified options
+// Options are applied in order, so funcs can be overridden after specifying a base
+// processor. Processors without an unmarshal or marshal func return an error.
+func NewProcessor(opts ...ProcessorOption) Processor {
+	p := &processor{
+		name:       "custom",
+		canProcess: func([]byte) bool { return false },
+		unmarshalRequest: func([]byte) (*Request, error) {
+			return nil, ErrUnsupportedEventType
+		},
+		marshalResponse: func(*Response) ([]byte, error) {
+			return nil, ErrUnsupportedEventType
+		},
+	}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	return p
+}
+
+// WithBaseProcessor uses the funcs of the specified processor
+// The processor name is also used if the base processor implements fmt.Stringer.
+func WithBaseProcessor(b Processor) ProcessorOption {
+	return func(p *processor) {
+		if s, ok := b.(fmt.Stringer); ok {
+			p.name = s.String()
+		}
+
+		p.canProcess = b.CanProcess
+		p.unmarshalRequest = b.UnmarshalRequest
+		p.marshalResponse = b.MarshalResponse
+	}
+}
+
+// WithProcessorName sets the processor name
+// The name is reported in resolver metrics.
+func WithProcessorName(name string) ProcessorOption {
+	return func(p *processor) {
+		p.name = name
+	}
+}
+
+// WithCanProcess sets the processor can process func
+func WithCanProcess(fn func(payload []byte) bool) ProcessorOption {
+	return func(p *processor) {
+		p.canProcess = fn
+	}
+}
+
+// WithUnmarshalRequest sets the processor unmarshal request func
+func WithUnmarshalRequest(fn func(payload []byte) (*Request, error)) ProcessorOption {
+	return func(p *processor) {
+		p.unmarshalRequest = fn
+	}
+}
+
+// WithMarshalResponse sets the processor marshal response func
+func WithMarshalResponse(fn func(res *Response) ([]byte, error)) ProcessorOption {
+	return func(p *processor) {
+		p.marshalResponse = fn
+	}
+}
+
+// NewALBTargetGroupEventProcessor returns a new alb target group event processor
+func NewALBTargetGroupEventProcessor(o ALBOptions) Processor {
+	return &processor{
+		name: "alb",
 		canProcess: func(payload []byte) bool {
 			return gjson.GetBytes(payload, "requestContext.elb").Exists()
 		},
@@ -123,33 +208,53 @@ var (
 			}
 
 			q := url.Values{}
-			mergeMaps(e.QueryStringParameters, e.MultiValueQueryStringParameters, q.Add)
+			addFn := q.Add
+			if !o.DisableQueryDecoding {
+				addFn = func(k, v string) {
+					q.Add(queryUnescape(k), queryUnescape(v))
+				}
+			}
+			mergeMaps(e.QueryStringParameters, e.MultiValueQueryStringParameters, addFn)
 
 			h := http.Header{}
 			mergeMaps(e.Headers, e.MultiValueHeaders, h.Add)
 
-			return &Request{
-				Method:  e.HTTPMethod,
-				RawPath: e.Path,
-				Path:    map[string]string{},
-				Query:   q,
-				Header:  h,
-				Body:    e.Body,
-				Event:   e,
-			}, nil
+			return (&Request{
+				Method:          e.HTTPMethod,
+				RawPath:         e.Path,
+				Path:            map[string]string{},
+				Query:           q,
+				Header:          h,
+				SourceIP:        forwardedFor(h, o.TrustedProxies),
+				Body:            e.Body,
+				IsBase64Encoded: e.IsBase64Encoded,
+				Event:           e,
+			}).normalize(), nil
 		},
 		marshalResponse: func(r *Response) ([]byte, error) {
 			return json.Marshal(&events.ALBTargetGroupResponse{
 				StatusCode:        r.StatusCode,
-				StatusDescription: http.StatusText(r.StatusCode),
-				Headers:           reduceHeaders(r.Headers),
+				StatusDescription: statusDescription(r),
+				Headers:           reduceHeadersWithCookies(r.Headers),
 				MultiValueHeaders: r.Headers,
 				Body:              r.Body,
-				IsBase64Encoded:   false,
+				IsBase64Encoded:   r.IsBase64Encoded,
 			})
 		},
 	}
-)
+}
+
+func statusDescription(r *Response) string {
+	if r.StatusDescription != "" {
+		return r.StatusDescription
+	}
+
+	return http.StatusText(r.StatusCode)
+}
+
+func (p *processor) String() string {
+	return p.name
+}
 
 func (p *processor) CanProcess(payload []byte) bool {
 	return p.canProcess(payload)
@@ -175,6 +280,87 @@ func mergeMaps(sv map[string]string, mv map[string][]string, addFn func(k, v str
 	}
 }
 
+// newV2HTTPRequest returns a canonical request for the specified v2 http request and event
+func newV2HTTPRequest(r *events.APIGatewayV2HTTPRequest, e interface{}) *Request {
+	q := url.Values{}
+	for k, ps := range r.QueryStringParameters {
+		for _, v := range strings.Split(ps, ",") {
+			q.Add(k, v)
+		}
+	}
+
+	h := http.Header{}
+	mergeMaps(r.Headers, nil, h.Add)
+
+	// v2 events move the cookie header to a separate field
+	if len(r.Cookies) > 0 && h.Get("Cookie") == "" {
+		h.Set("Cookie", strings.Join(r.Cookies, "; "))
+	}
+
+	return (&Request{
+		Method:          r.RequestContext.HTTP.Method,
+		Host:            r.RequestContext.DomainName,
+		Protocol:        r.RequestContext.HTTP.Protocol,
+		RawPath:         r.RequestContext.HTTP.Path,
+		RawQuery:        r.RawQueryString,
+		Path:            pathParameters(r.PathParameters),
+		Query:           q,
+		Header:          h,
+		SourceIP:        r.RequestContext.HTTP.SourceIP,
+		UserAgent:       r.RequestContext.HTTP.UserAgent,
+		Stage:           r.RequestContext.Stage,
+		Body:            r.Body,
+		IsBase64Encoded: r.IsBase64Encoded,
+		Event:           e,
+	}).normalize()
+}
+
+// forwardedFor returns the client address from the X-Forwarded-For header
+// Addresses on the left are supplied by the client, so the address is read from the
+// right, skipping the specified number of trusted proxies. The leftmost address is
+// returned if there are fewer addresses than proxies.
+func forwardedFor(h http.Header, proxies int) string {
+	var ips []string
+	for _, v := range h.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(v, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	if len(ips) == 0 {
+		return ""
+	}
+
+	i := len(ips) - 1 - proxies
+	if i < 0 {
+		i = 0
+	}
+
+	return ips[i]
+}
+
+func pathParameters(p map[string]string) map[string]string {
+	if p == nil {
+		return map[string]string{}
+	}
+
+	return p
+}
+
+// queryUnescape decodes the query component, returning the original value if it is malformed
+func queryUnescape(s string) string {
+	if u, err := url.QueryUnescape(s); err == nil {
+		return u
+	}
+
+	return s
+}
+
+// setCookieVariants is the number of case variants of the Set-Cookie header name
+const setCookieVariants = 1 << 9
+
 func reduceHeaders(h http.Header) map[string]string {
 	m := make(map[string]string, len(h))
 	for k := range h {
@@ -183,3 +369,70 @@ func reduceHeaders(h http.Header) map[string]string {
 
 	return m
 }
+
+// reduceHeadersWithCookies reduces the headers, retaining multiple Set-Cookie values
+// Single value maps cannot contain duplicate keys, so additional cookies are written
+// using case variants of the header name. This is required for ALB target groups
+// that do not have multi-value headers enabled. The header name has a limited number
+// of case variants, so cookies beyond that limit are dropped from the reduced headers.
+func reduceHeadersWithCookies(h http.Header) map[string]string {
+	m := reduceHeaders(h)
+
+	cs := h.Values("Set-Cookie")
+	for i, n := 1, 0; i < len(cs) && n < setCookieVariants; n++ {
+		k := headerCaseVariant("Set-Cookie", n)
+		if _, ok := m[k]; ok {
+			continue
+		}
+
+		m[k] = cs[i]
+		i++
+	}
+
+	return m
+}
+
+func headerCaseVariant(k string, n int) string {
+	b := []byte(strings.ToLower(k))
+	for i := range b {
+		if n == 0 {
+			break
+		}
+
+		if b[i] >= 'a' && b[i] <= 'z' {
+			if n&1 == 1 {
+				b[i] -= 'a' - 'A'
+			}
+			n >>= 1
+		}
+	}
+
+	return string(b)
+}
+
+// withoutCookies returns the headers without Set-Cookie values
+// API Gateway v2 responses write cookies to a separate field, and cookies that are
+// also present in the headers would be sent twice.
+func withoutCookies(h http.Header) http.Header {
+	if _, ok := h["Set-Cookie"]; !ok {
+		return h
+	}
+
+	c := make(http.Header, len(h)-1)
+	for k, vs := range h {
+		if k != "Set-Cookie" {
+			c[k] = vs
+		}
+	}
+
+	return c
+}
+
+func responseCookies(h http.Header) []string {
+	cs := h.Values("Set-Cookie")
+	if cs == nil {
+		return []string{}
+	}
+
+	return cs
+}
@@ -1,6 +1,7 @@
 package rack_test
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
 	"testing"
@@ -26,6 +27,11 @@ func TestAPIGatewayProxyEventProcessor_CanProcess(t *testing.T) {
 			payload: []byte(apiGatewayV2HTTPEventPayload),
 			exp:     false,
 		},
+		{
+			name:    "should return false for request authorizer events",
+			payload: []byte(`{"type":"REQUEST","methodArn":"arn","requestContext":{"apiId":"id"}}`),
+			exp:     false,
+		},
 		{
 			name:    "should return false for alb target group events",
 			payload: []byte(albTargetGroupSingleValueEventPayload),
@@ -61,8 +67,11 @@ func TestAPIGatewayProxyEventProcessor_UnmarshalRequest(t *testing.T) {
 			name:    "should return the request",
 			payload: []byte(apiGatewayProxyEventPayload),
 			exp: &rack.Request{
-				Method:  http.MethodGet,
-				RawPath: "/resource/",
+				Method:   http.MethodGet,
+				Host:     "api.example.com",
+				Protocol: "HTTP/1.1",
+				RawPath:  "/resource/",
+				RawQuery: "q1=v1&q2=v2&q2=v3",
 				Path: map[string]string{
 					"proxy": "resource",
 				},
@@ -74,8 +83,12 @@ func TestAPIGatewayProxyEventProcessor_UnmarshalRequest(t *testing.T) {
 					"X-Custom-Header1": {"v1"},
 					"X-Custom-Header2": {"v2", "v3"},
 				},
-				Body:  "body",
-				Event: unmarshal([]byte(apiGatewayProxyEventPayload), new(events.APIGatewayProxyRequest)),
+				SourceIP:      "1.1.1.1",
+				UserAgent:     "agent",
+				Stage:         "dev",
+				ContentLength: 4,
+				Body:          "body",
+				Event:         unmarshal([]byte(apiGatewayProxyEventPayload), new(events.APIGatewayProxyRequest)),
 			},
 		},
 	}
@@ -90,6 +103,62 @@ func TestAPIGatewayProxyEventProcessor_UnmarshalRequest(t *testing.T) {
 	}
 }
 
+func TestProcessor_UnmarshalRequest_Base64(t *testing.T) {
+	processors := map[string]struct {
+		processor rack.Processor
+		payload   func(body string) []byte
+	}{
+		"apigw-v1": {
+			processor: rack.APIGatewayProxyEventProcessor,
+			payload: func(body string) []byte {
+				return marshal(&events.APIGatewayProxyRequest{Body: body, IsBase64Encoded: true})
+			},
+		},
+		"apigw-v2": {
+			processor: rack.APIGatewayV2HTTPEventProcessor,
+			payload: func(body string) []byte {
+				return marshal(&events.APIGatewayV2HTTPRequest{Body: body, IsBase64Encoded: true})
+			},
+		},
+		"alb": {
+			processor: rack.ALBTargetGroupEventProcessor,
+			payload: func(body string) []byte {
+				return marshal(&events.ALBTargetGroupRequest{Body: body, IsBase64Encoded: true})
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		body    string
+		exp     string
+		encoded bool
+	}{
+		{
+			name: "should decode encoded bodies",
+			body: "Ym9keQ==",
+			exp:  "body",
+		},
+		{
+			name:    "should not decode invalid bodies",
+			body:    "body!",
+			exp:     "body!",
+			encoded: true,
+		},
+	}
+
+	for pn, p := range processors {
+		for _, tt := range tests {
+			t.Run(pn+" "+tt.name, func(t *testing.T) {
+				act, err := p.processor.UnmarshalRequest(p.payload(tt.body))
+				assertErrorExists(t, err, false)
+				assertDeepEqual(t, act.Body, tt.exp)
+				assertDeepEqual(t, act.IsBase64Encoded, tt.encoded)
+			})
+		}
+	}
+}
+
 func TestAPIGatewayProxyEventProcessor_MarshalResponse(t *testing.T) {
 	t.Run("should marshal the response", func(t *testing.T) {
 		res := &rack.Response{
@@ -121,6 +190,24 @@ func TestAPIGatewayProxyEventProcessor_MarshalResponse(t *testing.T) {
 	})
 }
 
+func TestAPIGatewayProxyEventProcessor_MarshalResponse_Cookies(t *testing.T) {
+	t.Run("should retain multiple cookies", func(t *testing.T) {
+		res := &rack.Response{
+			StatusCode: http.StatusOK,
+			Headers: http.Header{
+				"Set-Cookie": {"a=1", "b=2"},
+			},
+		}
+
+		sut := rack.APIGatewayProxyEventProcessor
+		b, err := sut.MarshalResponse(res)
+		assertErrorExists(t, err, false)
+
+		act := unmarshal(b, new(events.APIGatewayProxyResponse)).(*events.APIGatewayProxyResponse)
+		assertDeepEqual(t, act.MultiValueHeaders["Set-Cookie"], []string{"a=1", "b=2"})
+	})
+}
+
 func TestAPIGatewayV2HTTPEventProcessor_CanProcess(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -137,6 +224,11 @@ func TestAPIGatewayV2HTTPEventProcessor_CanProcess(t *testing.T) {
 			payload: []byte(apiGatewayProxyEventPayload),
 			exp:     false,
 		},
+		{
+			name:    "should return false for authorizer events",
+			payload: []byte(`{"version":"2.0","type":"REQUEST","routeArn":"arn","requestContext":{"apiId":"id"}}`),
+			exp:     false,
+		},
 		{
 			name:    "should return false for alb target group events",
 			payload: []byte(albTargetGroupSingleValueEventPayload),
@@ -172,8 +264,11 @@ func TestAPIGatewayV2HTTPEventProcessor_UnmarshalRequest(t *testing.T) {
 			name:    "should return the response",
 			payload: []byte(apiGatewayV2HTTPEventPayload),
 			exp: &rack.Request{
-				Method:  http.MethodGet,
-				RawPath: "/resource/",
+				Method:   http.MethodGet,
+				Host:     "api.example.com",
+				Protocol: "HTTP/1.1",
+				RawPath:  "/resource/",
+				RawQuery: "q1=v1&q2=v2&q2=v3",
 				Path: map[string]string{
 					"p": "v",
 				},
@@ -185,8 +280,12 @@ func TestAPIGatewayV2HTTPEventProcessor_UnmarshalRequest(t *testing.T) {
 					"X-Custom-Header1": {"v1"},
 					"X-Custom-Header2": {"v2"},
 				},
-				Body:  "body",
-				Event: unmarshal([]byte(apiGatewayV2HTTPEventPayload), new(events.APIGatewayV2HTTPRequest)),
+				SourceIP:      "1.1.1.1",
+				UserAgent:     "agent",
+				Stage:         "$default",
+				ContentLength: 4,
+				Body:          "body",
+				Event:         unmarshal([]byte(apiGatewayV2HTTPEventPayload), new(events.APIGatewayV2HTTPRequest)),
 			},
 		},
 	}
@@ -233,6 +332,79 @@ func TestAPIGatewayV2HTTPEventProcessor_MarshalResponse(t *testing.T) {
 	})
 }
 
+func TestAPIGatewayV2HTTPEventProcessor_MarshalResponse_Cookies(t *testing.T) {
+	t.Run("should write cookies to the cookies field", func(t *testing.T) {
+		res := &rack.Response{
+			StatusCode: http.StatusOK,
+			Headers: http.Header{
+				"Set-Cookie": {"a=1", "b=2"},
+			},
+		}
+
+		sut := rack.APIGatewayV2HTTPEventProcessor
+		b, err := sut.MarshalResponse(res)
+		assertErrorExists(t, err, false)
+
+		act := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		assertDeepEqual(t, act.Cookies, []string{"a=1", "b=2"})
+	})
+
+	t.Run("should not write cookies to the headers", func(t *testing.T) {
+		res := &rack.Response{
+			StatusCode: http.StatusOK,
+			Headers: http.Header{
+				"Content-Type": {"text/plain"},
+				"Set-Cookie":   {"a=1", "b=2"},
+			},
+		}
+
+		sut := rack.APIGatewayV2HTTPEventProcessor
+		b, err := sut.MarshalResponse(res)
+		assertErrorExists(t, err, false)
+
+		act := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		assertDeepEqual(t, act.Headers, map[string]string{"Content-Type": "text/plain"})
+		assertDeepEqual(t, act.MultiValueHeaders, map[string][]string{"Content-Type": {"text/plain"}})
+		assertDeepEqual(t, res.Headers.Values("Set-Cookie"), []string{"a=1", "b=2"})
+	})
+}
+
+func TestAPIGatewayV2HTTPEventProcessor_UnmarshalRequest_Cookies(t *testing.T) {
+	tests := []struct {
+		name    string
+		cookies []string
+		headers map[string]string
+		exp     []string
+	}{
+		{
+			name:    "should map cookies to the cookie header",
+			cookies: []string{"a=1", "b=2"},
+			exp:     []string{"a=1; b=2"},
+		},
+		{
+			name:    "should not overwrite the cookie header",
+			cookies: []string{"a=1"},
+			headers: map[string]string{"Cookie": "b=2"},
+			exp:     []string{"b=2"},
+		},
+		{
+			name: "should not set empty cookie headers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := rack.APIGatewayV2HTTPEventProcessor
+			act, err := sut.UnmarshalRequest(marshal(&events.APIGatewayV2HTTPRequest{
+				Cookies: tt.cookies,
+				Headers: tt.headers,
+			}))
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, act.Header.Values("Cookie"), tt.exp)
+		})
+	}
+}
+
 func TestALBTargetGroupEventProcessor_CanProcess(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -285,28 +457,37 @@ func TestALBTargetGroupEventProcessor_UnmarshalRequest(t *testing.T) {
 			name:    "should return the response for single value payloads",
 			payload: []byte(albTargetGroupSingleValueEventPayload),
 			exp: &rack.Request{
-				Method:  http.MethodGet,
-				RawPath: "/resource/",
-				Path:    map[string]string{},
+				Method:   http.MethodGet,
+				Host:     "alb.example.com",
+				RawPath:  "/resource/",
+				RawQuery: "q1=v1&q2=v2",
+				Path:     map[string]string{},
 				Query: url.Values{
 					"q1": {"v1"},
 					"q2": {"v2"},
 				},
 				Header: http.Header{
+					"Host":             {"alb.example.com"},
+					"User-Agent":       {"agent"},
+					"X-Forwarded-For":  {"1.1.1.1, 2.2.2.2"},
 					"X-Custom-Header1": {"v1"},
 					"X-Custom-Header2": {"v2"},
 				},
-				Body:  "body",
-				Event: unmarshal([]byte(albTargetGroupSingleValueEventPayload), new(events.ALBTargetGroupRequest)),
+				SourceIP:      "2.2.2.2",
+				UserAgent:     "agent",
+				ContentLength: 4,
+				Body:          "body",
+				Event:         unmarshal([]byte(albTargetGroupSingleValueEventPayload), new(events.ALBTargetGroupRequest)),
 			},
 		},
 		{
 			name:    "should return the response for multi value payloads",
 			payload: []byte(albTargetGroupMultiValueEventPayload),
 			exp: &rack.Request{
-				Method:  http.MethodGet,
-				RawPath: "/resource/",
-				Path:    map[string]string{},
+				Method:   http.MethodGet,
+				RawPath:  "/resource/",
+				RawQuery: "q1=v1&q2=v2&q2=v3",
+				Path:     map[string]string{},
 				Query: url.Values{
 					"q1": {"v1"},
 					"q2": {"v2", "v3"},
@@ -315,8 +496,9 @@ func TestALBTargetGroupEventProcessor_UnmarshalRequest(t *testing.T) {
 					"X-Custom-Header1": {"v1"},
 					"X-Custom-Header2": {"v2", "v3"},
 				},
-				Body:  "body",
-				Event: unmarshal([]byte(albTargetGroupMultiValueEventPayload), new(events.ALBTargetGroupRequest)),
+				ContentLength: 4,
+				Body:          "body",
+				Event:         unmarshal([]byte(albTargetGroupMultiValueEventPayload), new(events.ALBTargetGroupRequest)),
 			},
 		},
 	}
@@ -331,6 +513,131 @@ func TestALBTargetGroupEventProcessor_UnmarshalRequest(t *testing.T) {
 	}
 }
 
+func TestALBTargetGroupEventProcessor_UnmarshalRequest_SourceIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    rack.ALBOptions
+		headers map[string][]string
+		exp     string
+	}{
+		{
+			name: "should return an empty value if the header is not set",
+			exp:  "",
+		},
+		{
+			name:    "should return the rightmost address",
+			headers: map[string][]string{"X-Forwarded-For": {"1.1.1.1, 2.2.2.2"}},
+			exp:     "2.2.2.2",
+		},
+		{
+			name:    "should read addresses from multiple values",
+			headers: map[string][]string{"X-Forwarded-For": {"1.1.1.1", "2.2.2.2,3.3.3.3"}},
+			exp:     "3.3.3.3",
+		},
+		{
+			name:    "should skip trusted proxies",
+			opts:    rack.ALBOptions{TrustedProxies: 1},
+			headers: map[string][]string{"X-Forwarded-For": {"1.1.1.1, 2.2.2.2, 3.3.3.3"}},
+			exp:     "2.2.2.2",
+		},
+		{
+			name:    "should return the leftmost address if there are fewer addresses than proxies",
+			opts:    rack.ALBOptions{TrustedProxies: 2},
+			headers: map[string][]string{"X-Forwarded-For": {"2.2.2.2"}},
+			exp:     "2.2.2.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := marshal(&events.ALBTargetGroupRequest{
+				HTTPMethod:        http.MethodGet,
+				Path:              "/",
+				MultiValueHeaders: tt.headers,
+				RequestContext: events.ALBTargetGroupRequestContext{
+					ELB: events.ELBContext{TargetGroupArn: "arn"},
+				},
+			})
+
+			sut := rack.NewALBTargetGroupEventProcessor(tt.opts)
+			act, err := sut.UnmarshalRequest(payload)
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, act.SourceIP, tt.exp)
+		})
+	}
+}
+
+func TestALBTargetGroupEventProcessor_UnmarshalRequest_QueryDecoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   rack.ALBOptions
+		single map[string]string
+		multi  map[string][]string
+		exp    url.Values
+	}{
+		{
+			name:   "should decode encoded values",
+			single: map[string]string{"path": "%2Fa%2Fb"},
+			exp:    url.Values{"path": {"/a/b"}},
+		},
+		{
+			name:   "should decode encoded keys",
+			single: map[string]string{"a%5B0%5D": "v"},
+			exp:    url.Values{"a[0]": {"v"}},
+		},
+		{
+			name:   "should decode plus as space",
+			single: map[string]string{"q+1": "a+b"},
+			exp:    url.Values{"q 1": {"a b"}},
+		},
+		{
+			name:   "should decode encoded plus",
+			single: map[string]string{"q": "a%2Bb"},
+			exp:    url.Values{"q": {"a+b"}},
+		},
+		{
+			name:   "should decode once",
+			single: map[string]string{"q": "%252F"},
+			exp:    url.Values{"q": {"%2F"}},
+		},
+		{
+			name:  "should decode multi values",
+			multi: map[string][]string{"q": {"%2Fa", "%2Fb"}},
+			exp:   url.Values{"q": {"/a", "/b"}},
+		},
+		{
+			name:   "should retain malformed values",
+			single: map[string]string{"q": "100%"},
+			exp:    url.Values{"q": {"100%"}},
+		},
+		{
+			name:   "should not decode if disabled",
+			opts:   rack.ALBOptions{DisableQueryDecoding: true},
+			single: map[string]string{"q+1": "%2Fa+b"},
+			exp:    url.Values{"q+1": {"%2Fa+b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := marshal(&events.ALBTargetGroupRequest{
+				HTTPMethod:                      http.MethodGet,
+				Path:                            "/",
+				QueryStringParameters:           tt.single,
+				MultiValueQueryStringParameters: tt.multi,
+				RequestContext: events.ALBTargetGroupRequestContext{
+					ELB: events.ELBContext{TargetGroupArn: "arn"},
+				},
+			})
+
+			sut := rack.NewALBTargetGroupEventProcessor(tt.opts)
+			act, err := sut.UnmarshalRequest(payload)
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, act.Query, tt.exp)
+		})
+	}
+}
+
 func TestALBTargetGroupEventProcessor_MarshalResponse(t *testing.T) {
 	t.Run("should marshal the response", func(t *testing.T) {
 		res := &rack.Response{
@@ -363,6 +670,86 @@ func TestALBTargetGroupEventProcessor_MarshalResponse(t *testing.T) {
 	})
 }
 
+func TestALBTargetGroupEventProcessor_MarshalResponse_StatusDescription(t *testing.T) {
+	tests := []struct {
+		name string
+		res  *rack.Response
+		exp  string
+	}{
+		{
+			name: "should default to the status text",
+			res:  &rack.Response{StatusCode: http.StatusNotFound},
+			exp:  "Not Found",
+		},
+		{
+			name: "should use the status description if specified",
+			res:  &rack.Response{StatusCode: http.StatusOK, StatusDescription: "200 OK"},
+			exp:  "200 OK",
+		},
+		{
+			name: "should return an empty value for unknown status codes",
+			res:  &rack.Response{StatusCode: 599},
+			exp:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := rack.ALBTargetGroupEventProcessor
+			b, err := sut.MarshalResponse(tt.res)
+			assertErrorExists(t, err, false)
+
+			act := unmarshal(b, new(events.ALBTargetGroupResponse)).(*events.ALBTargetGroupResponse)
+			if act.StatusDescription != tt.exp {
+				t.Errorf("got %s, expected %s", act.StatusDescription, tt.exp)
+			}
+		})
+	}
+}
+
+func TestALBTargetGroupEventProcessor_MarshalResponse_Cookies(t *testing.T) {
+	t.Run("should retain multiple cookies", func(t *testing.T) {
+		res := &rack.Response{
+			StatusCode: http.StatusOK,
+			Headers: http.Header{
+				"Set-Cookie": {"a=1", "b=2", "c=3"},
+			},
+		}
+
+		sut := rack.ALBTargetGroupEventProcessor
+		b, err := sut.MarshalResponse(res)
+		assertErrorExists(t, err, false)
+
+		act := unmarshal(b, new(events.ALBTargetGroupResponse)).(*events.ALBTargetGroupResponse)
+		assertDeepEqual(t, act.MultiValueHeaders["Set-Cookie"], []string{"a=1", "b=2", "c=3"})
+		assertDeepEqual(t, act.Headers, map[string]string{
+			"Set-Cookie": "a=1",
+			"set-cookie": "b=2",
+			"Set-cookie": "c=3",
+		})
+	})
+
+	t.Run("should drop cookies beyond the header case variants", func(t *testing.T) {
+		cs := make([]string, 600)
+		for i := range cs {
+			cs[i] = fmt.Sprintf("c%d=1", i)
+		}
+
+		res := &rack.Response{
+			StatusCode: http.StatusOK,
+			Headers:    http.Header{"Set-Cookie": cs},
+		}
+
+		sut := rack.ALBTargetGroupEventProcessor
+		b, err := sut.MarshalResponse(res)
+		assertErrorExists(t, err, false)
+
+		act := unmarshal(b, new(events.ALBTargetGroupResponse)).(*events.ALBTargetGroupResponse)
+		assertDeepEqual(t, act.MultiValueHeaders["Set-Cookie"], cs)
+		assertDeepEqual(t, len(act.Headers), 512)
+	})
+}
+
 const (
 	apiGatewayProxyEventPayload = `{
 	"resource": "/{proxy+}",
@@ -403,6 +790,12 @@ const (
 		"httpMethod": "GET",
 		"path": "/dev/resource/",
 		"protocol": "HTTP/1.1",
+		"stage": "dev",
+		"domainName": "api.example.com",
+		"identity": {
+			"sourceIp": "1.1.1.1",
+			"userAgent": "agent"
+		},
 		"apiId": "apiid"
 	},
 	"body": "body",
@@ -427,10 +820,14 @@ const (
 	},
 	"requestContext": {
 		"apiId": "apiid",
+		"domainName": "api.example.com",
+		"stage": "$default",
 		"http": {
 			"method": "GET",
 			"path": "/resource/",
-			"protocol": "HTTP/1.1"
+			"protocol": "HTTP/1.1",
+			"sourceIp": "1.1.1.1",
+			"userAgent": "agent"
 		}
 	},
 	"body": "body",
@@ -450,6 +847,9 @@ const (
 		"q2": "v2"
 	},
 	"headers": {
+		"host": "alb.example.com",
+		"user-agent": "agent",
+		"x-forwarded-for": "1.1.1.1, 2.2.2.2",
 		"x-custom-header1": "v1",
 		"x-custom-header2": "v2"
 	},
@@ -487,3 +887,81 @@ const (
 	"isBase64Encoded": false
 }`
 )
+
+func TestNewProcessor(t *testing.T) {
+	payload := []byte(apiGatewayV2HTTPEventPayload)
+
+	t.Run("should return errors by default", func(t *testing.T) {
+		sut := rack.NewProcessor()
+
+		if sut.CanProcess(payload) {
+			t.Error("got true, expected false")
+		}
+
+		_, err := sut.UnmarshalRequest(payload)
+		assertErrorExists(t, err, true)
+
+		_, err = sut.MarshalResponse(&rack.Response{})
+		assertErrorExists(t, err, true)
+	})
+
+	t.Run("should use the specified funcs", func(t *testing.T) {
+		exp := &rack.Request{Method: http.MethodGet}
+
+		sut := rack.NewProcessor(
+			rack.WithProcessorName("name"),
+			rack.WithCanProcess(func([]byte) bool { return true }),
+			rack.WithUnmarshalRequest(func([]byte) (*rack.Request, error) { return exp, nil }),
+			rack.WithMarshalResponse(func(r *rack.Response) ([]byte, error) { return []byte(r.Body), nil }),
+		)
+
+		if act := sut.(fmt.Stringer).String(); act != "name" {
+			t.Errorf("got %s, expected name", act)
+		}
+
+		if !sut.CanProcess(nil) {
+			t.Error("got false, expected true")
+		}
+
+		req, err := sut.UnmarshalRequest(nil)
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, req, exp)
+
+		b, err := sut.MarshalResponse(&rack.Response{Body: "body"})
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, b, []byte("body"))
+	})
+
+	t.Run("should extend the base processor", func(t *testing.T) {
+		sut := rack.NewProcessor(
+			rack.WithBaseProcessor(rack.APIGatewayV2HTTPEventProcessor),
+			rack.WithMarshalResponse(func(r *rack.Response) ([]byte, error) {
+				r.Headers.Set("X-Custom-Header", "value")
+				return rack.APIGatewayV2HTTPEventProcessor.MarshalResponse(r)
+			}),
+		)
+
+		if act := sut.(fmt.Stringer).String(); act != "apigw-v2" {
+			t.Errorf("got %s, expected apigw-v2", act)
+		}
+
+		if !sut.CanProcess(payload) {
+			t.Error("got false, expected true")
+		}
+
+		exp, err := rack.APIGatewayV2HTTPEventProcessor.UnmarshalRequest(payload)
+		assertErrorExists(t, err, false)
+
+		act, err := sut.UnmarshalRequest(payload)
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, act, exp)
+
+		b, err := sut.MarshalResponse(&rack.Response{StatusCode: http.StatusOK, Headers: http.Header{}})
+		assertErrorExists(t, err, false)
+
+		res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		if act := res.Headers["X-Custom-Header"]; act != "value" {
+			t.Errorf("got %s, expected value", act)
+		}
+	})
+}
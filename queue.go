@@ -0,0 +1,131 @@
+package rack
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+type (
+	// Message represents a queue message
+	Message struct {
+		Queue      string
+		Body       string
+		Attributes map[string]string
+	}
+
+	// Enqueuer represents a message queue client
+	// Implementations would typically wrap an SQS client, using the message
+	// queue as the queue url and the attributes as string message attributes.
+	Enqueuer interface {
+		Enqueue(ctx context.Context, m *Message) error
+	}
+
+	// EnqueuerFunc represents an enqueuer func
+	EnqueuerFunc func(ctx context.Context, m *Message) error
+)
+
+const (
+	enqueuerKey          = "rack.enqueuer"
+	messageAttributesKey = "rack.messageAttributes"
+)
+
+// ErrNoEnqueuer indicates that no enqueuer has been configured
+var ErrNoEnqueuer = errors.New("no enqueuer configured")
+
+// Enqueue enqueues the message
+func (fn EnqueuerFunc) Enqueue(ctx context.Context, m *Message) error {
+	return fn(ctx, m)
+}
+
+// WithEnqueuer returns a middleware func that configures the enqueuer used by Enqueue
+func WithEnqueuer(e Enqueuer) MiddlewareFunc {
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			c.Set(enqueuerKey, e)
+			return n(c)
+		}
+	}
+}
+
+// WithMessageAttributes returns a middleware func that adds attributes to outgoing messages
+// The attributes returned by the func are added to the correlation attributes of
+// enqueued messages and emitted events.
+func WithMessageAttributes(fn func(Context) map[string]string) MiddlewareFunc {
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			c.Set(messageAttributesKey, fn)
+			return n(c)
+		}
+	}
+}
+
+// Enqueue sends the payload to the specified queue using the configured enqueuer
+// String and byte slice payloads are sent as-is, all other values are marshaled
+// using the configured codec. Request correlation attributes are added to the message.
+func Enqueue(c Context, queue string, payload interface{}) error {
+	e, ok := c.Get(enqueuerKey).(Enqueuer)
+	if !ok || e == nil {
+		return ErrNoEnqueuer
+	}
+
+	body, err := marshalPayload(contextCodec(c), payload)
+	if err != nil {
+		return err
+	}
+
+	return e.Enqueue(c.Context(), &Message{
+		Queue:      queue,
+		Body:       body,
+		Attributes: correlationAttributes(c),
+	})
+}
+
+// correlationAttributes returns the request correlation attributes
+// The lambda request id and trace id are included if available, along with any
+// attributes returned by the configured func.
+func correlationAttributes(c Context) map[string]string {
+	a := map[string]string{}
+
+	if lc, ok := lambdacontext.FromContext(c.Context()); ok {
+		a["RequestId"] = lc.AwsRequestID
+	}
+
+	if id := traceID(c); id != "" {
+		a["TraceId"] = id
+	}
+
+	if fn, ok := c.Get(messageAttributesKey).(func(Context) map[string]string); ok && fn != nil {
+		for k, v := range fn(c) {
+			a[k] = v
+		}
+	}
+
+	return a
+}
+
+func traceID(c Context) string {
+	if id := c.Request().Header.Get("X-Amzn-Trace-Id"); id != "" {
+		return id
+	}
+
+	return os.Getenv("_X_AMZN_TRACE_ID")
+}
+
+func marshalPayload(codec Codec, v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case []byte:
+		return string(t), nil
+	}
+
+	b, err := codec.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
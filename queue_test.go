@@ -0,0 +1,118 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestEnqueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		enqueuer func(*rack.Message) error
+		payload  interface{}
+		exp      *rack.Message
+		err      bool
+	}{
+		{
+			name:    "should return an error if no enqueuer is configured",
+			payload: "body",
+			err:     true,
+		},
+		{
+			name: "should return enqueuer errors",
+			enqueuer: func(*rack.Message) error {
+				return errors.New("error")
+			},
+			payload: "body",
+			err:     true,
+		},
+		{
+			name:     "should return marshal errors",
+			enqueuer: func(*rack.Message) error { return nil },
+			payload:  make(chan struct{}),
+			err:      true,
+		},
+		{
+			name:     "should enqueue the message",
+			enqueuer: func(*rack.Message) error { return nil },
+			payload:  map[string]string{"key": "value"},
+			exp: &rack.Message{
+				Queue: "queue",
+				Body:  `{"key":"value"}`,
+				Attributes: map[string]string{
+					"RequestId": "requestid",
+					"TraceId":   "traceid",
+					"Tenant":    "tenant",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var act *rack.Message
+
+			m := []rack.MiddlewareFunc{
+				rack.WithMessageAttributes(func(rack.Context) map[string]string {
+					return map[string]string{"Tenant": "tenant"}
+				}),
+			}
+
+			if tt.enqueuer != nil {
+				m = append(m, rack.WithEnqueuer(rack.EnqueuerFunc(func(_ context.Context, m *rack.Message) error {
+					act = m
+					return tt.enqueuer(m)
+				})))
+			}
+
+			h := rack.NewWithConfig(rack.Config{Middleware: rack.Chain(m...)}, func(c rack.Context) error {
+				err := rack.Enqueue(c, "queue", tt.payload)
+				assertErrorExists(t, err, tt.err)
+
+				return c.NoContent(http.StatusAccepted)
+			})
+
+			ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+				AwsRequestID: "requestid",
+			})
+
+			p := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"X-Amzn-Trace-Id": "traceid"}
+			})
+
+			_, err := h.Invoke(ctx, p)
+			assertErrorExists(t, err, false)
+
+			if tt.exp != nil {
+				assertDeepEqual(t, act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestEnqueue_Codec(t *testing.T) {
+	t.Run("should marshal the payload using the configured codec", func(t *testing.T) {
+		var act *rack.Message
+
+		h := rack.NewWithConfig(rack.Config{
+			Codec: rack.NewJSONCodec(rack.JSONOptions{Int64AsString: true}),
+			Middleware: rack.WithEnqueuer(rack.EnqueuerFunc(func(_ context.Context, m *rack.Message) error {
+				act = m
+				return nil
+			})),
+		}, func(c rack.Context) error {
+			return rack.Enqueue(c, "queue", map[string]int64{"id": 1})
+		})
+
+		_, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, act.Body, `{"id":"1"}`)
+	})
+}
@@ -0,0 +1,130 @@
+package rack
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type (
+	// QuotaPeriod represents a quota period
+	QuotaPeriod int
+
+	// QuotaOptions represents tenant quota options
+	QuotaOptions struct {
+		// Cache stores the usage counters, defaulting to a container-scoped memory cache
+		Cache Cache
+
+		// Tenant returns the tenant identity
+		// Requests with an empty tenant identity are not metered.
+		Tenant func(Context) string
+
+		// Limit returns the quota for the tenant, where zero indicates no limit
+		Limit func(c Context, tenant string) int64
+
+		// Period is the quota period, defaulting to QuotaMonthly
+		Period QuotaPeriod
+
+		// Now returns the current time, defaulting to time.Now
+		Now func() time.Time
+	}
+)
+
+const (
+	// QuotaMonthly resets usage at the start of each calendar month
+	QuotaMonthly QuotaPeriod = iota
+
+	// QuotaDaily resets usage at the start of each day
+	QuotaDaily
+)
+
+// Quota usage response headers
+const (
+	QuotaLimitHeader     = "X-Quota-Limit"
+	QuotaRemainingHeader = "X-Quota-Remaining"
+	QuotaResetHeader     = "X-Quota-Reset"
+)
+
+// ErrQuotaExceeded indicates that the tenant has exceeded the quota for the current period
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Quota returns a middleware func that meters requests against per-tenant quotas
+// Usage is counted per tenant for each UTC calendar period, and the limit, remaining
+// requests and seconds until reset are written to the response headers. Requests
+// that exceed the quota receive a 429 error with a Retry-After header, and are not
+// counted towards usage. The func panics if no tenant or limit func is specified.
+func Quota(o QuotaOptions) MiddlewareFunc {
+	if o.Tenant == nil || o.Limit == nil {
+		panic("rack: quota requires tenant and limit funcs")
+	}
+
+	cache := o.Cache
+	if cache == nil {
+		cache = NewMemoryCache()
+	}
+
+	now := o.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			tenant := o.Tenant(c)
+			if tenant == "" {
+				return n(c)
+			}
+
+			limit := o.Limit(c, tenant)
+			if limit <= 0 {
+				return n(c)
+			}
+
+			t := now().UTC()
+			start, end := o.Period.bounds(t)
+			key := "quota#" + tenant + "#" + strconv.FormatInt(start.Unix(), 10)
+
+			used, err := cache.Increment(c.Context(), key, 1, end.Sub(t))
+			if err != nil {
+				return err
+			}
+
+			exceeded := used > limit
+			if exceeded {
+				// rejected requests are not counted towards usage
+				if _, err = cache.Increment(c.Context(), key, -1, end.Sub(t)); err != nil {
+					return err
+				}
+			}
+
+			remaining := limit - used
+			if remaining < 0 {
+				remaining = 0
+			}
+
+			h := c.Response().Headers
+			h.Set(QuotaLimitHeader, strconv.FormatInt(limit, 10))
+			h.Set(QuotaRemainingHeader, strconv.FormatInt(remaining, 10))
+			h.Set(QuotaResetHeader, formatRetryAfter(end.Sub(t)))
+
+			if exceeded {
+				return WrapError(http.StatusTooManyRequests, ErrQuotaExceeded).
+					WithHeader("Retry-After", formatRetryAfter(end.Sub(t)))
+			}
+
+			return n(c)
+		}
+	}
+}
+
+// bounds returns the start and end of the period containing the specified time
+func (p QuotaPeriod) bounds(t time.Time) (time.Time, time.Time) {
+	if p == QuotaDaily {
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	}
+
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
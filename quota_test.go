@@ -0,0 +1,222 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestQuota(t *testing.T) {
+	type result struct {
+		status    int
+		remaining string
+		reset     string
+		retry     string
+	}
+
+	now := time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		period   rack.QuotaPeriod
+		tenants  []string
+		limit    int64
+		advance  time.Duration
+		exp      []result
+		handlers int
+	}{
+		{
+			name:    "should not meter requests without a tenant",
+			tenants: []string{"", ""},
+			limit:   1,
+			exp: []result{
+				{status: http.StatusOK},
+				{status: http.StatusOK},
+			},
+			handlers: 2,
+		},
+		{
+			name:    "should not meter tenants without a limit",
+			tenants: []string{"a", "a"},
+			exp: []result{
+				{status: http.StatusOK},
+				{status: http.StatusOK},
+			},
+			handlers: 2,
+		},
+		{
+			name:    "should reject requests that exceed the monthly quota",
+			tenants: []string{"a", "a", "a"},
+			limit:   2,
+			exp: []result{
+				{status: http.StatusOK, remaining: "1", reset: "3600"},
+				{status: http.StatusOK, remaining: "0", reset: "3600"},
+				{status: http.StatusTooManyRequests, remaining: "0", reset: "3600", retry: "3600"},
+			},
+			handlers: 2,
+		},
+		{
+			name:    "should not count rejected requests",
+			tenants: []string{"a", "a", "a"},
+			limit:   1,
+			exp: []result{
+				{status: http.StatusOK, remaining: "0", reset: "3600"},
+				{status: http.StatusTooManyRequests, remaining: "0", reset: "3600", retry: "3600"},
+				{status: http.StatusTooManyRequests, remaining: "0", reset: "3600", retry: "3600"},
+			},
+			handlers: 1,
+		},
+		{
+			name:    "should meter tenants separately",
+			tenants: []string{"a", "b", "a"},
+			limit:   1,
+			exp: []result{
+				{status: http.StatusOK, remaining: "0", reset: "3600"},
+				{status: http.StatusOK, remaining: "0", reset: "3600"},
+				{status: http.StatusTooManyRequests, remaining: "0", reset: "3600", retry: "3600"},
+			},
+			handlers: 2,
+		},
+		{
+			name:    "should reset usage at the end of the period",
+			tenants: []string{"a", "a"},
+			limit:   1,
+			advance: time.Hour,
+			exp: []result{
+				{status: http.StatusOK, remaining: "0", reset: "3600"},
+				{status: http.StatusOK, remaining: "0", reset: "2592000"},
+			},
+			handlers: 2,
+		},
+		{
+			name:    "should use the daily period",
+			period:  rack.QuotaDaily,
+			tenants: []string{"a", "a"},
+			limit:   1,
+			advance: time.Hour,
+			exp: []result{
+				{status: http.StatusOK, remaining: "0", reset: "3600"},
+				{status: http.StatusOK, remaining: "0", reset: "86400"},
+			},
+			handlers: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t0 := now
+			handlers := 0
+
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.Quota(rack.QuotaOptions{
+					Tenant: func(c rack.Context) string { return c.Request().HeaderValue("X-Tenant") },
+					Limit:  func(rack.Context, string) int64 { return tt.limit },
+					Period: tt.period,
+					Now:    func() time.Time { return t0 },
+				}),
+			}, func(c rack.Context) error {
+				handlers++
+				return c.NoContent(http.StatusOK)
+			})
+
+			for i, tenant := range tt.tenants {
+				if i > 0 {
+					t0 = t0.Add(tt.advance)
+				}
+
+				b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+					r.Headers = map[string]string{"X-Tenant": tenant}
+				}))
+				assertErrorExists(t, err, false)
+
+				res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+				assertDeepEqual(t, result{
+					status:    res.StatusCode,
+					remaining: res.Headers[rack.QuotaRemainingHeader],
+					reset:     res.Headers[rack.QuotaResetHeader],
+					retry:     res.Headers["Retry-After"],
+				}, tt.exp[i])
+			}
+
+			assertDeepEqual(t, handlers, tt.handlers)
+		})
+	}
+
+	t.Run("should return cache errors", func(t *testing.T) {
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.Quota(rack.QuotaOptions{
+				Cache:  errorCache{},
+				Tenant: func(rack.Context) string { return "a" },
+				Limit:  func(rack.Context, string) int64 { return 1 },
+			}),
+		}, func(c rack.Context) error {
+			t.Error("handler invoked")
+			return nil
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		assertDeepEqual(t, res.StatusCode, http.StatusInternalServerError)
+	})
+
+	panics := []struct {
+		name string
+		opts rack.QuotaOptions
+	}{
+		{
+			name: "should panic if no tenant func is specified",
+			opts: rack.QuotaOptions{Limit: func(rack.Context, string) int64 { return 1 }},
+		},
+		{
+			name: "should panic if no limit func is specified",
+			opts: rack.QuotaOptions{Tenant: func(rack.Context) string { return "a" }},
+		},
+	}
+
+	for _, tt := range panics {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("got nil, expected panic")
+				}
+			}()
+
+			rack.Quota(tt.opts)
+		})
+	}
+}
+
+type errorCache struct{}
+
+func (errorCache) Get(context.Context, string) ([]byte, bool, error) {
+	return nil, false, errors.New("error")
+}
+
+func (errorCache) Set(context.Context, string, []byte, time.Duration) error {
+	return errors.New("error")
+}
+
+func (errorCache) SetIfAbsent(context.Context, string, []byte, time.Duration) (bool, error) {
+	return false, errors.New("error")
+}
+
+func (errorCache) CompareAndSwap(context.Context, string, []byte, []byte, time.Duration) (bool, error) {
+	return false, errors.New("error")
+}
+
+func (errorCache) Delete(context.Context, string) error {
+	return errors.New("error")
+}
+
+func (errorCache) Increment(context.Context, string, int64, time.Duration) (int64, error) {
+	return 0, errors.New("error")
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/url"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
 )
@@ -18,29 +19,73 @@ type (
 
 	// Config represent handler configuration
 	Config struct {
-		Resolver        Resolver
-		Middleware      MiddlewareFunc
-		OnBind          func(Context, interface{}) error
-		OnError         func(Context, error) error
-		OnEmptyResponse HandlerFunc
+		Resolver             Resolver
+		Middleware           MiddlewareFunc
+		OnBind               func(Context, interface{}) error
+		OnError              func(Context, error) error
+		OnEmptyResponse      HandlerFunc
+		OnComplete           func(Context, FinalizedResponse, error)
+		OnWarmup             func(context.Context) error
+		ErrorCatalog         Catalog
+		Codec                Codec
+		BindLimits           BindLimits
+		OnDeferError         func(Context, error)
+		DeferTimeout         time.Duration
+		WritePolicy          WritePolicy
+		MaxHeaderSize        int
+		OnHeaderSizeExceeded func(Context, error)
+		BinaryContentTypes   []string
+		JSONETag             bool
+		SparseFields         bool
+		PreserveBody         bool
+		Recover              bool
+		Strict               bool
 	}
 
 	// Request represents a canonical request type
+	// The request is created by the processor and is never modified by the framework
+	// once it has been passed to the handler. Middleware may modify or replace the
+	// request, so components that require the original values should use Clone. Base64
+	// encoded bodies are decoded by the built-in processors, and RawBody contains the
+	// decoded body bytes if PreserveBody is enabled.
 	Request struct {
-		Method  string
-		RawPath string
-		Path    map[string]string
-		Query   url.Values
-		Header  http.Header
-		Body    string
-		Event   interface{}
+		Method          string
+		Host            string
+		Protocol        string
+		RawPath         string
+		RawQuery        string
+		Path            map[string]string
+		Query           url.Values
+		Header          http.Header
+		SourceIP        string
+		UserAgent       string
+		Stage           string
+		ContentLength   int64
+		Body            string
+		RawBody         []byte
+		IsBase64Encoded bool
+		Event           interface{}
 	}
 
 	// Response represents a canonical response type
+	// The response is committed by the first Context write, after which subsequent
+	// writes are handled according to the configured WritePolicy. StatusDescription is
+	// only used by ALB events, and defaults to the status text for the status code.
 	Response struct {
-		StatusCode int
-		Headers    http.Header
-		Body       string
+		StatusCode        int
+		StatusDescription string
+		Headers           http.Header
+		Body              string
+		IsBase64Encoded   bool
+
+		committed bool
+	}
+
+	// FinalizedResponse represents a marshaled response
+	// Payload will be nil if the invocation failed before or during marshaling.
+	FinalizedResponse struct {
+		*Response
+		Payload []byte
 	}
 
 	invokeFunc func(context.Context, []byte) ([]byte, error)
@@ -57,6 +102,10 @@ func NewWithConfig(c Config, h HandlerFunc) lambda.Handler {
 		h = c.Middleware(h)
 	}
 
+	if c.Recover {
+		h = Recover(h)
+	}
+
 	resolver := c.Resolver
 	if resolver == nil {
 		resolver = defaultResolver
@@ -64,7 +113,7 @@ func NewWithConfig(c Config, h HandlerFunc) lambda.Handler {
 
 	onError := c.OnError
 	if onError == nil {
-		onError = defaultErrorHandler
+		onError = newErrorHandler(c.ErrorCatalog)
 	}
 
 	onBind := c.OnBind
@@ -79,7 +128,58 @@ func NewWithConfig(c Config, h HandlerFunc) lambda.Handler {
 		}
 	}
 
-	return invokeFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+	onComplete := c.OnComplete
+	if onComplete == nil {
+		onComplete = func(Context, FinalizedResponse, error) {}
+	}
+
+	onDeferError := c.OnDeferError
+	if onDeferError == nil {
+		onDeferError = func(Context, error) {}
+	}
+
+	deferTimeout, etag := c.DeferTimeout, c.JSONETag
+	sparse, bindLimits := c.SparseFields, c.BindLimits
+
+	codec := c.Codec
+	if codec == nil {
+		codec = DefaultJSONCodec
+	}
+
+	strict, preserveBody := c.Strict, c.PreserveBody
+
+	policy := c.WritePolicy
+	if strict {
+		policy = WriteError
+	}
+
+	encodeBinary := func(*Response) {}
+	if len(c.BinaryContentTypes) > 0 {
+		encodeBinary = newBinaryEncoder(c.BinaryContentTypes)
+	}
+
+	var validators []func(Context, *Response) error
+	if strict {
+		validators = append(validators, func(_ Context, r *Response) error {
+			return validateResponse(r)
+		})
+	}
+	if c.MaxHeaderSize > 0 {
+		validators = append(validators, newHeaderSizeValidator(c.MaxHeaderSize, c.OnHeaderSizeExceeded))
+	}
+
+	handleError := func(c *handlerContext, err error) error {
+		// the error handler replaces any existing response
+		c.response.committed = false
+
+		for k, vs := range errorHeader(err) {
+			c.response.Headers[k] = append(c.response.Headers[k], vs...)
+		}
+
+		return c.onError(c, err)
+	}
+
+	invoke := func(c *handlerContext, payload []byte) ([]byte, error) {
 		p, err := resolver.Resolve(payload)
 		if err != nil {
 			return nil, err
@@ -90,31 +190,82 @@ func NewWithConfig(c Config, h HandlerFunc) lambda.Handler {
 			return nil, err
 		}
 
-		c := &handlerContext{
-			ctx:     ctx,
-			request: req,
-			response: &Response{
-				Headers: http.Header{},
-			},
-			onBind: onBind,
-			mu:     new(sync.RWMutex),
+		// custom processors may not initialize the request maps
+		req.ensureMaps()
+
+		if preserveBody && req.RawBody == nil {
+			// retain a copy of the decoded body before any middleware modifies the request
+			req.RawBody = []byte(req.Body)
 		}
 
-		if err = h(c); err != nil {
-			if err = onError(c, err); err != nil {
+		c.request = req
+
+		err = h(c)
+		c.runFinish(err)
+
+		if err != nil {
+			if err = handleError(c, err); err != nil {
 				return nil, err
 			}
 		}
 
 		if c.response.StatusCode == 0 {
 			if err = onEmptyResponse(c); err != nil {
-				if err = onError(c, err); err != nil {
+				if err = handleError(c, err); err != nil {
 					return nil, err
 				}
 			}
 		}
 
+		encodeBinary(c.response)
+
+		for _, v := range validators {
+			if err = v(c, c.response); err != nil {
+				c.response = &Response{Headers: http.Header{}}
+				if err = handleError(c, err); err != nil {
+					return nil, err
+				}
+
+				break
+			}
+		}
+
 		return p.MarshalResponse(c.response)
+	}
+
+	onWarmup := c.OnWarmup
+
+	return invokeFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+		if onWarmup != nil && IsWarmupEvent(payload) {
+			return nil, onWarmup(ctx)
+		}
+
+		c := &handlerContext{
+			ctx:      ctx,
+			request:  new(Request),
+			rawEvent: payload,
+			response: &Response{
+				Headers: http.Header{},
+			},
+			onBind:     onBind,
+			onError:    onError,
+			policy:     policy,
+			etag:       etag,
+			sparse:     sparse,
+			codec:      codec,
+			bindLimits: bindLimits,
+			mu:         new(sync.RWMutex),
+		}
+
+		b, err := invoke(c, payload)
+
+		for _, derr := range c.runDeferred(deferTimeout) {
+			onDeferError(c, derr)
+		}
+
+		onComplete(c, FinalizedResponse{Response: c.response, Payload: b}, err)
+
+		return b, err
 	})
 }
 
@@ -128,16 +279,43 @@ func Chain(m ...MiddlewareFunc) MiddlewareFunc {
 	})
 }
 
+// Committed returns true if the response has been written by the handler context
+// Setting StatusCode directly does not commit the response.
+func (r *Response) Committed() bool {
+	return r != nil && r.committed
+}
+
 func (fn invokeFunc) Invoke(ctx context.Context, payload []byte) ([]byte, error) {
 	return fn(ctx, payload)
 }
 
-func defaultErrorHandler(c Context, err error) error {
-	res := struct {
-		Message string `json:"message"`
-	}{
-		Message: err.Error(),
+// Recover is a middleware func that recovers handler panics
+// Recovered panics are returned as a *PanicError, allowing the error handler
+// to distinguish them from ordinary handler errors.
+func Recover(n HandlerFunc) HandlerFunc {
+	return func(c Context) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = NewPanicError(v)
+			}
+		}()
+
+		return n(c)
 	}
+}
 
-	return c.JSON(StatusCode(err), &res)
+func newErrorHandler(cat Catalog) func(Context, error) error {
+	return func(c Context, err error) error {
+		code := ErrorCode(err)
+
+		res := struct {
+			Code    string `json:"code,omitempty"`
+			Message string `json:"message"`
+		}{
+			Code:    code,
+			Message: localize(c, cat, code, err.Error()),
+		}
+
+		return c.JSON(StatusCode(err), &res)
+	}
 }
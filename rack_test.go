@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 
@@ -44,6 +45,42 @@ func TestNew(t *testing.T) {
 				r.Body = `{"message":"error"}`
 			}),
 		},
+		{
+			name: "should include error codes",
+			handler: func(rack.Context) error {
+				return rack.WrapError(http.StatusNotFound, errors.New("error")).WithCode("NOT_FOUND")
+			},
+			payload: newV2Request(nil),
+			exp: newV2Response(func(r *events.APIGatewayV2HTTPResponse) {
+				r.StatusCode = http.StatusNotFound
+				r.Headers = map[string]string{
+					"Content-Type": "application/json",
+				}
+				r.MultiValueHeaders = map[string][]string{
+					"Content-Type": {"application/json"},
+				}
+				r.Body = `{"code":"NOT_FOUND","message":"error"}`
+			}),
+		},
+		{
+			name: "should write error headers",
+			handler: func(rack.Context) error {
+				return rack.TooManyRequests(time.Minute)
+			},
+			payload: newV2Request(nil),
+			exp: newV2Response(func(r *events.APIGatewayV2HTTPResponse) {
+				r.StatusCode = http.StatusTooManyRequests
+				r.Headers = map[string]string{
+					"Content-Type": "application/json",
+					"Retry-After":  "60",
+				}
+				r.MultiValueHeaders = map[string][]string{
+					"Content-Type": {"application/json"},
+					"Retry-After":  {"60"},
+				}
+				r.Body = `{"message":"too many requests"}`
+			}),
+		},
 		{
 			name: "should return a default response if none is written",
 			handler: func(rack.Context) error {
@@ -193,6 +230,33 @@ func TestNewWithConfig(t *testing.T) {
 				r.Body = "body"
 			}),
 		},
+		{
+			name: "should recover panics",
+			setup: func(c *rack.Config) {
+				c.Recover = true
+				c.OnError = func(c rack.Context, err error) error {
+					var pe *rack.PanicError
+					if !errors.As(err, &pe) {
+						t.Errorf("got %T, expected *rack.PanicError", err)
+					}
+					return c.String(http.StatusInternalServerError, err.Error())
+				}
+			},
+			handler: func(c rack.Context) error {
+				panic("error")
+			},
+			payload: newV2Request(nil),
+			exp: newV2Response(func(r *events.APIGatewayV2HTTPResponse) {
+				r.StatusCode = http.StatusInternalServerError
+				r.Headers = map[string]string{
+					"Content-Type": "text/plain",
+				}
+				r.MultiValueHeaders = map[string][]string{
+					"Content-Type": {"text/plain"},
+				}
+				r.Body = "panic: error"
+			}),
+		},
 	}
 
 	for _, tt := range tests {
@@ -210,6 +274,192 @@ func TestNewWithConfig(t *testing.T) {
 	}
 }
 
+func TestNewWithConfig_OnComplete(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    rack.HandlerFunc
+		payload    []byte
+		expPayload bool
+		expStatus  int
+		err        bool
+	}{
+		{
+			name:    "should be invoked on failure",
+			payload: []byte("{"),
+			err:     true,
+		},
+		{
+			name: "should be invoked on success",
+			handler: func(c rack.Context) error {
+				return c.NoContent(http.StatusAccepted)
+			},
+			payload:    newV2Request(nil),
+			expPayload: true,
+			expStatus:  http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var invoked bool
+
+			h := rack.NewWithConfig(rack.Config{
+				OnComplete: func(c rack.Context, r rack.FinalizedResponse, err error) {
+					invoked = true
+
+					assertErrorExists(t, err, tt.err)
+					if act := r.Payload != nil; act != tt.expPayload {
+						t.Errorf("got %v, expected %v", act, tt.expPayload)
+					}
+					if r.StatusCode != tt.expStatus {
+						t.Errorf("got %d, expected %d", r.StatusCode, tt.expStatus)
+					}
+				},
+			}, tt.handler)
+
+			act, err := h.Invoke(context.Background(), tt.payload)
+			assertErrorExists(t, err, tt.err)
+
+			if !invoked {
+				t.Error("got false, expected true")
+			}
+			if tt.expPayload && act == nil {
+				t.Error("got nil, expected a payload")
+			}
+		})
+	}
+}
+
+func TestNewWithConfig_PreserveBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		preserve bool
+		body     string
+		encoded  bool
+		exp      []byte
+		expBody  string
+	}{
+		{
+			name:    "should not preserve the body by default",
+			body:    `{"key":"value"}`,
+			expBody: `{"key":"value"}`,
+		},
+		{
+			name:     "should preserve the body",
+			preserve: true,
+			body:     `{"key":"value"}`,
+			exp:      []byte(`{"key":"value"}`),
+			expBody:  `{"key":"value"}`,
+		},
+		{
+			name:    "should not preserve encoded bodies by default",
+			body:    "eyJrZXkiOiJ2YWx1ZSJ9",
+			encoded: true,
+			expBody: `{"key":"value"}`,
+		},
+		{
+			name:     "should preserve the decoded body of encoded bodies",
+			preserve: true,
+			body:     "eyJrZXkiOiJ2YWx1ZSJ9",
+			encoded:  true,
+			exp:      []byte(`{"key":"value"}`),
+			expBody:  `{"key":"value"}`,
+		},
+		{
+			name:     "should preserve decoded binary bodies",
+			preserve: true,
+			body:     "/wCA",
+			encoded:  true,
+			exp:      []byte{0xff, 0x00, 0x80},
+			expBody:  "\xff\x00\x80",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var act []byte
+			var actBody string
+
+			h := rack.NewWithConfig(rack.Config{
+				PreserveBody: tt.preserve,
+			}, func(c rack.Context) error {
+				act = c.Request().RawBody
+				actBody = c.Request().Body
+				return c.NoContent(http.StatusOK)
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Body = tt.body
+				r.IsBase64Encoded = tt.encoded
+			}))
+
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, act, tt.exp)
+			assertDeepEqual(t, actBody, tt.expBody)
+		})
+	}
+}
+
+func TestResponse_Committed(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(rack.Context) error
+		exp  bool
+	}{
+		{
+			name: "should return false if the response has not been written",
+			fn: func(c rack.Context) error {
+				return nil
+			},
+			exp: false,
+		},
+		{
+			name: "should return false if the status code is set directly",
+			fn: func(c rack.Context) error {
+				c.Response().StatusCode = http.StatusOK
+				return nil
+			},
+			exp: false,
+		},
+		{
+			name: "should return true if the response has been written",
+			fn: func(c rack.Context) error {
+				return c.NoContent(http.StatusAccepted)
+			},
+			exp: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var act bool
+			m := func(n rack.HandlerFunc) rack.HandlerFunc {
+				return func(c rack.Context) error {
+					err := n(c)
+					act = c.Response().Committed()
+					return err
+				}
+			}
+
+			h := rack.NewWithConfig(rack.Config{Middleware: m}, tt.fn)
+
+			_, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			if act != tt.exp {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+
+	t.Run("should handle nil responses", func(t *testing.T) {
+		var r *rack.Response
+		if r.Committed() {
+			t.Error("got true, expected false")
+		}
+	})
+}
+
 func TestChain(t *testing.T) {
 	mw := func(sb *strings.Builder, s string) rack.MiddlewareFunc {
 		return func(n rack.HandlerFunc) rack.HandlerFunc {
@@ -0,0 +1,203 @@
+package rack
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PathValue returns the path parameter with the specified key
+// An empty string is returned if the request or parameter does not exist.
+func (r *Request) PathValue(key string) string {
+	if r == nil {
+		return ""
+	}
+
+	return r.Path[key]
+}
+
+// QueryValue returns the first query value with the specified key
+// An empty string is returned if the request or value does not exist.
+func (r *Request) QueryValue(key string) string {
+	if r == nil {
+		return ""
+	}
+
+	return r.Query.Get(key)
+}
+
+// HeaderValue returns the first header value with the specified key
+// An empty string is returned if the request or value does not exist.
+func (r *Request) HeaderValue(key string) string {
+	if r == nil {
+		return ""
+	}
+
+	return r.Header.Get(key)
+}
+
+// Clone returns a deep copy of the request
+// The maps and raw body are copied, so the clone is unaffected by middleware
+// that modifies the request. The event is shared, and must be treated as read-only.
+func (r *Request) Clone() *Request {
+	if r == nil {
+		return nil
+	}
+
+	c := *r
+
+	if r.Path != nil {
+		c.Path = make(map[string]string, len(r.Path))
+		for k, v := range r.Path {
+			c.Path[k] = v
+		}
+	}
+
+	if r.Query != nil {
+		c.Query = make(url.Values, len(r.Query))
+		for k, vs := range r.Query {
+			c.Query[k] = append([]string(nil), vs...)
+		}
+	}
+
+	c.Header = r.Header.Clone()
+
+	if r.RawBody != nil {
+		c.RawBody = append([]byte(nil), r.RawBody...)
+	}
+
+	return &c
+}
+
+// Cookie returns the named request cookie
+// http.ErrNoCookie is returned if the cookie does not exist.
+func (r *Request) Cookie(name string) (*http.Cookie, error) {
+	if r == nil {
+		return nil, http.ErrNoCookie
+	}
+
+	return (&http.Request{Header: r.Header}).Cookie(name)
+}
+
+// Cookies returns the request cookies
+// API Gateway V2 event cookies are included, as they are mapped to the Cookie header.
+func (r *Request) Cookies() []*http.Cookie {
+	if r == nil {
+		return nil
+	}
+
+	return (&http.Request{Header: r.Header}).Cookies()
+}
+
+// ensureMaps guarantees that the request maps are non-nil
+func (r *Request) ensureMaps() {
+	if r.Path == nil {
+		r.Path = map[string]string{}
+	}
+
+	if r.Query == nil {
+		r.Query = url.Values{}
+	}
+
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+}
+
+// normalize decodes the request body and populates fields that are not present in the event
+// Host and UserAgent fall back to the request headers, and the raw query is encoded
+// from the query values if the event does not contain it.
+func (r *Request) normalize() *Request {
+	if r.Host == "" {
+		r.Host = r.Header.Get("Host")
+	}
+
+	if r.UserAgent == "" {
+		r.UserAgent = r.Header.Get("User-Agent")
+	}
+
+	if r.RawQuery == "" {
+		r.RawQuery = r.Query.Encode()
+	}
+
+	r.decodeBody()
+	r.ContentLength = int64(len(r.Body))
+
+	return r
+}
+
+// decodeBody decodes base64 encoded request bodies
+// Bodies that cannot be decoded are left unchanged, so that the error is returned
+// when the body is read.
+func (r *Request) decodeBody() {
+	if !r.IsBase64Encoded {
+		return
+	}
+
+	b, err := base64.StdEncoding.DecodeString(r.Body)
+	if err != nil {
+		return
+	}
+
+	r.Body = string(b)
+	r.IsBase64Encoded = false
+}
+
+// BodyReader returns a reader for the request body
+// Base64 encoded bodies are decoded as the reader is consumed, and decoding errors
+// are returned by Read. An empty reader is returned if the request does not exist.
+func (r *Request) BodyReader() io.Reader {
+	if r == nil {
+		return strings.NewReader("")
+	}
+
+	if r.IsBase64Encoded {
+		return base64.NewDecoder(base64.StdEncoding, strings.NewReader(r.Body))
+	}
+
+	return strings.NewReader(r.Body)
+}
+
+// URL returns the absolute url of the request
+// The scheme is read from the X-Forwarded-Proto header, defaulting to https. The path
+// is the request path, which excludes the stage for API Gateway proxy events.
+func (r *Request) URL() *url.URL {
+	if r == nil {
+		return &url.URL{}
+	}
+
+	u := &url.URL{
+		Scheme:   "https",
+		Host:     r.Host,
+		Path:     r.RawPath,
+		RawQuery: r.RawQuery,
+	}
+
+	if p, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ","); p != "" {
+		u.Scheme = strings.TrimSpace(p)
+	}
+
+	return u
+}
+
+// EventAs returns the request event as the specified type
+// Built-in processors store pointers to the event, for example
+// *events.APIGatewayV2HTTPRequest, but value types are also supported and receive
+// a copy of the event. False is returned with the zero value if the processor
+// produced a different event type.
+func EventAs[T any](c Context) (T, bool) {
+	var zero T
+
+	switch e := c.Request().Event.(type) {
+	case T:
+		return e, true
+	case *T:
+		if e != nil {
+			return *e, true
+		}
+	}
+
+	return zero, false
+}
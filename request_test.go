@@ -0,0 +1,380 @@
+package rack_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestRequest_Values(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *rack.Request
+		exp  [3]string
+	}{
+		{
+			name: "should handle nil requests",
+			exp:  [3]string{"", "", ""},
+		},
+		{
+			name: "should handle nil maps",
+			req:  &rack.Request{},
+			exp:  [3]string{"", "", ""},
+		},
+		{
+			name: "should return the values",
+			req: &rack.Request{
+				Path:   map[string]string{"id": "p"},
+				Query:  url.Values{"id": {"q1", "q2"}},
+				Header: http.Header{"Id": {"h"}},
+			},
+			exp: [3]string{"p", "q1", "h"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act := [3]string{
+				tt.req.PathValue("id"),
+				tt.req.QueryValue("id"),
+				tt.req.HeaderValue("id"),
+			}
+
+			if act != tt.exp {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestRequest_Cookie(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *rack.Request
+		exp  string
+		err  bool
+	}{
+		{
+			name: "should handle nil requests",
+			err:  true,
+		},
+		{
+			name: "should return an error if the cookie does not exist",
+			req:  &rack.Request{Header: http.Header{"Cookie": {"a=1"}}},
+			err:  true,
+		},
+		{
+			name: "should return the cookie",
+			req:  &rack.Request{Header: http.Header{"Cookie": {"a=1; id=v"}}},
+			exp:  "v",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := tt.req.Cookie("id")
+			assertErrorExists(t, err, tt.err)
+			if err == nil {
+				assertDeepEqual(t, act.Value, tt.exp)
+			}
+		})
+	}
+}
+
+func TestRequest_BodyReader(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *rack.Request
+		exp  string
+		err  bool
+	}{
+		{
+			name: "should handle nil requests",
+		},
+		{
+			name: "should read the body",
+			req:  &rack.Request{Body: "body"},
+			exp:  "body",
+		},
+		{
+			name: "should decode encoded bodies",
+			req:  &rack.Request{Body: "Ym9keQ==", IsBase64Encoded: true},
+			exp:  "body",
+		},
+		{
+			name: "should return an error if the body cannot be decoded",
+			req:  &rack.Request{Body: "body!", IsBase64Encoded: true},
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := io.ReadAll(tt.req.BodyReader())
+			assertErrorExists(t, err, tt.err)
+			if err == nil {
+				assertDeepEqual(t, string(b), tt.exp)
+			}
+		})
+	}
+}
+
+func TestRequest_URL(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *rack.Request
+		exp  string
+	}{
+		{
+			name: "should handle nil requests",
+			exp:  "",
+		},
+		{
+			name: "should default to https",
+			req:  &rack.Request{Host: "example.com", RawPath: "/orders", RawQuery: "page=2"},
+			exp:  "https://example.com/orders?page=2",
+		},
+		{
+			name: "should use the forwarded protocol",
+			req: &rack.Request{
+				Host:    "example.com",
+				RawPath: "/orders",
+				Header:  http.Header{"X-Forwarded-Proto": {"http, https"}},
+			},
+			exp: "http://example.com/orders",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertDeepEqual(t, tt.req.URL().String(), tt.exp)
+		})
+	}
+
+	t.Run("should build the url from the event", func(t *testing.T) {
+		r, err := rack.APIGatewayV2HTTPEventProcessor.UnmarshalRequest(newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.RequestContext.DomainName = "api.example.com"
+			r.RequestContext.HTTP.Path = "/orders"
+			r.RawQueryString = "page=2&sort=id"
+		}))
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, r.URL().String(), "https://api.example.com/orders?page=2&sort=id")
+	})
+}
+
+func TestRequest_Cookies(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *rack.Request
+		exp  []string
+	}{
+		{
+			name: "should handle nil requests",
+		},
+		{
+			name: "should return the cookies",
+			req:  &rack.Request{Header: http.Header{"Cookie": {"a=1; b=2"}}},
+			exp:  []string{"a=1", "b=2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var act []string
+			for _, c := range tt.req.Cookies() {
+				act = append(act, c.String())
+			}
+
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+
+	t.Run("should return api gateway v2 cookies", func(t *testing.T) {
+		r, err := rack.APIGatewayV2HTTPEventProcessor.UnmarshalRequest(newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.Cookies = []string{"a=1", "b=2"}
+		}))
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, len(r.Cookies()), 2)
+	})
+}
+
+func TestEventAs(t *testing.T) {
+	payload := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+		r.RouteKey = "GET /orders"
+	})
+
+	h := rack.New(func(c rack.Context) error {
+		t.Run("should return pointer events", func(t *testing.T) {
+			e, ok := rack.EventAs[*events.APIGatewayV2HTTPRequest](c)
+			assertDeepEqual(t, ok, true)
+			assertDeepEqual(t, e.RouteKey, "GET /orders")
+		})
+
+		t.Run("should return value events", func(t *testing.T) {
+			e, ok := rack.EventAs[events.APIGatewayV2HTTPRequest](c)
+			assertDeepEqual(t, ok, true)
+			assertDeepEqual(t, e.RouteKey, "GET /orders")
+		})
+
+		t.Run("should return false for other event types", func(t *testing.T) {
+			e, ok := rack.EventAs[*events.APIGatewayProxyRequest](c)
+			assertDeepEqual(t, ok, false)
+			assertDeepEqual(t, e, (*events.APIGatewayProxyRequest)(nil))
+
+			_, ok = rack.EventAs[events.ALBTargetGroupRequest](c)
+			assertDeepEqual(t, ok, false)
+		})
+
+		return nil
+	})
+
+	_, err := h.Invoke(context.Background(), payload)
+	assertErrorExists(t, err, false)
+}
+
+func TestRequest_EmptyMaps(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor rack.Processor
+		payload   []byte
+	}{
+		{
+			name:      "should initialize proxy event maps",
+			processor: rack.APIGatewayProxyEventProcessor,
+			payload:   []byte(`{"httpMethod":"GET","path":"/","requestContext":{"apiId":"id"}}`),
+		},
+		{
+			name:      "should initialize v2 http event maps",
+			processor: rack.APIGatewayV2HTTPEventProcessor,
+			payload:   []byte(`{"version":"2.0","requestContext":{"apiId":"id","http":{"method":"GET","path":"/"}}}`),
+		},
+		{
+			name:      "should initialize alb event maps",
+			processor: rack.ALBTargetGroupEventProcessor,
+			payload:   []byte(`{"httpMethod":"GET","path":"/","requestContext":{"elb":{"targetGroupArn":"arn"}}}`),
+		},
+		{
+			name:      "should initialize custom processor maps",
+			processor: &nilMapProcessor{rack.APIGatewayV2HTTPEventProcessor},
+			payload:   []byte(`{"version":"2.0","requestContext":{"apiId":"id","http":{"method":"GET","path":"/"}}}`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := rack.Config{Resolver: rack.ResolveStatic(tt.processor)}
+
+			h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+				r := c.Request()
+				if r.Path == nil || r.Query == nil || r.Header == nil {
+					t.Errorf("got nil maps, expected non-nil")
+				}
+
+				r.Query.Set("k", "v")
+				r.Header.Set("k", "v")
+				r.Path["k"] = "v"
+				return nil
+			})
+
+			if _, err := h.Invoke(context.Background(), tt.payload); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+type nilMapProcessor struct {
+	rack.Processor
+}
+
+func (p *nilMapProcessor) UnmarshalRequest(payload []byte) (*rack.Request, error) {
+	r, err := p.Processor.UnmarshalRequest(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Path, r.Query, r.Header = nil, nil, nil
+	return r, nil
+}
+
+func TestRequest_Clone(t *testing.T) {
+	t.Run("should return nil for nil requests", func(t *testing.T) {
+		var r *rack.Request
+		if act := r.Clone(); act != nil {
+			t.Errorf("got %v, expected nil", act)
+		}
+	})
+
+	t.Run("should return a deep copy", func(t *testing.T) {
+		newRequest := func() *rack.Request {
+			return &rack.Request{
+				Method:  http.MethodPost,
+				RawPath: "/resource",
+				Path:    map[string]string{"id": "abc"},
+				Query:   url.Values{"q": {"v1", "v2"}},
+				Header:  http.Header{"X-Custom-Header": {"v"}},
+				Body:    "body",
+				RawBody: []byte("body"),
+			}
+		}
+
+		sut := newRequest()
+		act := sut.Clone()
+
+		sut.Path["id"] = "def"
+		sut.Query["q"][0] = "changed"
+		sut.Query.Add("q2", "v")
+		sut.Header.Set("X-Custom-Header", "changed")
+		sut.RawBody[0] = 'B'
+
+		assertDeepEqual(t, act, newRequest())
+	})
+}
+
+func TestRequest_Immutable(t *testing.T) {
+	t.Run("should not modify the request", func(t *testing.T) {
+		var exp *rack.Request
+
+		cfg := rack.Config{
+			Middleware: rack.Chain(
+				rack.WithCorrelationChain(rack.CorrelationChainOptions{Hop: "service"}),
+				func(n rack.HandlerFunc) rack.HandlerFunc {
+					return func(c rack.Context) error {
+						exp = c.Request().Clone()
+						return n(c)
+					}
+				},
+			),
+			PreserveBody: true,
+		}
+
+		h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+			var v map[string]interface{}
+			if err := c.Bind(&v); err != nil {
+				return err
+			}
+
+			_ = c.Path("id")
+			_ = c.Query("q")
+
+			assertDeepEqual(t, c.Request(), exp)
+			return c.JSON(http.StatusOK, v)
+		})
+
+		payload := newV2Request(func(e *events.APIGatewayV2HTTPRequest) {
+			e.Body = "eyJrIjoidiJ9"
+			e.IsBase64Encoded = true
+			e.Headers = map[string]string{"X-Correlation-Chain": "a"}
+		})
+
+		if _, err := h.Invoke(context.Background(), payload); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
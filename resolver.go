@@ -1,6 +1,13 @@
 package rack
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+)
 
 type (
 	// Resolver represents an event processor resolver
@@ -8,25 +15,75 @@ type (
 		Resolve(payload []byte) (Processor, error)
 	}
 
-	resolverFunc func([]byte) (Processor, error)
+	// ResolverFunc represents a resolver func
+	ResolverFunc func(payload []byte) (Processor, error)
+
+	// UnsupportedEventError represents an unsupported event type error
+	// The error describes the payload and the processors that were evaluated, and
+	// matches ErrUnsupportedEventType using errors.Is.
+	UnsupportedEventError struct {
+		keys       []string
+		version    string
+		source     string
+		processors []string
+	}
+
+	stickyResolver struct {
+		resolver Resolver
+		cached   Processor
+		mu       sync.RWMutex
+	}
 )
 
 var (
 	// ErrUnsupportedEventType indicates that the supplied event payload is not supported
 	ErrUnsupportedEventType = errors.New("unsupported event type")
 
-	defaultResolver = ResolveConditional(
+	builtinProcessors = []Processor{
+		APIGatewayCustomAuthorizerEventProcessor,
+		APIGatewayV2AuthorizerEventProcessor,
 		APIGatewayProxyEventProcessor,
 		APIGatewayV2HTTPEventProcessor,
 		ALBTargetGroupEventProcessor,
-	)
+	}
+
+	defaultResolver = ResolverFunc(func(payload []byte) (Processor, error) {
+		registryMu.RLock()
+		ps := registry
+		registryMu.RUnlock()
+
+		if p := firstProcessor(payload, ps); p != nil {
+			return p, nil
+		}
+
+		if p := firstProcessor(payload, builtinProcessors); p != nil {
+			return p, nil
+		}
+
+		return nil, newUnsupportedEventError(payload, append(ps[:len(ps):len(ps)], builtinProcessors...))
+	})
+
+	registry   []Processor
+	registryMu sync.RWMutex
 )
 
+// RegisterProcessor registers the specified processor with the default resolver
+// Registered processors are evaluated in order of registration, before the built-in
+// processors. The func is typically called from a package init func, allowing
+// packages to support additional event types without a custom resolver.
+func RegisterProcessor(p Processor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	// copy on write, so that concurrent resolution is unaffected
+	registry = append(registry[:len(registry):len(registry)], p)
+}
+
 // ResolveStatic returns a new static event processor resolver
 // The supplied processor will be invoked for marshal/unmarshal
 // operations, regardless of the incoming payload.
 func ResolveStatic(p Processor) Resolver {
-	return resolverFunc(func([]byte) (Processor, error) {
+	return ResolverFunc(func([]byte) (Processor, error) {
 		return p, nil
 	})
 }
@@ -35,18 +92,145 @@ func ResolveStatic(p Processor) Resolver {
 // The first applicable processor will be returned, based on the
 // incoming payload.
 func ResolveConditional(p ...Processor) Resolver {
-	return resolverFunc(func(payload []byte) (Processor, error) {
-		for _, pp := range p {
-			if pp.CanProcess(payload) {
-				return pp, nil
-			}
+	return ResolverFunc(func(payload []byte) (Processor, error) {
+		if pp := firstProcessor(payload, p); pp != nil {
+			return pp, nil
 		}
 
-		return nil, ErrUnsupportedEventType
+		return nil, newUnsupportedEventError(payload, p)
 	})
 }
 
-// Resolve resolves a resolver for the specified payload
-func (r resolverFunc) Resolve(payload []byte) (Processor, error) {
+// ResolveConditionalWithFallback returns a new conditional event processor resolver with a fallback
+// The first applicable processor will be returned, based on the incoming payload,
+// or the fallback processor if no processor is applicable.
+func ResolveConditionalWithFallback(fallback Processor, p ...Processor) Resolver {
+	return ResolverFunc(func(payload []byte) (Processor, error) {
+		if pp := firstProcessor(payload, p); pp != nil {
+			return pp, nil
+		}
+
+		return fallback, nil
+	})
+}
+
+// ResolveSticky returns a resolver that caches the last resolved processor
+// A function is almost always wired to a single event source, so only the cached
+// processor is evaluated for each payload. The wrapped resolver is only invoked
+// again if the cached processor cannot process the payload. The order of the
+// wrapped resolver is therefore not respected once a processor is cached, so it
+// must not be used to resolve payloads that more than one processor can process.
+// The built-in processors do not overlap, so they can be mixed in any order.
+func ResolveSticky(r Resolver) Resolver {
+	return &stickyResolver{resolver: r}
+}
+
+func (r *stickyResolver) Resolve(payload []byte) (Processor, error) {
+	r.mu.RLock()
+	p := r.cached
+	r.mu.RUnlock()
+
+	if p != nil && p.CanProcess(payload) {
+		return p, nil
+	}
+
+	p, err := r.resolver.Resolve(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.cached = p
+	r.mu.Unlock()
+
+	return p, nil
+}
+
+// Resolve resolves a processor for the specified payload
+func (r ResolverFunc) Resolve(payload []byte) (Processor, error) {
 	return r(payload)
 }
+
+func firstProcessor(payload []byte, ps []Processor) Processor {
+	for _, p := range ps {
+		if p.CanProcess(payload) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func newUnsupportedEventError(payload []byte, ps []Processor) *UnsupportedEventError {
+	e := &UnsupportedEventError{
+		processors: make([]string, len(ps)),
+	}
+
+	for i, p := range ps {
+		e.processors[i] = processorName(p)
+	}
+
+	r := gjson.ParseBytes(payload)
+	if !r.IsObject() {
+		return e
+	}
+
+	r.ForEach(func(k, _ gjson.Result) bool {
+		e.keys = append(e.keys, k.String())
+		return true
+	})
+
+	pv := gjson.GetManyBytes(payload, "version", "source", "Records.0.eventSource", "Records.0.EventSource")
+	e.version = pv[0].String()
+	for _, v := range pv[1:] {
+		if e.source == "" {
+			e.source = v.String()
+		}
+	}
+
+	return e
+}
+
+// Keys returns the top-level keys of the payload
+func (e *UnsupportedEventError) Keys() []string {
+	return e.keys
+}
+
+// Version returns the payload version, if specified
+func (e *UnsupportedEventError) Version() string {
+	return e.version
+}
+
+// Source returns the payload event source, if specified
+// The source is read from the source field, or the event source of the first record.
+func (e *UnsupportedEventError) Source() string {
+	return e.source
+}
+
+// Processors returns the names of the processors that were evaluated
+func (e *UnsupportedEventError) Processors() []string {
+	return e.processors
+}
+
+// Error returns the error message
+func (e *UnsupportedEventError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(ErrUnsupportedEventType.Error())
+	fmt.Fprintf(&sb, ": keys [%s]", strings.Join(e.keys, " "))
+
+	if e.version != "" {
+		fmt.Fprintf(&sb, ", version %s", e.version)
+	}
+
+	if e.source != "" {
+		fmt.Fprintf(&sb, ", source %s", e.source)
+	}
+
+	fmt.Fprintf(&sb, ", processors [%s]", strings.Join(e.processors, " "))
+	return sb.String()
+}
+
+// Unwrap returns ErrUnsupportedEventType
+func (e *UnsupportedEventError) Unwrap() error {
+	return ErrUnsupportedEventType
+}
@@ -1,8 +1,13 @@
 package rack_test
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"testing"
 
+	"github.com/tidwall/gjson"
+
 	"github.com/stevecallear/rack"
 )
 
@@ -19,6 +24,24 @@ func TestResolveStatic(t *testing.T) {
 	})
 }
 
+func TestResolverFunc_Resolve(t *testing.T) {
+	t.Run("should invoke the func", func(t *testing.T) {
+		exp := &testProcessor{canProcess: true}
+		sut := rack.ResolverFunc(func(payload []byte) (rack.Processor, error) {
+			if string(payload) != "payload" {
+				t.Errorf("got %s, expected payload", payload)
+			}
+			return exp, nil
+		})
+
+		act, err := sut.Resolve([]byte("payload"))
+		assertErrorExists(t, err, false)
+		if act != exp {
+			t.Errorf("got %v, expected %v", act, exp)
+		}
+	})
+}
+
 func TestResolveConditional(t *testing.T) {
 	proc := &testProcessor{canProcess: true}
 
@@ -74,3 +97,261 @@ func (p *testProcessor) UnmarshalRequest([]byte) (*rack.Request, error) {
 func (p *testProcessor) MarshalResponse(*rack.Response) ([]byte, error) {
 	panic("not implemented")
 }
+
+func TestResolveConditionalWithFallback(t *testing.T) {
+	proc := &testProcessor{canProcess: true}
+	fallback := &testProcessor{canProcess: false}
+
+	tests := []struct {
+		name  string
+		procs []rack.Processor
+		exp   rack.Processor
+	}{
+		{
+			name: "should return the fallback if there are no valid processors",
+			procs: []rack.Processor{
+				&testProcessor{canProcess: false},
+			},
+			exp: fallback,
+		},
+		{
+			name: "should return the first valid processor",
+			procs: []rack.Processor{
+				&testProcessor{canProcess: false},
+				proc,
+				&testProcessor{canProcess: true},
+			},
+			exp: proc,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := rack.ResolveConditionalWithFallback(fallback, tt.procs...)
+
+			act, err := sut.Resolve(nil)
+			assertErrorExists(t, err, false)
+			if act != tt.exp {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestRegisterProcessor(t *testing.T) {
+	payload := []byte(`{"source":"rack.test.registry","body":"value"}`)
+
+	rack.RegisterProcessor(rack.NewProcessor(
+		rack.WithProcessorName("registry"),
+		rack.WithCanProcess(func(b []byte) bool {
+			return gjson.GetBytes(b, "source").String() == "rack.test.registry"
+		}),
+		rack.WithUnmarshalRequest(func(b []byte) (*rack.Request, error) {
+			return &rack.Request{Body: gjson.GetBytes(b, "body").String()}, nil
+		}),
+		rack.WithMarshalResponse(func(r *rack.Response) ([]byte, error) {
+			return []byte(r.Body), nil
+		}),
+	))
+
+	t.Run("should detect registered event types", func(t *testing.T) {
+		if act := rack.DetectEventType(payload); act != "registry" {
+			t.Errorf("got %s, expected registry", act)
+		}
+	})
+
+	t.Run("should resolve registered processors", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			return c.String(http.StatusOK, c.Request().Body)
+		})
+
+		act, err := h.Invoke(context.Background(), payload)
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, act, []byte("value"))
+	})
+
+	t.Run("should resolve built-in processors", func(t *testing.T) {
+		if act := rack.DetectEventType(newV2Request(nil)); act != "apigw-v2" {
+			t.Errorf("got %s, expected apigw-v2", act)
+		}
+	})
+}
+
+func TestResolveSticky(t *testing.T) {
+	var calls int
+
+	newProcessor := func(name string) rack.Processor {
+		return rack.NewProcessor(
+			rack.WithProcessorName(name),
+			rack.WithCanProcess(func(b []byte) bool {
+				calls++
+				return gjson.GetBytes(b, "source").String() == name
+			}),
+			rack.WithUnmarshalRequest(func(b []byte) (*rack.Request, error) {
+				if gjson.GetBytes(b, "invalid").Bool() {
+					return nil, errors.New("invalid payload")
+				}
+				return &rack.Request{}, nil
+			}),
+			rack.WithMarshalResponse(func(r *rack.Response) ([]byte, error) {
+				return []byte(name), nil
+			}),
+		)
+	}
+
+	h := rack.NewWithConfig(rack.Config{
+		Resolver: rack.ResolveSticky(rack.ResolveConditional(newProcessor("a"), newProcessor("b"))),
+	}, func(c rack.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	tests := []struct {
+		name    string
+		payload string
+		exp     []byte
+		calls   int
+		err     bool
+	}{
+		{
+			name:    "should resolve the first payload",
+			payload: `{"source":"a"}`,
+			exp:     []byte("a"),
+			calls:   1,
+		},
+		{
+			name:    "should use the cached processor",
+			payload: `{"source":"a"}`,
+			exp:     []byte("a"),
+			calls:   2,
+		},
+		{
+			name:    "should not resolve again if unmarshaling fails",
+			payload: `{"source":"a","invalid":true}`,
+			calls:   3,
+			err:     true,
+		},
+		{
+			name:    "should resolve again if the cached processor cannot process the payload",
+			payload: `{"source":"b"}`,
+			exp:     []byte("b"),
+			calls:   6,
+		},
+		{
+			name:    "should cache the resolved processor",
+			payload: `{"source":"b"}`,
+			exp:     []byte("b"),
+			calls:   7,
+		},
+		{
+			name:    "should return an error if the payload is not supported",
+			payload: `{"source":"c"}`,
+			calls:   10,
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := h.Invoke(context.Background(), []byte(tt.payload))
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+
+			if calls != tt.calls {
+				t.Errorf("got %d calls, expected %d", calls, tt.calls)
+			}
+		})
+	}
+
+	t.Run("should resolve mixed http and authorizer payloads", func(t *testing.T) {
+		v1Authorizer := []byte(`{"type":"REQUEST","methodArn":"arn","requestContext":{"apiId":"id"}}`)
+		v2Authorizer := []byte(`{"version":"2.0","type":"REQUEST","routeArn":"arn","requestContext":{"apiId":"id"}}`)
+
+		sut := rack.ResolveSticky(rack.ResolveConditional(
+			rack.APIGatewayCustomAuthorizerEventProcessor,
+			rack.APIGatewayV2AuthorizerEventProcessor,
+			rack.APIGatewayProxyEventProcessor,
+			rack.APIGatewayV2HTTPEventProcessor,
+		))
+
+		steps := []struct {
+			payload []byte
+			exp     rack.Processor
+		}{
+			{payload: []byte(apiGatewayV2HTTPEventPayload), exp: rack.APIGatewayV2HTTPEventProcessor},
+			{payload: v2Authorizer, exp: rack.APIGatewayV2AuthorizerEventProcessor},
+			{payload: []byte(apiGatewayV2HTTPEventPayload), exp: rack.APIGatewayV2HTTPEventProcessor},
+			{payload: []byte(apiGatewayProxyEventPayload), exp: rack.APIGatewayProxyEventProcessor},
+			{payload: v1Authorizer, exp: rack.APIGatewayCustomAuthorizerEventProcessor},
+		}
+
+		for i, s := range steps {
+			act, err := sut.Resolve(s.payload)
+			assertErrorExists(t, err, false)
+
+			if act != s.exp {
+				t.Errorf("step %d: got %v, expected %v", i, act, s.exp)
+			}
+		}
+	})
+}
+
+func TestUnsupportedEventError(t *testing.T) {
+	procs := []rack.Processor{
+		rack.APIGatewayProxyEventProcessor,
+		rack.APIGatewayV2HTTPEventProcessor,
+	}
+
+	tests := []struct {
+		name       string
+		payload    []byte
+		keys       []string
+		version    string
+		source     string
+		processors []string
+		msg        string
+	}{
+		{
+			name:       "should describe object payloads",
+			payload:    []byte(`{"version":"3.0","source":"aws.s3","detail":{}}`),
+			keys:       []string{"version", "source", "detail"},
+			version:    "3.0",
+			source:     "aws.s3",
+			processors: []string{"apigw-v1", "apigw-v2"},
+			msg:        "unsupported event type: keys [version source detail], version 3.0, source aws.s3, processors [apigw-v1 apigw-v2]",
+		},
+		{
+			name:       "should describe record payloads",
+			payload:    []byte(`{"Records":[{"EventSource":"aws:sns"}]}`),
+			keys:       []string{"Records"},
+			source:     "aws:sns",
+			processors: []string{"apigw-v1", "apigw-v2"},
+			msg:        "unsupported event type: keys [Records], source aws:sns, processors [apigw-v1 apigw-v2]",
+		},
+		{
+			name:       "should describe invalid payloads",
+			payload:    []byte(`"value"`),
+			processors: []string{"apigw-v1", "apigw-v2"},
+			msg:        "unsupported event type: keys [], processors [apigw-v1 apigw-v2]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rack.ResolveConditional(procs...).Resolve(tt.payload)
+			if !errors.Is(err, rack.ErrUnsupportedEventType) {
+				t.Fatalf("got %v, expected %v", err, rack.ErrUnsupportedEventType)
+			}
+
+			var act *rack.UnsupportedEventError
+			if !errors.As(err, &act) {
+				t.Fatalf("got %T, expected *rack.UnsupportedEventError", err)
+			}
+
+			assertDeepEqual(t, act.Keys(), tt.keys)
+			assertDeepEqual(t, act.Version(), tt.version)
+			assertDeepEqual(t, act.Source(), tt.source)
+			assertDeepEqual(t, act.Processors(), tt.processors)
+			assertDeepEqual(t, act.Error(), tt.msg)
+		})
+	}
+}
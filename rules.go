@@ -0,0 +1,360 @@
+package rack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// RuleAction represents a routing rule action
+	RuleAction string
+
+	// Rule represents a routing rule
+	// Either a path or a pattern must be specified. Paths ending in * match by prefix,
+	// otherwise the path must match exactly, and for prefix rules a target ending in *
+	// receives the remainder of the matched path. Patterns are regular expressions,
+	// and the target can reference submatches using $1 or ${name}. If a host is
+	// specified then it must also match, with a leading *. matching any subdomain.
+	Rule struct {
+		Action  RuleAction `json:"action"`
+		Host    string     `json:"host,omitempty"`
+		Path    string     `json:"path,omitempty"`
+		Pattern string     `json:"pattern,omitempty"`
+		Target  string     `json:"target,omitempty"`
+		Status  int        `json:"status,omitempty"`
+	}
+
+	// RuleSource represents a routing rule source
+	RuleSource interface {
+		LoadRules(ctx context.Context) ([]Rule, error)
+	}
+
+	// RuleSourceFunc represents a routing rule source func
+	RuleSourceFunc func(ctx context.Context) ([]Rule, error)
+
+	// RulesOptions represents routing rule middleware options
+	RulesOptions struct {
+		// Source loads the rules, for example StaticRules or AppConfigRuleSource
+		Source RuleSource
+
+		// Interval is the rule refresh interval, defaulting to one minute
+		Interval time.Duration
+
+		// OnError is invoked if the rules cannot be loaded
+		OnError func(Context, error)
+
+		// Now returns the current time, defaulting to time.Now
+		Now func() time.Time
+	}
+
+	// AppConfigOptions represents AppConfig Lambda extension rule source options
+	AppConfigOptions struct {
+		Application string
+		Environment string
+		Profile     string
+
+		// Endpoint is the extension endpoint, defaulting to the local extension port
+		Endpoint string
+
+		// HTTPClient is the extension client, defaulting to http.DefaultClient
+		HTTPClient *http.Client
+	}
+
+	ruleSet struct {
+		source   RuleSource
+		interval time.Duration
+		now      func() time.Time
+		mu       sync.Mutex
+		rules    []compiledRule
+		loadedAt time.Time
+	}
+
+	compiledRule struct {
+		Rule
+		re *regexp.Regexp
+	}
+)
+
+// Routing rule actions
+const (
+	// RuleRedirect redirects matching requests to the target, defaulting to a 302 status
+	RuleRedirect RuleAction = "redirect"
+
+	// RuleRewrite replaces the request path with the target before invoking the handler
+	RuleRewrite RuleAction = "rewrite"
+
+	// RuleBlock returns an ErrPathBlocked error for matching requests, defaulting to a 403 status
+	RuleBlock RuleAction = "block"
+)
+
+// ErrPathBlocked indicates that the request path has been blocked by a routing rule
+var ErrPathBlocked = errors.New("path blocked")
+
+// LoadRules calls the underlying func
+func (fn RuleSourceFunc) LoadRules(ctx context.Context) ([]Rule, error) {
+	return fn(ctx)
+}
+
+// Rules returns a middleware func that applies routing rules loaded from the source
+// Rules are evaluated in order and the first matching rule is applied. The rules
+// are refreshed on the first request after the interval has elapsed, so they can be
+// changed without redeploying. The existing rules are retained if a refresh fails.
+func Rules(o RulesOptions) MiddlewareFunc {
+	rs := &ruleSet{
+		source:   o.Source,
+		interval: o.Interval,
+		now:      o.Now,
+	}
+
+	if rs.interval <= 0 {
+		rs.interval = time.Minute
+	}
+
+	if rs.now == nil {
+		rs.now = time.Now
+	}
+
+	onError := o.OnError
+	if onError == nil {
+		onError = func(Context, error) {}
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			rules, err := rs.get(c.Context())
+			if err != nil {
+				onError(c, err)
+			}
+
+			r := c.Request()
+			for _, rule := range rules {
+				target, ok := rule.match(r)
+				if !ok {
+					continue
+				}
+
+				switch rule.Action {
+				case RuleRedirect:
+					if r.RawQuery != "" && !strings.Contains(target, "?") {
+						target += "?" + r.RawQuery
+					}
+
+					c.Response().Headers.Set("Location", target)
+					return c.NoContent(statusOrDefault(rule.Status, http.StatusFound))
+				case RuleBlock:
+					return WrapError(statusOrDefault(rule.Status, http.StatusForbidden), ErrPathBlocked)
+				case RuleRewrite:
+					c.SetRequest(rewriteRequest(r, target))
+				}
+
+				break
+			}
+
+			return n(c)
+		}
+	}
+}
+
+// ParseRules parses a JSON array of routing rules
+// An error is returned if any rule is invalid.
+func ParseRules(b []byte) ([]Rule, error) {
+	var rules []Rule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, err
+	}
+
+	if _, err := compileRules(rules); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// StaticRules returns a rule source that returns the specified rules
+func StaticRules(rules ...Rule) RuleSource {
+	return RuleSourceFunc(func(context.Context) ([]Rule, error) {
+		return rules, nil
+	})
+}
+
+// AppConfigRuleSource returns a rule source that loads rules from the AppConfig Lambda extension
+// The configuration must be a JSON array of rules. The extension caches and polls
+// the configuration, so each load is a local request.
+func AppConfigRuleSource(o AppConfigOptions) RuleSource {
+	endpoint := o.Endpoint
+	if endpoint == "" {
+		port := os.Getenv("AWS_APPCONFIG_EXTENSION_HTTP_PORT")
+		if port == "" {
+			port = "2772"
+		}
+
+		endpoint = "http://localhost:" + port
+	}
+
+	client := o.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	u := fmt.Sprintf("%s/applications/%s/environments/%s/configurations/%s", strings.TrimSuffix(endpoint, "/"),
+		url.PathEscape(o.Application), url.PathEscape(o.Environment), url.PathEscape(o.Profile))
+
+	return RuleSourceFunc(func(ctx context.Context) ([]Rule, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("appconfig: unexpected status %d: %s", res.StatusCode, b)
+		}
+
+		return ParseRules(b)
+	})
+}
+
+func (rs *ruleSet) get(ctx context.Context) ([]compiledRule, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := rs.now()
+	if rs.source == nil || (!rs.loadedAt.IsZero() && now.Sub(rs.loadedAt) < rs.interval) {
+		return rs.rules, nil
+	}
+
+	// failed loads are not retried until the next interval
+	rs.loadedAt = now
+
+	rules, err := rs.source.LoadRules(ctx)
+	if err != nil {
+		return rs.rules, err
+	}
+
+	crs, err := compileRules(rules)
+	if err != nil {
+		return rs.rules, err
+	}
+
+	rs.rules = crs
+	return crs, nil
+}
+
+// compileRules validates the rules and compiles any patterns
+func compileRules(rules []Rule) ([]compiledRule, error) {
+	crs := make([]compiledRule, len(rules))
+	for i, r := range rules {
+		switch {
+		case r.Path == "" && r.Pattern == "":
+			return nil, fmt.Errorf("invalid rule %d: path or pattern is required", i)
+		case r.Path != "" && r.Pattern != "":
+			return nil, fmt.Errorf("invalid rule %d: path and pattern are mutually exclusive", i)
+		case r.Action != RuleRedirect && r.Action != RuleRewrite && r.Action != RuleBlock:
+			return nil, fmt.Errorf("invalid rule %d: unknown action %q", i, r.Action)
+		case r.Action != RuleBlock && r.Target == "":
+			return nil, fmt.Errorf("invalid rule %d: target is required", i)
+		}
+
+		crs[i].Rule = r
+
+		if r.Pattern != "" {
+			re, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule %d: %w", i, err)
+			}
+
+			crs[i].re = re
+		}
+	}
+
+	return crs, nil
+}
+
+// match returns the target if the rule matches the request
+func (r compiledRule) match(req *Request) (string, bool) {
+	if r.Host != "" && !matchHost(r.Host, req.Host) {
+		return "", false
+	}
+
+	if r.re != nil {
+		m := r.re.FindStringSubmatchIndex(req.RawPath)
+		if m == nil {
+			return "", false
+		}
+
+		return string(r.re.ExpandString(nil, r.Target, req.RawPath, m)), true
+	}
+
+	if !strings.HasSuffix(r.Path, "*") {
+		return r.Target, req.RawPath == r.Path
+	}
+
+	prefix := strings.TrimSuffix(r.Path, "*")
+	if !strings.HasPrefix(req.RawPath, prefix) {
+		return "", false
+	}
+
+	if strings.HasSuffix(r.Target, "*") {
+		return strings.TrimSuffix(r.Target, "*") + strings.TrimPrefix(req.RawPath, prefix), true
+	}
+
+	return r.Target, true
+}
+
+func matchHost(pattern, host string) bool {
+	host = strings.ToLower(host)
+	if h, _, ok := strings.Cut(host, ":"); ok {
+		host = h
+	}
+
+	pattern = strings.ToLower(pattern)
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+
+	return host == pattern
+}
+
+// rewriteRequest returns a copy of the request with the target path and query
+// The existing query is retained unless the target contains a query.
+func rewriteRequest(r *Request, target string) *Request {
+	c := r.Clone()
+
+	p, q, ok := strings.Cut(target, "?")
+	c.RawPath = p
+
+	if ok {
+		c.RawQuery = q
+		c.Query, _ = url.ParseQuery(q)
+	}
+
+	return c
+}
+
+func statusOrDefault(code, def int) int {
+	if code == 0 {
+		return def
+	}
+
+	return code
+}
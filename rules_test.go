@@ -0,0 +1,334 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestRules(t *testing.T) {
+	rules := []rack.Rule{
+		{Action: rack.RuleRedirect, Path: "/old", Target: "/new"},
+		{Action: rack.RuleRedirect, Path: "/docs/*", Target: "https://docs.example.com/*", Status: http.StatusMovedPermanently},
+		{Action: rack.RuleRewrite, Path: "/v1/*", Target: "/v2/*"},
+		{Action: rack.RuleBlock, Path: "/admin/*"},
+		{Action: rack.RuleBlock, Path: "/v2/internal", Status: http.StatusNotFound},
+		{Action: rack.RuleRedirect, Pattern: `^/users/(?P<id>[0-9]+)$`, Target: "/accounts/${id}"},
+		{Action: rack.RuleRewrite, Pattern: `^/p/([0-9]+)$`, Target: "/products?id=$1"},
+		{Action: rack.RuleRedirect, Host: "*.legacy.com", Path: "/*", Target: "https://example.com/*"},
+		{Action: rack.RuleBlock, Host: "internal.example.com", Path: "/*"},
+	}
+
+	tests := []struct {
+		name     string
+		host     string
+		path     string
+		query    string
+		status   int
+		location string
+		handled  string
+	}{
+		{
+			name:    "should invoke the handler if no rules match",
+			path:    "/orders",
+			status:  http.StatusOK,
+			handled: "/orders",
+		},
+		{
+			name:     "should redirect exact matches",
+			path:     "/old",
+			query:    "a=1",
+			status:   http.StatusFound,
+			location: "/new?a=1",
+		},
+		{
+			name:    "should not redirect partial matches",
+			path:    "/older",
+			status:  http.StatusOK,
+			handled: "/older",
+		},
+		{
+			name:     "should redirect prefix matches",
+			path:     "/docs/guide/intro",
+			status:   http.StatusMovedPermanently,
+			location: "https://docs.example.com/guide/intro",
+		},
+		{
+			name:    "should rewrite the path",
+			path:    "/v1/orders",
+			status:  http.StatusOK,
+			handled: "/v2/orders",
+		},
+		{
+			name:    "should apply the first matching rule",
+			path:    "/v1/internal",
+			status:  http.StatusOK,
+			handled: "/v2/internal",
+		},
+		{
+			name:   "should block paths",
+			path:   "/admin/users",
+			status: http.StatusForbidden,
+		},
+		{
+			name:     "should redirect pattern matches",
+			path:     "/users/123",
+			status:   http.StatusFound,
+			location: "/accounts/123",
+		},
+		{
+			name:    "should not redirect pattern mismatches",
+			path:    "/users/abc",
+			status:  http.StatusOK,
+			handled: "/users/abc",
+		},
+		{
+			name:    "should rewrite the query",
+			path:    "/p/123",
+			query:   "a=1",
+			status:  http.StatusOK,
+			handled: "/products?id=123",
+		},
+		{
+			name:     "should match wildcard hosts",
+			host:     "www.legacy.com",
+			path:     "/orders",
+			status:   http.StatusFound,
+			location: "https://example.com/orders",
+		},
+		{
+			name:   "should match hosts",
+			host:   "Internal.Example.com",
+			path:   "/orders",
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "should block paths with the status",
+			path:   "/v2/internal",
+			status: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled string
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.Rules(rack.RulesOptions{
+					Source: rack.StaticRules(rules...),
+				}),
+			}, func(c rack.Context) error {
+				handled = c.Request().RawPath
+				if q := c.Request().Query.Encode(); q != "" {
+					handled += "?" + q
+				}
+
+				return c.NoContent(http.StatusOK)
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodGet
+				r.RequestContext.DomainName = tt.host
+				r.RequestContext.HTTP.Path = tt.path
+				r.RawQueryString = tt.query
+			}))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			assertDeepEqual(t, res.StatusCode, tt.status)
+			assertDeepEqual(t, res.Headers["Location"], tt.location)
+			assertDeepEqual(t, handled, tt.handled)
+		})
+	}
+
+	t.Run("should refresh the rules after the interval", func(t *testing.T) {
+		now := time.Now()
+		loads := 0
+		var loadErr error
+
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.Rules(rack.RulesOptions{
+				Source: rack.RuleSourceFunc(func(context.Context) ([]rack.Rule, error) {
+					loads++
+					if loadErr != nil {
+						return nil, loadErr
+					}
+
+					return []rack.Rule{{Action: rack.RuleBlock, Path: "/", Status: 400 + loads}}, nil
+				}),
+				Interval: time.Minute,
+				Now:      func() time.Time { return now },
+			}),
+		}, func(c rack.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		invoke := func() int {
+			b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Path = "/"
+			}))
+			assertErrorExists(t, err, false)
+
+			return unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse).StatusCode
+		}
+
+		assertDeepEqual(t, invoke(), 401)
+		assertDeepEqual(t, invoke(), 401)
+
+		now = now.Add(time.Minute)
+		assertDeepEqual(t, invoke(), 402)
+
+		now = now.Add(time.Minute)
+		loadErr = errors.New("error")
+		assertDeepEqual(t, invoke(), 402)
+		assertDeepEqual(t, invoke(), 402)
+		assertDeepEqual(t, loads, 3)
+	})
+
+	t.Run("should retain the rules if the loaded rules are invalid", func(t *testing.T) {
+		now := time.Now()
+		rules := []rack.Rule{{Action: rack.RuleBlock, Path: "/"}}
+		var errs []error
+
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.Rules(rack.RulesOptions{
+				Source: rack.RuleSourceFunc(func(context.Context) ([]rack.Rule, error) {
+					return rules, nil
+				}),
+				OnError: func(_ rack.Context, err error) { errs = append(errs, err) },
+				Now:     func() time.Time { return now },
+			}),
+		}, func(c rack.Context) error {
+			return c.NoContent(http.StatusOK)
+		})
+
+		invoke := func() int {
+			b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Path = "/"
+			}))
+			assertErrorExists(t, err, false)
+
+			return unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse).StatusCode
+		}
+
+		assertDeepEqual(t, invoke(), http.StatusForbidden)
+
+		now = now.Add(time.Hour)
+		rules = []rack.Rule{{Action: rack.RuleBlock, Pattern: "("}}
+		assertDeepEqual(t, invoke(), http.StatusForbidden)
+		assertDeepEqual(t, len(errs), 1)
+	})
+}
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		name string
+		b    string
+		exp  []rack.Rule
+		err  bool
+	}{
+		{
+			name: "should return an error if the json is invalid",
+			b:    `{`,
+			err:  true,
+		},
+		{
+			name: "should return an error if the path is missing",
+			b:    `[{"action":"block"}]`,
+			err:  true,
+		},
+		{
+			name: "should return an error if the action is unknown",
+			b:    `[{"action":"other","path":"/"}]`,
+			err:  true,
+		},
+		{
+			name: "should return an error if the path and pattern are specified",
+			b:    `[{"action":"block","path":"/","pattern":"^/$"}]`,
+			err:  true,
+		},
+		{
+			name: "should return an error if the pattern is invalid",
+			b:    `[{"action":"block","pattern":"("}]`,
+			err:  true,
+		},
+		{
+			name: "should return an error if the target is missing",
+			b:    `[{"action":"redirect","path":"/"}]`,
+			err:  true,
+		},
+		{
+			name: "should return the rules",
+			b:    `[{"action":"redirect","path":"/a","target":"/b","status":301},{"action":"block","path":"/c"}]`,
+			exp: []rack.Rule{
+				{Action: rack.RuleRedirect, Path: "/a", Target: "/b", Status: 301},
+				{Action: rack.RuleBlock, Path: "/c"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			act, err := rack.ParseRules([]byte(tt.b))
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestAppConfigRuleSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		exp    []rack.Rule
+		err    bool
+	}{
+		{
+			name:   "should return an error if the status is not ok",
+			status: http.StatusNotFound,
+			err:    true,
+		},
+		{
+			name:   "should return an error if the rules are invalid",
+			status: http.StatusOK,
+			body:   `[{"action":"block"}]`,
+			err:    true,
+		},
+		{
+			name:   "should return the rules",
+			status: http.StatusOK,
+			body:   `[{"action":"block","path":"/admin/*"}]`,
+			exp:    []rack.Rule{{Action: rack.RuleBlock, Path: "/admin/*"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assertDeepEqual(t, r.URL.Path, "/applications/app/environments/prod/configurations/rules")
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer s.Close()
+
+			sut := rack.AppConfigRuleSource(rack.AppConfigOptions{
+				Application: "app",
+				Environment: "prod",
+				Profile:     "rules",
+				Endpoint:    s.URL,
+			})
+
+			act, err := sut.LoadRules(context.Background())
+			assertErrorExists(t, err, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package rack
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// SanitizeOptions represents request input sanitization options
+type SanitizeOptions struct {
+	// Trim removes leading and trailing white space
+	Trim bool
+
+	// StripControl removes control characters, other than tabs and line breaks
+	StripControl bool
+
+	// Normalize is applied to each value, for example norm.NFC.String from golang.org/x/text
+	Normalize func(string) string
+
+	// ExcludePath contains path parameter keys that are not sanitized
+	ExcludePath []string
+
+	// ExcludeQuery contains query parameter keys that are not sanitized
+	ExcludeQuery []string
+
+	// ExcludeHeaders contains header names that are not sanitized
+	ExcludeHeaders []string
+}
+
+// Sanitize returns a middleware func that sanitizes path, query and header values
+// Values are sanitized in place before the handler is invoked. Keys, raw fields and
+// the request body are not modified.
+func Sanitize(o SanitizeOptions) MiddlewareFunc {
+	excludePath := stringSet(o.ExcludePath, nil)
+	excludeQuery := stringSet(o.ExcludeQuery, nil)
+	excludeHeaders := stringSet(o.ExcludeHeaders, http.CanonicalHeaderKey)
+
+	sanitize := func(s string) string {
+		if o.StripControl {
+			s = strings.Map(func(r rune) rune {
+				if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+					return -1
+				}
+				return r
+			}, s)
+		}
+
+		if o.Normalize != nil {
+			s = o.Normalize(s)
+		}
+
+		if o.Trim {
+			s = strings.TrimSpace(s)
+		}
+
+		return s
+	}
+
+	sanitizeValues := func(m map[string][]string, exclude map[string]bool) {
+		for k, vs := range m {
+			if exclude[k] {
+				continue
+			}
+
+			for i, v := range vs {
+				vs[i] = sanitize(v)
+			}
+		}
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			r := c.Request()
+
+			for k, v := range r.Path {
+				if !excludePath[k] {
+					r.Path[k] = sanitize(v)
+				}
+			}
+
+			sanitizeValues(r.Query, excludeQuery)
+			sanitizeValues(r.Header, excludeHeaders)
+
+			return n(c)
+		}
+	}
+}
+
+func stringSet(ss []string, fn func(string) string) map[string]bool {
+	m := make(map[string]bool, len(ss))
+	for _, s := range ss {
+		if fn != nil {
+			s = fn(s)
+		}
+		m[s] = true
+	}
+
+	return m
+}
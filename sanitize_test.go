@@ -0,0 +1,105 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   rack.SanitizeOptions
+		path   map[string]string
+		query  url.Values
+		header http.Header
+	}{
+		{
+			name: "should not modify values by default",
+			path: map[string]string{"id": " a\x00 "},
+			query: url.Values{
+				"q": {" a\x00 "},
+			},
+			header: http.Header{
+				"X-Value": {" a\x00 "},
+			},
+		},
+		{
+			name: "should trim values",
+			opts: rack.SanitizeOptions{Trim: true},
+			path: map[string]string{"id": "a\x00"},
+			query: url.Values{
+				"q": {"a\x00"},
+			},
+			header: http.Header{
+				"X-Value": {"a\x00"},
+			},
+		},
+		{
+			name: "should strip control characters",
+			opts: rack.SanitizeOptions{StripControl: true},
+			path: map[string]string{"id": " a "},
+			query: url.Values{
+				"q": {" a "},
+			},
+			header: http.Header{
+				"X-Value": {" a "},
+			},
+		},
+		{
+			name: "should apply all sanitizers",
+			opts: rack.SanitizeOptions{Trim: true, StripControl: true, Normalize: strings.ToUpper},
+			path: map[string]string{"id": "A"},
+			query: url.Values{
+				"q": {"A"},
+			},
+			header: http.Header{
+				"X-Value": {"A"},
+			},
+		},
+		{
+			name: "should exclude fields",
+			opts: rack.SanitizeOptions{
+				Trim:           true,
+				StripControl:   true,
+				ExcludePath:    []string{"id"},
+				ExcludeQuery:   []string{"q"},
+				ExcludeHeaders: []string{"x-value"},
+			},
+			path: map[string]string{"id": " a\x00 "},
+			query: url.Values{
+				"q": {" a\x00 "},
+			},
+			header: http.Header{
+				"X-Value": {" a\x00 "},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.Sanitize(tt.opts),
+			}, func(c rack.Context) error {
+				r := c.Request()
+				assertDeepEqual(t, r.Path, tt.path)
+				assertDeepEqual(t, r.Query, tt.query)
+				assertDeepEqual(t, r.Header, tt.header)
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.PathParameters = map[string]string{"id": " a\x00 "}
+				r.QueryStringParameters = map[string]string{"q": " a\x00 "}
+				r.Headers = map[string]string{"x-value": " a\x00 "}
+			}))
+			assertErrorExists(t, err, false)
+		})
+	}
+}
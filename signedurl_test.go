@@ -0,0 +1,155 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestSignURL(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	o := rack.SignedURLOptions{
+		Key: []byte("key"),
+		Now: func() time.Time { return now },
+	}
+
+	t.Run("should return an error if the key is empty", func(t *testing.T) {
+		_, err := rack.SignURL(rack.SignedURLOptions{}, "https://example.com", time.Minute)
+		if !errors.Is(err, rack.ErrNoSigningKey) {
+			t.Errorf("got %v, expected %v", err, rack.ErrNoSigningKey)
+		}
+	})
+
+	t.Run("should return an error if the url is invalid", func(t *testing.T) {
+		_, err := rack.SignURL(o, "%", time.Minute)
+		assertErrorExists(t, err, true)
+	})
+
+	t.Run("should add the expiry and signature", func(t *testing.T) {
+		act, err := rack.SignURL(o, "https://example.com/files/abc?user=1", time.Minute)
+		assertErrorExists(t, err, false)
+
+		u, _ := url.Parse(act)
+		assertDeepEqual(t, u.Path, "/files/abc")
+		assertDeepEqual(t, u.Query().Get("user"), "1")
+		assertDeepEqual(t, u.Query().Get("expires"), "1609459260")
+
+		if u.Query().Get("signature") == "" {
+			t.Error("got empty signature, expected a value")
+		}
+	})
+}
+
+func TestVerifySignedURL(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	o := rack.SignedURLOptions{
+		Key: []byte("key"),
+		Now: func() time.Time { return now },
+	}
+
+	signed, err := rack.SignURL(o, "/files/abc?user=1&tags=a&tags=b", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		opts rack.SignedURLOptions
+		code int
+	}{
+		{
+			name: "should return unauthorized if there is no signature",
+			url:  "/files/abc?user=1",
+			opts: o,
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "should return forbidden if the url has expired",
+			url:  signed,
+			opts: rack.SignedURLOptions{
+				Key: o.Key,
+				Now: func() time.Time { return now.Add(2 * time.Minute) },
+			},
+			code: http.StatusForbidden,
+		},
+		{
+			name: "should return forbidden if the key is invalid",
+			url:  signed,
+			opts: rack.SignedURLOptions{Key: []byte("other"), Now: o.Now},
+			code: http.StatusForbidden,
+		},
+		{
+			name: "should return forbidden if the path has been modified",
+			url:  strings.Replace(signed, "abc", "def", 1),
+			opts: o,
+			code: http.StatusForbidden,
+		},
+		{
+			name: "should return forbidden if the query has been modified",
+			url:  strings.Replace(signed, "user=1", "user=2", 1),
+			opts: o,
+			code: http.StatusForbidden,
+		},
+		{
+			name: "should return forbidden if parameters have been added",
+			url:  signed + "&admin=true",
+			opts: o,
+			code: http.StatusForbidden,
+		},
+		{
+			name: "should invoke the handler if the signature is valid",
+			url:  signed,
+			opts: o,
+			code: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := rack.Config{Middleware: rack.VerifySignedURL(tt.opts)}
+
+			h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			q := map[string]string{}
+			for k, vs := range u.Query() {
+				q[k] = strings.Join(vs, ",")
+			}
+
+			b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Path = u.Path
+				r.QueryStringParameters = q
+			}))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			if res.StatusCode != tt.code {
+				t.Errorf("got %d, expected %d", res.StatusCode, tt.code)
+			}
+		})
+	}
+	t.Run("should panic if the key is empty", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got nil, expected panic")
+			}
+		}()
+
+		rack.VerifySignedURL(rack.SignedURLOptions{})
+	})
+}
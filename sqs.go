@@ -0,0 +1,79 @@
+package rack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/tidwall/gjson"
+)
+
+type (
+	// SQSBridgeOptions represents sqs bridge options
+	SQSBridgeOptions struct {
+		// Retry returns true if the record should be retried for the response status code
+		// By default 429 and 5xx responses are retried.
+		Retry func(code int) bool
+	}
+
+	// SQSBatchResponse represents an sqs partial batch response
+	SQSBatchResponse struct {
+		BatchItemFailures []SQSBatchItemFailure `json:"batchItemFailures"`
+	}
+
+	// SQSBatchItemFailure represents an sqs batch item failure
+	SQSBatchItemFailure struct {
+		ItemIdentifier string `json:"itemIdentifier"`
+	}
+)
+
+// SQSBridge returns a lambda handler that unwraps api gateway and alb events from sqs records
+// Each record body must contain a serialized event, which is invoked using the
+// specified handler. Failed records are reported as batch item failures, so the
+// event source mapping must enable ReportBatchItemFailures. Records in a FIFO message
+// group following a failure are also reported, preserving ordering. Payloads that are
+// not sqs events are passed through to the handler.
+func SQSBridge(h lambda.Handler, o SQSBridgeOptions) lambda.Handler {
+	retry := o.Retry
+	if retry == nil {
+		retry = func(code int) bool {
+			return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+		}
+	}
+
+	return invokeFunc(func(ctx context.Context, payload []byte) ([]byte, error) {
+		if gjson.GetBytes(payload, "Records.0.eventSource").String() != "aws:sqs" {
+			return h.Invoke(ctx, payload)
+		}
+
+		e := new(events.SQSEvent)
+		if err := json.Unmarshal(payload, e); err != nil {
+			return nil, err
+		}
+
+		res := SQSBatchResponse{BatchItemFailures: []SQSBatchItemFailure{}}
+		failedGroups := map[string]bool{}
+
+		for _, r := range e.Records {
+			group := r.Attributes["MessageGroupId"]
+			if group != "" && failedGroups[group] {
+				res.BatchItemFailures = append(res.BatchItemFailures, SQSBatchItemFailure{ItemIdentifier: r.MessageId})
+				continue
+			}
+
+			b, err := h.Invoke(ctx, []byte(r.Body))
+			if err == nil && !retry(int(gjson.GetBytes(b, "statusCode").Int())) {
+				continue
+			}
+
+			res.BatchItemFailures = append(res.BatchItemFailures, SQSBatchItemFailure{ItemIdentifier: r.MessageId})
+			if group != "" {
+				failedGroups[group] = true
+			}
+		}
+
+		return json.Marshal(&res)
+	})
+}
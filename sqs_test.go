@@ -0,0 +1,124 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestSQSBridge(t *testing.T) {
+	newRecord := func(id, group, path string) events.SQSMessage {
+		m := events.SQSMessage{
+			MessageId:   id,
+			EventSource: "aws:sqs",
+			Body: string(newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Path = path
+			})),
+		}
+
+		if group != "" {
+			m.Attributes = map[string]string{"MessageGroupId": group}
+		}
+
+		return m
+	}
+
+	handler := rack.New(func(c rack.Context) error {
+		switch c.Request().RawPath {
+		case "/error":
+			return errors.New("error")
+		case "/conflict":
+			return rack.WrapError(http.StatusConflict, errors.New("conflict"))
+		case "/throttle":
+			return rack.TooManyRequests(0)
+		}
+
+		return c.NoContent(http.StatusOK)
+	})
+
+	tests := []struct {
+		name    string
+		opts    rack.SQSBridgeOptions
+		records []events.SQSMessage
+		exp     []string
+	}{
+		{
+			name:    "should return no failures if all records succeed",
+			records: []events.SQSMessage{newRecord("1", "", "/"), newRecord("2", "", "/")},
+			exp:     []string{},
+		},
+		{
+			name: "should return retryable failures",
+			records: []events.SQSMessage{
+				newRecord("1", "", "/error"),
+				newRecord("2", "", "/conflict"),
+				newRecord("3", "", "/throttle"),
+				newRecord("4", "", "/"),
+			},
+			exp: []string{"1", "3"},
+		},
+		{
+			name: "should use the retry func",
+			opts: rack.SQSBridgeOptions{
+				Retry: func(code int) bool { return code >= 400 },
+			},
+			records: []events.SQSMessage{
+				newRecord("1", "", "/conflict"),
+				newRecord("2", "", "/"),
+			},
+			exp: []string{"1"},
+		},
+		{
+			name: "should fail subsequent records in failed message groups",
+			records: []events.SQSMessage{
+				newRecord("1", "a", "/"),
+				newRecord("2", "a", "/error"),
+				newRecord("3", "b", "/"),
+				newRecord("4", "a", "/"),
+			},
+			exp: []string{"2", "4"},
+		},
+		{
+			name: "should fail records with invalid bodies",
+			records: []events.SQSMessage{
+				{MessageId: "1", EventSource: "aws:sqs", Body: "{}"},
+			},
+			exp: []string{"1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sut := rack.SQSBridge(handler, tt.opts)
+
+			b, err := sut.Invoke(context.Background(), marshal(&events.SQSEvent{Records: tt.records}))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(rack.SQSBatchResponse)).(*rack.SQSBatchResponse)
+
+			act := []string{}
+			for _, f := range res.BatchItemFailures {
+				act = append(act, f.ItemIdentifier)
+			}
+
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+
+	t.Run("should pass through other events", func(t *testing.T) {
+		sut := rack.SQSBridge(handler, rack.SQSBridgeOptions{})
+
+		b, err := sut.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("got %d, expected %d", res.StatusCode, http.StatusOK)
+		}
+	})
+}
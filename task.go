@@ -0,0 +1,83 @@
+package rack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// TaskSender represents a step functions task callback client
+// Implementations would typically wrap the SendTaskSuccess and SendTaskFailure
+// step functions operations.
+type TaskSender interface {
+	SendTaskSuccess(ctx context.Context, token, output string) error
+	SendTaskFailure(ctx context.Context, token, code, cause string) error
+}
+
+const taskSenderKey = "rack.taskSender"
+
+var (
+	// ErrNoTaskSender indicates that no task sender has been configured
+	ErrNoTaskSender = errors.New("no task sender configured")
+
+	// ErrNoTaskToken indicates that the request does not contain a task token
+	ErrNoTaskToken = errors.New("task token not specified")
+)
+
+// TaskToken returns the step functions task token for the request
+// The token is read from the taskToken query string parameter, falling back to
+// the X-Task-Token header. An empty string is returned if no token exists.
+func TaskToken(c Context) string {
+	if t := c.Query("taskToken"); t != "" {
+		return t
+	}
+
+	return c.Request().Header.Get("X-Task-Token")
+}
+
+// WithTaskSender returns a middleware func that configures the step functions task sender
+func WithTaskSender(s TaskSender) MiddlewareFunc {
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			c.Set(taskSenderKey, s)
+			return n(c)
+		}
+	}
+}
+
+// SendTaskSuccess completes the step functions task for the request task token
+// The output is marshaled using the configured codec unless it is a string or byte slice.
+func SendTaskSuccess(c Context, output interface{}) error {
+	s, ok := c.Get(taskSenderKey).(TaskSender)
+	if !ok || s == nil {
+		return ErrNoTaskSender
+	}
+
+	t := TaskToken(c)
+	if t == "" {
+		return WrapError(http.StatusBadRequest, ErrNoTaskToken)
+	}
+
+	o, err := marshalPayload(contextCodec(c), output)
+	if err != nil {
+		return err
+	}
+
+	return s.SendTaskSuccess(c.Context(), t, o)
+}
+
+// SendTaskFailure fails the step functions task for the request task token
+// The error code and message are sent as the task error and cause.
+func SendTaskFailure(c Context, err error) error {
+	s, ok := c.Get(taskSenderKey).(TaskSender)
+	if !ok || s == nil {
+		return ErrNoTaskSender
+	}
+
+	t := TaskToken(c)
+	if t == "" {
+		return WrapError(http.StatusBadRequest, ErrNoTaskToken)
+	}
+
+	return s.SendTaskFailure(c.Context(), t, ErrorCode(err), err.Error())
+}
@@ -0,0 +1,159 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestTaskToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     string
+	}{
+		{
+			name:    "should return empty if no token exists",
+			payload: newV2Request(nil),
+			exp:     "",
+		},
+		{
+			name: "should return the query string token",
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.QueryStringParameters = map[string]string{"taskToken": "query"}
+				r.Headers = map[string]string{"X-Task-Token": "header"}
+			}),
+			exp: "query",
+		},
+		{
+			name: "should return the header token",
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = map[string]string{"X-Task-Token": "header"}
+			}),
+			exp: "header",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				if act := rack.TaskToken(c); act != tt.exp {
+					t.Errorf("got %s, expected %s", act, tt.exp)
+				}
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), tt.payload)
+			assertErrorExists(t, err, false)
+		})
+	}
+}
+
+func TestSendTaskSuccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		sender  *testTaskSender
+		payload []byte
+		output  interface{}
+		exp     []string
+		status  int
+		err     bool
+	}{
+		{
+			name:    "should return an error if no sender is configured",
+			payload: newV2Request(nil),
+			err:     true,
+		},
+		{
+			name:    "should return a bad request error if no token exists",
+			sender:  &testTaskSender{},
+			payload: newV2Request(nil),
+			status:  http.StatusBadRequest,
+			err:     true,
+		},
+		{
+			name:   "should return sender errors",
+			sender: &testTaskSender{err: errors.New("error")},
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.QueryStringParameters = map[string]string{"taskToken": "token"}
+			}),
+			output: "{}",
+			status: http.StatusInternalServerError,
+			err:    true,
+		},
+		{
+			name:   "should send the task success",
+			sender: &testTaskSender{},
+			payload: newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.QueryStringParameters = map[string]string{"taskToken": "token"}
+			}),
+			output: map[string]bool{"approved": true},
+			exp:    []string{"success", "token", `{"approved":true}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := rack.Config{}
+			if tt.sender != nil {
+				cfg.Middleware = rack.WithTaskSender(tt.sender)
+			}
+
+			h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+				err := rack.SendTaskSuccess(c, tt.output)
+				assertErrorExists(t, err, tt.err)
+				if err != nil && tt.status != 0 && rack.StatusCode(err) != tt.status {
+					t.Errorf("got %d, expected %d", rack.StatusCode(err), tt.status)
+				}
+
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), tt.payload)
+			assertErrorExists(t, err, false)
+
+			if tt.exp != nil {
+				assertDeepEqual(t, tt.sender.calls, tt.exp)
+			}
+		})
+	}
+}
+
+func TestSendTaskFailure(t *testing.T) {
+	t.Run("should send the task failure", func(t *testing.T) {
+		s := &testTaskSender{}
+
+		h := rack.NewWithConfig(rack.Config{Middleware: rack.WithTaskSender(s)}, func(c rack.Context) error {
+			err := rack.WrapError(http.StatusForbidden, errors.New("rejected")).WithCode("REJECTED")
+			return rack.SendTaskFailure(c, err)
+		})
+
+		p := newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.Headers = map[string]string{"X-Task-Token": "token"}
+		})
+
+		_, err := h.Invoke(context.Background(), p)
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, s.calls, []string{"failure", "token", "REJECTED", "rejected"})
+	})
+}
+
+type testTaskSender struct {
+	calls []string
+	err   error
+}
+
+func (s *testTaskSender) SendTaskSuccess(_ context.Context, token, output string) error {
+	s.calls = []string{"success", token, output}
+	return s.err
+}
+
+func (s *testTaskSender) SendTaskFailure(_ context.Context, token, code, cause string) error {
+	s.calls = []string{"failure", token, code, cause}
+	return s.err
+}
@@ -0,0 +1,15 @@
+package rack
+
+import "html/template"
+
+// TemplateFuncs returns html template funcs for the request
+// The csrfToken and csrfField funcs render the request csrf token, and the flashes
+// func returns the request flash messages. Funcs are bound to the request, so
+// templates should be cloned and the funcs added per request.
+func TemplateFuncs(c Context) template.FuncMap {
+	return template.FuncMap{
+		"csrfToken": func() string { return CSRFToken(c) },
+		"csrfField": func() template.HTML { return CSRFField(c) },
+		"flashes":   func() []FlashMessage { return Flashes(c) },
+	}
+}
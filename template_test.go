@@ -0,0 +1,73 @@
+package rack_test
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		name  string
+		csrf  bool
+		empty bool
+	}{
+		{
+			name: "should render the csrf token",
+			csrf: true,
+		},
+		{
+			name:  "should render empty values without the csrf middleware",
+			empty: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ms []rack.MiddlewareFunc
+			if tt.csrf {
+				ms = append(ms, rack.CSRF(rack.CSRFOptions{Key: []byte("key"), FieldName: "_token"}))
+			}
+
+			var act string
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.Chain(ms...),
+			}, func(c rack.Context) error {
+				tmpl := template.Must(template.New("form").
+					Funcs(rack.TemplateFuncs(c)).
+					Parse(`{{csrfField}}|{{csrfToken}}`))
+
+				var buf bytes.Buffer
+				if err := tmpl.Execute(&buf, nil); err != nil {
+					return err
+				}
+
+				act = buf.String()
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.Method = http.MethodGet
+			}))
+			assertErrorExists(t, err, false)
+
+			if tt.empty {
+				assertDeepEqual(t, act, "|")
+				return
+			}
+
+			parts := strings.SplitN(act, "|", 2)
+			exp := `<input type="hidden" name="_token" value="` + parts[1] + `">`
+			if parts[1] == "" || parts[0] != exp {
+				t.Errorf("got %s, expected %s", parts[0], exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package rack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+type (
+	// ConnectionPoster represents a websocket connection management client
+	// Implementations would typically wrap the API Gateway management API
+	// PostToConnection operation, returning ErrConnectionGone for closed connections.
+	ConnectionPoster interface {
+		PostToConnection(ctx context.Context, connectionID string, data []byte) error
+	}
+
+	// ConnectionPosterFunc represents a connection poster func
+	ConnectionPosterFunc func(ctx context.Context, connectionID string, data []byte) error
+
+	// TopicStore represents a topic subscription store
+	TopicStore interface {
+		// Subscribe adds the connection to the topic
+		Subscribe(ctx context.Context, topic, connectionID string) error
+
+		// Unsubscribe removes the connection from the topic
+		Unsubscribe(ctx context.Context, topic, connectionID string) error
+
+		// Subscribers returns the connections subscribed to the topic
+		Subscribers(ctx context.Context, topic string) ([]string, error)
+	}
+
+	// Topics represents a websocket topic fan-out helper
+	Topics struct {
+		store  TopicStore
+		poster ConnectionPoster
+	}
+
+	cacheTopicStore struct {
+		cache Cache
+		mu    sync.Mutex
+	}
+)
+
+// ErrConnectionGone indicates that the websocket connection has been closed
+var ErrConnectionGone = errors.New("connection gone")
+
+// PostToConnection posts the data to the connection
+func (fn ConnectionPosterFunc) PostToConnection(ctx context.Context, connectionID string, data []byte) error {
+	return fn(ctx, connectionID, data)
+}
+
+// NewCacheTopicStore returns a new topic store backed by the specified cache
+// Subscriptions are stored as a single value per topic, so concurrent updates from
+// separate containers can be lost. Stores with set semantics should be preferred
+// for high-volume topics.
+func NewCacheTopicStore(c Cache) TopicStore {
+	return &cacheTopicStore{cache: c}
+}
+
+func (s *cacheTopicStore) Subscribe(ctx context.Context, topic, connectionID string) error {
+	return s.update(ctx, topic, func(ids []string) []string {
+		for _, id := range ids {
+			if id == connectionID {
+				return ids
+			}
+		}
+		return append(ids, connectionID)
+	})
+}
+
+func (s *cacheTopicStore) Unsubscribe(ctx context.Context, topic, connectionID string) error {
+	return s.update(ctx, topic, func(ids []string) []string {
+		res := ids[:0]
+		for _, id := range ids {
+			if id != connectionID {
+				res = append(res, id)
+			}
+		}
+		return res
+	})
+}
+
+func (s *cacheTopicStore) Subscribers(ctx context.Context, topic string) ([]string, error) {
+	b, ok, err := s.cache.Get(ctx, topic)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var ids []string
+	if err = json.Unmarshal(b, &ids); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (s *cacheTopicStore) update(ctx context.Context, topic string, fn func([]string) []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids, err := s.Subscribers(ctx, topic)
+	if err != nil {
+		return err
+	}
+
+	ids = fn(ids)
+	if len(ids) < 1 {
+		return s.cache.Delete(ctx, topic)
+	}
+
+	b, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Set(ctx, topic, b, 0)
+}
+
+// NewTopics returns a new topic fan-out helper
+func NewTopics(store TopicStore, poster ConnectionPoster) *Topics {
+	return &Topics{
+		store:  store,
+		poster: poster,
+	}
+}
+
+// Subscribe subscribes the current websocket connection to the topic
+// If a connection store is configured then the topic is also added to the
+// connection state, allowing subscriptions to be removed on disconnect.
+func (t *Topics) Subscribe(c Context, topic string) error {
+	rc, ok := WebSocketRequestContext(c)
+	if !ok {
+		return ErrNotWebSocket
+	}
+
+	if err := t.store.Subscribe(c.Context(), topic, rc.ConnectionID); err != nil {
+		return err
+	}
+
+	s, err := LoadConnectionState(c)
+	if errors.Is(err, ErrNoConnectionStore) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, st := range s.Subscriptions {
+		if st == topic {
+			return nil
+		}
+	}
+
+	s.Subscriptions = append(s.Subscriptions, topic)
+	return nil
+}
+
+// Unsubscribe unsubscribes the current websocket connection from the topic
+func (t *Topics) Unsubscribe(c Context, topic string) error {
+	rc, ok := WebSocketRequestContext(c)
+	if !ok {
+		return ErrNotWebSocket
+	}
+
+	if err := t.store.Unsubscribe(c.Context(), topic, rc.ConnectionID); err != nil {
+		return err
+	}
+
+	s, err := LoadConnectionState(c)
+	if errors.Is(err, ErrNoConnectionStore) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	res := s.Subscriptions[:0]
+	for _, st := range s.Subscriptions {
+		if st != topic {
+			res = append(res, st)
+		}
+	}
+
+	s.Subscriptions = res
+	return nil
+}
+
+// UnsubscribeAll unsubscribes the current websocket connection from all topics
+// Subscriptions are read from the connection state, so a connection store must be
+// configured. The func would typically be called from the $disconnect handler.
+func (t *Topics) UnsubscribeAll(c Context) error {
+	s, err := LoadConnectionState(c)
+	if err != nil {
+		return err
+	}
+
+	for _, st := range append([]string(nil), s.Subscriptions...) {
+		if err = t.Unsubscribe(c, st); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Publish posts the payload to all connections subscribed to the topic
+// The payload is marshaled as JSON unless it is a string or byte slice. Closed
+// connections are unsubscribed, and the first delivery error is returned once
+// all connections have been attempted.
+func (t *Topics) Publish(ctx context.Context, topic string, payload interface{}) error {
+	p, err := marshalPayload(DefaultJSONCodec, payload)
+	if err != nil {
+		return err
+	}
+
+	ids, err := t.store.Subscribers(ctx, topic)
+	if err != nil {
+		return err
+	}
+
+	var res error
+	for _, id := range ids {
+		err = t.poster.PostToConnection(ctx, id, []byte(p))
+		if errors.Is(err, ErrConnectionGone) {
+			err = t.store.Unsubscribe(ctx, topic, id)
+		}
+
+		if err != nil && res == nil {
+			res = err
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,130 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestCacheTopicStore(t *testing.T) {
+	ctx := context.Background()
+	sut := rack.NewCacheTopicStore(rack.NewMemoryCache())
+
+	for _, id := range []string{"a", "b", "a"} {
+		err := sut.Subscribe(ctx, "topic", id)
+		assertErrorExists(t, err, false)
+	}
+
+	act, err := sut.Subscribers(ctx, "topic")
+	assertErrorExists(t, err, false)
+	assertDeepEqual(t, act, []string{"a", "b"})
+
+	for _, id := range []string{"a", "b"} {
+		err = sut.Unsubscribe(ctx, "topic", id)
+		assertErrorExists(t, err, false)
+	}
+
+	act, err = sut.Subscribers(ctx, "topic")
+	assertErrorExists(t, err, false)
+	assertDeepEqual(t, act, []string(nil))
+}
+
+func TestTopics(t *testing.T) {
+	ctx := context.Background()
+
+	topics := rack.NewCacheTopicStore(rack.NewMemoryCache())
+	connections := rack.NewCacheConnectionStore(rack.NewMemoryCache(), time.Hour)
+
+	posted := map[string]string{}
+	sut := rack.NewTopics(topics, rack.ConnectionPosterFunc(func(_ context.Context, id string, data []byte) error {
+		if id == "gone" {
+			return rack.ErrConnectionGone
+		}
+		posted[id] = string(data)
+		return nil
+	}))
+
+	invoke := func(id, route string, fn func(rack.Context) error) {
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.WithConnectionStore(connections),
+		}, func(c rack.Context) error {
+			if err := fn(c); err != nil {
+				return err
+			}
+			return c.NoContent(http.StatusOK)
+		})
+
+		b, err := h.Invoke(ctx, newWebSocketRequest(func(r *events.APIGatewayWebsocketProxyRequest) {
+			r.RequestContext.ConnectionID = id
+			r.RequestContext.RouteKey = route
+		}))
+		assertErrorExists(t, err, false)
+
+		res := new(events.APIGatewayProxyResponse)
+		unmarshal(b, res)
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("got %d, expected %d", res.StatusCode, http.StatusOK)
+		}
+	}
+
+	subscribe := func(topic string) func(rack.Context) error {
+		return func(c rack.Context) error {
+			return sut.Subscribe(c, topic)
+		}
+	}
+
+	invoke("a", "subscribe", subscribe("x"))
+	invoke("a", "subscribe", subscribe("y"))
+	invoke("b", "subscribe", subscribe("x"))
+	invoke("gone", "subscribe", subscribe("x"))
+
+	t.Run("should track subscriptions in the connection state", func(t *testing.T) {
+		s, err := connections.Load(ctx, "a")
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, s.Subscriptions, []string{"x", "y"})
+	})
+
+	t.Run("should publish to subscribers", func(t *testing.T) {
+		err := sut.Publish(ctx, "x", map[string]string{"k": "v"})
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, posted, map[string]string{"a": `{"k":"v"}`, "b": `{"k":"v"}`})
+	})
+
+	t.Run("should unsubscribe closed connections", func(t *testing.T) {
+		act, err := topics.Subscribers(ctx, "x")
+		assertErrorExists(t, err, false)
+		sort.Strings(act)
+		assertDeepEqual(t, act, []string{"a", "b"})
+	})
+
+	t.Run("should unsubscribe from all topics", func(t *testing.T) {
+		invoke("a", "$disconnect", sut.UnsubscribeAll)
+
+		x, err := topics.Subscribers(ctx, "x")
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, x, []string{"b"})
+
+		y, err := topics.Subscribers(ctx, "y")
+		assertErrorExists(t, err, false)
+		assertDeepEqual(t, y, []string(nil))
+	})
+
+	t.Run("should return an error for non-websocket requests", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			err := sut.Subscribe(c, "x")
+			if !errors.Is(err, rack.ErrNotWebSocket) {
+				t.Errorf("got %v, expected ErrNotWebSocket", err)
+			}
+			return nil
+		})
+
+		h.Invoke(ctx, newV2Request(nil))
+	})
+}
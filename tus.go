@@ -0,0 +1,489 @@
+package rack
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// MultipartUploader represents an s3 multipart upload client
+	// Implementations would typically wrap an S3 client for a single bucket.
+	MultipartUploader interface {
+		CreateMultipartUpload(ctx context.Context, key string) (uploadID string, err error)
+		UploadPart(ctx context.Context, key, uploadID string, partNumber int, body []byte) (etag string, err error)
+		CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []UploadPart) error
+		AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+	}
+
+	// UploadPart represents a completed multipart upload part
+	UploadPart struct {
+		PartNumber int    `json:"partNumber"`
+		ETag       string `json:"etag"`
+	}
+
+	// TusUpload represents the state of a resumable upload
+	TusUpload struct {
+		ID        string            `json:"id"`
+		Key       string            `json:"key"`
+		UploadID  string            `json:"uploadId"`
+		Length    int64             `json:"length"`
+		Offset    int64             `json:"offset"`
+		Metadata  map[string]string `json:"metadata,omitempty"`
+		Parts     []UploadPart      `json:"parts,omitempty"`
+		Pending   []byte            `json:"pending,omitempty"`
+		Completed bool              `json:"completed,omitempty"`
+
+		// LockedUntil is the unix millisecond time at which the PATCH lock expires
+		LockedUntil int64 `json:"lockedUntil,omitempty"`
+	}
+
+	// TusOptions represents tus resumable upload options
+	TusOptions struct {
+		// Uploader is the multipart upload client
+		Uploader MultipartUploader
+
+		// Store is the upload state cache
+		// Chunks smaller than the part size are buffered in the upload state as base64
+		// encoded JSON, so the cache must support values of at least 4/3 of the part
+		// size. This exceeds the DynamoDB item size limit of 400KB, so an S3 or Redis
+		// backed cache should be used.
+		Store Cache
+
+		// TTL is the upload state lifetime
+		TTL time.Duration
+
+		// Location returns the upload url for the specified upload id
+		Location func(id string) string
+
+		// Key returns the object key for the upload, defaulting to the upload id
+		Key func(c Context, id string, metadata map[string]string) string
+
+		// PathParam is the upload id path parameter, defaulting to id
+		PathParam string
+
+		// MaxSize is the maximum upload size, or zero for no limit
+		MaxSize int64
+
+		// PartSize is the multipart upload part size, defaulting to 5MiB
+		PartSize int64
+
+		// OnComplete is invoked once the upload has been completed
+		OnComplete func(Context, *TusUpload) error
+	}
+)
+
+const (
+	// TusVersion is the supported tus protocol version
+	TusVersion = "1.0.0"
+
+	// TusContentType is the tus upload chunk media type
+	TusContentType = "application/offset+octet-stream"
+
+	defaultPartSize = 5 << 20
+
+	// defaultUploadLock is the lock duration if the invocation has no deadline
+	defaultUploadLock = 15 * time.Minute
+)
+
+var (
+	// ErrUploadNotFound indicates that the upload does not exist
+	ErrUploadNotFound = errors.New("upload not found")
+
+	// ErrUploadLocked indicates that a chunk is being written to the upload by another request
+	ErrUploadLocked = errors.New("upload locked")
+
+	// ErrUploadModified indicates that the upload state was modified by another request
+	ErrUploadModified = errors.New("upload modified")
+
+	errTusVersion = errors.New("unsupported tus version")
+)
+
+// Tus returns a handler func that implements the tus resumable upload protocol
+// The creation and termination extensions are supported. The handler should be
+// routed for OPTIONS and POST requests to the collection url, and HEAD, PATCH and
+// DELETE requests to the upload url. Chunks are buffered until the part size is
+// reached, then uploaded as multipart upload parts, with the upload completed once
+// all bytes have been received. Upload state is written using conditional cache
+// writes, and each PATCH request locks the upload until the invocation deadline,
+// so concurrent requests receive a 423 error rather than uploading the same parts.
+func Tus(o TusOptions) HandlerFunc {
+	param := o.PathParam
+	if param == "" {
+		param = "id"
+	}
+
+	partSize := o.PartSize
+	if partSize <= 0 {
+		partSize = defaultPartSize
+	}
+
+	key := o.Key
+	if key == nil {
+		key = func(_ Context, id string, _ map[string]string) string {
+			return id
+		}
+	}
+
+	onComplete := o.OnComplete
+	if onComplete == nil {
+		onComplete = func(Context, *TusUpload) error { return nil }
+	}
+
+	// load returns the upload state along with the stored value for conditional writes
+	load := func(c Context) (*TusUpload, []byte, error) {
+		b, ok, err := o.Store.Get(c.Context(), c.Path(param))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !ok {
+			return nil, nil, WrapError(http.StatusNotFound, ErrUploadNotFound)
+		}
+
+		u := new(TusUpload)
+		if err = json.Unmarshal(b, u); err != nil {
+			return nil, nil, err
+		}
+
+		return u, b, nil
+	}
+
+	// save stores the upload state if the stored value has not been modified
+	// New uploads are stored with a nil previous value. The stored value is returned.
+	save := func(c Context, u *TusUpload, prev []byte) ([]byte, error) {
+		b, err := json.Marshal(u)
+		if err != nil {
+			return nil, err
+		}
+
+		var ok bool
+		if prev == nil {
+			ok, err = o.Store.SetIfAbsent(c.Context(), u.ID, b, o.TTL)
+		} else {
+			ok, err = o.Store.CompareAndSwap(c.Context(), u.ID, prev, b, o.TTL)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return nil, WrapError(http.StatusConflict, ErrUploadModified)
+		}
+
+		return b, nil
+	}
+
+	// complete completes the multipart upload and saves the state before invoking
+	// the completion func, so that retries do not complete the upload again
+	complete := func(c Context, u *TusUpload, prev []byte) error {
+		if err := completeUpload(c, o.Uploader, u); err != nil {
+			return err
+		}
+
+		u.Completed = true
+		u.LockedUntil = 0
+		if _, err := save(c, u, prev); err != nil {
+			return err
+		}
+
+		return onComplete(c, u)
+	}
+
+	// write buffers the chunk, uploading parts once the part size is reached, and
+	// saves the state with the lock released
+	write := func(c Context, u *TusUpload, b, prev []byte) error {
+		u.Pending = append(u.Pending, b...)
+		u.Offset += int64(len(b))
+
+		for int64(len(u.Pending)) >= partSize {
+			if err := uploadPart(c, o.Uploader, u, u.Pending[:partSize]); err != nil {
+				return err
+			}
+
+			u.Pending = u.Pending[partSize:]
+		}
+
+		if u.Offset == u.Length {
+			return complete(c, u, prev)
+		}
+
+		u.LockedUntil = 0
+		_, err := save(c, u, prev)
+		return err
+	}
+
+	options := func(c Context) error {
+		h := c.Response().Headers
+		h.Set("Tus-Version", TusVersion)
+		h.Set("Tus-Extension", "creation,termination")
+		if o.MaxSize > 0 {
+			h.Set("Tus-Max-Size", strconv.FormatInt(o.MaxSize, 10))
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	create := func(c Context) error {
+		length, err := strconv.ParseInt(c.Request().HeaderValue("Upload-Length"), 10, 64)
+		if err != nil || length < 0 {
+			return WrapError(http.StatusBadRequest, errors.New("invalid upload length"))
+		}
+
+		if o.MaxSize > 0 && length > o.MaxSize {
+			return WrapError(http.StatusRequestEntityTooLarge, errors.New("upload length exceeds the maximum size"))
+		}
+
+		md, err := parseUploadMetadata(c.Request().HeaderValue("Upload-Metadata"))
+		if err != nil {
+			return WrapError(http.StatusBadRequest, err)
+		}
+
+		u := &TusUpload{
+			ID:       newUploadID(),
+			Length:   length,
+			Metadata: md,
+		}
+		u.Key = key(c, u.ID, md)
+
+		if u.UploadID, err = o.Uploader.CreateMultipartUpload(c.Context(), u.Key); err != nil {
+			return err
+		}
+
+		if length == 0 {
+			err = complete(c, u, nil)
+		} else {
+			_, err = save(c, u, nil)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if o.Location != nil {
+			c.Response().Headers.Set("Location", o.Location(u.ID))
+		}
+
+		return c.NoContent(http.StatusCreated)
+	}
+
+	head := func(c Context) error {
+		u, _, err := load(c)
+		if err != nil {
+			return err
+		}
+
+		h := c.Response().Headers
+		h.Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+		h.Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+		h.Set("Cache-Control", "no-store")
+		if len(u.Metadata) > 0 {
+			h.Set("Upload-Metadata", formatUploadMetadata(u.Metadata))
+		}
+
+		return c.NoContent(http.StatusOK)
+	}
+
+	patch := func(c Context) error {
+		if mt, _, _ := mime.ParseMediaType(c.Request().HeaderValue("Content-Type")); mt != TusContentType {
+			return WrapError(http.StatusUnsupportedMediaType, fmt.Errorf("content type must be %s", TusContentType))
+		}
+
+		offset, err := strconv.ParseInt(c.Request().HeaderValue("Upload-Offset"), 10, 64)
+		if err != nil {
+			return WrapError(http.StatusBadRequest, errors.New("invalid upload offset"))
+		}
+
+		u, prev, err := load(c)
+		if err != nil {
+			return err
+		}
+
+		if offset != u.Offset {
+			return WrapError(http.StatusConflict, errors.New("upload offset does not match"))
+		}
+
+		b, err := requestBody(c.Request())
+		if err != nil {
+			return err
+		}
+
+		if u.Offset+int64(len(b)) > u.Length {
+			return WrapError(http.StatusRequestEntityTooLarge, errors.New("chunk exceeds the upload length"))
+		}
+
+		if u.Completed {
+			// the offset equals the length, so the chunk is empty and there is nothing to write
+			c.Response().Headers.Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+			return c.NoContent(http.StatusNoContent)
+		}
+
+		now := time.Now()
+		if u.LockedUntil > now.UnixMilli() {
+			return WrapError(http.StatusLocked, ErrUploadLocked)
+		}
+
+		// lock the upload before uploading parts, as concurrent requests at the same
+		// offset would otherwise upload different data with the same part number
+		lockedUntil := now.Add(defaultUploadLock)
+		if d, ok := c.Context().Deadline(); ok {
+			lockedUntil = d
+		}
+
+		u.LockedUntil = lockedUntil.UnixMilli()
+		locked, err := save(c, u, prev)
+		if err != nil {
+			return err
+		}
+
+		if err = write(c, u, b, locked); err != nil {
+			// release the lock so that the client can retry, unless the state has changed
+			if _, rerr := o.Store.CompareAndSwap(c.Context(), u.ID, locked, prev, o.TTL); rerr != nil {
+				err = fmt.Errorf("%w: release lock: %v", err, rerr)
+			}
+			return err
+		}
+
+		c.Response().Headers.Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	terminate := func(c Context) error {
+		u, _, err := load(c)
+		if err != nil {
+			return err
+		}
+
+		if !u.Completed {
+			if err = o.Uploader.AbortMultipartUpload(c.Context(), u.Key, u.UploadID); err != nil {
+				return err
+			}
+		}
+
+		if err = o.Store.Delete(c.Context(), u.ID); err != nil {
+			return err
+		}
+
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	return func(c Context) error {
+		c.Response().Headers.Set("Tus-Resumable", TusVersion)
+
+		m := c.Request().Method
+		if m == http.MethodOptions {
+			return options(c)
+		}
+
+		if c.Request().HeaderValue("Tus-Resumable") != TusVersion {
+			return WrapError(http.StatusPreconditionFailed, errTusVersion).WithHeader("Tus-Version", TusVersion)
+		}
+
+		switch m {
+		case http.MethodPost:
+			return create(c)
+		case http.MethodHead:
+			return head(c)
+		case http.MethodPatch:
+			return patch(c)
+		case http.MethodDelete:
+			return terminate(c)
+		}
+
+		return WrapError(http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// newUploadID returns a new random upload id
+// Upload ids are used as the upload url capability, so 128 bits of randomness are
+// encoded without padding for use in paths.
+func newUploadID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func uploadPart(c Context, up MultipartUploader, u *TusUpload, b []byte) error {
+	n := len(u.Parts) + 1
+
+	etag, err := up.UploadPart(c.Context(), u.Key, u.UploadID, n, b)
+	if err != nil {
+		return err
+	}
+
+	u.Parts = append(u.Parts, UploadPart{PartNumber: n, ETag: etag})
+	return nil
+}
+
+// completeUpload uploads any pending bytes as the final part and completes the upload
+// S3 requires at least one part, so an empty part is uploaded for empty uploads.
+func completeUpload(c Context, up MultipartUploader, u *TusUpload) error {
+	if len(u.Pending) > 0 || len(u.Parts) == 0 {
+		if err := uploadPart(c, up, u, u.Pending); err != nil {
+			return err
+		}
+
+		u.Pending = nil
+	}
+
+	return up.CompleteMultipartUpload(c.Context(), u.Key, u.UploadID, u.Parts)
+}
+
+// parseUploadMetadata parses the tus Upload-Metadata header
+// The header contains comma separated key value pairs, with base64 encoded values.
+func parseUploadMetadata(h string) (map[string]string, error) {
+	if strings.TrimSpace(h) == "" {
+		return nil, nil
+	}
+
+	md := map[string]string{}
+	for _, p := range strings.Split(h, ",") {
+		kv := strings.Fields(p)
+		if len(kv) == 0 || len(kv) > 2 {
+			return nil, errors.New("invalid upload metadata")
+		}
+
+		var v []byte
+		if len(kv) == 2 {
+			var err error
+			if v, err = base64.StdEncoding.DecodeString(kv[1]); err != nil {
+				return nil, errors.New("invalid upload metadata")
+			}
+		}
+
+		md[kv[0]] = string(v)
+	}
+
+	return md, nil
+}
+
+func formatUploadMetadata(md map[string]string) string {
+	ks := make([]string, 0, len(md))
+	for k := range md {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+
+	ps := make([]string, len(ks))
+	for i, k := range ks {
+		ps[i] = k
+		if v := md[k]; v != "" {
+			ps[i] += " " + base64.StdEncoding.EncodeToString([]byte(v))
+		}
+	}
+
+	return strings.Join(ps, ",")
+}
@@ -0,0 +1,540 @@
+package rack_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+type testUploader struct {
+	parts     map[int]string
+	completed []rack.UploadPart
+	completes int
+	aborted   bool
+	err       error
+}
+
+func (u *testUploader) CreateMultipartUpload(context.Context, string) (string, error) {
+	u.parts = map[int]string{}
+	return "uploadid", u.err
+}
+
+func (u *testUploader) UploadPart(_ context.Context, _, _ string, n int, b []byte) (string, error) {
+	u.parts[n] = string(b)
+	return "etag" + string(rune('0'+n)), u.err
+}
+
+func (u *testUploader) CompleteMultipartUpload(_ context.Context, _, _ string, parts []rack.UploadPart) error {
+	u.completed = parts
+	u.completes++
+	return u.err
+}
+
+func (u *testUploader) AbortMultipartUpload(context.Context, string, string) error {
+	u.aborted = true
+	return u.err
+}
+
+func TestTus(t *testing.T) {
+	type request struct {
+		method  string
+		headers map[string]string
+		body    string
+	}
+
+	type response struct {
+		code    int
+		headers map[string]string
+	}
+
+	tusHeaders := func(kv ...string) map[string]string {
+		h := map[string]string{"Tus-Resumable": rack.TusVersion}
+		for i := 0; i < len(kv); i += 2 {
+			h[kv[i]] = kv[i+1]
+		}
+		return h
+	}
+
+	chunk := func(offset, body string) request {
+		return request{
+			method:  http.MethodPatch,
+			headers: tusHeaders("Content-Type", rack.TusContentType, "Upload-Offset", offset),
+			body:    body,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		opts      rack.TusOptions
+		requests  []request
+		exp       []response
+		parts     map[int]string
+		completed []rack.UploadPart
+		aborted   bool
+	}{
+		{
+			name:     "should return options",
+			opts:     rack.TusOptions{MaxSize: 100},
+			requests: []request{{method: http.MethodOptions}},
+			exp: []response{{
+				code: http.StatusNoContent,
+				headers: map[string]string{
+					"Tus-Resumable": rack.TusVersion,
+					"Tus-Version":   rack.TusVersion,
+					"Tus-Extension": "creation,termination",
+					"Tus-Max-Size":  "100",
+				},
+			}},
+		},
+		{
+			name:     "should return precondition failed for unsupported versions",
+			requests: []request{{method: http.MethodPost, headers: map[string]string{"Upload-Length": "1"}}},
+			exp: []response{{
+				code:    http.StatusPreconditionFailed,
+				headers: map[string]string{"Tus-Version": rack.TusVersion},
+			}},
+		},
+		{
+			name:     "should return bad request for invalid lengths",
+			requests: []request{{method: http.MethodPost, headers: tusHeaders("Upload-Length", "a")}},
+			exp:      []response{{code: http.StatusBadRequest}},
+		},
+		{
+			name:     "should return entity too large if the length exceeds the maximum size",
+			opts:     rack.TusOptions{MaxSize: 10},
+			requests: []request{{method: http.MethodPost, headers: tusHeaders("Upload-Length", "11")}},
+			exp:      []response{{code: http.StatusRequestEntityTooLarge}},
+		},
+		{
+			name:     "should return bad request for invalid metadata",
+			requests: []request{{method: http.MethodPost, headers: tusHeaders("Upload-Length", "1", "Upload-Metadata", "name !")}},
+			exp:      []response{{code: http.StatusBadRequest}},
+		},
+		{
+			name:     "should return not found for missing uploads",
+			requests: []request{{method: http.MethodHead, headers: tusHeaders()}},
+			exp:      []response{{code: http.StatusNotFound}},
+		},
+		{
+			name: "should create and upload chunks",
+			requests: []request{
+				{method: http.MethodPost, headers: tusHeaders("Upload-Length", "10", "Upload-Metadata", "filename ZmlsZS50eHQ=,empty")},
+				chunk("0", "abc"),
+				{method: http.MethodHead, headers: tusHeaders()},
+				chunk("3", "defgh"),
+				chunk("8", "ij"),
+			},
+			exp: []response{
+				{code: http.StatusCreated, headers: map[string]string{"Location": "/files/upload"}},
+				{code: http.StatusNoContent, headers: map[string]string{"Upload-Offset": "3"}},
+				{code: http.StatusOK, headers: map[string]string{
+					"Upload-Offset":   "3",
+					"Upload-Length":   "10",
+					"Upload-Metadata": "empty,filename ZmlsZS50eHQ=",
+					"Cache-Control":   "no-store",
+				}},
+				{code: http.StatusNoContent, headers: map[string]string{"Upload-Offset": "8"}},
+				{code: http.StatusNoContent, headers: map[string]string{"Upload-Offset": "10"}},
+			},
+			parts:     map[int]string{1: "abcd", 2: "efgh", 3: "ij"},
+			completed: []rack.UploadPart{{PartNumber: 1, ETag: "etag1"}, {PartNumber: 2, ETag: "etag2"}, {PartNumber: 3, ETag: "etag3"}},
+		},
+		{
+			name: "should complete empty uploads",
+			requests: []request{
+				{method: http.MethodPost, headers: tusHeaders("Upload-Length", "0")},
+			},
+			exp: []response{
+				{code: http.StatusCreated},
+			},
+			parts:     map[int]string{1: ""},
+			completed: []rack.UploadPart{{PartNumber: 1, ETag: "etag1"}},
+		},
+		{
+			name: "should reject invalid chunks",
+			requests: []request{
+				{method: http.MethodPost, headers: tusHeaders("Upload-Length", "4")},
+				{method: http.MethodPatch, headers: tusHeaders("Upload-Offset", "0"), body: "ab"},
+				chunk("1", "ab"),
+				chunk("0", "abcde"),
+				chunk("a", "ab"),
+			},
+			exp: []response{
+				{code: http.StatusCreated},
+				{code: http.StatusUnsupportedMediaType},
+				{code: http.StatusConflict},
+				{code: http.StatusRequestEntityTooLarge},
+				{code: http.StatusBadRequest},
+			},
+			parts: map[int]string{},
+		},
+		{
+			name: "should terminate uploads",
+			requests: []request{
+				{method: http.MethodPost, headers: tusHeaders("Upload-Length", "4")},
+				{method: http.MethodDelete, headers: tusHeaders()},
+				{method: http.MethodHead, headers: tusHeaders()},
+			},
+			exp: []response{
+				{code: http.StatusCreated},
+				{code: http.StatusNoContent},
+				{code: http.StatusNotFound},
+			},
+			parts:   map[int]string{},
+			aborted: true,
+		},
+		{
+			name:     "should return method not allowed for other methods",
+			requests: []request{{method: http.MethodGet, headers: tusHeaders()}},
+			exp:      []response{{code: http.StatusMethodNotAllowed}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := new(testUploader)
+
+			o := tt.opts
+			o.Uploader = up
+			o.Store = rack.NewMemoryCache()
+			o.TTL = time.Hour
+			o.PartSize = 4
+			o.Location = func(string) string { return "/files/upload" }
+
+			// the upload id is random, so the key is captured on creation
+			var id string
+			o.Key = func(_ rack.Context, uid string, _ map[string]string) string {
+				id = uid
+				return "key"
+			}
+
+			h := rack.New(rack.Tus(o))
+
+			for i, r := range tt.requests {
+				b, err := h.Invoke(context.Background(), newV2Request(func(e *events.APIGatewayV2HTTPRequest) {
+					e.RequestContext.HTTP.Method = r.method
+					e.PathParameters = map[string]string{"id": id}
+					e.Headers = r.headers
+					e.Body = base64.StdEncoding.EncodeToString([]byte(r.body))
+					e.IsBase64Encoded = true
+				}))
+				assertErrorExists(t, err, false)
+
+				res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+				if res.StatusCode != tt.exp[i].code {
+					t.Errorf("request %d: got %d, expected %d (%s)", i, res.StatusCode, tt.exp[i].code, res.Body)
+				}
+
+				for k, v := range tt.exp[i].headers {
+					if act := res.Headers[k]; act != v {
+						t.Errorf("request %d: got %s %s, expected %s", i, k, act, v)
+					}
+				}
+			}
+
+			assertDeepEqual(t, up.parts, tt.parts)
+			assertDeepEqual(t, up.completed, tt.completed)
+			assertDeepEqual(t, up.aborted, tt.aborted)
+		})
+	}
+
+	t.Run("should not complete uploads more than once", func(t *testing.T) {
+		up := new(testUploader)
+
+		var id string
+		var calls int
+		h := rack.New(rack.Tus(rack.TusOptions{
+			Uploader: up,
+			Store:    rack.NewMemoryCache(),
+			Key: func(_ rack.Context, uid string, _ map[string]string) string {
+				id = uid
+				return "key"
+			},
+			OnComplete: func(rack.Context, *rack.TusUpload) error {
+				calls++
+				return nil
+			},
+		}))
+
+		requests := []struct {
+			method  string
+			headers map[string]string
+			body    string
+			code    int
+		}{
+			{method: http.MethodPost, headers: map[string]string{"Upload-Length": "2"}, code: http.StatusCreated},
+			{method: http.MethodPatch, headers: map[string]string{"Upload-Offset": "0"}, body: "ab", code: http.StatusNoContent},
+			{method: http.MethodPatch, headers: map[string]string{"Upload-Offset": "2"}, code: http.StatusNoContent},
+			{method: http.MethodDelete, code: http.StatusNoContent},
+		}
+
+		for i, r := range requests {
+			b, err := h.Invoke(context.Background(), newV2Request(func(e *events.APIGatewayV2HTTPRequest) {
+				e.RequestContext.HTTP.Method = r.method
+				e.PathParameters = map[string]string{"id": id}
+				e.Headers = map[string]string{"Tus-Resumable": rack.TusVersion, "Content-Type": rack.TusContentType}
+				for k, v := range r.headers {
+					e.Headers[k] = v
+				}
+				e.Body = r.body
+			}))
+			assertErrorExists(t, err, false)
+
+			res := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			if res.StatusCode != r.code {
+				t.Errorf("request %d: got %d, expected %d (%s)", i, res.StatusCode, r.code, res.Body)
+			}
+		}
+
+		assertDeepEqual(t, up.completes, 1)
+		assertDeepEqual(t, calls, 1)
+		assertDeepEqual(t, up.aborted, false)
+	})
+
+	t.Run("should control concurrent chunks", func(t *testing.T) {
+		type step struct {
+			offset string
+			body   string
+			code   int
+		}
+
+		tests := []struct {
+			name   string
+			before func(store rack.Cache, id string)
+			err    bool
+			steps  []step
+			parts  map[int]string
+		}{
+			{
+				name: "should return locked if a chunk is being written",
+				before: func(store rack.Cache, id string) {
+					b, _, _ := store.Get(context.Background(), id)
+					u := new(rack.TusUpload)
+					unmarshal(b, u)
+					u.LockedUntil = time.Now().Add(time.Hour).UnixMilli()
+					store.Set(context.Background(), id, marshal(u), time.Hour)
+				},
+				steps: []step{{offset: "0", body: "abcd", code: http.StatusLocked}},
+				parts: map[int]string{},
+			},
+			{
+				name: "should ignore expired locks",
+				before: func(store rack.Cache, id string) {
+					b, _, _ := store.Get(context.Background(), id)
+					u := new(rack.TusUpload)
+					unmarshal(b, u)
+					u.LockedUntil = time.Now().Add(-time.Second).UnixMilli()
+					store.Set(context.Background(), id, marshal(u), time.Hour)
+				},
+				steps: []step{{offset: "0", body: "abcd", code: http.StatusNoContent}},
+				parts: map[int]string{1: "abcd"},
+			},
+			{
+				name: "should release the lock on error",
+				err:  true,
+				steps: []step{
+					{offset: "0", body: "abcd", code: http.StatusInternalServerError},
+					{offset: "0", body: "abcd", code: http.StatusNoContent},
+				},
+				parts: map[int]string{1: "abcd"},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				up := new(testUploader)
+				store := rack.NewMemoryCache()
+
+				var id string
+				h := rack.New(rack.Tus(rack.TusOptions{
+					Uploader: up,
+					Store:    store,
+					PartSize: 4,
+					Key: func(_ rack.Context, uid string, _ map[string]string) string {
+						id = uid
+						return "key"
+					},
+				}))
+
+				invoke := func(method, offset, body string) int {
+					b, err := h.Invoke(context.Background(), newV2Request(func(e *events.APIGatewayV2HTTPRequest) {
+						e.RequestContext.HTTP.Method = method
+						e.PathParameters = map[string]string{"id": id}
+						e.Headers = map[string]string{
+							"Tus-Resumable": rack.TusVersion,
+							"Content-Type":  rack.TusContentType,
+							"Upload-Length": "8",
+							"Upload-Offset": offset,
+						}
+						e.Body = body
+					}))
+					assertErrorExists(t, err, false)
+
+					return unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse).StatusCode
+				}
+
+				assertDeepEqual(t, invoke(http.MethodPost, "", ""), http.StatusCreated)
+
+				if tt.before != nil {
+					tt.before(store, id)
+				}
+
+				for i, s := range tt.steps {
+					up.err = nil
+					if tt.err && i == 0 {
+						up.err = errors.New("error")
+					}
+
+					if act := invoke(http.MethodPatch, s.offset, s.body); act != s.code {
+						t.Errorf("step %d: got %d, expected %d", i, act, s.code)
+					}
+				}
+
+				assertDeepEqual(t, up.parts, tt.parts)
+			})
+		}
+	})
+
+	t.Run("should return conflict if the upload is modified", func(t *testing.T) {
+		up := new(testUploader)
+		store := &swapCache{Cache: rack.NewMemoryCache()}
+
+		var id string
+		h := rack.New(rack.Tus(rack.TusOptions{
+			Uploader: up,
+			Store:    store,
+			PartSize: 4,
+			Key: func(_ rack.Context, uid string, _ map[string]string) string {
+				id = uid
+				return "key"
+			},
+		}))
+
+		var codes []int
+		for _, method := range []string{http.MethodPost, http.MethodPatch} {
+			b, err := h.Invoke(context.Background(), newV2Request(func(e *events.APIGatewayV2HTTPRequest) {
+				e.RequestContext.HTTP.Method = method
+				e.PathParameters = map[string]string{"id": id}
+				e.Headers = map[string]string{
+					"Tus-Resumable": rack.TusVersion,
+					"Content-Type":  rack.TusContentType,
+					"Upload-Length": "8",
+					"Upload-Offset": "0",
+				}
+				e.Body = "abcd"
+			}))
+			assertErrorExists(t, err, false)
+
+			codes = append(codes, unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse).StatusCode)
+
+			// simulate a concurrent write once the upload has been loaded
+			store.modify = true
+		}
+
+		assertDeepEqual(t, codes, []int{http.StatusCreated, http.StatusConflict})
+		assertDeepEqual(t, up.parts, map[int]string{})
+	})
+
+	t.Run("should return lock release errors", func(t *testing.T) {
+		werr, rerr := errors.New("write error"), errors.New("release error")
+		up := new(testUploader)
+
+		var id string
+		tus := rack.Tus(rack.TusOptions{
+			Uploader: up,
+			Store:    &errorSwapCache{Cache: rack.NewMemoryCache(), err: rerr},
+			PartSize: 4,
+			Key: func(_ rack.Context, uid string, _ map[string]string) string {
+				id = uid
+				return "key"
+			},
+		})
+
+		var act error
+		h := rack.New(func(c rack.Context) error {
+			act = tus(c)
+			return act
+		})
+
+		for _, method := range []string{http.MethodPost, http.MethodPatch} {
+			_, err := h.Invoke(context.Background(), newV2Request(func(e *events.APIGatewayV2HTTPRequest) {
+				e.RequestContext.HTTP.Method = method
+				e.PathParameters = map[string]string{"id": id}
+				e.Headers = map[string]string{
+					"Tus-Resumable": rack.TusVersion,
+					"Content-Type":  rack.TusContentType,
+					"Upload-Length": "8",
+					"Upload-Offset": "0",
+				}
+				e.Body = "abcd"
+			}))
+			assertErrorExists(t, err, false)
+
+			// fail the chunk write once the upload has been created
+			up.err = werr
+		}
+
+		if !errors.Is(act, werr) || !strings.Contains(act.Error(), rerr.Error()) {
+			t.Errorf("got %v, expected %v and %v", act, werr, rerr)
+		}
+	})
+
+	t.Run("should return uploader errors", func(t *testing.T) {
+		exp := errors.New("error")
+
+		h := rack.New(func(c rack.Context) error {
+			err := rack.Tus(rack.TusOptions{
+				Uploader: &testUploader{err: exp},
+				Store:    rack.NewMemoryCache(),
+			})(c)
+
+			if err != exp {
+				t.Errorf("got %v, expected %v", err, exp)
+			}
+			return nil
+		})
+
+		_, err := h.Invoke(context.Background(), newV2Request(func(e *events.APIGatewayV2HTTPRequest) {
+			e.RequestContext.HTTP.Method = http.MethodPost
+			e.Headers = map[string]string{"Tus-Resumable": rack.TusVersion, "Upload-Length": "1"}
+		}))
+		assertErrorExists(t, err, false)
+	})
+}
+
+type swapCache struct {
+	rack.Cache
+	modify bool
+}
+
+func (c *swapCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	if c.modify {
+		c.Cache.Set(ctx, key, append([]byte(" "), old...), ttl)
+	}
+
+	return c.Cache.CompareAndSwap(ctx, key, old, value, ttl)
+}
+
+type errorSwapCache struct {
+	rack.Cache
+	swaps int
+	err   error
+}
+
+// CompareAndSwap returns the error for each swap after the first
+func (c *errorSwapCache) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	c.swaps++
+	if c.swaps > 1 {
+		return false, c.err
+	}
+
+	return c.Cache.CompareAndSwap(ctx, key, old, value, ttl)
+}
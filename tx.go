@@ -0,0 +1,80 @@
+package rack
+
+import (
+	"context"
+	"database/sql"
+)
+
+type (
+	// Tx represents a transaction
+	Tx interface {
+		Commit() error
+		Rollback() error
+	}
+
+	// TxStarter represents a transaction starter
+	TxStarter interface {
+		BeginTx(ctx context.Context) (Tx, error)
+	}
+
+	// TxStarterFunc represents a transaction starter func
+	TxStarterFunc func(ctx context.Context) (Tx, error)
+)
+
+const txKey = "rack.tx"
+
+// BeginTx begins a new transaction
+func (fn TxStarterFunc) BeginTx(ctx context.Context) (Tx, error) {
+	return fn(ctx)
+}
+
+// SQLTxStarter returns a transaction starter for the specified database
+func SQLTxStarter(db *sql.DB, opts *sql.TxOptions) TxStarter {
+	return TxStarterFunc(func(ctx context.Context) (Tx, error) {
+		return db.BeginTx(ctx, opts)
+	})
+}
+
+// Transaction returns a middleware func that wraps the handler in a transaction
+// The transaction is committed if the handler succeeds, and rolled back if the
+// handler returns an error or panics. Panics are re-raised after rollback.
+func Transaction(s TxStarter) MiddlewareFunc {
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) (err error) {
+			tx, err := s.BeginTx(c.Context())
+			if err != nil {
+				return err
+			}
+
+			defer func() {
+				if v := recover(); v != nil {
+					tx.Rollback()
+					panic(v)
+				}
+			}()
+
+			c.Set(txKey, tx)
+
+			if err = n(c); err != nil {
+				tx.Rollback()
+				return err
+			}
+
+			return tx.Commit()
+		}
+	}
+}
+
+// TxFromContext returns the request transaction
+// Nil is returned if the Transaction middleware has not been applied.
+func TxFromContext(c Context) Tx {
+	tx, _ := c.Get(txKey).(Tx)
+	return tx
+}
+
+// SQLTxFromContext returns the request database/sql transaction
+// Nil is returned if the transaction was not started using SQLTxStarter.
+func SQLTxFromContext(c Context) *sql.Tx {
+	tx, _ := c.Get(txKey).(*sql.Tx)
+	return tx
+}
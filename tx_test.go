@@ -0,0 +1,113 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stevecallear/rack"
+)
+
+type testTx struct {
+	committed  bool
+	rolledBack bool
+}
+
+func (t *testTx) Commit() error {
+	t.committed = true
+	return nil
+}
+
+func (t *testTx) Rollback() error {
+	t.rolledBack = true
+	return nil
+}
+
+func TestTransaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		beginErr error
+		handler  rack.HandlerFunc
+		status   int
+		exp      testTx
+	}{
+		{
+			name:     "should return begin errors",
+			beginErr: errors.New("error"),
+			handler: func(c rack.Context) error {
+				return c.NoContent(http.StatusOK)
+			},
+			status: http.StatusInternalServerError,
+		},
+		{
+			name: "should commit on success",
+			handler: func(c rack.Context) error {
+				if rack.TxFromContext(c) == nil {
+					return errors.New("no transaction")
+				}
+				return c.NoContent(http.StatusOK)
+			},
+			status: http.StatusOK,
+			exp:    testTx{committed: true},
+		},
+		{
+			name: "should rollback on error",
+			handler: func(c rack.Context) error {
+				return rack.WrapError(http.StatusConflict, errors.New("error"))
+			},
+			status: http.StatusConflict,
+			exp:    testTx{rolledBack: true},
+		},
+		{
+			name: "should rollback on panic",
+			handler: func(c rack.Context) error {
+				panic("error")
+			},
+			status: http.StatusInternalServerError,
+			exp:    testTx{rolledBack: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := new(testTx)
+
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.Transaction(rack.TxStarterFunc(func(context.Context) (rack.Tx, error) {
+					if tt.beginErr != nil {
+						return nil, tt.beginErr
+					}
+					return tx, nil
+				})),
+				Recover: true,
+				OnComplete: func(_ rack.Context, r rack.FinalizedResponse, _ error) {
+					if r.StatusCode != tt.status {
+						t.Errorf("got %d, expected %d", r.StatusCode, tt.status)
+					}
+				},
+			}, tt.handler)
+
+			_, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+			assertDeepEqual(t, *tx, tt.exp)
+		})
+	}
+}
+
+func TestTxFromContext(t *testing.T) {
+	t.Run("should return nil if no transaction exists", func(t *testing.T) {
+		h := rack.New(func(c rack.Context) error {
+			if tx := rack.TxFromContext(c); tx != nil {
+				t.Errorf("got %v, expected nil", tx)
+			}
+			if tx := rack.SQLTxFromContext(c); tx != nil {
+				t.Errorf("got %v, expected nil", tx)
+			}
+			return nil
+		})
+
+		_, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+	})
+}
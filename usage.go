@@ -0,0 +1,245 @@
+package rack
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type (
+	// Usage represents billable usage units recorded for a request
+	Usage struct {
+		Tenant    string
+		Dimension string
+		Units     int64
+	}
+
+	// UsageRecord represents usage units aggregated by tenant and dimension
+	UsageRecord struct {
+		Tenant    string    `json:"tenant"`
+		Dimension string    `json:"dimension"`
+		Units     int64     `json:"units"`
+		Start     time.Time `json:"start"`
+		End       time.Time `json:"end"`
+	}
+
+	// UsageSink represents a usage record sink
+	// Implementations would typically wrap a Kinesis PutRecords call or an S3 PutObject
+	// call. EventUsageSink publishes records using an EventPublisher.
+	UsageSink interface {
+		WriteUsage(ctx context.Context, records []UsageRecord) error
+	}
+
+	// UsageSinkFunc represents a usage record sink func
+	UsageSinkFunc func(ctx context.Context, records []UsageRecord) error
+
+	// MeterOptions represents usage meter options
+	MeterOptions struct {
+		// Extract returns the usage for the request
+		// The handler error is specified so that failed requests can be excluded.
+		Extract func(c Context, err error) []Usage
+
+		// Sink receives the aggregated usage records
+		Sink UsageSink
+
+		// Interval is the flush interval, defaulting to one minute
+		Interval time.Duration
+
+		// Now returns the current time, defaulting to time.Now
+		Now func() time.Time
+	}
+
+	// Meter aggregates usage and flushes records to a sink
+	Meter struct {
+		extract   func(Context, error) []Usage
+		sink      UsageSink
+		interval  time.Duration
+		now       func() time.Time
+		mu        sync.Mutex
+		pending   map[usageKey]*UsageRecord
+		flushedAt time.Time
+		flushing  bool
+	}
+
+	usageKey struct {
+		tenant    string
+		dimension string
+	}
+)
+
+// UsageEventDetailType is the detail type of events published by EventUsageSink
+const UsageEventDetailType = "UsageRecorded"
+
+// WriteUsage writes the usage records
+func (fn UsageSinkFunc) WriteUsage(ctx context.Context, records []UsageRecord) error {
+	return fn(ctx, records)
+}
+
+// EventUsageSink returns a usage sink that publishes each record as an event
+// Records are published to the specified bus and source with the detail type
+// UsageEventDetailType.
+func EventUsageSink(p EventPublisher, bus, source string) UsageSink {
+	return UsageSinkFunc(func(ctx context.Context, records []UsageRecord) error {
+		for _, r := range records {
+			d, err := marshalPayload(DefaultJSONCodec, r)
+			if err != nil {
+				return err
+			}
+
+			if err = p.Publish(ctx, &Event{
+				Bus:        bus,
+				Source:     source,
+				DetailType: UsageEventDetailType,
+				Detail:     d,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// NewMeter returns a new usage meter for the specified options
+func NewMeter(o MeterOptions) *Meter {
+	interval := o.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	now := o.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Meter{
+		extract:   o.Extract,
+		sink:      o.Sink,
+		interval:  interval,
+		now:       now,
+		pending:   map[usageKey]*UsageRecord{},
+		flushedAt: now(),
+	}
+}
+
+// Middleware is a middleware func that records usage for each request
+// Usage is aggregated in memory by tenant and dimension. Once the flush interval has
+// elapsed the records are flushed as a deferred task, so errors are passed to
+// OnDeferError and the records are retained for the next flush.
+func (m *Meter) Middleware(n HandlerFunc) HandlerFunc {
+	return func(c Context) error {
+		err := n(c)
+
+		m.Record(m.extract(c, err)...)
+
+		if m.due() {
+			Defer(c, m.Flush)
+		}
+
+		return err
+	}
+}
+
+// Record adds the specified usage to the pending records
+// Usage with an empty tenant or zero units is ignored.
+func (m *Meter) Record(u ...Usage) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.now()
+	for _, v := range u {
+		if v.Tenant == "" || v.Units == 0 {
+			continue
+		}
+
+		k := usageKey{tenant: v.Tenant, dimension: v.Dimension}
+		if r, ok := m.pending[k]; ok {
+			r.Units += v.Units
+			r.End = t
+			continue
+		}
+
+		m.pending[k] = &UsageRecord{
+			Tenant:    v.Tenant,
+			Dimension: v.Dimension,
+			Units:     v.Units,
+			Start:     t,
+			End:       t,
+		}
+	}
+}
+
+// Flush writes the pending records to the sink
+// Records are retained if the sink returns an error. Flush should be called on
+// shutdown to avoid losing usage recorded since the last flush.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	if m.flushing {
+		m.mu.Unlock()
+		return nil
+	}
+
+	m.flushedAt = m.now()
+	if len(m.pending) < 1 {
+		m.mu.Unlock()
+		return nil
+	}
+
+	records := make([]UsageRecord, 0, len(m.pending))
+	for _, r := range m.pending {
+		records = append(records, *r)
+	}
+
+	m.pending = map[usageKey]*UsageRecord{}
+	m.flushing = true
+	m.mu.Unlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Tenant != records[j].Tenant {
+			return records[i].Tenant < records[j].Tenant
+		}
+		return records[i].Dimension < records[j].Dimension
+	})
+
+	err := m.sink.WriteUsage(ctx, records)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.flushing = false
+	if err != nil {
+		m.restore(records)
+	}
+
+	return err
+}
+
+func (m *Meter) due() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.pending) > 0 && !m.flushing && m.now().Sub(m.flushedAt) >= m.interval
+}
+
+// restore merges unflushed records into the pending records
+func (m *Meter) restore(records []UsageRecord) {
+	for _, r := range records {
+		k := usageKey{tenant: r.Tenant, dimension: r.Dimension}
+
+		p, ok := m.pending[k]
+		if !ok {
+			v := r
+			m.pending[k] = &v
+			continue
+		}
+
+		p.Units += r.Units
+		if r.Start.Before(p.Start) {
+			p.Start = r.Start
+		}
+		if r.End.After(p.End) {
+			p.End = r.End
+		}
+	}
+}
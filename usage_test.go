@@ -0,0 +1,205 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestMeter_Middleware(t *testing.T) {
+	st := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		handler rack.HandlerFunc
+		advance time.Duration
+		sinkErr error
+		exp     [][]rack.UsageRecord
+		err     bool
+	}{
+		{
+			name: "should not flush before the interval",
+			handler: func(c rack.Context) error {
+				return c.NoContent(http.StatusOK)
+			},
+			advance: 0,
+			exp:     nil,
+		},
+		{
+			name: "should flush aggregated records once the interval has elapsed",
+			handler: func(c rack.Context) error {
+				return c.NoContent(http.StatusOK)
+			},
+			advance: 30 * time.Second,
+			exp: [][]rack.UsageRecord{
+				{
+					{Tenant: "a", Dimension: "bytes", Units: 20, Start: st, End: st.Add(30 * time.Second)},
+					{Tenant: "a", Dimension: "requests", Units: 2, Start: st, End: st.Add(30 * time.Second)},
+				},
+			},
+		},
+		{
+			name: "should not record usage for failed requests",
+			handler: func(c rack.Context) error {
+				return errors.New("error")
+			},
+			advance: 30 * time.Second,
+			exp:     nil,
+		},
+		{
+			name: "should pass sink errors to the defer error handler",
+			handler: func(c rack.Context) error {
+				return c.NoContent(http.StatusOK)
+			},
+			advance: 30 * time.Second,
+			sinkErr: errors.New("error"),
+			exp: [][]rack.UsageRecord{
+				{
+					{Tenant: "a", Dimension: "bytes", Units: 20, Start: st, End: st.Add(30 * time.Second)},
+					{Tenant: "a", Dimension: "requests", Units: 2, Start: st, End: st.Add(30 * time.Second)},
+				},
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := st
+			var act [][]rack.UsageRecord
+
+			m := rack.NewMeter(rack.MeterOptions{
+				Extract: func(c rack.Context, err error) []rack.Usage {
+					if err != nil {
+						return nil
+					}
+					return []rack.Usage{
+						{Tenant: "a", Dimension: "requests", Units: 1},
+						{Tenant: "a", Dimension: "bytes", Units: 10},
+						{Tenant: "", Dimension: "requests", Units: 1},
+					}
+				},
+				Sink: rack.UsageSinkFunc(func(ctx context.Context, r []rack.UsageRecord) error {
+					act = append(act, r)
+					return tt.sinkErr
+				}),
+				Interval: 30 * time.Second,
+				Now:      func() time.Time { return now },
+			})
+
+			var derr error
+			h := rack.NewWithConfig(rack.Config{
+				Middleware:   m.Middleware,
+				OnDeferError: func(_ rack.Context, err error) { derr = err },
+			}, tt.handler)
+
+			for i := 0; i < 2; i++ {
+				if i == 1 {
+					now = now.Add(tt.advance)
+				}
+
+				_, err := h.Invoke(context.Background(), newV2Request(nil))
+				assertErrorExists(t, err, false)
+			}
+
+			assertErrorExists(t, derr, tt.err)
+			assertDeepEqual(t, act, tt.exp)
+		})
+	}
+}
+
+func TestMeter_Flush(t *testing.T) {
+	st := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should not write empty batches", func(t *testing.T) {
+		m := rack.NewMeter(rack.MeterOptions{
+			Sink: rack.UsageSinkFunc(func(context.Context, []rack.UsageRecord) error {
+				t.Error("got write, expected none")
+				return nil
+			}),
+		})
+
+		err := m.Flush(context.Background())
+		assertErrorExists(t, err, false)
+	})
+
+	t.Run("should retain records if the sink fails", func(t *testing.T) {
+		now := st
+		var calls int
+		var act []rack.UsageRecord
+
+		m := rack.NewMeter(rack.MeterOptions{
+			Sink: rack.UsageSinkFunc(func(_ context.Context, r []rack.UsageRecord) error {
+				calls++
+				if calls == 1 {
+					return errors.New("error")
+				}
+				act = r
+				return nil
+			}),
+			Now: func() time.Time { return now },
+		})
+
+		m.Record(rack.Usage{Tenant: "a", Dimension: "requests", Units: 1})
+
+		err := m.Flush(context.Background())
+		assertErrorExists(t, err, true)
+
+		now = now.Add(time.Second)
+		m.Record(rack.Usage{Tenant: "a", Dimension: "requests", Units: 2})
+
+		err = m.Flush(context.Background())
+		assertErrorExists(t, err, false)
+
+		assertDeepEqual(t, act, []rack.UsageRecord{
+			{Tenant: "a", Dimension: "requests", Units: 3, Start: st, End: st.Add(time.Second)},
+		})
+
+		err = m.Flush(context.Background())
+		assertErrorExists(t, err, false)
+
+		if calls != 2 {
+			t.Errorf("got %d, expected 2", calls)
+		}
+	})
+}
+
+func TestEventUsageSink(t *testing.T) {
+	st := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []rack.UsageRecord{
+		{Tenant: "a", Dimension: "requests", Units: 2, Start: st, End: st},
+	}
+
+	t.Run("should return publisher errors", func(t *testing.T) {
+		sut := rack.EventUsageSink(rack.EventPublisherFunc(func(context.Context, *rack.Event) error {
+			return errors.New("error")
+		}), "bus", "source")
+
+		err := sut.WriteUsage(context.Background(), records)
+		assertErrorExists(t, err, true)
+	})
+
+	t.Run("should publish each record", func(t *testing.T) {
+		var act []*rack.Event
+		sut := rack.EventUsageSink(rack.EventPublisherFunc(func(_ context.Context, e *rack.Event) error {
+			act = append(act, e)
+			return nil
+		}), "bus", "source")
+
+		err := sut.WriteUsage(context.Background(), records)
+		assertErrorExists(t, err, false)
+
+		assertDeepEqual(t, act, []*rack.Event{
+			{
+				Bus:        "bus",
+				Source:     "source",
+				DetailType: rack.UsageEventDetailType,
+				Detail:     `{"tenant":"a","dimension":"requests","units":2,"start":"2024-01-01T00:00:00Z","end":"2024-01-01T00:00:00Z"}`,
+			},
+		})
+	})
+}
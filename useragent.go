@@ -0,0 +1,144 @@
+package rack
+
+import "strings"
+
+type (
+	// ClientKind represents the kind of client that made a request
+	ClientKind string
+
+	// Client represents a client descriptor parsed from the User-Agent header
+	Client struct {
+		Kind    ClientKind
+		Name    string
+		Version string
+		Mobile  bool
+	}
+
+	// ClientOptions represents client classification middleware options
+	ClientOptions struct {
+		// Parse parses the User-Agent header, defaulting to ParseUserAgent
+		Parse func(userAgent string) Client
+	}
+)
+
+// Client kinds returned by ParseUserAgent
+const (
+	ClientUnknown ClientKind = ""
+	ClientBot     ClientKind = "bot"
+	ClientBrowser ClientKind = "browser"
+	ClientSDK     ClientKind = "sdk"
+)
+
+const clientKey = "rack.client"
+
+var (
+	botTokens = []string{"bot", "crawl", "spider", "slurp", "facebookexternalhit", "mediapartners", "headless"}
+
+	sdkTokens = []string{
+		"aws-sdk", "go-http-client", "python-requests", "python-urllib", "aiohttp", "okhttp", "axios",
+		"node-fetch", "undici", "curl", "wget", "postmanruntime", "insomnia", "java", "apache-httpclient",
+	}
+
+	// browserTokens are ordered so that tokens appended by derived browsers match first
+	browserTokens = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"EdgiOS/", "Edge"},
+		{"OPR/", "Opera"},
+		{"SamsungBrowser/", "Samsung Internet"},
+		{"Firefox/", "Firefox"},
+		{"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"},
+		{"Chrome/", "Chrome"},
+		{"Version/", "Safari"},
+	}
+)
+
+// ClassifyClient returns a middleware func that classifies the request client
+// The client is parsed from the User-Agent header and can be read using RequestClient.
+func ClassifyClient(o ClientOptions) MiddlewareFunc {
+	if o.Parse == nil {
+		o.Parse = ParseUserAgent
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			c.Set(clientKey, o.Parse(c.Request().UserAgent))
+			return n(c)
+		}
+	}
+}
+
+// RequestClient returns the request client
+// The User-Agent header is parsed if the ClassifyClient middleware has not been configured.
+func RequestClient(c Context) Client {
+	if cl, ok := c.Get(clientKey).(Client); ok {
+		return cl
+	}
+
+	return ParseUserAgent(c.Request().UserAgent)
+}
+
+// ParseUserAgent parses the specified User-Agent header into a client descriptor
+// Classification is heuristic: bots are identified by common crawler tokens, sdks and
+// command line tools by their product name, and browsers by their engine tokens.
+func ParseUserAgent(ua string) Client {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return Client{}
+	}
+
+	lua := strings.ToLower(ua)
+	cl := Client{
+		Mobile: strings.Contains(ua, "Mobile") || strings.Contains(ua, "Android") || strings.Contains(ua, "iPhone"),
+	}
+
+	for _, t := range botTokens {
+		if strings.Contains(lua, t) {
+			cl.Kind = ClientBot
+			cl.Name, cl.Version = botProduct(ua, t)
+			return cl
+		}
+	}
+
+	name, version := product(ua)
+	for _, t := range sdkTokens {
+		if strings.HasPrefix(strings.ToLower(name), t) {
+			cl.Kind = ClientSDK
+			cl.Name, cl.Version = name, version
+			return cl
+		}
+	}
+
+	if strings.HasPrefix(ua, "Mozilla/") {
+		for _, b := range browserTokens {
+			if i := strings.Index(ua, b.token); i >= 0 {
+				cl.Kind = ClientBrowser
+				cl.Name = b.name
+				cl.Version, _, _ = strings.Cut(ua[i+len(b.token):], " ")
+				return cl
+			}
+		}
+	}
+
+	cl.Name, cl.Version = name, version
+	return cl
+}
+
+// product returns the name and version of the first product token
+func product(ua string) (string, string) {
+	p, _, _ := strings.Cut(ua, " ")
+	name, version, _ := strings.Cut(p, "/")
+	return name, version
+}
+
+// botProduct returns the name and version of the product token containing the bot token
+func botProduct(ua, token string) (string, string) {
+	for _, f := range strings.FieldsFunc(ua, func(r rune) bool { return r == ' ' || r == ';' || r == '(' || r == ')' }) {
+		if strings.Contains(strings.ToLower(f), token) {
+			name, version, _ := strings.Cut(f, "/")
+			return name, version
+		}
+	}
+
+	return product(ua)
+}
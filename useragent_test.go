@@ -0,0 +1,125 @@
+package rack_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		exp  rack.Client
+	}{
+		{
+			name: "should return unknown for empty values",
+			exp:  rack.Client{},
+		},
+		{
+			name: "should classify search engine bots",
+			ua:   "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			exp:  rack.Client{Kind: rack.ClientBot, Name: "Googlebot", Version: "2.1"},
+		},
+		{
+			name: "should classify link preview bots",
+			ua:   "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			exp:  rack.Client{Kind: rack.ClientBot, Name: "facebookexternalhit", Version: "1.1"},
+		},
+		{
+			name: "should classify headless browsers as bots",
+			ua:   "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36",
+			exp:  rack.Client{Kind: rack.ClientBot, Name: "HeadlessChrome", Version: "120.0.0.0"},
+		},
+		{
+			name: "should classify sdks",
+			ua:   "aws-sdk-go-v2/1.24.0 os/linux lang/go#1.21",
+			exp:  rack.Client{Kind: rack.ClientSDK, Name: "aws-sdk-go-v2", Version: "1.24.0"},
+		},
+		{
+			name: "should classify command line tools",
+			ua:   "curl/8.4.0",
+			exp:  rack.Client{Kind: rack.ClientSDK, Name: "curl", Version: "8.4.0"},
+		},
+		{
+			name: "should classify chrome",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			exp:  rack.Client{Kind: rack.ClientBrowser, Name: "Chrome", Version: "120.0.0.0"},
+		},
+		{
+			name: "should classify edge",
+			ua:   "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			exp:  rack.Client{Kind: rack.ClientBrowser, Name: "Edge", Version: "120.0.2210.91"},
+		},
+		{
+			name: "should classify firefox",
+			ua:   "Mozilla/5.0 (Macintosh; Intel Mac OS X 14.2; rv:121.0) Gecko/20100101 Firefox/121.0",
+			exp:  rack.Client{Kind: rack.ClientBrowser, Name: "Firefox", Version: "121.0"},
+		},
+		{
+			name: "should classify mobile safari",
+			ua:   "Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			exp:  rack.Client{Kind: rack.ClientBrowser, Name: "Safari", Version: "17.2", Mobile: true},
+		},
+		{
+			name: "should classify android browsers",
+			ua:   "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			exp:  rack.Client{Kind: rack.ClientBrowser, Name: "Chrome", Version: "120.0.6099.144", Mobile: true},
+		},
+		{
+			name: "should return the product for unknown clients",
+			ua:   "MyApp/1.0",
+			exp:  rack.Client{Name: "MyApp", Version: "1.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertDeepEqual(t, rack.ParseUserAgent(tt.ua), tt.exp)
+		})
+	}
+}
+
+func TestClassifyClient(t *testing.T) {
+	tests := []struct {
+		name       string
+		middleware rack.MiddlewareFunc
+		exp        rack.Client
+	}{
+		{
+			name:       "should classify the client",
+			middleware: rack.ClassifyClient(rack.ClientOptions{}),
+			exp:        rack.Client{Kind: rack.ClientSDK, Name: "curl", Version: "8.4.0"},
+		},
+		{
+			name: "should use the parse func",
+			middleware: rack.ClassifyClient(rack.ClientOptions{
+				Parse: func(string) rack.Client { return rack.Client{Name: "custom"} },
+			}),
+			exp: rack.Client{Name: "custom"},
+		},
+		{
+			name: "should parse the user agent without the middleware",
+			exp:  rack.Client{Kind: rack.ClientSDK, Name: "curl", Version: "8.4.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: tt.middleware,
+			}, func(c rack.Context) error {
+				assertDeepEqual(t, rack.RequestClient(c), tt.exp)
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.RequestContext.HTTP.UserAgent = "curl/8.4.0"
+			}))
+			assertErrorExists(t, err, false)
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package rack
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type (
+	// SchemaValidator represents a response body schema validator
+	// Implementations would typically look up the OpenAPI operation for the request
+	// method and path, and validate the body against the response schema for the
+	// status code.
+	SchemaValidator interface {
+		ValidateResponse(r *Request, code int, body []byte) error
+	}
+
+	// SchemaValidatorFunc represents a schema validator func
+	SchemaValidatorFunc func(r *Request, code int, body []byte) error
+
+	// SchemaValidationOptions represents response schema validation options
+	SchemaValidationOptions struct {
+		// Validator is the schema validator
+		Validator SchemaValidator
+
+		// OnMismatch is invoked with the validation error if the body does not match
+		OnMismatch func(Context, error)
+
+		// Fail returns an ErrInvalidResponse status error if the body does not match
+		Fail bool
+	}
+)
+
+// ErrInvalidResponse indicates that the handler wrote an invalid response
+var ErrInvalidResponse = errors.New("invalid response")
+
+// ValidateResponse validates the response body
+func (fn SchemaValidatorFunc) ValidateResponse(r *Request, code int, body []byte) error {
+	return fn(r, code, body)
+}
+
+// ValidateSchema returns a middleware func that validates JSON response bodies
+// The middleware is intended for development stages, catching contract drift
+// before clients do. Mismatches are reported to OnMismatch and, if Fail is set,
+// returned as a 500 status error. The func panics if no validator is specified.
+func ValidateSchema(o SchemaValidationOptions) MiddlewareFunc {
+	if o.Validator == nil {
+		panic("rack: validate schema requires a validator")
+	}
+
+	onMismatch := o.OnMismatch
+	if onMismatch == nil {
+		onMismatch = func(Context, error) {}
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			if err := n(c); err != nil {
+				return err
+			}
+
+			r := c.Response()
+			if !isJSON(r.Headers.Get("Content-Type")) {
+				return nil
+			}
+
+			err := o.Validator.ValidateResponse(c.Request(), r.StatusCode, []byte(r.Body))
+			if err == nil {
+				return nil
+			}
+
+			err = fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+			onMismatch(c, err)
+
+			if o.Fail {
+				return WrapError(http.StatusInternalServerError, err)
+			}
+
+			return nil
+		}
+	}
+}
+
+func validateResponse(r *Response) error {
+	if r.Body != "" && !bodyAllowed(r.StatusCode) {
+		return fmt.Errorf("%w: body written with status %d", ErrInvalidResponse, r.StatusCode)
+	}
+
+	if v := r.Headers.Get("Content-Length"); v != "" {
+		l := bodyLength(r)
+		if n, err := strconv.Atoi(v); err != nil || n != l {
+			return fmt.Errorf("%w: content length %s does not match body length %d", ErrInvalidResponse, v, l)
+		}
+	}
+
+	if err := validateCookies(r); err != nil {
+		return err
+	}
+
+	for k, vs := range r.Headers {
+		if !validHeaderName(k) {
+			return fmt.Errorf("%w: invalid header name %q", ErrInvalidResponse, k)
+		}
+
+		for _, v := range vs {
+			if !validHeaderValue(v) {
+				return fmt.Errorf("%w: invalid value for header %s", ErrInvalidResponse, k)
+			}
+		}
+	}
+
+	return nil
+}
+
+// bodyLength returns the length of the response body once base64 decoded
+func bodyLength(r *Response) int {
+	if !r.IsBase64Encoded {
+		return len(r.Body)
+	}
+
+	n := base64.StdEncoding.DecodedLen(len(r.Body))
+	for i := len(r.Body) - 1; i >= 0 && i >= len(r.Body)-2 && r.Body[i] == '='; i-- {
+		n--
+	}
+
+	return n
+}
+
+// newHeaderSizeValidator returns a validator that limits the response header size
+// If the exceeded func is specified then it is invoked with the error, and the
+// response is written unchanged.
+func newHeaderSizeValidator(max int, exceeded func(Context, error)) func(Context, *Response) error {
+	return func(c Context, r *Response) error {
+		var n int
+		for k, vs := range r.Headers {
+			for _, v := range vs {
+				n += len(k) + len(v) + 4 // ": " and CRLF
+			}
+		}
+
+		if n > max {
+			err := fmt.Errorf("%w: header size %d exceeds limit %d", ErrInvalidResponse, n, max)
+			if exceeded == nil {
+				return err
+			}
+
+			exceeded(c, err)
+		}
+
+		return validateCookies(r)
+	}
+}
+
+func validateCookies(r *Response) error {
+	for _, v := range r.Headers.Values("Set-Cookie") {
+		if !validSetCookie(v) {
+			return fmt.Errorf("%w: invalid set-cookie value %q", ErrInvalidResponse, v)
+		}
+	}
+
+	return nil
+}
+
+func validSetCookie(s string) bool {
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s = s[:i]
+	}
+
+	i := strings.IndexByte(s, '=')
+	if i < 1 || !validHeaderName(strings.TrimSpace(s[:i])) {
+		return false
+	}
+
+	v := strings.TrimSpace(s[i+1:])
+	if len(v) > 1 && v[0] == '"' && v[len(v)-1] == '"' {
+		v = v[1 : len(v)-1]
+	}
+
+	for _, r := range v {
+		// cookie-octet as defined in rfc 6265
+		if r < 0x21 || r > 0x7e || r == '"' || r == ',' || r == ';' || r == '\\' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func bodyAllowed(code int) bool {
+	switch {
+	case code >= 100 && code < 200:
+		return false
+	case code == http.StatusNoContent, code == http.StatusNotModified:
+		return false
+	}
+
+	return true
+}
+
+func validHeaderName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validHeaderValue(s string) bool {
+	for _, r := range s {
+		if (r < ' ' && r != '\t') || r == 0x7f {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
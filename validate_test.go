@@ -0,0 +1,335 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestConfig_Strict(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler rack.HandlerFunc
+		exp     int
+		err     bool
+	}{
+		{
+			name: "should return an error for multiple writes",
+			handler: func(c rack.Context) error {
+				c.NoContent(http.StatusAccepted)
+				return c.String(http.StatusOK, "body")
+			},
+			err: true,
+		},
+		{
+			name: "should return an error for bodies with no content",
+			handler: func(c rack.Context) error {
+				c.Response().Body = "body"
+				return c.NoContent(http.StatusNoContent)
+			},
+			err: true,
+		},
+		{
+			name: "should return an error for content length mismatches",
+			handler: func(c rack.Context) error {
+				c.Response().Headers.Set("Content-Length", "1")
+				return c.String(http.StatusOK, "body")
+			},
+			err: true,
+		},
+		{
+			name: "should return an error for invalid header names",
+			handler: func(c rack.Context) error {
+				c.Response().Headers["X-Invalid Header"] = []string{"value"}
+				return c.NoContent(http.StatusOK)
+			},
+			err: true,
+		},
+		{
+			name: "should return an error for invalid header values",
+			handler: func(c rack.Context) error {
+				c.Response().Headers.Set("X-Header", "value\r\nX-Injected: value")
+				return c.NoContent(http.StatusOK)
+			},
+			err: true,
+		},
+		{
+			name: "should allow the error handler to replace the response",
+			handler: func(c rack.Context) error {
+				c.String(http.StatusOK, "body")
+				return rack.WrapError(http.StatusConflict, errors.New("error"))
+			},
+			exp: http.StatusConflict,
+		},
+		{
+			name: "should return valid responses",
+			handler: func(c rack.Context) error {
+				c.Response().Headers.Set("Content-Length", "4")
+				return c.String(http.StatusOK, "body")
+			},
+			exp: http.StatusOK,
+		},
+		{
+			name: "should compare the content length with the decoded length of binary bodies",
+			handler: func(c rack.Context) error {
+				c.Response().Headers.Set("Content-Length", "4")
+				return rack.CBOR(c, http.StatusOK, map[string]int{"a": 1})
+			},
+			exp: http.StatusOK,
+		},
+		{
+			name: "should return an error for binary content length mismatches",
+			handler: func(c rack.Context) error {
+				c.Response().Headers.Set("Content-Length", "8")
+				return rack.CBOR(c, http.StatusOK, map[string]int{"a": 1})
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				Strict: true,
+				OnError: func(c rack.Context, err error) error {
+					if errors.Is(err, rack.ErrInvalidResponse) || errors.Is(err, rack.ErrResponseCommitted) {
+						return err
+					}
+					return c.NoContent(rack.StatusCode(err))
+				},
+			}, tt.handler)
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, tt.err)
+			if tt.err {
+				return
+			}
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if act.StatusCode != tt.exp {
+				t.Errorf("got %d, expected %d", act.StatusCode, tt.exp)
+			}
+		})
+	}
+}
+
+func TestConfig_Strict_BinaryContentTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		exp  int
+	}{
+		{
+			name: "should allow content lengths without padding",
+			body: "abc",
+			exp:  http.StatusOK,
+		},
+		{
+			name: "should allow content lengths with padding",
+			body: "abcde",
+			exp:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				Strict:             true,
+				BinaryContentTypes: []string{"image/*"},
+			}, func(c rack.Context) error {
+				c.Response().Headers.Set("Content-Length", strconv.Itoa(len(tt.body)))
+				return rack.Blob(c, http.StatusOK, "image/png", []byte(tt.body))
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			assertDeepEqual(t, act.StatusCode, tt.exp)
+			assertDeepEqual(t, act.IsBase64Encoded, true)
+		})
+	}
+}
+
+func TestConfig_MaxHeaderSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler rack.HandlerFunc
+		exp     int
+	}{
+		{
+			name: "should return an error if the header size exceeds the limit",
+			handler: func(c rack.Context) error {
+				c.Response().Headers.Set("X-Header", strings.Repeat("a", 64))
+				return c.NoContent(http.StatusOK)
+			},
+			exp: http.StatusInternalServerError,
+		},
+		{
+			name: "should return an error for malformed cookies",
+			handler: func(c rack.Context) error {
+				c.Response().Headers.Add("Set-Cookie", "a=b")
+				c.Response().Headers.Add("Set-Cookie", "invalid value; Path=/")
+				return c.NoContent(http.StatusOK)
+			},
+			exp: http.StatusInternalServerError,
+		},
+		{
+			name: "should return valid responses",
+			handler: func(c rack.Context) error {
+				c.Response().Headers.Add("Set-Cookie", `id="abc"; Path=/; HttpOnly`)
+				return c.NoContent(http.StatusOK)
+			},
+			exp: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				MaxHeaderSize: 64,
+				OnError: func(c rack.Context, err error) error {
+					if !errors.Is(err, rack.ErrInvalidResponse) {
+						t.Errorf("got %v, expected ErrInvalidResponse", err)
+					}
+					return c.NoContent(rack.StatusCode(err))
+				},
+			}, tt.handler)
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if act.StatusCode != tt.exp {
+				t.Errorf("got %d, expected %d", act.StatusCode, tt.exp)
+			}
+		})
+	}
+}
+
+func TestConfig_OnHeaderSizeExceeded(t *testing.T) {
+	var act error
+
+	h := rack.NewWithConfig(rack.Config{
+		MaxHeaderSize:        64,
+		OnHeaderSizeExceeded: func(_ rack.Context, err error) { act = err },
+	}, func(c rack.Context) error {
+		c.Response().Headers.Set("X-Header", strings.Repeat("a", 64))
+		return c.NoContent(http.StatusOK)
+	})
+
+	b, err := h.Invoke(context.Background(), newV2Request(nil))
+	assertErrorExists(t, err, false)
+
+	res := new(events.APIGatewayV2HTTPResponse)
+	unmarshal(b, res)
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("got %d, expected %d", res.StatusCode, http.StatusOK)
+	}
+	if !errors.Is(act, rack.ErrInvalidResponse) {
+		t.Errorf("got %v, expected ErrInvalidResponse", act)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	validator := rack.SchemaValidatorFunc(func(_ *rack.Request, _ int, body []byte) error {
+		if !strings.Contains(string(body), `"id"`) {
+			return errors.New("id is required")
+		}
+		return nil
+	})
+
+	tests := []struct {
+		name     string
+		fail     bool
+		handler  rack.HandlerFunc
+		mismatch bool
+		exp      int
+	}{
+		{
+			name: "should ignore non-json responses",
+			handler: func(c rack.Context) error {
+				return c.String(http.StatusOK, "value")
+			},
+			exp: http.StatusOK,
+		},
+		{
+			name: "should pass valid responses",
+			handler: func(c rack.Context) error {
+				return c.JSON(http.StatusOK, map[string]string{"id": "1"})
+			},
+			exp: http.StatusOK,
+		},
+		{
+			name: "should report mismatches",
+			handler: func(c rack.Context) error {
+				return c.JSON(http.StatusOK, map[string]string{})
+			},
+			mismatch: true,
+			exp:      http.StatusOK,
+		},
+		{
+			name: "should fail on mismatches",
+			fail: true,
+			handler: func(c rack.Context) error {
+				return c.JSON(http.StatusOK, map[string]string{})
+			},
+			mismatch: true,
+			exp:      http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var merr error
+
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.ValidateSchema(rack.SchemaValidationOptions{
+					Validator: validator,
+					OnMismatch: func(_ rack.Context, err error) {
+						merr = err
+					},
+					Fail: tt.fail,
+				}),
+			}, tt.handler)
+
+			b, err := h.Invoke(context.Background(), newV2Request(nil))
+			assertErrorExists(t, err, false)
+			assertErrorExists(t, merr, tt.mismatch)
+
+			if tt.mismatch && !errors.Is(merr, rack.ErrInvalidResponse) {
+				t.Errorf("got %v, expected ErrInvalidResponse", merr)
+			}
+
+			act := new(events.APIGatewayV2HTTPResponse)
+			unmarshal(b, act)
+
+			if act.StatusCode != tt.exp {
+				t.Errorf("got %d, expected %d", act.StatusCode, tt.exp)
+			}
+		})
+	}
+	t.Run("should panic if no validator is specified", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got nil, expected panic")
+			}
+		}()
+
+		rack.ValidateSchema(rack.SchemaValidationOptions{})
+	})
+}
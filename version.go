@@ -0,0 +1,95 @@
+package rack
+
+import (
+	"net/http"
+	"os"
+	"runtime/debug"
+)
+
+// BuildInfo represents application build information
+type BuildInfo struct {
+	Module     string `json:"module,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Revision   string `json:"revision,omitempty"`
+	Modified   bool   `json:"modified,omitempty"`
+	CommitTime string `json:"commitTime,omitempty"`
+	DeployTime string `json:"deployTime,omitempty"`
+	GoVersion  string `json:"goVersion,omitempty"`
+}
+
+// VersionHeader is the build version response header
+const VersionHeader = "X-App-Version"
+
+const develVersion = "(devel)"
+
+// ReadBuildInfo returns the build information embedded in the running binary
+// The deploy time is read from the DEPLOY_TIME environment variable, allowing it
+// to be specified by the deployment pipeline.
+func ReadBuildInfo() BuildInfo {
+	i := BuildInfo{
+		DeployTime: os.Getenv("DEPLOY_TIME"),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return i
+	}
+
+	i.Module = bi.Main.Path
+	i.Version = bi.Main.Version
+	i.GoVersion = bi.GoVersion
+
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			i.Revision = s.Value
+		case "vcs.time":
+			i.CommitTime = s.Value
+		case "vcs.modified":
+			i.Modified = s.Value == "true"
+		}
+	}
+
+	return i
+}
+
+// String returns the build version
+// The short vcs revision is returned if the module version is not known.
+func (i BuildInfo) String() string {
+	if i.Version != "" && i.Version != develVersion {
+		return i.Version
+	}
+
+	r := i.Revision
+	if len(r) > 12 {
+		r = r[:12]
+	}
+
+	if r != "" && i.Modified {
+		return r + "-dirty"
+	}
+
+	return r
+}
+
+// Version returns a handler func that writes the build information as JSON
+func Version(i BuildInfo) HandlerFunc {
+	return func(c Context) error {
+		return c.JSON(http.StatusOK, &i)
+	}
+}
+
+// WithVersionHeader returns a middleware func that writes the build version header
+func WithVersionHeader(i BuildInfo) MiddlewareFunc {
+	v := i.String()
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			if v != "" {
+				c.Response().Headers.Set(VersionHeader, v)
+			}
+
+			return n(c)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package rack_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestReadBuildInfo(t *testing.T) {
+	t.Run("should read the deploy time from the environment", func(t *testing.T) {
+		t.Setenv("DEPLOY_TIME", "2021-01-01T00:00:00Z")
+
+		act := rack.ReadBuildInfo()
+		if exp := "2021-01-01T00:00:00Z"; act.DeployTime != exp {
+			t.Errorf("got %s, expected %s", act.DeployTime, exp)
+		}
+		if act.GoVersion == "" {
+			t.Error("got empty string, expected go version")
+		}
+	})
+}
+
+func TestBuildInfo_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		input rack.BuildInfo
+		exp   string
+	}{
+		{
+			name: "should return an empty string if the version is unknown",
+		},
+		{
+			name:  "should return the module version",
+			input: rack.BuildInfo{Version: "v1.2.3", Revision: "0123456789abcdef"},
+			exp:   "v1.2.3",
+		},
+		{
+			name:  "should return the short revision for devel builds",
+			input: rack.BuildInfo{Version: "(devel)", Revision: "0123456789abcdef"},
+			exp:   "0123456789ab",
+		},
+		{
+			name:  "should mark modified revisions",
+			input: rack.BuildInfo{Revision: "0123456789abcdef", Modified: true},
+			exp:   "0123456789ab-dirty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if act := tt.input.String(); act != tt.exp {
+				t.Errorf("got %s, expected %s", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	t.Run("should write the build info", func(t *testing.T) {
+		i := rack.BuildInfo{Version: "v1.2.3", Revision: "abc"}
+
+		h := rack.NewWithConfig(rack.Config{
+			Middleware: rack.WithVersionHeader(i),
+		}, rack.Version(i))
+
+		act, err := h.Invoke(context.Background(), newV2Request(nil))
+		assertErrorExists(t, err, false)
+
+		exp := newV2Response(func(r *events.APIGatewayV2HTTPResponse) {
+			r.StatusCode = http.StatusOK
+			r.Headers = map[string]string{
+				"Content-Type":  "application/json",
+				"X-App-Version": "v1.2.3",
+			}
+			r.MultiValueHeaders = map[string][]string{
+				"Content-Type":  {"application/json"},
+				"X-App-Version": {"v1.2.3"},
+			}
+			r.Body = `{"version":"v1.2.3","revision":"abc"}`
+		})
+
+		assertDeepEqual(t, act, exp)
+	})
+}
@@ -0,0 +1,21 @@
+package rack
+
+import "github.com/tidwall/gjson"
+
+// IsWarmupEvent returns true if the payload is a common keep-warm event
+// CloudWatch scheduled events, serverless-plugin-warmup and lambda-warmer
+// payloads are recognised.
+func IsWarmupEvent(payload []byte) bool {
+	pv := gjson.GetManyBytes(payload, "source", "detail-type", "warmer")
+
+	switch {
+	case pv[0].String() == "aws.events" && pv[1].String() == "Scheduled Event":
+		return true
+	case pv[0].String() == "serverless-plugin-warmup":
+		return true
+	case pv[2].Bool():
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestIsWarmupEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     bool
+	}{
+		{
+			name:    "should return true for scheduled events",
+			payload: []byte(`{"source":"aws.events","detail-type":"Scheduled Event","detail":{}}`),
+			exp:     true,
+		},
+		{
+			name:    "should return true for serverless-plugin-warmup events",
+			payload: []byte(`{"source":"serverless-plugin-warmup"}`),
+			exp:     true,
+		},
+		{
+			name:    "should return true for lambda-warmer events",
+			payload: []byte(`{"warmer":true,"concurrency":3}`),
+			exp:     true,
+		},
+		{
+			name:    "should return false for other eventbridge events",
+			payload: []byte(`{"source":"aws.events","detail-type":"Other"}`),
+		},
+		{
+			name:    "should return false for http events",
+			payload: newV2Request(nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if act := rack.IsWarmupEvent(tt.payload); act != tt.exp {
+				t.Errorf("got %v, expected %v", act, tt.exp)
+			}
+		})
+	}
+}
+
+func TestConfig_OnWarmup(t *testing.T) {
+	payload := []byte(`{"source":"serverless-plugin-warmup"}`)
+
+	t.Run("should return unsupported event errors if not configured", func(t *testing.T) {
+		h := rack.New(func(rack.Context) error {
+			t.Error("handler invoked")
+			return nil
+		})
+
+		_, err := h.Invoke(context.Background(), payload)
+		if !errors.Is(err, rack.ErrUnsupportedEventType) {
+			t.Errorf("got %v, expected %v", err, rack.ErrUnsupportedEventType)
+		}
+	})
+
+	t.Run("should invoke the warmup func", func(t *testing.T) {
+		exp := errors.New("error")
+		invoked := false
+
+		cfg := rack.Config{
+			OnWarmup: func(context.Context) error {
+				invoked = true
+				return exp
+			},
+		}
+
+		h := rack.NewWithConfig(cfg, func(rack.Context) error {
+			t.Error("handler invoked")
+			return nil
+		})
+
+		b, err := h.Invoke(context.Background(), payload)
+		if err != exp {
+			t.Errorf("got %v, expected %v", err, exp)
+		}
+		if b != nil {
+			t.Errorf("got %s, expected nil", b)
+		}
+		if !invoked {
+			t.Error("got false, expected true")
+		}
+	})
+}
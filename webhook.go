@@ -0,0 +1,340 @@
+package rack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// WebhookOptions represents webhook dispatcher options
+	WebhookOptions struct {
+		// Secret is the HMAC signing key
+		Secret []byte
+
+		// HTTPClient is the delivery client, defaulting to http.DefaultClient
+		HTTPClient *http.Client
+
+		// Store records delivery outcomes, if specified
+		Store WebhookStore
+
+		// MaxAttempts is the maximum number of delivery attempts, defaulting to 3
+		MaxAttempts int
+
+		// Backoff returns the delay before the specified retry attempt
+		// By default the delay doubles from one second.
+		Backoff func(attempt int) time.Duration
+
+		// Now returns the current time, defaulting to time.Now
+		Now func() time.Time
+	}
+
+	// VerifyWebhookOptions represents inbound webhook verification options
+	VerifyWebhookOptions struct {
+		// Secret is the HMAC signing key
+		Secret []byte
+
+		// Tolerance is the maximum difference between the webhook timestamp and the
+		// current time, defaulting to 5 minutes
+		Tolerance time.Duration
+
+		// Now returns the current time, defaulting to time.Now
+		Now func() time.Time
+	}
+
+	// WebhookDelivery represents the outcome of a webhook delivery
+	WebhookDelivery struct {
+		ID         string    `json:"id"`
+		URL        string    `json:"url"`
+		Event      string    `json:"event"`
+		Attempts   int       `json:"attempts"`
+		StatusCode int       `json:"statusCode,omitempty"`
+		Error      string    `json:"error,omitempty"`
+		Succeeded  bool      `json:"succeeded"`
+		CreatedAt  time.Time `json:"createdAt"`
+		UpdatedAt  time.Time `json:"updatedAt"`
+	}
+
+	// WebhookStore represents a webhook delivery store
+	WebhookStore interface {
+		Record(ctx context.Context, d *WebhookDelivery) error
+	}
+
+	// WebhookStoreFunc represents a webhook store func
+	WebhookStoreFunc func(context.Context, *WebhookDelivery) error
+
+	// WebhookDispatcher represents an outbound webhook dispatcher
+	WebhookDispatcher struct {
+		secret      []byte
+		client      *http.Client
+		store       WebhookStore
+		maxAttempts int
+		backoff     func(int) time.Duration
+		now         func() time.Time
+	}
+)
+
+const (
+	// WebhookIDHeader is the webhook message id header
+	WebhookIDHeader = "Webhook-Id"
+
+	// WebhookTimestampHeader is the webhook timestamp header
+	WebhookTimestampHeader = "Webhook-Timestamp"
+
+	// WebhookSignatureHeader is the webhook signature header
+	WebhookSignatureHeader = "Webhook-Signature"
+
+	// WebhookEventHeader is the webhook event type header
+	WebhookEventHeader = "Webhook-Event"
+)
+
+const (
+	defaultWebhookTolerance = 5 * time.Minute
+
+	// maxWebhookDrainSize is the maximum response body size read so that the
+	// connection can be reused for subsequent attempts
+	maxWebhookDrainSize = 4 << 10
+)
+
+var (
+	// ErrWebhookDelivery indicates that the webhook was not accepted by the receiver
+	ErrWebhookDelivery = errors.New("webhook delivery failed")
+
+	// ErrInvalidWebhook indicates that the inbound webhook signature or timestamp is invalid
+	ErrInvalidWebhook = errors.New("invalid webhook signature")
+)
+
+// NewWebhookDispatcher returns a new webhook dispatcher
+func NewWebhookDispatcher(o WebhookOptions) *WebhookDispatcher {
+	d := &WebhookDispatcher{
+		secret:      o.Secret,
+		client:      o.HTTPClient,
+		store:       o.Store,
+		maxAttempts: o.MaxAttempts,
+		backoff:     o.Backoff,
+		now:         o.Now,
+	}
+
+	if d.client == nil {
+		d.client = http.DefaultClient
+	}
+
+	if d.store == nil {
+		d.store = WebhookStoreFunc(func(context.Context, *WebhookDelivery) error { return nil })
+	}
+
+	if d.maxAttempts <= 0 {
+		d.maxAttempts = 3
+	}
+
+	if d.backoff == nil {
+		d.backoff = func(attempt int) time.Duration {
+			return time.Second << (attempt - 1)
+		}
+	}
+
+	if d.now == nil {
+		d.now = time.Now
+	}
+
+	return d
+}
+
+// Deliver signs the payload and posts it to the specified url
+// Network errors, 429 and 5xx responses are retried with backoff, honouring any
+// Retry-After header. Retries stop early if the next attempt would exceed the
+// context deadline, so deliveries never outlive the invocation. The outcome is
+// recorded using the configured store, and ErrWebhookDelivery is returned if the
+// receiver did not accept the webhook.
+func (d *WebhookDispatcher) Deliver(ctx context.Context, url, event string, payload interface{}) (*WebhookDelivery, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	now := d.now().UTC()
+	wd := &WebhookDelivery{
+		ID:        NewJobID(),
+		URL:       url,
+		Event:     event,
+		CreatedAt: now,
+	}
+
+	var delay time.Duration
+	for {
+		wd.Attempts++
+
+		delay, err = d.attempt(ctx, wd, b)
+		if err == nil || delay < 0 || wd.Attempts >= d.maxAttempts {
+			break
+		}
+
+		if delay == 0 {
+			delay = d.backoff(wd.Attempts)
+		}
+
+		if !sleepContext(ctx, delay) {
+			break
+		}
+	}
+
+	wd.Succeeded = err == nil
+	if err != nil {
+		wd.Error = err.Error()
+	}
+	wd.UpdatedAt = d.now().UTC()
+
+	if serr := d.store.Record(ctx, wd); serr != nil {
+		return wd, serr
+	}
+
+	return wd, err
+}
+
+// attempt performs a single delivery attempt
+// The returned delay is negative if the failure should not be retried, or zero if
+// the default backoff should be used.
+func (d *WebhookDispatcher) attempt(ctx context.Context, wd *WebhookDelivery, b []byte) (time.Duration, error) {
+	ts := strconv.FormatInt(d.now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wd.URL, bytes.NewReader(b))
+	if err != nil {
+		return -1, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, wd.ID)
+	req.Header.Set(WebhookTimestampHeader, ts)
+	req.Header.Set(WebhookEventHeader, wd.Event)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(d.secret, wd.ID, ts, b))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, err
+		}
+		return 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(res.Body, maxWebhookDrainSize))
+	res.Body.Close()
+
+	wd.StatusCode = res.StatusCode
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		return 0, nil
+	}
+
+	err = fmt.Errorf("%w: status code %d", ErrWebhookDelivery, res.StatusCode)
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode < http.StatusInternalServerError {
+		return -1, err
+	}
+
+	if s, perr := strconv.Atoi(res.Header.Get("Retry-After")); perr == nil && s > 0 {
+		return time.Duration(s) * time.Second, err
+	}
+
+	return 0, err
+}
+
+// SignWebhook returns the webhook signature header value
+// The signature is compatible with the Standard Webhooks specification, signing
+// the message id, timestamp and body.
+func SignWebhook(secret []byte, id, timestamp string, body []byte) string {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(id + "." + timestamp + "."))
+	h.Write(body)
+
+	return "v1," + base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// VerifyWebhook returns a middleware func that verifies inbound webhook signatures
+// The signature is verified over the message id, timestamp and request body using
+// SignWebhook, and any of the space separated signatures in the header may match.
+// Requests with a missing or invalid signature, or a timestamp outside the tolerance,
+// receive a 401 error. The preserved request body is verified if available, so
+// PreserveBody must be enabled if earlier middleware replaces the body. The func
+// panics if the secret is empty.
+func VerifyWebhook(o VerifyWebhookOptions) MiddlewareFunc {
+	if len(o.Secret) == 0 {
+		panic("rack: verify webhook requires a secret")
+	}
+
+	tolerance := o.Tolerance
+	if tolerance <= 0 {
+		tolerance = defaultWebhookTolerance
+	}
+
+	now := o.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			h := c.Request().Header
+
+			id, ts, sigs := h.Get(WebhookIDHeader), h.Get(WebhookTimestampHeader), h.Get(WebhookSignatureHeader)
+			if id == "" || sigs == "" {
+				return WrapError(http.StatusUnauthorized, ErrInvalidWebhook)
+			}
+
+			// the timestamp tolerance prevents captured webhooks from being replayed
+			t, err := strconv.ParseInt(ts, 10, 64)
+			if err != nil {
+				return WrapError(http.StatusUnauthorized, ErrInvalidWebhook)
+			}
+
+			if d := now().Sub(time.Unix(t, 0)); d > tolerance || d < -tolerance {
+				return WrapError(http.StatusUnauthorized, ErrInvalidWebhook)
+			}
+
+			b := c.Request().RawBody
+			if b == nil {
+				if b, err = requestBody(c.Request()); err != nil {
+					return err
+				}
+			}
+
+			exp := []byte(SignWebhook(o.Secret, id, ts, b))
+			for _, sig := range strings.Fields(sigs) {
+				if hmac.Equal([]byte(sig), exp) {
+					return n(c)
+				}
+			}
+
+			return WrapError(http.StatusUnauthorized, ErrInvalidWebhook)
+		}
+	}
+}
+
+// Record records the delivery
+func (fn WebhookStoreFunc) Record(ctx context.Context, d *WebhookDelivery) error {
+	return fn(ctx, d)
+}
+
+// sleepContext waits for the specified duration
+// False is returned without waiting if the context deadline would be exceeded.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if dl, ok := ctx.Deadline(); ok && time.Until(dl) < d {
+		return false
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
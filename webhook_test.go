@@ -0,0 +1,305 @@
+package rack_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestWebhookDispatcher_Deliver(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		codes    []int
+		header   http.Header
+		timeout  time.Duration
+		attempts int
+		exp      int
+		err      error
+	}{
+		{
+			name:     "should deliver webhooks",
+			codes:    []int{http.StatusNoContent},
+			attempts: 1,
+			exp:      http.StatusNoContent,
+		},
+		{
+			name:     "should retry server errors",
+			codes:    []int{http.StatusInternalServerError, http.StatusTooManyRequests, http.StatusOK},
+			attempts: 3,
+			exp:      http.StatusOK,
+		},
+		{
+			name:     "should not retry client errors",
+			codes:    []int{http.StatusBadRequest, http.StatusOK},
+			attempts: 1,
+			exp:      http.StatusBadRequest,
+			err:      rack.ErrWebhookDelivery,
+		},
+		{
+			name:     "should stop after the maximum attempts",
+			codes:    []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusOK},
+			attempts: 3,
+			exp:      http.StatusBadGateway,
+			err:      rack.ErrWebhookDelivery,
+		},
+		{
+			name:     "should not retry beyond the context deadline",
+			codes:    []int{http.StatusServiceUnavailable, http.StatusOK},
+			header:   http.Header{"Retry-After": []string{"60"}},
+			timeout:  time.Second,
+			attempts: 1,
+			exp:      http.StatusServiceUnavailable,
+			err:      rack.ErrWebhookDelivery,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := io.ReadAll(r.Body)
+
+				exp := rack.SignWebhook(secret, r.Header.Get(rack.WebhookIDHeader), r.Header.Get(rack.WebhookTimestampHeader), b)
+				if act := r.Header.Get(rack.WebhookSignatureHeader); act != exp {
+					t.Errorf("got %s, expected %s", act, exp)
+				}
+
+				assertDeepEqual(t, r.Header.Get(rack.WebhookEventHeader), "order.created")
+				assertDeepEqual(t, string(b), `{"id":"abc"}`)
+
+				for k, vs := range tt.header {
+					w.Header()[k] = vs
+				}
+
+				w.WriteHeader(tt.codes[calls])
+				calls++
+			}))
+			defer srv.Close()
+
+			var recorded *rack.WebhookDelivery
+			sut := rack.NewWebhookDispatcher(rack.WebhookOptions{
+				Secret: secret,
+				Store: rack.WebhookStoreFunc(func(_ context.Context, d *rack.WebhookDelivery) error {
+					recorded = d
+					return nil
+				}),
+				Backoff: func(int) time.Duration { return time.Millisecond },
+				Now:     func() time.Time { return now },
+			})
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			act, err := sut.Deliver(ctx, srv.URL, "order.created", map[string]string{"id": "abc"})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("got %v, expected %v", err, tt.err)
+			}
+
+			if recorded != act {
+				t.Errorf("got %v, expected %v", recorded, act)
+			}
+
+			assertDeepEqual(t, act.Attempts, tt.attempts)
+			assertDeepEqual(t, act.StatusCode, tt.exp)
+			assertDeepEqual(t, act.Succeeded, tt.err == nil)
+			assertDeepEqual(t, act.CreatedAt, now)
+		})
+	}
+
+	t.Run("should return store errors", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+		defer srv.Close()
+
+		exp := errors.New("error")
+		sut := rack.NewWebhookDispatcher(rack.WebhookOptions{
+			Store: rack.WebhookStoreFunc(func(context.Context, *rack.WebhookDelivery) error {
+				return exp
+			}),
+		})
+
+		_, err := sut.Deliver(context.Background(), srv.URL, "event", nil)
+		if err != exp {
+			t.Errorf("got %v, expected %v", err, exp)
+		}
+	})
+}
+
+func TestSignWebhook(t *testing.T) {
+	// standard webhooks specification test vector
+	secret, err := base64.StdEncoding.DecodeString("MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	act := rack.SignWebhook(secret, "msg_p5jXN8AQM9LWM0D4loKWxJek", "1614265330", []byte(`{"test": 2432232314}`))
+
+	assertDeepEqual(t, act, "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=")
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	body := `{"a":1}`
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		body    string
+		code    int
+	}{
+		{
+			name: "should return an error if the signature is missing",
+			headers: map[string]string{
+				rack.WebhookIDHeader:        "id",
+				rack.WebhookTimestampHeader: ts,
+			},
+			body: body,
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "should return an error if the timestamp is invalid",
+			headers: map[string]string{
+				rack.WebhookIDHeader:        "id",
+				rack.WebhookTimestampHeader: "invalid",
+				rack.WebhookSignatureHeader: rack.SignWebhook(secret, "id", "invalid", []byte(body)),
+			},
+			body: body,
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "should return an error if the timestamp is outside the tolerance",
+			headers: map[string]string{
+				rack.WebhookIDHeader:        "id",
+				rack.WebhookTimestampHeader: strconv.FormatInt(now.Add(-6*time.Minute).Unix(), 10),
+				rack.WebhookSignatureHeader: rack.SignWebhook(secret, "id", strconv.FormatInt(now.Add(-6*time.Minute).Unix(), 10), []byte(body)),
+			},
+			body: body,
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "should return an error if the body has been modified",
+			headers: map[string]string{
+				rack.WebhookIDHeader:        "id",
+				rack.WebhookTimestampHeader: ts,
+				rack.WebhookSignatureHeader: rack.SignWebhook(secret, "id", ts, []byte(body)),
+			},
+			body: `{"a":2}`,
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "should return an error if the secret does not match",
+			headers: map[string]string{
+				rack.WebhookIDHeader:        "id",
+				rack.WebhookTimestampHeader: ts,
+				rack.WebhookSignatureHeader: rack.SignWebhook([]byte("other"), "id", ts, []byte(body)),
+			},
+			body: body,
+			code: http.StatusUnauthorized,
+		},
+		{
+			name: "should verify valid signatures",
+			headers: map[string]string{
+				rack.WebhookIDHeader:        "id",
+				rack.WebhookTimestampHeader: ts,
+				rack.WebhookSignatureHeader: rack.SignWebhook(secret, "id", ts, []byte(body)),
+			},
+			body: body,
+			code: http.StatusNoContent,
+		},
+		{
+			name: "should verify any of multiple signatures",
+			headers: map[string]string{
+				rack.WebhookIDHeader:        "id",
+				rack.WebhookTimestampHeader: ts,
+				rack.WebhookSignatureHeader: "v1,invalid " + rack.SignWebhook(secret, "id", ts, []byte(body)),
+			},
+			body: body,
+			code: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.VerifyWebhook(rack.VerifyWebhookOptions{
+					Secret: secret,
+					Now:    func() time.Time { return now },
+				}),
+			}, func(c rack.Context) error {
+				return c.NoContent(http.StatusNoContent)
+			})
+
+			b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+				r.Headers = tt.headers
+				r.Body = tt.body
+			}))
+			assertErrorExists(t, err, false)
+
+			act := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+			assertDeepEqual(t, act.StatusCode, tt.code)
+		})
+	}
+
+	t.Run("should verify the preserved body", func(t *testing.T) {
+		h := rack.NewWithConfig(rack.Config{
+			PreserveBody: true,
+			Middleware: rack.Chain(
+				func(n rack.HandlerFunc) rack.HandlerFunc {
+					return func(c rack.Context) error {
+						r := c.Request().Clone()
+						r.Body = `{"a":2}`
+						c.SetRequest(r)
+						return n(c)
+					}
+				},
+				rack.VerifyWebhook(rack.VerifyWebhookOptions{
+					Secret: secret,
+					Now:    func() time.Time { return now },
+				}),
+			),
+		}, func(c rack.Context) error {
+			return c.NoContent(http.StatusNoContent)
+		})
+
+		b, err := h.Invoke(context.Background(), newV2Request(func(r *events.APIGatewayV2HTTPRequest) {
+			r.Headers = map[string]string{
+				rack.WebhookIDHeader:        "id",
+				rack.WebhookTimestampHeader: ts,
+				rack.WebhookSignatureHeader: rack.SignWebhook(secret, "id", ts, []byte(body)),
+			}
+			r.Body = body
+		}))
+		assertErrorExists(t, err, false)
+
+		act := unmarshal(b, new(events.APIGatewayV2HTTPResponse)).(*events.APIGatewayV2HTTPResponse)
+		assertDeepEqual(t, act.StatusCode, http.StatusNoContent)
+	})
+
+	t.Run("should panic if the secret is empty", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got nil, expected panic")
+			}
+		}()
+
+		rack.VerifyWebhook(rack.VerifyWebhookOptions{})
+	})
+}
@@ -0,0 +1,121 @@
+package rack
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tidwall/gjson"
+)
+
+// WebSocketAuthOptions represents websocket $connect authentication options
+type WebSocketAuthOptions struct {
+	// QueryParam is the token query string parameter, defaulting to token
+	QueryParam string
+
+	// Protocol is the subprotocol that precedes the token in the
+	// Sec-WebSocket-Protocol header, defaulting to bearer
+	Protocol string
+
+	// Authenticate validates the token, typically by verifying a JWT
+	// Returned status errors are written as-is, all other errors result in a 403.
+	Authenticate func(c Context, token string) error
+}
+
+// WebSocketConnectRoute is the websocket $connect route key
+const WebSocketConnectRoute = "$connect"
+
+// ErrNoWebSocketToken indicates that the $connect request does not contain a token
+var ErrNoWebSocketToken = errors.New("websocket token not specified")
+
+// WebSocketRequestContext returns the websocket request context for the request
+// False is returned if the invocation payload is not a websocket event.
+func WebSocketRequestContext(c Context) (events.APIGatewayWebsocketProxyRequestContext, bool) {
+	var rc events.APIGatewayWebsocketProxyRequestContext
+
+	r := gjson.GetBytes(RawEvent(c), "requestContext")
+	if !r.Get("connectionId").Exists() {
+		return rc, false
+	}
+
+	if err := json.Unmarshal([]byte(r.Raw), &rc); err != nil {
+		return rc, false
+	}
+
+	return rc, true
+}
+
+// WebSocketToken returns the $connect token and the subprotocol it was sent with
+// The token is read from the query string parameter, falling back to the value
+// following the protocol in the Sec-WebSocket-Protocol header. Browsers cannot set
+// custom headers on websocket requests, so these are the conventional locations.
+func WebSocketToken(c Context, o WebSocketAuthOptions) (token, protocol string) {
+	param, proto := o.QueryParam, o.Protocol
+	if param == "" {
+		param = "token"
+	}
+	if proto == "" {
+		proto = "bearer"
+	}
+
+	if t := c.Query(param); t != "" {
+		return t, ""
+	}
+
+	var ps []string
+	for _, v := range c.Request().Header.Values("Sec-WebSocket-Protocol") {
+		for _, p := range strings.Split(v, ",") {
+			ps = append(ps, strings.TrimSpace(p))
+		}
+	}
+
+	for i := 0; i < len(ps)-1; i++ {
+		if ps[i] == proto {
+			return ps[i+1], proto
+		}
+	}
+
+	return "", ""
+}
+
+// WebSocketAuth returns a middleware func that authenticates websocket connections
+// Only $connect requests are authenticated. Connections without a token are denied
+// with a 401, and those that fail authentication with a 403. If the token was sent
+// as a subprotocol then the protocol is selected in the response, as required by
+// browsers to complete the handshake. The func panics if no authenticate func is specified.
+func WebSocketAuth(o WebSocketAuthOptions) MiddlewareFunc {
+	if o.Authenticate == nil {
+		panic("rack: websocket auth requires an authenticate func")
+	}
+
+	return func(n HandlerFunc) HandlerFunc {
+		return func(c Context) error {
+			rc, ok := WebSocketRequestContext(c)
+			if !ok || rc.RouteKey != WebSocketConnectRoute {
+				return n(c)
+			}
+
+			t, p := WebSocketToken(c, o)
+			if t == "" {
+				return WrapError(http.StatusUnauthorized, ErrNoWebSocketToken)
+			}
+
+			if err := o.Authenticate(c, t); err != nil {
+				var se statusError
+				if errors.As(err, &se) {
+					return err
+				}
+
+				return WrapError(http.StatusForbidden, err)
+			}
+
+			if p != "" {
+				c.Response().Headers.Set("Sec-WebSocket-Protocol", p)
+			}
+
+			return n(c)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package rack_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+)
+
+func TestWebSocketRequestContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		exp     string
+		ok      bool
+	}{
+		{
+			name:    "should return false for non-websocket events",
+			payload: newV2Request(nil),
+		},
+		{
+			name: "should return the request context",
+			payload: newWebSocketRequest(func(r *events.APIGatewayWebsocketProxyRequest) {
+				r.RequestContext.RouteKey = "$default"
+			}),
+			exp: "$default",
+			ok:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := rack.New(func(c rack.Context) error {
+				rc, ok := rack.WebSocketRequestContext(c)
+				if ok != tt.ok {
+					t.Errorf("got %v, expected %v", ok, tt.ok)
+				}
+				if rc.RouteKey != tt.exp {
+					t.Errorf("got %s, expected %s", rc.RouteKey, tt.exp)
+				}
+				return nil
+			})
+
+			_, err := h.Invoke(context.Background(), tt.payload)
+			assertErrorExists(t, err, false)
+		})
+	}
+}
+
+func TestWebSocketAuth(t *testing.T) {
+	authenticate := func(_ rack.Context, token string) error {
+		switch token {
+		case "valid":
+			return nil
+		case "expired":
+			return rack.WrapError(http.StatusUnauthorized, errors.New("token expired"))
+		}
+		return errors.New("invalid token")
+	}
+
+	tests := []struct {
+		name     string
+		opts     rack.WebSocketAuthOptions
+		fn       func(*events.APIGatewayWebsocketProxyRequest)
+		status   int
+		protocol string
+	}{
+		{
+			name: "should ignore non-connect routes",
+			fn: func(r *events.APIGatewayWebsocketProxyRequest) {
+				r.RequestContext.RouteKey = "$default"
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "should deny connections without a token",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "should deny invalid tokens",
+			fn: func(r *events.APIGatewayWebsocketProxyRequest) {
+				r.MultiValueQueryStringParameters = map[string][]string{"token": {"invalid"}}
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "should return status errors",
+			fn: func(r *events.APIGatewayWebsocketProxyRequest) {
+				r.MultiValueQueryStringParameters = map[string][]string{"token": {"expired"}}
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "should allow query string tokens",
+			fn: func(r *events.APIGatewayWebsocketProxyRequest) {
+				r.MultiValueQueryStringParameters = map[string][]string{"token": {"valid"}}
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "should use the configured query string parameter",
+			opts: rack.WebSocketAuthOptions{QueryParam: "access_token"},
+			fn: func(r *events.APIGatewayWebsocketProxyRequest) {
+				r.MultiValueQueryStringParameters = map[string][]string{"access_token": {"valid"}}
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "should allow subprotocol tokens",
+			fn: func(r *events.APIGatewayWebsocketProxyRequest) {
+				r.MultiValueHeaders = map[string][]string{"Sec-WebSocket-Protocol": {"bearer, valid"}}
+			},
+			status:   http.StatusOK,
+			protocol: "bearer",
+		},
+		{
+			name: "should use the configured subprotocol",
+			opts: rack.WebSocketAuthOptions{Protocol: "token"},
+			fn: func(r *events.APIGatewayWebsocketProxyRequest) {
+				r.MultiValueHeaders = map[string][]string{"Sec-WebSocket-Protocol": {"chat", "token, valid"}}
+			},
+			status:   http.StatusOK,
+			protocol: "token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Authenticate = authenticate
+
+			h := rack.NewWithConfig(rack.Config{
+				Middleware: rack.WebSocketAuth(tt.opts),
+			}, func(c rack.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			b, err := h.Invoke(context.Background(), newWebSocketRequest(tt.fn))
+			assertErrorExists(t, err, false)
+
+			act := new(events.APIGatewayProxyResponse)
+			unmarshal(b, act)
+
+			if act.StatusCode != tt.status {
+				t.Errorf("got %d, expected %d", act.StatusCode, tt.status)
+			}
+			if p := act.Headers["Sec-Websocket-Protocol"]; p != tt.protocol {
+				t.Errorf("got %s, expected %s", p, tt.protocol)
+			}
+		})
+	}
+
+	t.Run("should panic if no authenticate func is specified", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Error("got nil, expected panic")
+			}
+		}()
+
+		rack.WebSocketAuth(rack.WebSocketAuthOptions{})
+	})
+}
+
+func newWebSocketRequest(fn func(*events.APIGatewayWebsocketProxyRequest)) []byte {
+	r := &events.APIGatewayWebsocketProxyRequest{
+		RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+			APIID:        "apiid",
+			ConnectionID: "connectionid",
+			DomainName:   "abc.execute-api.eu-west-1.amazonaws.com",
+			Stage:        "prod",
+			RouteKey:     "$connect",
+		},
+	}
+
+	if fn != nil {
+		fn(r)
+	}
+
+	return marshal(r)
+}
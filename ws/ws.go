@@ -0,0 +1,142 @@
+// Package ws provides API Gateway websocket connection management helpers
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/stevecallear/rack"
+)
+
+type (
+	// ManagementAPI represents an API Gateway management API client
+	// Implementations would typically wrap the AWS SDK apigatewaymanagementapi
+	// client, using the endpoint as the base endpoint and returning
+	// rack.ErrConnectionGone for GoneException errors.
+	ManagementAPI interface {
+		PostToConnection(ctx context.Context, endpoint, connectionID string, data []byte) error
+		DeleteConnection(ctx context.Context, endpoint, connectionID string) error
+	}
+
+	// Client represents a management API client for a single callback endpoint
+	// Client can be used as the rack.ConnectionPoster for rack.Topics.
+	Client struct {
+		endpoint string
+		api      ManagementAPI
+	}
+)
+
+const managementAPIKey = "rack.wsManagementAPI"
+
+var (
+	// ErrNoEndpoint indicates that the callback endpoint cannot be derived from the request
+	ErrNoEndpoint = errors.New("ws: callback endpoint not available")
+
+	// ErrNoManagementAPI indicates that no management API client has been configured
+	ErrNoManagementAPI = errors.New("ws: no management api configured")
+)
+
+// NewClient returns a new management API client for the specified callback endpoint
+// The endpoint is the websocket API url including the stage, for example
+// https://abc123.execute-api.eu-west-1.amazonaws.com/prod.
+func NewClient(endpoint string, api ManagementAPI) *Client {
+	return &Client{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		api:      api,
+	}
+}
+
+// PostToConnection posts the data to the specified connection
+// rack.ErrConnectionGone is returned if the connection has been closed.
+func (c *Client) PostToConnection(ctx context.Context, connectionID string, data []byte) error {
+	return c.api.PostToConnection(ctx, c.endpoint, connectionID, data)
+}
+
+// Disconnect closes the specified connection
+// rack.ErrConnectionGone is returned if the connection has already been closed.
+func (c *Client) Disconnect(ctx context.Context, connectionID string) error {
+	return c.api.DeleteConnection(ctx, c.endpoint, connectionID)
+}
+
+// WithManagementAPI returns a middleware func that configures the management API client
+// The client is used by PostToConnection and Disconnect.
+func WithManagementAPI(api ManagementAPI) rack.MiddlewareFunc {
+	return func(n rack.HandlerFunc) rack.HandlerFunc {
+		return func(c rack.Context) error {
+			c.Set(managementAPIKey, api)
+			return n(c)
+		}
+	}
+}
+
+// Endpoint returns the callback endpoint for the websocket request
+// The endpoint is derived from the api id, stage and AWS_REGION environment variable
+// rather than the request domain name, as custom domain api mappings do not have to
+// match the stage.
+func Endpoint(c rack.Context) (string, error) {
+	rc, ok := rack.WebSocketRequestContext(c)
+	region := os.Getenv("AWS_REGION")
+	if !ok || rc.APIID == "" || rc.Stage == "" || region == "" {
+		return "", ErrNoEndpoint
+	}
+
+	domain := "amazonaws.com"
+	if strings.HasPrefix(region, "cn-") {
+		domain += ".cn"
+	}
+
+	return "https://" + rc.APIID + ".execute-api." + region + "." + domain + "/" + rc.Stage, nil
+}
+
+// PostToConnection posts the payload to the specified connection
+// The callback endpoint is derived from the request. The payload is sent as-is if
+// it is a string or byte slice, otherwise it is marshaled as JSON.
+func PostToConnection(c rack.Context, connectionID string, payload interface{}) error {
+	cl, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+
+	var b []byte
+	switch t := payload.(type) {
+	case string:
+		b = []byte(t)
+	case []byte:
+		b = t
+	default:
+		if b, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	return cl.PostToConnection(c.Context(), connectionID, b)
+}
+
+// Disconnect closes the specified connection
+// The callback endpoint is derived from the request.
+func Disconnect(c rack.Context, connectionID string) error {
+	cl, err := contextClient(c)
+	if err != nil {
+		return err
+	}
+
+	return cl.Disconnect(c.Context(), connectionID)
+}
+
+// contextClient returns a client for the configured management API and request endpoint
+func contextClient(c rack.Context) (*Client, error) {
+	api, ok := c.Get(managementAPIKey).(ManagementAPI)
+	if !ok || api == nil {
+		return nil, ErrNoManagementAPI
+	}
+
+	e, err := Endpoint(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewClient(e, api), nil
+}
@@ -0,0 +1,270 @@
+package ws_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/stevecallear/rack"
+	"github.com/stevecallear/rack/ws"
+)
+
+func TestClient(t *testing.T) {
+	tests := []struct {
+		name   string
+		fn     func(*ws.Client) error
+		exp    call
+		apiErr error
+	}{
+		{
+			name: "should post to the connection",
+			fn: func(c *ws.Client) error {
+				return c.PostToConnection(context.Background(), "abc=", []byte("data"))
+			},
+			exp: call{op: "post", endpoint: "https://api/prod", connectionID: "abc=", data: "data"},
+		},
+		{
+			name: "should disconnect the connection",
+			fn: func(c *ws.Client) error {
+				return c.Disconnect(context.Background(), "abc=")
+			},
+			exp: call{op: "delete", endpoint: "https://api/prod", connectionID: "abc="},
+		},
+		{
+			name: "should return api errors",
+			fn: func(c *ws.Client) error {
+				return c.PostToConnection(context.Background(), "abc=", []byte("data"))
+			},
+			exp:    call{op: "post", endpoint: "https://api/prod", connectionID: "abc=", data: "data"},
+			apiErr: rack.ErrConnectionGone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &mockAPI{err: tt.apiErr}
+
+			err := tt.fn(ws.NewClient("https://api/prod/", api))
+			if !errors.Is(err, tt.apiErr) {
+				t.Errorf("got %v, expected %v", err, tt.apiErr)
+			}
+
+			if len(api.calls) != 1 || api.calls[0] != tt.exp {
+				t.Errorf("got %+v, expected %+v", api.calls, tt.exp)
+			}
+		})
+	}
+}
+
+func TestPostToConnection(t *testing.T) {
+	payload := marshal(&events.APIGatewayWebsocketProxyRequest{
+		RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+			APIID:        "abc",
+			ConnectionID: "connectionid",
+			DomainName:   "abc.execute-api.eu-west-1.amazonaws.com",
+			Stage:        "prod",
+		},
+	})
+
+	const endpoint = "https://abc.execute-api.eu-west-1.amazonaws.com/prod"
+	t.Setenv("AWS_REGION", "eu-west-1")
+
+	tests := []struct {
+		name    string
+		api     ws.ManagementAPI
+		payload interface{}
+		exp     []call
+		err     error
+	}{
+		{
+			name: "should return an error if no management api is configured",
+			err:  ws.ErrNoManagementAPI,
+		},
+		{
+			name:    "should post strings",
+			api:     &mockAPI{},
+			payload: "data",
+			exp:     []call{{op: "post", endpoint: endpoint, connectionID: "target", data: "data"}},
+		},
+		{
+			name:    "should post json values",
+			api:     &mockAPI{},
+			payload: map[string]string{"a": "b"},
+			exp:     []call{{op: "post", endpoint: endpoint, connectionID: "target", data: `{"a":"b"}`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := rack.Config{}
+			if tt.api != nil {
+				cfg.Middleware = ws.WithManagementAPI(tt.api)
+			}
+
+			h := rack.NewWithConfig(cfg, func(c rack.Context) error {
+				if err := ws.PostToConnection(c, "target", tt.payload); !errors.Is(err, tt.err) {
+					t.Errorf("got %v, expected %v", err, tt.err)
+				}
+				return nil
+			})
+
+			if _, err := h.Invoke(context.Background(), payload); err != nil {
+				t.Fatal(err)
+			}
+
+			if api, ok := tt.api.(*mockAPI); ok && !reflect.DeepEqual(api.calls, tt.exp) {
+				t.Errorf("got %+v, expected %+v", api.calls, tt.exp)
+			}
+		})
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-west-1")
+	api := &mockAPI{}
+
+	h := rack.NewWithConfig(rack.Config{
+		Middleware: ws.WithManagementAPI(api),
+	}, func(c rack.Context) error {
+		return ws.Disconnect(c, "target")
+	})
+
+	_, err := h.Invoke(context.Background(), marshal(&events.APIGatewayWebsocketProxyRequest{
+		RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+			APIID:        "abc",
+			ConnectionID: "connectionid",
+			DomainName:   "abc.execute-api.eu-west-1.amazonaws.com",
+			Stage:        "prod",
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := []call{{op: "delete", endpoint: "https://abc.execute-api.eu-west-1.amazonaws.com/prod", connectionID: "target"}}
+	if !reflect.DeepEqual(api.calls, exp) {
+		t.Errorf("got %+v, expected %+v", api.calls, exp)
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		region  string
+		payload []byte
+		exp     string
+		err     bool
+	}{
+		{
+			name:    "should return an error for non-websocket requests",
+			payload: []byte(`{"version":"2.0","requestContext":{"apiId":"apiid"}}`),
+			err:     true,
+		},
+		{
+			name: "should return an error if the region is not available",
+			payload: marshal(&events.APIGatewayWebsocketProxyRequest{
+				RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+					APIID:        "abc",
+					ConnectionID: "connectionid",
+					Stage:        "prod",
+				},
+			}),
+			err: true,
+		},
+		{
+			name:   "should return the callback endpoint",
+			region: "eu-west-1",
+			payload: marshal(&events.APIGatewayWebsocketProxyRequest{
+				RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+					APIID:        "abc",
+					ConnectionID: "connectionid",
+					DomainName:   "abc.execute-api.eu-west-1.amazonaws.com",
+					Stage:        "prod",
+				},
+			}),
+			exp: "https://abc.execute-api.eu-west-1.amazonaws.com/prod",
+		},
+		{
+			name:   "should ignore custom domain names",
+			region: "eu-west-1",
+			payload: marshal(&events.APIGatewayWebsocketProxyRequest{
+				RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+					APIID:        "abc",
+					ConnectionID: "connectionid",
+					DomainName:   "ws.example.com",
+					Stage:        "prod",
+				},
+			}),
+			exp: "https://abc.execute-api.eu-west-1.amazonaws.com/prod",
+		},
+		{
+			name:   "should return china region endpoints",
+			region: "cn-north-1",
+			payload: marshal(&events.APIGatewayWebsocketProxyRequest{
+				RequestContext: events.APIGatewayWebsocketProxyRequestContext{
+					APIID:        "abc",
+					ConnectionID: "connectionid",
+					Stage:        "prod",
+				},
+			}),
+			exp: "https://abc.execute-api.cn-north-1.amazonaws.com.cn/prod",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AWS_REGION", tt.region)
+
+			h := rack.New(func(c rack.Context) error {
+				act, err := ws.Endpoint(c)
+				if (err != nil) != tt.err {
+					t.Errorf("got %v, expected error %v", err, tt.err)
+				}
+				if act != tt.exp {
+					t.Errorf("got %s, expected %s", act, tt.exp)
+				}
+				return nil
+			})
+
+			if _, err := h.Invoke(context.Background(), tt.payload); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func marshal(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+type (
+	call struct {
+		op           string
+		endpoint     string
+		connectionID string
+		data         string
+	}
+
+	mockAPI struct {
+		calls []call
+		err   error
+	}
+)
+
+func (a *mockAPI) PostToConnection(ctx context.Context, endpoint, connectionID string, data []byte) error {
+	a.calls = append(a.calls, call{op: "post", endpoint: endpoint, connectionID: connectionID, data: string(data)})
+	return a.err
+}
+
+func (a *mockAPI) DeleteConnection(ctx context.Context, endpoint, connectionID string) error {
+	a.calls = append(a.calls, call{op: "delete", endpoint: endpoint, connectionID: connectionID})
+	return a.err
+}